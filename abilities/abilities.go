@@ -0,0 +1,200 @@
+package abilities
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"tribble/classes"
+	"tribble/combat"
+	"tribble/data"
+	"tribble/levels"
+	"tribble/settings"
+)
+
+const (
+	// TargetEnemy abilities are cast on a monster in range, hitting the
+	// monsters within Radius of it.
+	TargetEnemy = "enemy"
+	// TargetSelf abilities are cast on the caster, reaching the players
+	// within Radius of it.
+	TargetSelf = "self"
+)
+
+var (
+	ErrUnknownAbility = errors.New("unknown ability")
+	ErrAbilityLocked  = errors.New("ability not unlocked")
+	ErrOnCooldown     = errors.New("ability on cooldown")
+	ErrNotEnoughMana  = errors.New("not enough mana")
+	ErrInvalidTarget  = errors.New("invalid target")
+	ErrOutOfRange     = errors.New("target out of range")
+	ErrDead           = errors.New("caster is dead")
+)
+
+type Ability struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Class      string       `json:"class"`
+	Level      int          `json:"level"`
+	Target     string       `json:"target"`
+	Range      int          `json:"range,omitempty"`
+	Radius     int          `json:"radius,omitempty"`
+	Mana       int          `json:"mana"`
+	CooldownMS int          `json:"cooldown_ms"`
+	Effects    []EffectSpec `json:"effects"`
+
+	effects []Effect
+}
+
+func (a *Ability) Cooldown() time.Duration {
+	return time.Duration(a.CooldownMS) * time.Millisecond
+}
+
+// Unlocked tells whether a player of the class and level can use the
+// ability.
+func (a *Ability) Unlocked(class string, level int) bool {
+	return a.Class == class && a.Level <= level
+}
+
+// Check validates a cast by the caster before its targets are looked at,
+// readyAt being the tick the ability of the caster is off cooldown.
+func (a *Ability) Check(caster *combat.Combatant, tick, readyAt uint64) error {
+	switch {
+	case !caster.Alive():
+		return ErrDead
+	case !a.Unlocked(caster.Class, caster.Level):
+		return ErrAbilityLocked
+	case tick < readyAt:
+		return ErrOnCooldown
+	case caster.Mana < a.Mana:
+		return ErrNotEnoughMana
+	}
+	return nil
+}
+
+// Apply runs every effect of the ability on every target, in order.
+func (a *Ability) Apply(cast *Cast, targets []*combat.Combatant) []Outcome {
+	outcomes := make([]Outcome, 0, len(targets)*len(a.effects))
+	for _, target := range targets {
+		for i, effect := range a.effects {
+			outcome := effect.Apply(cast, target)
+			outcome.Effect = a.Effects[i].Type
+			outcome.Target = target.Ref
+			outcomes = append(outcomes, outcome)
+		}
+	}
+	return outcomes
+}
+
+func (a *Ability) validate() error {
+	if _, ok := classes.Default.Get(a.Class); !ok {
+		return fmt.Errorf("ability %v has unknown class %v", a.ID, a.Class)
+	}
+	if a.Level < 1 || a.Level > levels.Default.MaxLevel() {
+		return fmt.Errorf("ability %v has invalid level %v", a.ID, a.Level)
+	}
+	if a.Target != TargetEnemy && a.Target != TargetSelf {
+		return fmt.Errorf("ability %v has invalid target %v", a.ID, a.Target)
+	}
+	if a.Target == TargetEnemy && a.Range < 1 {
+		return fmt.Errorf("ability %v has no range", a.ID)
+	}
+	if a.Range < 0 || a.Radius < 0 || a.Mana < 0 || a.CooldownMS < 0 {
+		return fmt.Errorf("ability %v has negative values", a.ID)
+	}
+	if len(a.Effects) == 0 {
+		return fmt.Errorf("ability %v has no effects", a.ID)
+	}
+	a.effects = make([]Effect, len(a.Effects))
+	for i, spec := range a.Effects {
+		effect, err := newEffect(spec)
+		if err != nil {
+			return fmt.Errorf("ability %v: %w", a.ID, err)
+		}
+		if effect.Hostile() != (a.Target == TargetEnemy) {
+			return fmt.Errorf("ability %v has a %v effect on the wrong target", a.ID, spec.Type)
+		}
+		a.effects[i] = effect
+	}
+	return nil
+}
+
+type Catalog struct {
+	abilities []*Ability
+	byID      map[string]*Ability
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Abilities []*Ability `json:"abilities"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Abilities) == 0 {
+		return nil, errors.New("ability catalog is empty")
+	}
+
+	catalog := &Catalog{abilities: file.Abilities, byID: make(map[string]*Ability)}
+	for _, a := range file.Abilities {
+		if a.ID == "" {
+			return nil, errors.New("ability without id")
+		}
+		if _, ok := catalog.byID[a.ID]; ok {
+			return nil, fmt.Errorf("duplicated ability %v", a.ID)
+		}
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+		catalog.byID[a.ID] = a
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Ability, bool) {
+	a, ok := c.byID[ID]
+	return a, ok
+}
+
+// List returns the abilities of the class, or every ability when class is
+// empty.
+func (c *Catalog) List(class string) []*Ability {
+	if class == "" {
+		return c.abilities
+	}
+	abilities := make([]*Ability, 0)
+	for _, a := range c.abilities {
+		if a.Class == class {
+			abilities = append(abilities, a)
+		}
+	}
+	return abilities
+}
+
+// Unlocked returns the abilities a player of the class and level can use.
+func (c *Catalog) Unlocked(class string, level int) []*Ability {
+	abilities := make([]*Ability, 0)
+	for _, a := range c.abilities {
+		if a.Unlocked(class, level) {
+			abilities = append(abilities, a)
+		}
+	}
+	return abilities
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("abilities.json", settings.AbilityCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open ability catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load ability catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,140 @@
+package abilities
+
+import (
+	"strings"
+	"testing"
+	"tribble/combat"
+	"tribble/models"
+	"tribble/status"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func caster(class string, level, mana int) *combat.Combatant {
+	return &combat.Combatant{
+		Ref:       world.Ref{Kind: world.KindPlayer, ID: 1},
+		Level:     level,
+		Class:     class,
+		Attribute: models.Intellect,
+		Stats:     models.Stats{HP: 100, Mana: 100, Intellect: 20},
+		HP:        100,
+		Mana:      mana,
+	}
+}
+
+func TestUnlocked(t *testing.T) {
+	for _, a := range Default.Unlocked("warrior", 3) {
+		if a.Class != "warrior" || a.Level > 3 {
+			t.Errorf("%s FAILED: %v unlocked for a level 3 warrior", t.Name(), a.ID)
+		}
+	}
+	assert.Equal(t, len(Default.Unlocked("warrior", 3)), 2)
+	assert.Equal(t, len(Default.List("warrior")), 3)
+}
+
+func TestCheck(t *testing.T) {
+	a, _ := Default.Get("battle_shout")
+	dead := caster("warrior", 3, 100)
+	dead.HP = 0
+	tests := []struct {
+		caster  *combat.Combatant
+		readyAt uint64
+		err     error
+	}{
+		{caster("warrior", 3, 100), 0, nil},
+		{caster("warrior", 3, 100), 11, ErrOnCooldown},
+		{caster("warrior", 2, 100), 0, ErrAbilityLocked},
+		{caster("mage", 10, 100), 0, ErrAbilityLocked},
+		{caster("warrior", 3, a.Mana-1), 0, ErrNotEnoughMana},
+		{dead, 0, ErrDead},
+	}
+	for _, test := range tests {
+		if err := a.Check(test.caster, 10, test.readyAt); err != test.err {
+			t.Errorf("%s FAILED: got %v want %v", t.Name(), err, test.err)
+		}
+	}
+}
+
+func TestEffects(t *testing.T) {
+	config := combat.DefaultConfig
+	config.BaseEvasion, config.EvasionPerAgility = 0, 0
+	afflicted := make(map[world.Ref]string)
+	cast := func(a *Ability, c *combat.Combatant) *Cast {
+		return &Cast{
+			Ability:  a,
+			Caster:   c,
+			Resolver: combat.NewResolver(1, config),
+			AddEffect: func(target *combat.Combatant, d *status.Definition) {
+				afflicted[target.Ref] = d.ID
+			},
+		}
+	}
+	goblin := &combat.Combatant{Ref: world.Ref{Kind: world.KindMonster, ID: 7}, Level: 1, Stats: models.Stats{HP: 500}, HP: 500}
+
+	nova, _ := Default.Get("frost_nova")
+	mage := caster("mage", 4, 100)
+	outcomes := nova.Apply(cast(nova, mage), []*combat.Combatant{goblin})
+	assert.Equal(t, len(outcomes), 2)
+	assert.Equal(t, outcomes[0].Effect, "damage")
+	assert.Equal(t, outcomes[0].Target, goblin.Ref)
+	assert.Equal(t, goblin.HP, 500-outcomes[0].Damage)
+	assert.Equal(t, outcomes[1].Effect, "debuff")
+	assert.Equal(t, outcomes[1].Status, "chilled")
+	assert.Equal(t, afflicted[goblin.Ref], "chilled")
+
+	heal, _ := Default.Get("lay_on_hands")
+	templar := caster("templar", 3, 100)
+	templar.Stats.HP, templar.HP = 300, 10
+	outcomes = heal.Apply(cast(heal, templar), []*combat.Combatant{templar})
+	assert.Equal(t, outcomes[0].Healed, 60+2*20)
+	assert.Equal(t, templar.HP, 110)
+	assert.Equal(t, afflicted[templar.Ref], "renewal")
+	// healing stops at the maximum
+	templar.HP = 290
+	outcomes = heal.Apply(cast(heal, templar), []*combat.Combatant{templar})
+	assert.Equal(t, outcomes[0].Healed, 10)
+	assert.Equal(t, templar.HP, 300)
+}
+
+type drain struct{}
+
+func (drain) Hostile() bool { return true }
+
+func (drain) Apply(cast *Cast, target *combat.Combatant) Outcome {
+	target.Mana = 0
+	return Outcome{Result: combat.Result{TargetHP: target.HP}}
+}
+
+func TestRegisterEffect(t *testing.T) {
+	RegisterEffect("drain", func(spec EffectSpec) (Effect, error) {
+		return drain{}, nil
+	})
+	catalog, err := Load(strings.NewReader(`{"abilities": [{"id": "drain", "class": "mage", "level": 1, "target": "enemy", "range": 3, "effects": [{"type": "drain"}]}]}`))
+	if err != nil {
+		t.Fatalf("%s FAILED: %v", t.Name(), err)
+	}
+	a, _ := catalog.Get("drain")
+	target := caster("warrior", 1, 50)
+	a.Apply(&Cast{Ability: a}, []*combat.Combatant{target})
+	assert.Equal(t, target.Mana, 0)
+}
+
+func TestInvalidAbilities(t *testing.T) {
+	tests := []string{
+		`{"abilities": []}`,
+		`{"abilities": [{"id": "a", "class": "bard", "level": 1, "target": "enemy", "range": 1, "effects": [{"type": "damage", "power": 1}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "enemy", "range": 0, "effects": [{"type": "damage", "power": 1}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "ally", "effects": [{"type": "heal", "amount": 1}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "self", "effects": [{"type": "damage", "power": 1}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "self", "effects": [{"type": "buff", "status": "poison"}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "self", "effects": [{"type": "buff", "status": "unknown"}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "enemy", "range": 1, "effects": [{"type": "teleport"}]}]}`,
+		`{"abilities": [{"id": "a", "class": "mage", "level": 1, "target": "enemy", "range": 1, "effects": []}]}`,
+	}
+	for _, test := range tests {
+		if _, err := Load(strings.NewReader(test)); err == nil {
+			t.Errorf("%s FAILED: %v loaded", t.Name(), test)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package abilities
+
+import (
+	"fmt"
+	"math"
+	"tribble/combat"
+	"tribble/status"
+	"tribble/world"
+)
+
+// EffectSpec is an effect as defined in the ability data, which fields
+// are used depends on its type.
+type EffectSpec struct {
+	Type   string  `json:"type"`
+	Power  float64 `json:"power,omitempty"`
+	Amount int     `json:"amount,omitempty"`
+	// Status is the status effect buffs and debuffs apply.
+	Status string `json:"status,omitempty"`
+}
+
+// Cast is an ability being cast, what its effects act through. The
+// simulation loop provides it, tests can fake it.
+type Cast struct {
+	Ability  *Ability
+	Caster   *combat.Combatant
+	Resolver *combat.Resolver
+	// AddEffect applies a status effect to the target.
+	AddEffect func(target *combat.Combatant, d *status.Definition)
+}
+
+// Outcome is what an effect did to a target.
+type Outcome struct {
+	Effect string    `json:"effect"`
+	Target world.Ref `json:"target"`
+	combat.Result
+	Healed int    `json:"healed,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+type Effect interface {
+	// Hostile effects are cast on enemies, the others on the caster and
+	// its allies.
+	Hostile() bool
+	Apply(cast *Cast, target *combat.Combatant) Outcome
+}
+
+// Factory builds an effect from its spec, rejecting invalid specs.
+type Factory func(spec EffectSpec) (Effect, error)
+
+var factories = map[string]Factory{
+	"damage": newDamage,
+	"heal":   newHeal,
+	"buff":   newStatus,
+	"debuff": newStatus,
+}
+
+// RegisterEffect makes the effect type usable by the abilities loaded
+// afterwards.
+func RegisterEffect(kind string, factory Factory) {
+	factories[kind] = factory
+}
+
+func newEffect(spec EffectSpec) (Effect, error) {
+	factory, ok := factories[spec.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown effect %v", spec.Type)
+	}
+	return factory(spec)
+}
+
+// damage hits for Power times the base damage of the caster, resolved
+// like a basic attack.
+type damage struct {
+	power float64
+}
+
+func newDamage(spec EffectSpec) (Effect, error) {
+	if spec.Power <= 0 {
+		return nil, fmt.Errorf("damage effect without power")
+	}
+	return damage{spec.Power}, nil
+}
+
+func (d damage) Hostile() bool {
+	return true
+}
+
+func (d damage) Apply(cast *Cast, target *combat.Combatant) Outcome {
+	return Outcome{Result: cast.Resolver.Hit(cast.Caster, target, combat.BaseDamage(cast.Caster)*d.power)}
+}
+
+// heal restores Amount plus Power per intellect of the caster.
+type heal struct {
+	amount int
+	power  float64
+}
+
+func newHeal(spec EffectSpec) (Effect, error) {
+	if spec.Amount < 0 || spec.Power < 0 || spec.Amount == 0 && spec.Power == 0 {
+		return nil, fmt.Errorf("heal effect without amount")
+	}
+	return heal{spec.Amount, spec.Power}, nil
+}
+
+func (h heal) Hostile() bool {
+	return false
+}
+
+func (h heal) Apply(cast *Cast, target *combat.Combatant) Outcome {
+	if !target.Alive() {
+		return Outcome{Result: combat.Result{TargetHP: target.HP}}
+	}
+	amount := h.amount + int(math.Round(h.power*float64(cast.Caster.Stats.Intellect)))
+	if amount > target.Stats.HP-target.HP {
+		amount = target.Stats.HP - target.HP
+	}
+	target.HP += amount
+	return Outcome{Healed: amount, Result: combat.Result{TargetHP: target.HP}}
+}
+
+// statusEffect applies a status effect of the same type, buffs to the
+// caster and its allies and debuffs to enemies.
+type statusEffect struct {
+	definition *status.Definition
+}
+
+func newStatus(spec EffectSpec) (Effect, error) {
+	d, ok := status.Default.Get(spec.Status)
+	if !ok {
+		return nil, fmt.Errorf("%v effect with unknown status %v", spec.Type, spec.Status)
+	}
+	if d.Type != spec.Type {
+		return nil, fmt.Errorf("%v effect with %v status %v", spec.Type, d.Type, d.ID)
+	}
+	return statusEffect{d}, nil
+}
+
+func (s statusEffect) Hostile() bool {
+	return s.definition.Type == status.TypeDebuff
+}
+
+func (s statusEffect) Apply(cast *Cast, target *combat.Combatant) Outcome {
+	if target.Alive() {
+		cast.AddEffect(target, s.definition)
+	}
+	return Outcome{Status: s.definition.ID, Result: combat.Result{TargetHP: target.HP}}
+}
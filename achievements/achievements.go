@@ -0,0 +1,230 @@
+package achievements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"tribble/data"
+	"tribble/events"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/monsters"
+	"tribble/settings"
+)
+
+const (
+	Created     = "created"
+	Level       = "level"
+	Kills       = "kills"
+	Quests      = "quests"
+	LoginStreak = "login_streak"
+)
+
+// Achievement is unlocked once its count is reached. Kills and quests
+// count events, levels track the highest level and login streaks the
+// consecutive days a player connected. Target restricts kills to a monster.
+type Achievement struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Target      string `json:"target,omitempty"`
+	Count       int    `json:"count"`
+}
+
+const (
+	Add    = "add"
+	Raise  = "raise"
+	Streak = "streak"
+)
+
+// Update is how an event advances an achievement.
+type Update struct {
+	Mode   string
+	Amount int
+	Day    time.Time
+}
+
+func (a *Achievement) Update(e events.Event) (Update, bool) {
+	switch data := e.Data.(type) {
+	case events.MonsterKilledData:
+		if a.Type == Kills && (a.Target == "" || a.Target == data.Monster) {
+			return Update{Mode: Add, Amount: 1}, true
+		}
+	case events.LevelUpData:
+		if a.Type == Level {
+			return Update{Mode: Raise, Amount: data.To}, true
+		}
+	case events.XPGainedData:
+		if a.Type == Level {
+			return Update{Mode: Raise, Amount: levels.Default.Level(data.XP)}, true
+		}
+	case events.QuestCompletedData:
+		if a.Type == Quests {
+			return Update{Mode: Add, Amount: 1}, true
+		}
+	case events.PlayerConnectedData:
+		if a.Type == LoginStreak {
+			return Update{Mode: Streak, Day: e.Time.UTC()}, true
+		}
+	}
+	if e.Type == events.PlayerCreated && a.Type == Created {
+		return Update{Mode: Add, Amount: 1}, true
+	}
+	return Update{}, false
+}
+
+func (a *Achievement) validate() error {
+	if a.Count < 1 {
+		return fmt.Errorf("achievement %v has no count", a.ID)
+	}
+	switch a.Type {
+	case Created, Quests, LoginStreak:
+	case Level:
+		if a.Count > levels.Default.MaxLevel() {
+			return fmt.Errorf("achievement %v needs unreachable level %v", a.ID, a.Count)
+		}
+	case Kills:
+		if _, ok := monsters.Default.Get(a.Target); a.Target != "" && !ok {
+			return fmt.Errorf("achievement %v kills unknown monster %v", a.ID, a.Target)
+		}
+	default:
+		return fmt.Errorf("achievement %v has invalid type %v", a.ID, a.Type)
+	}
+	return nil
+}
+
+type Catalog struct {
+	achievements []*Achievement
+	byID         map[string]*Achievement
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Achievements []*Achievement `json:"achievements"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Achievements) == 0 {
+		return nil, errors.New("achievement catalog is empty")
+	}
+
+	catalog := &Catalog{achievements: file.Achievements, byID: make(map[string]*Achievement)}
+	for _, a := range file.Achievements {
+		if a.ID == "" {
+			return nil, errors.New("achievement without id")
+		}
+		if _, ok := catalog.byID[a.ID]; ok {
+			return nil, fmt.Errorf("duplicated achievement %v", a.ID)
+		}
+		if err := a.validate(); err != nil {
+			return nil, err
+		}
+		catalog.byID[a.ID] = a
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Achievement, bool) {
+	a, ok := c.byID[ID]
+	return a, ok
+}
+
+func (c *Catalog) List() []*Achievement {
+	return c.achievements
+}
+
+func mustLoad() *Catalog {
+	f, err := data.Open("achievements.json", settings.AchievementCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open achievement catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load achievement catalog: %v", err)
+	}
+	return catalog
+}
+
+var Default = mustLoad()
+
+// Status is an achievement with the progress of a player.
+type Status struct {
+	*Achievement
+	Progress   int        `json:"progress"`
+	UnlockedAt *time.Time `json:"unlocked_at"`
+}
+
+// Statuses lists every achievement of the catalog with the progress of the
+// player.
+func Statuses(catalog *Catalog, progress []*models.PlayerAchievement) []Status {
+	byID := make(map[string]*models.PlayerAchievement, len(progress))
+	for _, p := range progress {
+		byID[p.AchievementID] = p
+	}
+	statuses := make([]Status, 0, len(catalog.achievements))
+	for _, a := range catalog.achievements {
+		status := Status{Achievement: a}
+		if p, ok := byID[a.ID]; ok {
+			status.Progress = p.Progress
+			status.UnlockedAt = p.UnlockedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func apply(ctx context.Context, repo models.AchievementRepository, playerID int, a *Achievement, u Update) (bool, error) {
+	switch u.Mode {
+	case Raise:
+		return repo.RaiseAchievementProgress(ctx, playerID, a.ID, u.Amount, a.Count)
+	case Streak:
+		return repo.ExtendAchievementStreak(ctx, playerID, a.ID, u.Day, a.Count)
+	}
+	return repo.AddAchievementProgress(ctx, playerID, a.ID, u.Amount, a.Count)
+}
+
+// Track advances the achievements of the player of the event, publishing
+// AchievementUnlocked for the ones it unlocks.
+func Track(catalog *Catalog, repo models.AchievementRepository) events.Handler {
+	return func(e events.Event) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		progress, err := repo.GetAchievements(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not track achievements of player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		unlocked := make(map[string]bool, len(progress))
+		for _, p := range progress {
+			unlocked[p.AchievementID] = p.UnlockedAt != nil
+		}
+
+		for _, a := range catalog.achievements {
+			u, ok := a.Update(e)
+			if !ok || unlocked[a.ID] {
+				continue
+			}
+			done, err := apply(ctx, repo, e.PlayerID, a, u)
+			if err != nil {
+				log.Printf("could not track achievement %v of player %v: %v", a.ID, e.PlayerID, err.Error())
+				continue
+			}
+			if done {
+				events.Publish(events.Event{
+					Type:     events.AchievementUnlocked,
+					UserID:   e.UserID,
+					PlayerID: e.PlayerID,
+					Data:     events.AchievementUnlockedData{Achievement: a.ID},
+				})
+			}
+		}
+	}
+}
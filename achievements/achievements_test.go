@@ -0,0 +1,93 @@
+package achievements
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"tribble/events"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"no count":          `{"achievements": [{"id": "a", "type": "kills"}]}`,
+		"unknown monster":   `{"achievements": [{"id": "a", "type": "kills", "target": "dragon", "count": 1}]}`,
+		"unreachable level": `{"achievements": [{"id": "a", "type": "level", "count": 1000}]}`,
+		"unknown type":      `{"achievements": [{"id": "a", "type": "dance", "count": 1}]}`,
+		"duplicated":        `{"achievements": [{"id": "a", "type": "quests", "count": 1}, {"id": "a", "type": "quests", "count": 2}]}`,
+	}
+	for name, file := range tests {
+		if _, err := Load(strings.NewReader(file)); err == nil {
+			t.Errorf("%s FAILED: %s want error", t.Name(), name)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	hunter, _ := Default.Get("monster_hunter")
+	wolves, _ := Default.Get("pack_breaker")
+	streak, _ := Default.Get("dedicated")
+	seasoned, _ := Default.Get("seasoned")
+
+	kill := events.Event{Type: events.MonsterKilled, Data: events.MonsterKilledData{Monster: "goblin"}}
+	u, ok := hunter.Update(kill)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, u, Update{Mode: Add, Amount: 1})
+	_, ok = wolves.Update(kill)
+	assert.Equal(t, ok, false)
+
+	u, ok = seasoned.Update(events.Event{Type: events.LevelUp, Data: events.LevelUpData{From: 3, To: 4}})
+	assert.Equal(t, ok, true)
+	assert.Equal(t, u, Update{Mode: Raise, Amount: 4})
+
+	now := time.Date(2024, 3, 1, 23, 30, 0, 0, time.FixedZone("", -3*3600))
+	u, ok = streak.Update(events.Event{Type: events.PlayerConnected, Data: events.PlayerConnectedData{Map: "village"}, Time: now})
+	assert.Equal(t, ok, true)
+	assert.Equal(t, u.Day.Day(), 2)
+}
+
+type fakeRepo struct {
+	models.AchievementRepository
+	progress []*models.PlayerAchievement
+	added    map[string]int
+}
+
+func (r *fakeRepo) GetAchievements(ctx context.Context, playerID int) ([]*models.PlayerAchievement, error) {
+	return r.progress, nil
+}
+
+func (r *fakeRepo) AddAchievementProgress(ctx context.Context, playerID int, achievementID string, amount, goal int) (bool, error) {
+	r.added[achievementID] += amount
+	return r.added[achievementID] >= goal, nil
+}
+
+func TestTrack(t *testing.T) {
+	now := time.Now()
+	repo := &fakeRepo{
+		progress: []*models.PlayerAchievement{{AchievementID: "first_blood", Progress: 1, UnlockedAt: &now}},
+		added:    make(map[string]int),
+	}
+	unlocked := make([]string, 0)
+	events.Subscribe(events.AchievementUnlocked, func(e events.Event) {
+		unlocked = append(unlocked, e.Data.(events.AchievementUnlockedData).Achievement)
+	})
+
+	track := Track(Default, repo)
+	for i := 0; i < 25; i++ {
+		track(events.Event{Type: events.MonsterKilled, PlayerID: 1, Data: events.MonsterKilledData{Monster: "wolf"}})
+	}
+	// unlocked achievements are left alone
+	assert.Equal(t, repo.added, map[string]int{"monster_hunter": 25, "pack_breaker": 25})
+	assert.Equal(t, unlocked, []string{"pack_breaker"})
+
+	statuses := Statuses(Default, repo.progress)
+	assert.Equal(t, len(statuses), len(Default.List()))
+	for _, s := range statuses {
+		if s.ID == "first_blood" {
+			assert.Equal(t, s.UnlockedAt, &now)
+		}
+	}
+}
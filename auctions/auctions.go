@@ -0,0 +1,67 @@
+package auctions
+
+import (
+	"time"
+	"tribble/models"
+	"tribble/settings"
+)
+
+// Durations are the hours a listing can run.
+var Durations = []int{12, 24, 48}
+
+// Validate checks a new listing before its item is taken.
+func Validate(listing models.NewAuction) error {
+	if listing.StartBid < 1 || listing.Buyout < 0 {
+		return models.ErrInvalidAmount
+	}
+	if listing.Buyout > 0 && listing.Buyout < listing.StartBid {
+		return models.ErrInvalidBuyout
+	}
+	for _, hours := range Durations {
+		if listing.Hours == hours {
+			return nil
+		}
+	}
+	return models.ErrInvalidDuration
+}
+
+// Duration is how long the listing runs.
+func Duration(listing models.NewAuction) time.Duration {
+	return time.Duration(listing.Hours) * time.Hour
+}
+
+// MinBid is the lowest bid the auction accepts, the starting bid or the
+// highest bid raised by the increment.
+func MinBid(a *models.Auction) int64 {
+	if a.Bid == 0 {
+		return a.StartBid
+	}
+	increment := a.Bid * settings.AuctionBidIncrementPercent / 100
+	if increment < 1 {
+		increment = 1
+	}
+	return a.Bid + increment
+}
+
+// Fee is what the auction house keeps of a winning bid.
+func Fee(bid int64) int64 {
+	return bid * settings.AuctionFeePercent / 100
+}
+
+// Bid returns the amount a bid settles at, bids reaching the buyout
+// buy the item at the buyout price.
+func Bid(a *models.Auction, playerID int, amount int64) (int64, bool, error) {
+	if a.SellerID == playerID {
+		return 0, false, models.ErrOwnAuction
+	}
+	if a.Buyout > 0 && amount >= a.Buyout {
+		return a.Buyout, true, nil
+	}
+	if a.BidderID == playerID {
+		return 0, false, models.ErrHighestBidder
+	}
+	if amount < MinBid(a) {
+		return 0, false, models.ErrBidTooLow
+	}
+	return amount, false, nil
+}
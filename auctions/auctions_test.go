@@ -0,0 +1,56 @@
+package auctions
+
+import (
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		listing models.NewAuction
+		err     error
+	}{
+		{models.NewAuction{Quantity: 1, StartBid: 10, Hours: 24}, nil},
+		{models.NewAuction{Quantity: 1, StartBid: 10, Buyout: 10, Hours: 12}, nil},
+		{models.NewAuction{Quantity: 1, StartBid: 10, Buyout: 9, Hours: 12}, models.ErrInvalidBuyout},
+		{models.NewAuction{Quantity: 1, StartBid: 0, Hours: 12}, models.ErrInvalidAmount},
+		{models.NewAuction{Quantity: 1, StartBid: 10, Hours: 13}, models.ErrInvalidDuration},
+	}
+	for _, test := range tests {
+		if err := Validate(test.listing); err != test.err {
+			t.Errorf("%s FAILED: %+v got %v want %v", t.Name(), test.listing, err, test.err)
+		}
+	}
+}
+
+func TestBid(t *testing.T) {
+	a := &models.Auction{SellerID: 1, StartBid: 100, Buyout: 500}
+	_, _, err := Bid(a, 1, 200)
+	assert.Equal(t, err, models.ErrOwnAuction)
+	_, _, err = Bid(a, 2, 99)
+	assert.Equal(t, err, models.ErrBidTooLow)
+	amount, buyout, err := Bid(a, 2, 100)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, int64(100))
+	assert.Equal(t, buyout, false)
+
+	a.Bid, a.BidderID = 100, 2
+	assert.Equal(t, MinBid(a), int64(105))
+	_, _, err = Bid(a, 2, 200)
+	assert.Equal(t, err, models.ErrHighestBidder)
+	_, _, err = Bid(a, 3, 104)
+	assert.Equal(t, err, models.ErrBidTooLow)
+
+	// bids over the buyout pay the buyout, even from the highest bidder
+	amount, buyout, err = Bid(a, 2, 900)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, amount, int64(500))
+	assert.Equal(t, buyout, true)
+
+	a.Bid = 10
+	assert.Equal(t, MinBid(a), int64(11))
+	assert.Equal(t, Fee(1000), int64(50))
+	assert.Equal(t, Fee(10), int64(0))
+}
@@ -0,0 +1,167 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"tribble/gateway"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/social"
+	"unicode/utf8"
+)
+
+const (
+	Global  = "global"
+	Local   = "map"
+	Party   = "party"
+	Whisper = "whisper"
+)
+
+// Key names the history of a channel. Map channels are keyed by map,
+// party channels by the channel of the party and whispers by the pair of
+// players.
+func Key(channel, mapName, party string, a, b int) string {
+	switch channel {
+	case Local:
+		return Local + ":" + mapName
+	case Party:
+		return Party + ":" + party
+	case Whisper:
+		if a > b {
+			a, b = b, a
+		}
+		return fmt.Sprintf("%v:%d:%d", Whisper, a, b)
+	}
+	return Global
+}
+
+// Outgoing is a chat message sent by a client, To names the recipient of
+// whispers.
+type Outgoing struct {
+	Channel string `json:"channel"`
+	To      string `json:"to,omitempty"`
+	Text    string `json:"text"`
+}
+
+type Repository interface {
+	models.ChatRepository
+	models.BlockRepository
+}
+
+// Service delivers chat messages to the sessions of the gateway.
+type Service struct {
+	Repo    Repository
+	Hub     *gateway.Hub
+	Limiter *Limiter
+	Filters []Filter
+	// PartyOf returns the channel of the party of the player and its
+	// members.
+	PartyOf func(playerID int) (party string, members []int, ok bool)
+	Now     func() time.Time
+}
+
+func NewService(repo Repository, hub *gateway.Hub) *Service {
+	return &Service{
+		Repo:    repo,
+		Hub:     hub,
+		Limiter: NewLimiter(settings.ChatBurst, settings.ChatRefill),
+		Filters: []Filter{DefaultFilter},
+		PartyOf: func(int) (string, []int, bool) { return "", nil, false },
+		Now:     time.Now,
+	}
+}
+
+// Register handles the chat messages of clients.
+func (c *Service) Register() {
+	c.Hub.Handle("chat", func(s *gateway.Session, data json.RawMessage) error {
+		var out Outgoing
+		if err := json.Unmarshal(data, &out); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		_, err := c.Send(ctx, s, out)
+		return err
+	})
+}
+
+func (c *Service) Send(ctx context.Context, sender *gateway.Session, out Outgoing) (*models.ChatMessage, error) {
+	text := strings.TrimSpace(out.Text)
+	if text == "" {
+		return nil, models.ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(text) > settings.ChatMaxLength {
+		return nil, models.ErrMessageTooLong
+	}
+
+	message := models.ChatMessage{SenderID: sender.PlayerID, SenderName: sender.Name}
+	var recipients []*gateway.Session
+	switch out.Channel {
+	case Global:
+		message.Channel = Key(Global, "", "", 0, 0)
+		recipients = c.Hub.Sessions()
+	case Local:
+		message.Channel = Key(Local, sender.Map, "", 0, 0)
+		for _, s := range c.Hub.Sessions() {
+			if s.Map == sender.Map {
+				recipients = append(recipients, s)
+			}
+		}
+	case Party:
+		party, members, ok := c.PartyOf(sender.PlayerID)
+		if !ok {
+			return nil, models.ErrNotInParty
+		}
+		message.Channel = Key(Party, "", party, 0, 0)
+		for _, ID := range members {
+			if s, ok := c.Hub.Session(ID); ok {
+				recipients = append(recipients, s)
+			}
+		}
+	case Whisper:
+		to, ok := c.Hub.Find(out.To)
+		if !ok || to.PlayerID == sender.PlayerID {
+			return nil, models.ErrPlayerOffline
+		}
+		if err := social.CheckBlocked(ctx, c.Repo, sender.UserID, to.UserID); err != nil {
+			return nil, err
+		}
+		message.Channel = Key(Whisper, "", "", sender.PlayerID, to.PlayerID)
+		message.RecipientID = &to.PlayerID
+		recipients = []*gateway.Session{sender, to}
+	default:
+		return nil, models.ErrUnknownChannel
+	}
+
+	if !c.Limiter.Allow(sender.UserID, c.Now()) {
+		return nil, models.ErrChatRateLimited
+	}
+	mute, err := c.Repo.GetChatMute(ctx, sender.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if mute != nil && mute.Until.After(c.Now()) {
+		return nil, models.ErrMuted
+	}
+	for _, f := range c.Filters {
+		if text, err = f.Filter(text); err != nil {
+			return nil, err
+		}
+	}
+	message.Text = text
+
+	saved, err := c.Repo.SaveChatMessage(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range recipients {
+		s.Send("chat", saved)
+	}
+	return saved, nil
+}
+
+// Default is the chat of the gateway, set up once the database is ready.
+var Default *Service
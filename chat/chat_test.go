@@ -0,0 +1,154 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"tribble/gateway"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type memoryRepo struct {
+	models.BlockRepository
+	messages []models.ChatMessage
+	mute     *models.ChatMute
+	blocked  bool
+}
+
+func (r *memoryRepo) IsBlocked(ctx context.Context, a, b int) (bool, error) {
+	return r.blocked, nil
+}
+
+func (r *memoryRepo) SaveChatMessage(ctx context.Context, m models.ChatMessage) (*models.ChatMessage, error) {
+	m.ID = int64(len(r.messages) + 1)
+	r.messages = append(r.messages, m)
+	return &m, nil
+}
+
+func (r *memoryRepo) GetChatMessages(ctx context.Context, channel string, before int64, limit int) ([]*models.ChatMessage, error) {
+	return nil, nil
+}
+
+func (r *memoryRepo) GetChatMute(ctx context.Context, userID int) (*models.ChatMute, error) {
+	return r.mute, nil
+}
+
+func (r *memoryRepo) MuteUser(ctx context.Context, mute models.ChatMute) error { return nil }
+
+func (r *memoryRepo) UnmuteUser(ctx context.Context, userID int) error { return nil }
+
+func inbox(s *gateway.Session) []string {
+	texts := make([]string, 0)
+	for {
+		select {
+		case raw := <-s.Outbox():
+			var e struct {
+				Data models.ChatMessage `json:"data"`
+			}
+			_ = json.Unmarshal(raw, &e)
+			texts = append(texts, e.Data.Channel+" "+e.Data.SenderName+": "+e.Data.Text)
+		default:
+			return texts
+		}
+	}
+}
+
+func testService() (*Service, *memoryRepo, []*gateway.Session) {
+	repo := &memoryRepo{}
+	hub := gateway.NewHub()
+	sessions := []*gateway.Session{
+		gateway.NewSession(1, 10, "Ayla", "village"),
+		gateway.NewSession(2, 20, "Bren", "village"),
+		gateway.NewSession(3, 30, "Cato", "forest"),
+	}
+	for _, s := range sessions {
+		hub.Register(s)
+	}
+	service := NewService(repo, hub)
+	service.Now = func() time.Time { return time.Unix(1000, 0) }
+	return service, repo, sessions
+}
+
+func TestChannels(t *testing.T) {
+	service, repo, s := testService()
+	service.PartyOf = func(ID int) (string, []int, bool) { return "7f3a", []int{1, 3}, ID != 2 }
+	ctx := context.Background()
+
+	for _, out := range []Outgoing{
+		{Channel: Global, Text: "hello all"},
+		{Channel: Local, Text: " hello village "},
+		{Channel: Party, Text: "hello party"},
+		{Channel: Whisper, To: "cato", Text: "psst"},
+	} {
+		if _, err := service.Send(ctx, s[0], out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assert.Equal(t, inbox(s[0]), []string{"global Ayla: hello all", "map:village Ayla: hello village", "party:7f3a Ayla: hello party", "whisper:1:3 Ayla: psst"})
+	assert.Equal(t, inbox(s[1]), []string{"global Ayla: hello all", "map:village Ayla: hello village"})
+	assert.Equal(t, inbox(s[2]), []string{"global Ayla: hello all", "party:7f3a Ayla: hello party", "whisper:1:3 Ayla: psst"})
+	assert.Equal(t, *repo.messages[3].RecipientID, 3)
+
+	repo.blocked = true
+	_, err := service.Send(ctx, s[1], Outgoing{Channel: Whisper, To: "Ayla", Text: "hi"})
+	assert.Equal(t, err, models.ErrBlocked)
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: Party, Text: "hi"})
+	assert.Equal(t, err, models.ErrNotInParty)
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: Whisper, To: "nobody", Text: "hi"})
+	assert.Equal(t, err, models.ErrPlayerOffline)
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: "trade", Text: "hi"})
+	assert.Equal(t, err, models.ErrUnknownChannel)
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: Global, Text: strings.Repeat("a", 257)})
+	assert.Equal(t, err, models.ErrMessageTooLong)
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: Global, Text: "  "})
+	assert.Equal(t, err, models.ErrEmptyMessage)
+}
+
+func TestRateLimitAndMute(t *testing.T) {
+	service, repo, s := testService()
+	ctx := context.Background()
+	now := time.Unix(1000, 0)
+	service.Now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		if _, err := service.Send(ctx, s[0], Outgoing{Channel: Global, Text: "spam"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := service.Send(ctx, s[0], Outgoing{Channel: Global, Text: "spam"})
+	assert.Equal(t, err, models.ErrChatRateLimited)
+	// other users have their own limit
+	_, err = service.Send(ctx, s[1], Outgoing{Channel: Global, Text: "hi"})
+	assert.Equal(t, err, nil)
+
+	now = now.Add(time.Second)
+	repo.mute = &models.ChatMute{UserID: 10, Until: now.Add(time.Minute)}
+	_, err = service.Send(ctx, s[0], Outgoing{Channel: Global, Text: "hi"})
+	assert.Equal(t, err, models.ErrMuted)
+
+	now = now.Add(2 * time.Minute)
+	_, err = service.Send(ctx, s[0], Outgoing{Channel: Global, Text: "hi"})
+	assert.Equal(t, err, nil)
+}
+
+func TestFilters(t *testing.T) {
+	service, _, s := testService()
+	rejected := errors.New("no links")
+	service.Filters = append(service.Filters, FilterFunc(func(text string) (string, error) {
+		if strings.Contains(text, "http") {
+			return "", rejected
+		}
+		return text, nil
+	}))
+
+	m, err := service.Send(context.Background(), s[0], Outgoing{Channel: Global, Text: "you Idiot, stupidity!"})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, m.Text, "you *****, stupidity!")
+	_, err = service.Send(context.Background(), s[0], Outgoing{Channel: Global, Text: "http://spam"})
+	assert.Equal(t, err, rejected)
+}
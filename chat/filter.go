@@ -0,0 +1,81 @@
+package chat
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"tribble/data"
+	"tribble/settings"
+	"unicode"
+)
+
+// Filter is a moderation hook run on every message before it is sent, it
+// may rewrite the text or reject the message with an error.
+type Filter interface {
+	Filter(text string) (string, error)
+}
+
+type FilterFunc func(text string) (string, error)
+
+func (f FilterFunc) Filter(text string) (string, error) {
+	return f(text)
+}
+
+// WordFilter masks listed words, ignoring case.
+type WordFilter struct {
+	words map[string]bool
+}
+
+func NewWordFilter(words []string) *WordFilter {
+	f := &WordFilter{words: make(map[string]bool, len(words))}
+	for _, w := range words {
+		f.words[strings.ToLower(w)] = true
+	}
+	return f
+}
+
+func (f *WordFilter) Filter(text string) (string, error) {
+	runes := []rune(text)
+	start := -1
+	for i := 0; i <= len(runes); i++ {
+		if i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 && f.words[strings.ToLower(string(runes[start:i]))] {
+			for j := start; j < i; j++ {
+				runes[j] = '*'
+			}
+		}
+		start = -1
+	}
+	return string(runes), nil
+}
+
+func LoadWordFilter(r io.Reader) (*WordFilter, error) {
+	var file struct {
+		Words []string `json:"words"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	return NewWordFilter(file.Words), nil
+}
+
+var DefaultFilter = mustLoadFilter()
+
+func mustLoadFilter() *WordFilter {
+	f, err := data.Open("chat_filter.json", settings.ChatFilterFile)
+	if err != nil {
+		log.Fatalf("Unable to open chat filter: %v", err)
+	}
+	defer f.Close()
+	filter, err := LoadWordFilter(f)
+	if err != nil {
+		log.Fatalf("Unable to load chat filter: %v", err)
+	}
+	return filter
+}
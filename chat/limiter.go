@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter is a token bucket per key: burst messages can be sent at once,
+// then the bucket refills one token every refill interval.
+type Limiter struct {
+	burst  int
+	refill time.Duration
+
+	mu      sync.Mutex
+	buckets map[int]*bucket
+}
+
+func NewLimiter(burst int, refill time.Duration) *Limiter {
+	return &Limiter{burst: burst, refill: refill, buckets: make(map[int]*bucket)}
+}
+
+func (l *Limiter) Allow(key int, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += float64(now.Sub(b.last)) / float64(l.refill)
+	if b.tokens > float64(l.burst) {
+		b.tokens = float64(l.burst)
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
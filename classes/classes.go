@@ -0,0 +1,99 @@
+package classes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"tribble/data"
+	"tribble/models"
+	"tribble/settings"
+)
+
+type Class struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Attribute   string       `json:"attribute"`
+	AttackRange int          `json:"attack_range"`
+	Base        models.Stats `json:"base"`
+	Growth      models.Stats `json:"growth"`
+}
+
+// Stats returns the class attributes at the given level.
+func (c *Class) Stats(level int) models.Stats {
+	if level < 1 {
+		level = 1
+	}
+	return c.Base.Add(c.Growth.Scale(level - 1))
+}
+
+type Catalog struct {
+	classes []*Class
+	byName  map[string]*Class
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Classes []*Class `json:"classes"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Classes) == 0 {
+		return nil, errors.New("class catalog is empty")
+	}
+
+	catalog := &Catalog{classes: file.Classes, byName: make(map[string]*Class)}
+	for _, class := range file.Classes {
+		if class.Name == "" {
+			return nil, errors.New("class without name")
+		}
+		if _, ok := catalog.byName[class.Name]; ok {
+			return nil, fmt.Errorf("duplicated class %v", class.Name)
+		}
+		if !models.IsAttribute(class.Attribute) {
+			return nil, fmt.Errorf("class %v has invalid attribute %v", class.Name, class.Attribute)
+		}
+		if class.AttackRange < 1 {
+			class.AttackRange = 1
+		}
+		if class.Base.HP <= 0 {
+			return nil, fmt.Errorf("class %v must have hp", class.Name)
+		}
+		catalog.byName[class.Name] = class
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(name string) (*Class, bool) {
+	class, ok := c.byName[name]
+	return class, ok
+}
+
+func (c *Catalog) List() []*Class {
+	return c.classes
+}
+
+func (c *Catalog) Names() []string {
+	names := make([]string, 0, len(c.classes))
+	for _, class := range c.classes {
+		names = append(names, class.Name)
+	}
+	return names
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("classes.json", settings.ClassCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open class catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load class catalog: %v", err)
+	}
+	return catalog
+}
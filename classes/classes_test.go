@@ -0,0 +1,35 @@
+package classes
+
+import (
+	"strings"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestClassStats(t *testing.T) {
+	class := &Class{
+		Name:   "warrior",
+		Base:   models.Stats{HP: 100, Mana: 10, Strength: 10, MovementSpeed: 4},
+		Growth: models.Stats{HP: 10, Strength: 2},
+	}
+
+	assert.Equal(t, class.Stats(1), class.Base)
+	assert.Equal(t, class.Stats(0), class.Base)
+	assert.Equal(t, class.Stats(5), models.Stats{HP: 140, Mana: 10, Strength: 18, MovementSpeed: 4})
+}
+
+func TestLoadRejectsDuplicates(t *testing.T) {
+	_, err := Load(strings.NewReader(`{"classes": [
+		{"name": "mage", "attribute": "intellect", "base": {"hp": 10}},
+		{"name": "mage", "attribute": "intellect", "base": {"hp": 10}}
+	]}`))
+	if err == nil {
+		t.Errorf("%s FAILED: want error for duplicated class", t.Name())
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	assert.Equal(t, Default.Names(), []string{"assassin", "warrior", "templar", "archer", "mage"})
+}
@@ -0,0 +1,36 @@
+package classes
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/models"
+)
+
+// ApplyGrowth updates the stored stats of players that level up.
+func ApplyGrowth(repo models.PlayerRepository) events.Handler {
+	return func(e events.Event) {
+		levelUp, ok := e.Data.(events.LevelUpData)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		player, err := repo.GetPlayer(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not apply class growth to player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		class, ok := Default.Get(player.Sprite)
+		if !ok {
+			log.Printf("could not apply class growth to player %v: unknown class %v", e.PlayerID, player.Sprite)
+			return
+		}
+		if err = repo.UpdatePlayerStats(ctx, player.ID, class.Stats(levelUp.To)); err != nil {
+			log.Printf("could not apply class growth to player %v: %v", e.PlayerID, err.Error())
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package combat
+
+import (
+	"math"
+	"math/rand"
+	"time"
+	"tribble/models"
+	"tribble/status"
+	"tribble/world"
+)
+
+// Combatant is the combat view of a player or monster. Stats are the
+// derived totals, Stats.HP and Stats.Mana being the maximum values.
+type Combatant struct {
+	Ref       world.Ref
+	Level     int
+	Class     string
+	Attribute string
+	Stats     models.Stats
+	HP        int
+	Mana      int
+	// Effects are the status effects on the combatant, their modifiers
+	// are part of Stats.
+	Effects status.List
+
+	modifiers models.Stats
+}
+
+func (c *Combatant) Alive() bool {
+	return c.HP > 0
+}
+
+// EffectTick is the damage or healing dealt by a status effect.
+type EffectTick struct {
+	Effect   string    `json:"effect"`
+	Source   world.Ref `json:"source"`
+	Damage   int       `json:"damage,omitempty"`
+	Healed   int       `json:"healed,omitempty"`
+	TargetHP int       `json:"target_hp"`
+	Killed   bool      `json:"killed"`
+}
+
+// AddEffect applies the status effect, reporting whether the effects
+// changed.
+func (c *Combatant) AddEffect(d *status.Definition, source world.Ref) bool {
+	changed := c.Effects.Add(d, source)
+	c.refresh()
+	return changed
+}
+
+// SetEffects replaces the status effects, like the ones restored from a
+// previous session.
+func (c *Combatant) SetEffects(effects status.List) {
+	c.Effects = effects
+	c.refresh()
+}
+
+// SetStats replaces the level and the stats without status effects, like
+// when the player levels up, keeping the modifiers of its effects.
+func (c *Combatant) SetStats(level int, stats models.Stats) {
+	c.Level = level
+	c.Stats = stats.Add(c.modifiers)
+	c.clamp()
+}
+
+// ClearEffects removes the status effects, reporting whether there were
+// any.
+func (c *Combatant) ClearEffects() bool {
+	cleared := c.Effects.Clear()
+	c.refresh()
+	return cleared
+}
+
+// AdvanceEffects runs the status effects for dt, applying their damage
+// and healing while the combatant is alive. It also reports whether some
+// effects ended.
+func (c *Combatant) AdvanceEffects(dt time.Duration) ([]EffectTick, bool) {
+	ticks, ended := c.Effects.Advance(dt)
+	if ended {
+		c.refresh()
+	}
+	results := make([]EffectTick, 0, len(ticks))
+	for _, tick := range ticks {
+		if !c.Alive() {
+			break
+		}
+		result := EffectTick{Effect: tick.Effect, Source: tick.Source}
+		if tick.Heal > 0 {
+			result.Healed = tick.Heal
+			if result.Healed > c.Stats.HP-c.HP {
+				result.Healed = c.Stats.HP - c.HP
+			}
+			c.HP += result.Healed
+		}
+		if tick.Damage > 0 {
+			result.Damage = tick.Damage
+			c.HP -= tick.Damage
+			if c.HP <= 0 {
+				c.HP = 0
+				result.Killed = true
+			}
+		}
+		result.TargetHP = c.HP
+		results = append(results, result)
+	}
+	return results, ended
+}
+
+// refresh applies the stat modifiers of the status effects to the stats,
+// replacing the modifiers applied before.
+func (c *Combatant) refresh() {
+	modifiers := c.Effects.Stats()
+	c.Stats = c.Stats.Add(c.modifiers.Scale(-1)).Add(modifiers)
+	c.modifiers = modifiers
+	c.clamp()
+}
+
+// clamp keeps HP and mana within their maximum values.
+func (c *Combatant) clamp() {
+	if c.HP > c.Stats.HP {
+		c.HP = c.Stats.HP
+	}
+	if c.Mana > c.Stats.Mana {
+		c.Mana = c.Stats.Mana
+	}
+}
+
+type Config struct {
+	BaseCritChance    float64
+	CritPerAgility    float64
+	MaxCritChance     float64
+	CritMultiplier    float64
+	BaseEvasion       float64
+	EvasionPerAgility float64
+	MaxEvasion        float64
+	// Variance is the +/- fraction applied to every hit.
+	Variance float64
+	// DeathXPPenalty is the fraction of the XP earned inside the current
+	// level that is lost on death, players never lose levels.
+	DeathXPPenalty float64
+}
+
+var DefaultConfig = Config{
+	BaseCritChance:    0.05,
+	CritPerAgility:    0.004,
+	MaxCritChance:     0.5,
+	CritMultiplier:    1.5,
+	BaseEvasion:       0.02,
+	EvasionPerAgility: 0.003,
+	MaxEvasion:        0.4,
+	Variance:          0.1,
+	DeathXPPenalty:    0.1,
+}
+
+type Result struct {
+	Evaded   bool `json:"evaded"`
+	Critical bool `json:"critical"`
+	Damage   int  `json:"damage"`
+	TargetHP int  `json:"target_hp"`
+	Killed   bool `json:"killed"`
+}
+
+// Resolver resolves attacks drawing from its own RNG, two resolvers
+// created with the same seed produce the same outcomes. It is not safe
+// for concurrent use, each simulation loop owns one.
+type Resolver struct {
+	rng    *rand.Rand
+	config Config
+}
+
+func NewResolver(seed int64, config Config) *Resolver {
+	return &Resolver{
+		rng:    rand.New(rand.NewSource(seed)),
+		config: config,
+	}
+}
+
+func (r *Resolver) Config() Config {
+	return r.config
+}
+
+func (r *Resolver) CritChance(attacker *Combatant) float64 {
+	chance := r.config.BaseCritChance + float64(attacker.Stats.Agility)*r.config.CritPerAgility
+	return math.Min(chance, r.config.MaxCritChance)
+}
+
+func (r *Resolver) EvasionChance(defender *Combatant) float64 {
+	chance := r.config.BaseEvasion + float64(defender.Stats.Agility)*r.config.EvasionPerAgility
+	return math.Min(chance, r.config.MaxEvasion)
+}
+
+// BaseDamage is the damage before variance, critical hits and
+// mitigation: twice the primary attribute plus three per level.
+func BaseDamage(attacker *Combatant) float64 {
+	return float64(attacker.Stats.Attribute(attacker.Attribute)*2 + attacker.Level*3)
+}
+
+// Mitigation is the fraction of damage taken by the defender, reduced by
+// its strength and level.
+func Mitigation(defender *Combatant) float64 {
+	return 100 / float64(100+defender.Stats.Strength+defender.Level*2)
+}
+
+// Attack resolves a single hit and applies it to the defender. Every call
+// draws exactly three numbers so outcomes only depend on the seed and the
+// order of attacks.
+func (r *Resolver) Attack(attacker, defender *Combatant) Result {
+	return r.Hit(attacker, defender, BaseDamage(attacker))
+}
+
+// Hit resolves an attack with the given base damage, letting abilities
+// scale it before evasion, critical hits and mitigation are applied.
+func (r *Resolver) Hit(attacker, defender *Combatant, base float64) Result {
+	evasionRoll, critRoll, varianceRoll := r.rng.Float64(), r.rng.Float64(), r.rng.Float64()
+
+	if !defender.Alive() {
+		return Result{TargetHP: defender.HP}
+	}
+	if evasionRoll < r.EvasionChance(defender) {
+		return Result{Evaded: true, TargetHP: defender.HP}
+	}
+
+	damage := base * (1 - r.config.Variance + varianceRoll*2*r.config.Variance)
+	critical := critRoll < r.CritChance(attacker)
+	if critical {
+		damage *= r.config.CritMultiplier
+	}
+	damage *= Mitigation(defender)
+
+	result := Result{Critical: critical, Damage: int(math.Max(1, math.Round(damage)))}
+	defender.HP -= result.Damage
+	if defender.HP <= 0 {
+		defender.HP = 0
+		result.Killed = true
+	}
+	result.TargetHP = defender.HP
+	return result
+}
@@ -0,0 +1,171 @@
+package combat
+
+import (
+	"testing"
+	"time"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/status"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func warrior() *Combatant {
+	return &Combatant{
+		Ref:       world.Ref{Kind: world.KindPlayer, ID: 1},
+		Level:     5,
+		Attribute: models.Strength,
+		Stats:     models.Stats{HP: 186, Mana: 24, Strength: 26, Agility: 11, Intellect: 3},
+		HP:        186,
+	}
+}
+
+func goblin() *Combatant {
+	return &Combatant{
+		Ref:       world.Ref{Kind: world.KindMonster, ID: 1},
+		Level:     3,
+		Attribute: models.Agility,
+		Stats:     models.Stats{HP: 260, Strength: 6, Agility: 12},
+		HP:        260,
+	}
+}
+
+func TestAttackIsReproducible(t *testing.T) {
+	fight := func() []Result {
+		r := NewResolver(42, DefaultConfig)
+		w, g := warrior(), goblin()
+		results := make([]Result, 0)
+		for w.Alive() && g.Alive() {
+			results = append(results, r.Attack(w, g))
+			if g.Alive() {
+				results = append(results, r.Attack(g, w))
+			}
+		}
+		return results
+	}
+
+	want := []Result{
+		{Critical: true, Damage: 92, TargetHP: 168},
+		{Critical: true, Damage: 36, TargetHP: 150},
+		{Damage: 58, TargetHP: 110},
+		{Damage: 23, TargetHP: 127},
+		{Damage: 62, TargetHP: 48},
+		{Damage: 25, TargetHP: 102},
+		{Damage: 55, TargetHP: 0, Killed: true},
+	}
+	assert.Equal(t, fight(), want)
+	assert.Equal(t, fight(), want)
+}
+
+func TestDifferentSeedsDiverge(t *testing.T) {
+	a, b := NewResolver(1, DefaultConfig), NewResolver(2, DefaultConfig)
+	same := true
+	for i := 0; i < 10; i++ {
+		if a.Attack(warrior(), goblin()) != b.Attack(warrior(), goblin()) {
+			same = false
+		}
+	}
+	if same {
+		t.Errorf("%s FAILED: want different outcomes for different seeds", t.Name())
+	}
+}
+
+func TestEvasionAndCriticalCaps(t *testing.T) {
+	config := DefaultConfig
+	config.BaseEvasion = 1
+	config.MaxEvasion = 1
+	r := NewResolver(7, config)
+
+	g := goblin()
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, r.Attack(warrior(), g), Result{Evaded: true, TargetHP: 260})
+	}
+
+	config = DefaultConfig
+	config.BaseEvasion = 0
+	config.BaseCritChance = 1
+	config.MaxCritChance = 1
+	config.Variance = 0
+	r = NewResolver(7, config)
+
+	// (26*2 + 5*3) * 1.5 * 100/(100+6+3*2) = 89.73
+	assert.Equal(t, r.Attack(warrior(), goblin()), Result{Critical: true, Damage: 90, TargetHP: 170})
+
+	r = NewResolver(1, DefaultConfig)
+	agile := &Combatant{Stats: models.Stats{Agility: 1000}}
+	assert.Equal(t, r.CritChance(agile), DefaultConfig.MaxCritChance)
+	assert.Equal(t, r.EvasionChance(agile), DefaultConfig.MaxEvasion)
+}
+
+func TestMinimumDamageAndDeadTargets(t *testing.T) {
+	config := DefaultConfig
+	config.BaseEvasion = 0
+	config.BaseCritChance = 0
+	config.CritPerAgility = 0
+	r := NewResolver(3, config)
+
+	weak := &Combatant{Level: 1, Attribute: models.Strength}
+	tank := &Combatant{Level: 50, Stats: models.Stats{HP: 10, Strength: 5000}, HP: 10}
+	assert.Equal(t, r.Attack(weak, tank), Result{Damage: 1, TargetHP: 9})
+
+	tank.HP = 0
+	assert.Equal(t, r.Attack(weak, tank), Result{TargetHP: 0})
+}
+
+func TestXPPenalty(t *testing.T) {
+	curve, err := levels.NewCurve([]int{0, 100, 300})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, XPPenalty(250, curve, 0.1), 15)
+	assert.Equal(t, XPPenalty(100, curve, 0.1), 0)
+	assert.Equal(t, XPPenalty(50, curve, 0.5), 25)
+}
+
+func TestFromPlayerClampsVitalsWithEffects(t *testing.T) {
+	player := &models.Player{ID: 3, Sprite: "mage", XP: 0, HP: 500, Mana: 10}
+	c := FromPlayer(player, models.StatSheet{Total: models.Stats{HP: 75, Mana: 120, Intellect: 15}}, status.List{})
+
+	assert.Equal(t, c.Ref, world.Ref{Kind: world.KindPlayer, ID: 3})
+	assert.Equal(t, c.Attribute, models.Intellect)
+	assert.Equal(t, c.Level, 1)
+	assert.Equal(t, c.HP, 75)
+	assert.Equal(t, c.Mana, 10)
+
+	// vitals above the unbuffed maximum are kept with the buff restored
+	blessed := &status.Definition{ID: "blessed", DurationMS: 1000, Stacking: status.StackRefresh, MaxStacks: 1, Stats: models.Stats{HP: 20}}
+	var effects status.List
+	effects.Add(blessed, c.Ref)
+	player.HP = 95
+	c = FromPlayer(player, models.StatSheet{Total: models.Stats{HP: 95}, Effects: models.Stats{HP: 20}}, effects)
+	assert.Equal(t, c.Stats.HP, 95)
+	assert.Equal(t, c.HP, 95)
+}
+
+func TestStatusEffects(t *testing.T) {
+	shout := &status.Definition{ID: "shout", DurationMS: 1000, Stacking: status.StackRefresh, MaxStacks: 1, Stats: models.Stats{Strength: 5}}
+	curse := &status.Definition{ID: "curse", DurationMS: 500, Stacking: status.StackRefresh, MaxStacks: 1, Stats: models.Stats{HP: -40}}
+	poison := &status.Definition{ID: "poison", DurationMS: 3000, TickMS: 1000, Stacking: status.StackStack, MaxStacks: 3, Damage: 30}
+	c := &Combatant{Stats: models.Stats{HP: 100, Strength: 10}, HP: 100}
+	c.AddEffect(shout, world.Ref{})
+	c.AddEffect(curse, world.Ref{})
+	assert.Equal(t, c.Stats, models.Stats{HP: 60, Strength: 15})
+	assert.Equal(t, c.HP, 60)
+
+	ticks, ended := c.AdvanceEffects(500 * time.Millisecond)
+	assert.Equal(t, len(ticks), 0)
+	assert.Equal(t, ended, true)
+	assert.Equal(t, c.Stats, models.Stats{HP: 100, Strength: 15})
+	assert.Equal(t, c.HP, 60)
+
+	source := world.Ref{Kind: world.KindPlayer, ID: 2}
+	c.AddEffect(poison, source)
+	c.AddEffect(poison, source)
+	ticks, ended = c.AdvanceEffects(time.Second)
+	assert.Equal(t, ended, true)
+	assert.Equal(t, c.Stats, models.Stats{HP: 100, Strength: 10})
+	assert.Equal(t, ticks, []EffectTick{{Effect: "poison", Source: source, Damage: 60, TargetHP: 0, Killed: true}})
+	assert.Equal(t, c.ClearEffects(), true)
+}
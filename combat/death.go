@@ -0,0 +1,84 @@
+package combat
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/classes"
+	"tribble/events"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/status"
+	"tribble/world"
+)
+
+// FromPlayer builds the combatant of a player from its derived stats and
+// the status effects it had, which the sheet already counts. HP and mana
+// are clamped once the effects apply, so buffed vitals are kept.
+func FromPlayer(player *models.Player, sheet models.StatSheet, effects status.List) *Combatant {
+	attribute := models.Strength
+	if class, ok := classes.Default.Get(player.Sprite); ok {
+		attribute = class.Attribute
+	}
+	c := &Combatant{
+		Ref:       world.Ref{Kind: world.KindPlayer, ID: player.ID},
+		Level:     levels.Default.Level(player.XP),
+		Class:     player.Sprite,
+		Attribute: attribute,
+		Stats:     sheet.Total.Add(sheet.Effects.Scale(-1)),
+		HP:        player.HP,
+		Mana:      player.Mana,
+	}
+	c.SetEffects(effects)
+	return c
+}
+
+// XPPenalty returns how much XP is lost on death, a fraction of the XP
+// earned since the current level was reached.
+func XPPenalty(xp int, curve *levels.Curve, rate float64) int {
+	progress := xp - curve.XP(curve.Level(xp))
+	return int(float64(progress) * rate)
+}
+
+// RecordDeath persists the death of players killed in the simulation so
+// they have to respawn before playing again.
+func RecordDeath(repo models.PlayerRepository) events.Handler {
+	return func(e events.Event) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		player, err := repo.GetPlayer(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not record death of player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		if err = repo.UpdatePlayerVitals(ctx, player.ID, 0, player.Mana); err != nil {
+			log.Printf("could not record death of player %v: %v", e.PlayerID, err.Error())
+		}
+	}
+}
+
+// Respawn brings a dead player back at the spawn point of its map with
+// the full HP and mana of its stat sheet, applying the death XP penalty.
+func Respawn(ctx context.Context, repo models.PlayerRepository, player *models.Player, sheet models.StatSheet, config Config) (*models.Player, error) {
+	if player.HP > 0 {
+		return nil, models.ErrNotDead
+	}
+	m, ok := world.Maps.Get(player.Map)
+	if !ok {
+		m = world.Maps.Start()
+	}
+
+	loss := XPPenalty(player.XP, levels.Default, config.DeathXPPenalty)
+	respawned, err := repo.RespawnPlayer(ctx, player.ID, m.Name, m.Spawn.X, m.Spawn.Y, sheet.Total.HP, sheet.Total.Mana, loss)
+	if err != nil {
+		return nil, err
+	}
+	events.Publish(events.Event{
+		Type:     events.PlayerRespawned,
+		UserID:   respawned.UserID,
+		PlayerID: respawned.ID,
+		Data:     events.PlayerRespawnedData{Map: m.Name, XPLost: loss},
+	})
+	return respawned, nil
+}
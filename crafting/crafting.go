@@ -0,0 +1,208 @@
+package crafting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"tribble/data"
+	"tribble/gateway"
+	"tribble/items"
+	"tribble/jobs"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/settings"
+)
+
+// FinishJob is the scheduled job finishing the crafts past their time.
+const FinishJob = "crafting.finish"
+
+// Recipe turns ingredients into an output in Seconds, recipes without time
+// are crafted right away. Level is the profession level required.
+type Recipe struct {
+	ID          string             `json:"id"`
+	Profession  string             `json:"profession"`
+	Level       int                `json:"level"`
+	Ingredients []models.ItemStack `json:"ingredients"`
+	Output      models.ItemStack   `json:"output"`
+	Seconds     int                `json:"seconds,omitempty"`
+	XP          int                `json:"xp"`
+}
+
+func (r *Recipe) validate(professions map[string]bool, maxLevel int) error {
+	if !professions[r.Profession] {
+		return fmt.Errorf("recipe %v has unknown profession %v", r.ID, r.Profession)
+	}
+	if r.Level < 1 || r.Level > maxLevel {
+		return fmt.Errorf("recipe %v has invalid level %v", r.ID, r.Level)
+	}
+	if len(r.Ingredients) == 0 {
+		return fmt.Errorf("recipe %v has no ingredients", r.ID)
+	}
+	stacks := append([]models.ItemStack{r.Output}, r.Ingredients...)
+	for _, s := range stacks {
+		if _, ok := items.Default.Get(s.ItemID); !ok {
+			return fmt.Errorf("recipe %v uses unknown item %v", r.ID, s.ItemID)
+		}
+		if s.Quantity < 1 {
+			return fmt.Errorf("recipe %v has an invalid %v quantity", r.ID, s.ItemID)
+		}
+	}
+	if r.Seconds < 0 || r.XP < 0 {
+		return fmt.Errorf("recipe %v has invalid time or xp", r.ID)
+	}
+	return nil
+}
+
+type Catalog struct {
+	curve       *levels.Curve
+	professions []string
+	recipes     []*Recipe
+	byID        map[string]*Recipe
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Curve       levels.Formula `json:"curve"`
+		Professions []string       `json:"professions"`
+		Recipes     []*Recipe      `json:"recipes"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Professions) == 0 || len(file.Recipes) == 0 {
+		return nil, errors.New("recipe catalog is empty")
+	}
+	curve, err := file.Curve.Curve()
+	if err != nil {
+		return nil, err
+	}
+
+	professions := make(map[string]bool, len(file.Professions))
+	for _, p := range file.Professions {
+		if professions[p] {
+			return nil, fmt.Errorf("duplicated profession %v", p)
+		}
+		professions[p] = true
+	}
+	catalog := &Catalog{
+		curve:       curve,
+		professions: file.Professions,
+		recipes:     file.Recipes,
+		byID:        make(map[string]*Recipe),
+	}
+	for _, r := range file.Recipes {
+		if r.ID == "" {
+			return nil, errors.New("recipe without id")
+		}
+		if _, ok := catalog.byID[r.ID]; ok {
+			return nil, fmt.Errorf("duplicated recipe %v", r.ID)
+		}
+		if err := r.validate(professions, curve.MaxLevel()); err != nil {
+			return nil, err
+		}
+		catalog.byID[r.ID] = r
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Recipe, bool) {
+	r, ok := c.byID[ID]
+	return r, ok
+}
+
+func (c *Catalog) List() []*Recipe {
+	return c.recipes
+}
+
+func (c *Catalog) Professions() []string {
+	return c.professions
+}
+
+// Levels returns every profession of the catalog with the level of the
+// player, professions never practiced are at level 1.
+func (c *Catalog) Levels(stored []*models.Profession) []*models.Profession {
+	xp := make(map[string]int, len(stored))
+	for _, p := range stored {
+		xp[p.Profession] = p.XP
+	}
+	professions := make([]*models.Profession, 0, len(c.professions))
+	for _, name := range c.professions {
+		professions = append(professions, &models.Profession{
+			Profession: name,
+			XP:         xp[name],
+			Level:      c.curve.Level(xp[name]),
+		})
+	}
+	return professions
+}
+
+// Known returns the recipes the player has the profession level for.
+func (c *Catalog) Known(stored []*models.Profession) []*Recipe {
+	level := make(map[string]int)
+	for _, p := range c.Levels(stored) {
+		level[p.Profession] = p.Level
+	}
+	known := make([]*Recipe, 0)
+	for _, r := range c.recipes {
+		if r.Level <= level[r.Profession] {
+			known = append(known, r)
+		}
+	}
+	return known
+}
+
+// Order prices count crafts of the recipe, done one after the other.
+func (c *Catalog) Order(r *Recipe, count int) models.CraftOrder {
+	ingredients := make([]models.ItemStack, len(r.Ingredients))
+	for i, s := range r.Ingredients {
+		ingredients[i] = models.ItemStack{ItemID: s.ItemID, Quantity: s.Quantity * count}
+	}
+	return models.CraftOrder{
+		RecipeID:    r.ID,
+		Profession:  r.Profession,
+		MinXP:       c.curve.XP(r.Level),
+		Ingredients: ingredients,
+		Output:      models.ItemStack{ItemID: r.Output.ItemID, Quantity: r.Output.Quantity * count},
+		XP:          r.XP * count,
+		Duration:    time.Duration(r.Seconds*count) * time.Second,
+	}
+}
+
+// Finish returns the job finishing the crafts past their time, telling
+// their connected players.
+func Finish(repo models.CraftingRepository, hub *gateway.Hub) jobs.Func {
+	return func(ctx context.Context) error {
+		crafts, err := repo.FinishCrafts(ctx, settings.CraftFinishBatch)
+		if err != nil {
+			return err
+		}
+		for _, craft := range crafts {
+			if s, ok := hub.Session(craft.PlayerID); ok {
+				s.Send("craft", craft)
+			}
+		}
+		if len(crafts) == settings.CraftFinishBatch {
+			jobs.Default.At(FinishJob, time.Now())
+		}
+		return nil
+	}
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("recipes.json", settings.RecipeCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open recipe catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load recipe catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,70 @@
+package crafting
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	r, ok := Default.Get("iron_sword")
+	if !ok {
+		t.Fatalf("%s FAILED: iron_sword recipe missing", t.Name())
+	}
+	order := Default.Order(r, 3)
+	assert.Equal(t, order.Profession, "blacksmithing")
+	assert.Equal(t, order.MinXP, Default.curve.XP(r.Level))
+	assert.Equal(t, order.Ingredients, []models.ItemStack{{ItemID: "iron_ore", Quantity: 18}, {ItemID: "oak_log", Quantity: 3}})
+	assert.Equal(t, order.Output, models.ItemStack{ItemID: "iron_sword", Quantity: 3})
+	assert.Equal(t, order.XP, 3*r.XP)
+	assert.Equal(t, order.Duration, 3*time.Duration(r.Seconds)*time.Second)
+}
+
+func TestKnown(t *testing.T) {
+	for _, r := range Default.Known(nil) {
+		if r.Level != 1 {
+			t.Errorf("%s FAILED: %v known at level 1", t.Name(), r.ID)
+		}
+	}
+
+	xp := Default.curve.XP(5)
+	known := Default.Known([]*models.Profession{{Profession: "blacksmithing", XP: xp}})
+	var sword bool
+	for _, r := range known {
+		sword = sword || r.ID == "iron_sword"
+		if r.ID == "runed_blade" {
+			t.Errorf("%s FAILED: runed_blade known at level 5", t.Name())
+		}
+	}
+	assert.Equal(t, sword, true)
+
+	levels := Default.Levels([]*models.Profession{{Profession: "blacksmithing", XP: xp}})
+	assert.Equal(t, len(levels), len(Default.Professions()))
+	for _, p := range levels {
+		if p.Profession == "blacksmithing" {
+			assert.Equal(t, p.Level, 5)
+		} else {
+			assert.Equal(t, p.Level, 1)
+		}
+	}
+}
+
+func TestInvalidRecipes(t *testing.T) {
+	curve := `"curve": {"max_level": 10, "base": 10, "exponent": 1.5}, "professions": ["alchemy"]`
+	tests := []string{
+		`{` + curve + `, "recipes": []}`,
+		`{` + curve + `, "recipes": [{"id": "a", "profession": "cooking", "level": 1, "ingredients": [{"item_id": "goblin_ear", "quantity": 1}], "output": {"item_id": "bread", "quantity": 1}}]}`,
+		`{` + curve + `, "recipes": [{"id": "a", "profession": "alchemy", "level": 11, "ingredients": [{"item_id": "goblin_ear", "quantity": 1}], "output": {"item_id": "bread", "quantity": 1}}]}`,
+		`{` + curve + `, "recipes": [{"id": "a", "profession": "alchemy", "level": 1, "ingredients": [], "output": {"item_id": "bread", "quantity": 1}}]}`,
+		`{` + curve + `, "recipes": [{"id": "a", "profession": "alchemy", "level": 1, "ingredients": [{"item_id": "unknown", "quantity": 1}], "output": {"item_id": "bread", "quantity": 1}}]}`,
+		`{` + curve + `, "recipes": [{"id": "a", "profession": "alchemy", "level": 1, "ingredients": [{"item_id": "goblin_ear", "quantity": 1}], "output": {"item_id": "bread", "quantity": 0}}]}`,
+	}
+	for _, test := range tests {
+		if _, err := Load(strings.NewReader(test)); err == nil {
+			t.Errorf("%s FAILED: %v loaded", t.Name(), test)
+		}
+	}
+}
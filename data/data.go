@@ -0,0 +1,21 @@
+package data
+
+import (
+	"embed"
+	"io"
+	"os"
+)
+
+// Files holds the game data shipped with the binary.
+//
+//go:embed *.json maps/*.txt
+var Files embed.FS
+
+// Open returns the embedded file called name, or the file at path when a
+// path is given so deployments can override the defaults.
+func Open(name, path string) (io.ReadCloser, error) {
+	if path != "" {
+		return os.Open(path)
+	}
+	return Files.Open(name)
+}
@@ -0,0 +1,169 @@
+package events
+
+import (
+	"sync"
+	"time"
+	"tribble/models"
+)
+
+type Type string
+
+const (
+	PlayerCreated Type = "player.created"
+	XPGained      Type = "player.xp_gained"
+	LevelUp       Type = "player.level_up"
+
+	PlayerDied      Type = "player.died"
+	PlayerRespawned Type = "player.respawned"
+
+	MonsterKilled Type = "monster.killed"
+
+	ItemAcquired    Type = "player.item_acquired"
+	LandmarkEntered Type = "player.landmark_entered"
+	QuestCompleted  Type = "player.quest_completed"
+
+	PlayerConnected     Type = "player.connected"
+	PlayerLeft          Type = "player.left"
+	AchievementUnlocked Type = "player.achievement_unlocked"
+)
+
+type Event struct {
+	Type     Type        `json:"type"`
+	UserID   int         `json:"user_id,omitempty"`
+	PlayerID int         `json:"player_id,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Time     time.Time   `json:"time"`
+}
+
+type XPGainedData struct {
+	Amount int `json:"amount"`
+	XP     int `json:"xp"`
+}
+
+type LevelUpData struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+type PlayerDiedData struct {
+	Map        string `json:"map"`
+	KillerKind string `json:"killer_kind"`
+	KillerID   int    `json:"killer_id"`
+}
+
+type PlayerRespawnedData struct {
+	Map    string `json:"map"`
+	XPLost int    `json:"xp_lost"`
+}
+
+type MonsterKilledData struct {
+	Map       string `json:"map"`
+	Monster   string `json:"monster"`
+	MonsterID int    `json:"monster_id"`
+	XP        int    `json:"xp"`
+	// Loot is what the monster dropped for its killer.
+	Loot []models.ItemStack `json:"loot,omitempty"`
+}
+
+type ItemAcquiredData struct {
+	Item     string `json:"item"`
+	Quantity int    `json:"quantity"`
+	Source   string `json:"source"`
+}
+
+type LandmarkEnteredData struct {
+	Map      string `json:"map"`
+	Landmark string `json:"landmark"`
+}
+
+type QuestCompletedData struct {
+	Quest string `json:"quest"`
+}
+
+type PlayerConnectedData struct {
+	Map string `json:"map"`
+}
+
+// PlayerLeftData holds the status effects of the player to keep until it
+// comes back.
+type PlayerLeftData struct {
+	Map     string                 `json:"map"`
+	Effects []*models.PlayerEffect `json:"effects"`
+}
+
+type AchievementUnlockedData struct {
+	Achievement string `json:"achievement"`
+}
+
+type Handler func(Event)
+
+// Bus is a synchronous in-process publisher, handlers run in the
+// publisher's goroutine in subscription order.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[Type][]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[Type][]Handler)}
+}
+
+func (b *Bus) Subscribe(t Type, handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[t] = append(b.handlers[t], handler)
+}
+
+func (b *Bus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	b.mu.RLock()
+	handlers := b.handlers[e.Type]
+	b.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+var Default = NewBus()
+
+func Subscribe(t Type, handler Handler) {
+	Default.Subscribe(t, handler)
+}
+
+func Publish(e Event) {
+	Default.Publish(e)
+}
+
+// Queue publishes events on a bus from a single background goroutine,
+// keeping their order without blocking the publisher on slow handlers.
+type Queue struct {
+	bus    *Bus
+	events chan Event
+	done   chan struct{}
+}
+
+func NewQueue(bus *Bus, size int) *Queue {
+	q := &Queue{bus: bus, events: make(chan Event, size), done: make(chan struct{})}
+	go func() {
+		defer close(q.done)
+		for e := range q.events {
+			q.bus.Publish(e)
+		}
+	}()
+	return q
+}
+
+func (q *Queue) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	q.events <- e
+}
+
+// Close waits for every queued event to be published.
+func (q *Queue) Close() {
+	close(q.events)
+	<-q.done
+}
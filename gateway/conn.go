@@ -0,0 +1,86 @@
+package gateway
+
+import (
+	"net/http"
+	"time"
+	"tribble/settings"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait  = 10 * time.Second
+	pongWait   = 60 * time.Second
+	pingPeriod = pongWait * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// clients authenticate with a bearer token, not cookies, so any origin
+	// may connect like with the REST API
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// Upgrade switches the request to a websocket connection.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*websocket.Conn, error) {
+	return upgrader.Upgrade(w, r, nil)
+}
+
+// Serve pumps messages between the connection and the session until
+// either side closes, then unregisters the session.
+func (h *Hub) Serve(conn *websocket.Conn, s *Session) {
+	defer func() {
+		h.Unregister(s)
+		_ = conn.Close()
+	}()
+
+	go h.write(conn, s)
+
+	conn.SetReadLimit(settings.GatewayMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		select {
+		case <-s.Done():
+			return
+		default:
+		}
+		h.Dispatch(s, message)
+	}
+}
+
+func (h *Hub) write(conn *websocket.Conn, s *Session) {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = conn.Close()
+	}()
+
+	for {
+		select {
+		case message := <-s.Outbox():
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				s.Close()
+				return
+			}
+		case <-ticker.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				s.Close()
+				return
+			}
+		case <-s.Done():
+			_ = conn.SetWriteDeadline(time.Now().Add(writeWait))
+			_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return
+		}
+	}
+}
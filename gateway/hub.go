@@ -0,0 +1,252 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"tribble/settings"
+	"tribble/simulation"
+)
+
+// Message is what clients send, Data is decoded by the handler of the type.
+type Message struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Envelope is what the server sends to clients.
+type Envelope struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+type ErrorData struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// Session is the real-time connection of a player. Outgoing messages are
+// buffered, a client that can't keep up is disconnected.
+type Session struct {
+	PlayerID int
+	UserID   int
+	Name     string
+	Map      string
+
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func NewSession(playerID, userID int, name, mapName string) *Session {
+	return &Session{
+		PlayerID: playerID,
+		UserID:   userID,
+		Name:     name,
+		Map:      mapName,
+		send:     make(chan []byte, settings.GatewaySendBuffer),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *Session) Send(t string, data interface{}) bool {
+	message, err := json.Marshal(Envelope{Type: t, Data: data})
+	if err != nil {
+		log.Printf("could not encode %v message: %v", t, err.Error())
+		return false
+	}
+	select {
+	case <-s.done:
+		return false
+	default:
+	}
+	select {
+	case s.send <- message:
+		return true
+	default:
+		s.Close()
+		return false
+	}
+}
+
+// Outbox holds the encoded messages waiting to be written.
+func (s *Session) Outbox() <-chan []byte {
+	return s.send
+}
+
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) Close() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
+
+type Handler func(s *Session, data json.RawMessage) error
+
+// Hub keeps the connected sessions, one per player, and routes the
+// messages of clients to the handlers registered for their type.
+type Hub struct {
+	mu           sync.RWMutex
+	sessions     map[int]*Session
+	handlers     map[string]Handler
+	disconnected []func(*Session)
+	loops        *simulation.Manager
+	holds        map[int]*hold
+}
+
+type hold struct {
+	sync.Mutex
+	waiting int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		sessions: make(map[int]*Session),
+		handlers: make(map[string]Handler),
+		holds:    make(map[int]*hold),
+	}
+}
+
+func (h *Hub) Handle(t string, handler Handler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers[t] = handler
+}
+
+// OnDisconnect runs fn every time a session is unregistered.
+func (h *Hub) OnDisconnect(fn func(*Session)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.disconnected = append(h.disconnected, fn)
+}
+
+// Register adds the session, closing the previous session of the player.
+func (h *Hub) Register(s *Session) {
+	h.mu.Lock()
+	previous, ok := h.sessions[s.PlayerID]
+	h.sessions[s.PlayerID] = s
+	h.mu.Unlock()
+	if ok {
+		previous.Close()
+	}
+}
+
+// Hold makes the connection and disconnection of the player happen one at
+// a time, disconnect hooks included, until the returned function is called.
+func (h *Hub) Hold(playerID int) func() {
+	h.mu.Lock()
+	held, ok := h.holds[playerID]
+	if !ok {
+		held = &hold{}
+		h.holds[playerID] = held
+	}
+	held.waiting++
+	h.mu.Unlock()
+
+	held.Lock()
+	return func() {
+		held.Unlock()
+		h.mu.Lock()
+		if held.waiting--; held.waiting == 0 {
+			delete(h.holds, playerID)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Unregister removes the session if it is still the one of its player and
+// runs the disconnect hooks while holding the player.
+func (h *Hub) Unregister(s *Session) {
+	defer h.Hold(s.PlayerID)()
+
+	h.mu.Lock()
+	current, ok := h.sessions[s.PlayerID]
+	if !ok || current != s {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.sessions, s.PlayerID)
+	hooks := h.disconnected
+	h.mu.Unlock()
+
+	s.Close()
+	for _, fn := range hooks {
+		fn(s)
+	}
+}
+
+func (h *Hub) Session(playerID int) (*Session, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	s, ok := h.sessions[playerID]
+	return s, ok
+}
+
+// User returns a session of the user, users connect one player at a time
+// unless they run several clients.
+func (h *Hub) User(userID int) (*Session, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, s := range h.sessions {
+		if s.UserID == userID {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Find returns the session of the player with the given name, ignoring case.
+func (h *Hub) Find(name string) (*Session, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, s := range h.sessions {
+		if strings.EqualFold(s.Name, name) {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func (h *Hub) Sessions() []*Session {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	sessions := make([]*Session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// Dispatch decodes a client message and runs its handler, errors are sent
+// back to the client.
+func (h *Hub) Dispatch(s *Session, raw []byte) {
+	var message Message
+	if err := json.Unmarshal(raw, &message); err != nil {
+		s.Send("error", ErrorData{Error: "unable to decode message"})
+		return
+	}
+	h.mu.RLock()
+	handler, ok := h.handlers[message.Type]
+	h.mu.RUnlock()
+	if !ok {
+		s.Send("error", ErrorData{Type: message.Type, Error: fmt.Sprintf("unknown message type %q", message.Type)})
+		return
+	}
+	if err := handler(s, message.Data); err != nil {
+		s.Send("error", ErrorData{Type: message.Type, Error: err.Error()})
+	}
+}
+
+// Close disconnects every session.
+func (h *Hub) Close() {
+	for _, s := range h.Sessions() {
+		h.Unregister(s)
+	}
+}
+
+var Default = NewHub()
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tribble/settings"
+
+	"github.com/gorilla/websocket"
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func receive(t *testing.T, s *Session) Envelope {
+	var e struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	select {
+	case message := <-s.Outbox():
+		if err := json.Unmarshal(message, &e); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		t.Fatalf("%s FAILED: want a message", t.Name())
+	}
+	return Envelope{Type: e.Type, Data: string(e.Data)}
+}
+
+func TestRegisterReplacesSession(t *testing.T) {
+	hub := NewHub()
+	left := 0
+	hub.OnDisconnect(func(*Session) { left++ })
+
+	first := NewSession(1, 1, "Ayla", "village")
+	second := NewSession(1, 1, "Ayla", "village")
+	hub.Register(first)
+	hub.Register(second)
+
+	select {
+	case <-first.Done():
+	default:
+		t.Errorf("%s FAILED: want previous session closed", t.Name())
+	}
+	// the replaced session going away doesn't disconnect the player
+	hub.Unregister(first)
+	assert.Equal(t, left, 0)
+	s, _ := hub.Find("ayla")
+	assert.Equal(t, s, second)
+
+	hub.Unregister(second)
+	assert.Equal(t, left, 1)
+	assert.Equal(t, len(hub.Sessions()), 0)
+}
+
+func TestDispatch(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("ping", func(s *Session, data json.RawMessage) error {
+		s.Send("pong", nil)
+		return nil
+	})
+	hub.Handle("fail", func(s *Session, data json.RawMessage) error {
+		return errors.New("nope")
+	})
+	s := NewSession(1, 1, "Ayla", "village")
+
+	hub.Dispatch(s, []byte(`{"type": "ping"}`))
+	assert.Equal(t, receive(t, s).Type, "pong")
+	hub.Dispatch(s, []byte(`{"type": "fail"}`))
+	assert.Equal(t, receive(t, s), Envelope{Type: "error", Data: `{"type":"fail","error":"nope"}`})
+	hub.Dispatch(s, []byte(`{"type": "dance"}`))
+	assert.Equal(t, receive(t, s).Type, "error")
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	s := NewSession(1, 1, "Ayla", "village")
+	for i := 0; i < settings.GatewaySendBuffer; i++ {
+		assert.Equal(t, s.Send("tick", i), true)
+	}
+	assert.Equal(t, s.Send("tick", 0), false)
+	select {
+	case <-s.Done():
+	default:
+		t.Errorf("%s FAILED: want session closed", t.Name())
+	}
+}
+
+func TestServe(t *testing.T) {
+	hub := NewHub()
+	hub.Handle("echo", func(s *Session, data json.RawMessage) error {
+		s.Send("echo", data)
+		return nil
+	})
+	left := make(chan *Session, 1)
+	hub.OnDisconnect(func(s *Session) { left <- s })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		s := NewSession(1, 1, "Ayla", "village")
+		hub.Register(s)
+		hub.Serve(conn, s)
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conn.WriteMessage(websocket.TextMessage, []byte(`{"type": "echo", "data": "hi"}`)); err != nil {
+		t.Fatal(err)
+	}
+	_, message, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(message), `{"type":"echo","data":"hi"}`)
+
+	_ = conn.Close()
+	assert.Equal(t, (<-left).PlayerID, 1)
+}
+
+func TestHoldDelaysDisconnect(t *testing.T) {
+	hub := NewHub()
+	left := make(chan int, 1)
+	hub.OnDisconnect(func(s *Session) { left <- s.PlayerID })
+
+	s := NewSession(1, 1, "Ayla", "village")
+	hub.Register(s)
+	release := hub.Hold(1)
+	done := make(chan struct{})
+	go func() {
+		hub.Unregister(s)
+		close(done)
+	}()
+	select {
+	case <-left:
+		t.Fatalf("%s FAILED: want disconnect held", t.Name())
+	case <-time.After(10 * time.Millisecond):
+	}
+	release()
+	<-done
+	assert.Equal(t, <-left, 1)
+	hub.mu.RLock()
+	assert.Equal(t, len(hub.holds), 0)
+	hub.mu.RUnlock()
+}
@@ -0,0 +1,111 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"tribble/events"
+	"tribble/simulation"
+	"tribble/world"
+)
+
+var ErrNotInWorld = errors.New("player is not in a simulated map")
+
+type moveData struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type attackData struct {
+	MonsterID int `json:"monster_id"`
+}
+
+type castData struct {
+	Ability   string `json:"ability"`
+	MonsterID int    `json:"monster_id"`
+}
+
+// Attach forwards the world events of every loop to the sessions observing
+// them and routes movement, attacks and casts of clients to their map loop.
+func (h *Hub) Attach(manager *simulation.Manager) {
+	h.mu.Lock()
+	h.loops = manager
+	h.mu.Unlock()
+
+	for _, loop := range manager.Loops() {
+		loop.Broadcast = h.broadcast
+		loop.Notify = h.notify
+	}
+
+	h.Handle("move", func(s *Session, data json.RawMessage) error {
+		var move moveData
+		if err := json.Unmarshal(data, &move); err != nil {
+			return err
+		}
+		loop, ok := h.Loop(s.Map)
+		if !ok {
+			return ErrNotInWorld
+		}
+		loop.Move(s.PlayerID, move.X, move.Y)
+		return nil
+	})
+	h.Handle("attack", func(s *Session, data json.RawMessage) error {
+		var attack attackData
+		if err := json.Unmarshal(data, &attack); err != nil {
+			return err
+		}
+		loop, ok := h.Loop(s.Map)
+		if !ok {
+			return ErrNotInWorld
+		}
+		loop.Attack(s.PlayerID, attack.MonsterID)
+		return nil
+	})
+	h.Handle("cast", func(s *Session, data json.RawMessage) error {
+		var cast castData
+		if err := json.Unmarshal(data, &cast); err != nil {
+			return err
+		}
+		loop, ok := h.Loop(s.Map)
+		if !ok {
+			return ErrNotInWorld
+		}
+		loop.Cast(s.PlayerID, cast.Ability, cast.MonsterID)
+		return nil
+	})
+	h.OnDisconnect(func(s *Session) {
+		loop, ok := h.Loop(s.Map)
+		if !ok {
+			return
+		}
+		// published here rather than queued by the loop, so it is handled
+		// before the player can connect again
+		if e, ok := loop.Leave(s.PlayerID); ok {
+			e.UserID = s.UserID
+			events.Publish(e)
+		}
+	})
+}
+
+// Loop returns the simulation loop of the map once a manager is attached.
+func (h *Hub) Loop(mapName string) (*simulation.Loop, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.loops == nil {
+		return nil, false
+	}
+	return h.loops.Loop(mapName)
+}
+
+func (h *Hub) broadcast(events []world.Event) {
+	for _, e := range events {
+		if s, ok := h.Session(e.Observer.ID); ok {
+			s.Send("world", e)
+		}
+	}
+}
+
+func (h *Hub) notify(playerID int, msgType string, data interface{}) {
+	if s, ok := h.Session(playerID); ok {
+		s.Send(msgType, data)
+	}
+}
@@ -0,0 +1,115 @@
+package guilds
+
+import (
+	"context"
+	"strings"
+	"tribble/gateway"
+	"tribble/models"
+	"tribble/settings"
+)
+
+// DefaultRanks are the ranks of new guilds.
+var DefaultRanks = []models.GuildRank{
+	{Name: "Guild Master", Permissions: models.GuildPermissions},
+	{Name: "Officer", Permissions: models.GuildPermissions},
+	{Name: "Member", Permissions: []string{models.GuildInvite}},
+	{Name: "Initiate", Permissions: []string{}},
+}
+
+// ValidateRanks checks ranks sent by a guild master and numbers them in
+// order. The guild master and at least one other rank must be defined.
+func ValidateRanks(ranks []models.GuildRank) ([]models.GuildRank, error) {
+	if len(ranks) < 2 || len(ranks) > settings.MaxGuildRanks {
+		return nil, models.ErrInvalidGuildRank
+	}
+	valid := make([]models.GuildRank, len(ranks))
+	for i, rank := range ranks {
+		name := strings.TrimSpace(rank.Name)
+		if name == "" || len(name) > 32 {
+			return nil, models.ErrInvalidGuildRank
+		}
+		permissions := make([]string, 0, len(rank.Permissions))
+		seen := make(map[string]bool)
+		for _, p := range rank.Permissions {
+			if !known(p) {
+				return nil, models.ErrInvalidGuildRank
+			}
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+		if i == 0 {
+			permissions = models.GuildPermissions
+		}
+		valid[i] = models.GuildRank{Rank: i, Name: name, Permissions: permissions}
+	}
+	return valid, nil
+}
+
+func known(permission string) bool {
+	for _, p := range models.GuildPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type Repository interface {
+	GetGuildMembers(ctx context.Context, guildID int) ([]*models.GuildMember, error)
+}
+
+// Roster lists the members of the guild, flagging the connected ones.
+func Roster(ctx context.Context, repo Repository, hub *gateway.Hub, guildID int) ([]*models.GuildMember, error) {
+	members, err := repo.GetGuildMembers(ctx, guildID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		_, m.Online = hub.Session(m.ID)
+	}
+	return members, nil
+}
+
+const (
+	EventInvited   = "invited"
+	EventJoined    = "joined"
+	EventLeft      = "left"
+	EventKicked    = "kicked"
+	EventRank      = "rank"
+	EventRanks     = "ranks"
+	EventMOTD      = "motd"
+	EventDisbanded = "disbanded"
+)
+
+// Event is sent to the connected members through the gateway when the
+// guild changes.
+type Event struct {
+	Event    string        `json:"event"`
+	GuildID  int           `json:"guild_id"`
+	PlayerID int           `json:"player_id,omitempty"`
+	Name     string        `json:"name,omitempty"`
+	Rank     *int          `json:"rank,omitempty"`
+	Guild    *models.Guild `json:"guild,omitempty"`
+}
+
+// Notify sends the event to the connected members of the guild and to the
+// player of the event, who may no longer be a member.
+func Notify(ctx context.Context, repo Repository, hub *gateway.Hub, e Event) error {
+	members, err := repo.GetGuildMembers(ctx, e.GuildID)
+	if err != nil {
+		return err
+	}
+	sent := false
+	for _, m := range members {
+		if s, ok := hub.Session(m.ID); ok {
+			s.Send("guild", e)
+		}
+		sent = sent || m.ID == e.PlayerID
+	}
+	if s, ok := hub.Session(e.PlayerID); ok && !sent {
+		s.Send("guild", e)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package guilds
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"tribble/gateway"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type fakeRepo struct{}
+
+func (fakeRepo) GetGuildMembers(ctx context.Context, guildID int) ([]*models.GuildMember, error) {
+	return []*models.GuildMember{
+		{Player: models.Player{ID: 1, Name: "Ayla"}, GuildID: guildID},
+		{Player: models.Player{ID: 2, Name: "Bren"}, GuildID: guildID, Rank: 1},
+	}, nil
+}
+
+func TestValidateRanks(t *testing.T) {
+	ranks, err := ValidateRanks([]models.GuildRank{
+		{Name: " Chief ", Permissions: []string{}},
+		{Name: "Banker", Permissions: []string{models.GuildWithdraw, models.GuildWithdraw}},
+		{Name: "Member"},
+	})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, ranks, []models.GuildRank{
+		{Rank: 0, Name: "Chief", Permissions: models.GuildPermissions},
+		{Rank: 1, Name: "Banker", Permissions: []string{models.GuildWithdraw}},
+		{Rank: 2, Name: "Member", Permissions: []string{}},
+	})
+	assert.Equal(t, ranks[1].Can(models.GuildWithdraw), true)
+	assert.Equal(t, ranks[1].Can(models.GuildKick), false)
+
+	invalid := map[string][]models.GuildRank{
+		"single rank":        {{Name: "Chief"}},
+		"blank name":         {{Name: "Chief"}, {Name: " "}},
+		"unknown permission": {{Name: "Chief"}, {Name: "Member", Permissions: []string{"fly"}}},
+	}
+	for name, ranks := range invalid {
+		if _, err := ValidateRanks(ranks); err != models.ErrInvalidGuildRank {
+			t.Errorf("%s FAILED: %v: want ErrInvalidGuildRank, got %v", t.Name(), name, err)
+		}
+	}
+}
+
+func TestRoster(t *testing.T) {
+	hub := gateway.NewHub()
+	hub.Register(gateway.NewSession(2, 20, "Bren", "village"))
+
+	members, err := Roster(context.Background(), fakeRepo{}, hub, 7)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, members[0].Online, false)
+	assert.Equal(t, members[1].Online, true)
+}
+
+func received(s *gateway.Session) []Event {
+	var events []Event
+	for {
+		select {
+		case message := <-s.Outbox():
+			var e struct {
+				Type string `json:"type"`
+				Data Event  `json:"data"`
+			}
+			_ = json.Unmarshal(message, &e)
+			events = append(events, e.Data)
+		default:
+			return events
+		}
+	}
+}
+
+func TestNotify(t *testing.T) {
+	hub := gateway.NewHub()
+	member := gateway.NewSession(2, 20, "Bren", "village")
+	kicked := gateway.NewSession(3, 30, "Cato", "village")
+	hub.Register(member)
+	hub.Register(kicked)
+
+	e := Event{Event: EventKicked, GuildID: 7, PlayerID: 3, Name: "Cato"}
+	assert.Equal(t, Notify(context.Background(), fakeRepo{}, hub, e), nil)
+	assert.Equal(t, received(member), []Event{e})
+	// players no longer in the guild hear about it too
+	assert.Equal(t, received(kicked), []Event{e})
+
+	e = Event{Event: EventRank, GuildID: 7, PlayerID: 2, Name: "Bren"}
+	assert.Equal(t, Notify(context.Background(), fakeRepo{}, hub, e), nil)
+	assert.Equal(t, received(member), []Event{e})
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"tribble/abilities"
+	"tribble/levels"
+	"tribble/status"
+)
+
+// GetAbilityList lists the abilities, only those of a class with ?class=.
+func GetAbilityList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, abilities.Default.List(r.URL.Query().Get("class")))
+}
+
+// GetPlayerAbilities lists the abilities the player unlocked.
+func GetPlayerAbilities(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, abilities.Default.Unlocked(player.Sprite, levels.Default.Level(player.XP)))
+}
+
+// GetStatusEffectList lists the status effects abilities apply.
+func GetStatusEffectList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, status.Default.List())
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"tribble/achievements"
+	"tribble/storages"
+)
+
+// GetPlayerAchievements lists every achievement with the progress of the
+// player.
+func GetPlayerAchievements(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	progress, err := storages.DB.GetAchievements(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, achievements.Statuses(achievements.Default, progress))
+}
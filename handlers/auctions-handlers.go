@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/models"
+	"tribble/storages"
+)
+
+// auctionQuery reads the search filters of the auction list.
+func auctionQuery(w http.ResponseWriter, r *http.Request) (models.AuctionQuery, bool) {
+	before, limit, ok := pageQuery(w, r)
+	if !ok {
+		return models.AuctionQuery{}, false
+	}
+	query := r.URL.Query()
+	q := models.AuctionQuery{
+		ItemID: query.Get("item"),
+		Type:   query.Get("type"),
+		Rarity: query.Get("rarity"),
+		Before: before,
+		Limit:  limit,
+	}
+	for name, price := range map[string]*int64{"min_price": &q.MinPrice, "max_price": &q.MaxPrice} {
+		if v := query.Get(name); v != "" {
+			var err error
+			if *price, err = strconv.ParseInt(v, 10, 64); err != nil || *price < 0 {
+				HandleApiErrors(w, http.StatusBadRequest, "invalid "+name)
+				return models.AuctionQuery{}, false
+			}
+		}
+	}
+	return q, true
+}
+
+// GetAuctionList searches the active auctions, newest first. Auctions can
+// be filtered by item, item type, rarity and current price.
+func GetAuctionList(w http.ResponseWriter, r *http.Request) {
+	query, ok := auctionQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	list, err := storages.DB.GetAuctions(ctx, query)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func GetAuction(w http.ResponseWriter, r *http.Request) {
+	auctionID, ok := pathID(w, r, "auction")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	auction, err := storages.DB.GetAuction(ctx, int64(auctionID))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, auction)
+}
+
+// GetPlayerAuctions lists the active auctions of the player.
+func GetPlayerAuctions(w http.ResponseWriter, r *http.Request) {
+	query, ok := auctionQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	query.SellerID = player.ID
+	list, err := storages.DB.GetAuctions(ctx, query)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// CreateAuction lists items of an inventory slot, they are held by the
+// auction house until it ends.
+func CreateAuction(w http.ResponseWriter, r *http.Request) {
+	var listing models.NewAuction
+	if !decodeBody(w, r, &listing) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	auction, err := storages.DB.CreateAuction(ctx, player.ID, listing)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, auction)
+}
+
+func CancelAuction(w http.ResponseWriter, r *http.Request) {
+	auctionID, ok := pathID(w, r, "auction")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	auction, err := storages.DB.CancelAuction(ctx, player.ID, int64(auctionID))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, auction)
+}
+
+// BidOnAuction escrows the bid, the previous highest bidder gets its bid
+// back by mail.
+func BidOnAuction(w http.ResponseWriter, r *http.Request) {
+	auctionID, ok := pathID(w, r, "auction")
+	if !ok {
+		return
+	}
+	var amount models.CurrencyAmount
+	if !decodeBody(w, r, &amount) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("auction:%d:%d", auctionID, player.ID))
+	if !ok {
+		return
+	}
+	auction, err := storages.DB.BidOnAuction(ctx, player.ID, int64(auctionID), amount.Amount, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, auction)
+}
+
+// BuyoutAuction pays the buyout price, the items arrive by mail.
+func BuyoutAuction(w http.ResponseWriter, r *http.Request) {
+	auctionID, ok := pathID(w, r, "auction")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("auction:%d:%d", auctionID, player.ID))
+	if !ok {
+		return
+	}
+	auction, err := storages.DB.BuyoutAuction(ctx, player.ID, int64(auctionID), key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, auction)
+}
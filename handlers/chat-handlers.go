@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/chat"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/storages"
+
+	"github.com/gorilla/mux"
+)
+
+type chatHistory struct {
+	Messages []*models.ChatMessage `json:"messages"`
+	// Next is the cursor of the following page, nil on the last one.
+	Next *int64 `json:"next"`
+}
+
+// pageQuery reads the ?before= cursor and ?limit= of paged lists.
+func pageQuery(w http.ResponseWriter, r *http.Request) (int64, int, bool) {
+	query := r.URL.Query()
+	var before int64
+	limit := 50
+	var err error
+	if b := query.Get("before"); b != "" {
+		if before, err = strconv.ParseInt(b, 10, 64); err != nil || before < 0 {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid before")
+			return 0, 0, false
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid limit")
+			return 0, 0, false
+		}
+	}
+	return before, limit, true
+}
+
+// GetChatHistory pages through the history of a channel the player can
+// read, newest messages first. Whispers name the other player with ?with=.
+func GetChatHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	before, limit, ok := pageQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var key string
+	switch channel := mux.Vars(r)["channel"]; channel {
+	case chat.Global, chat.Local:
+		key = chat.Key(channel, player.Map, "", 0, 0)
+	case chat.Party:
+		party, _, ok := chat.Default.PartyOf(player.ID)
+		if !ok {
+			HandleRepositoryErrors(w, models.ErrNotInParty)
+			return
+		}
+		key = chat.Key(chat.Party, "", party, 0, 0)
+	case chat.Whisper:
+		other, err := storages.DB.GetPlayerByName(ctx, query.Get("with"))
+		if err != nil {
+			HandleRepositoryErrors(w, err)
+			return
+		}
+		key = chat.Key(chat.Whisper, "", "", player.ID, other.ID)
+	default:
+		HandleRepositoryErrors(w, models.ErrUnknownChannel)
+		return
+	}
+
+	// one more message than asked tells whether there is a next page
+	messages, err := storages.DB.GetChatMessages(ctx, key, before, limit+1)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	history := chatHistory{Messages: messages}
+	if len(messages) > limit {
+		history.Messages = messages[:limit]
+		history.Next = &messages[limit-1].ID
+	}
+	response, err := json.Marshal(history)
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func isModerator(r *http.Request) bool {
+	return hasUserID(r, settings.ChatModerators)
+}
+
+func MuteUser(w http.ResponseWriter, r *http.Request) {
+	if !isModerator(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	var mute models.ChatMute
+	if err := json.NewDecoder(r.Body).Decode(&mute); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return
+	}
+	if validationErr := validate.Struct(mute); validationErr != nil {
+		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
+		return
+	}
+	if !mute.Until.After(time.Now()) {
+		HandleApiErrors(w, http.StatusBadRequest, "until must be in the future")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := storages.DB.MuteUser(ctx, mute); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func UnmuteUser(w http.ResponseWriter, r *http.Request) {
+	if !isModerator(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err = storages.DB.UnmuteUser(ctx, userID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"tribble/classes"
+
+	"github.com/go-playground/validator"
+)
+
+func init() {
+	_ = validate.RegisterValidation("class", func(fl validator.FieldLevel) bool {
+		_, ok := classes.Default.Get(fl.Field().String())
+		return ok
+	})
+}
+
+func GetClassList(w http.ResponseWriter, r *http.Request) {
+	response, err := json.Marshal(classes.Default.List())
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
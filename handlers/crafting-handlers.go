@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"tribble/crafting"
+	"tribble/jobs"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/storages"
+)
+
+// GetRecipeList lists every recipe of the game.
+func GetRecipeList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, crafting.Default.List())
+}
+
+// GetProfessions returns the level of the player in every profession.
+func GetProfessions(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	professions, err := storages.DB.GetProfessions(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, crafting.Default.Levels(professions))
+}
+
+// GetKnownRecipes lists the recipes the player has the profession level
+// for.
+func GetKnownRecipes(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	professions, err := storages.DB.GetProfessions(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, crafting.Default.Known(professions))
+}
+
+// GetCrafts lists the crafts of the player in progress or ready.
+func GetCrafts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	crafts, err := storages.DB.GetCrafts(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, crafts)
+}
+
+// StartCraft crafts a recipe count times, once when no count is given.
+// Recipes without crafting time are collected right away, the others
+// finish through the job scheduler.
+func StartCraft(w http.ResponseWriter, r *http.Request) {
+	var newCraft models.NewCraft
+	if !decodeBody(w, r, &newCraft) {
+		return
+	}
+	if newCraft.Count == 0 {
+		newCraft.Count = 1
+	}
+	if newCraft.Count > settings.MaxCraftCount {
+		HandleApiErrors(w, http.StatusBadRequest, fmt.Sprintf("count must be at most %d", settings.MaxCraftCount))
+		return
+	}
+	recipe, ok := crafting.Default.Get(newCraft.RecipeID)
+	if !ok {
+		HandleApiErrors(w, http.StatusBadRequest, "unknown recipe")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	craft, err := storages.DB.StartCraft(ctx, player.ID, crafting.Default.Order(recipe, newCraft.Count))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if craft.Status == models.CraftActive {
+		jobs.Default.At(crafting.FinishJob, craft.FinishesAt)
+	}
+	writeJSON(w, http.StatusCreated, craft)
+}
+
+// CollectCraft moves the output of a finished craft to the inventory.
+func CollectCraft(w http.ResponseWriter, r *http.Request) {
+	craftID, ok := pathID(w, r, "craft")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	craft, err := storages.DB.CollectCraft(ctx, player.ID, int64(craftID))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, craft)
+}
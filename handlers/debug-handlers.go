@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"tribble/pathfinding"
+	"tribble/world"
+
+	"github.com/gorilla/mux"
+)
+
+type pathResponse struct {
+	Map  string        `json:"map"`
+	From world.Point   `json:"from"`
+	To   world.Point   `json:"to"`
+	Path []world.Point `json:"path"`
+}
+
+func parsePoint(value string) (world.Point, error) {
+	var p world.Point
+	if _, err := fmt.Sscanf(value, "%d,%d", &p.X, &p.Y); err != nil {
+		return p, fmt.Errorf("invalid point %q, want x,y", value)
+	}
+	return p, nil
+}
+
+// GetMapPath returns the path monsters would walk between two points of a
+// map, from and to are given as x,y query parameters.
+func GetMapPath(w http.ResponseWriter, r *http.Request) {
+	m, ok := world.Maps.Get(mux.Vars(r)["map"])
+	if !ok {
+		HandleApiErrors(w, http.StatusNotFound, "")
+		return
+	}
+	from, err := parsePoint(r.URL.Query().Get("from"))
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	to, err := parsePoint(r.URL.Query().Get("to"))
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	path, err := pathfinding.For(m).Path(from, to)
+	if err != nil {
+		HandleApiErrors(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	response, err := json.Marshal(pathResponse{Map: m.Name, From: from, To: to, Path: path})
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/storages"
+)
+
+// hasUserID tells whether the caller is one of the users.
+func hasUserID(r *http.Request, IDs []int) bool {
+	value, _ := r.Context().Value(settings.I).(string)
+	userID, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	for _, ID := range IDs {
+		if ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func isAdmin(r *http.Request) bool {
+	return hasUserID(r, settings.Admins)
+}
+
+// GetCharacterSlots shows how many characters the caller has and may have.
+func GetCharacterSlots(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	slots, err := storages.DB.GetCharacterSlots(ctx, userID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, slots)
+}
+
+func GetEntitlements(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	entitlements, err := storages.DB.GetEntitlements(ctx, userID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entitlements)
+}
+
+func GrantEntitlement(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	userID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	var entitlement models.Entitlement
+	if !decodeBody(w, r, &entitlement) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if _, err := storages.DB.GetUser(ctx, userID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	granted, err := storages.DB.GrantEntitlement(ctx, userID, entitlement)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, granted)
+}
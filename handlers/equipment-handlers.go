@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+	"tribble/items"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/stats"
+	"tribble/storages"
+
+	"github.com/gorilla/mux"
+)
+
+func writePlayerDetail(ctx context.Context, w http.ResponseWriter, player *models.Player) {
+	equipment, err := storages.DB.GetEquipment(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	effects, err := storages.DB.GetPlayerEffects(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	unread, err := storages.DB.CountUnreadMail(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	levels.Apply(player)
+
+	response, err := json.Marshal(models.PlayerDetail{
+		Player:     *player,
+		Equipment:  equipment,
+		StatSheet:  stats.Calculate(player, equipment, effects),
+		UnreadMail: unread,
+	})
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func GetPlayerDetail(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	writePlayerDetail(ctx, w, player)
+}
+
+func EquipItem(w http.ResponseWriter, r *http.Request) {
+	var equip models.Equip
+	if err := json.NewDecoder(r.Body).Decode(&equip); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return
+	}
+	if validationErr := validate.Struct(equip); validationErr != nil {
+		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := storages.DB.GetInventory(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	var item *models.Item
+	for _, slot := range slots {
+		if slot.Slot == equip.InventorySlot {
+			item, _ = items.Default.Get(slot.ItemID)
+		}
+	}
+	if item == nil {
+		HandleApiErrors(w, http.StatusBadRequest, models.ErrEmptySlot.Error())
+		return
+	}
+
+	levels.Apply(player)
+	if err = items.CanEquip(item, player.Sprite, player.Level); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// the repository checks the slot still holds the same item
+	if _, err = storages.DB.EquipItem(ctx, player.ID, equip.InventorySlot, *item); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writePlayerDetail(ctx, w, player)
+}
+
+func UnequipItem(w http.ResponseWriter, r *http.Request) {
+	slot := mux.Vars(r)["slot"]
+	if !items.ValidSlot(slot) {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid equipment slot")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := storages.DB.UnequipItem(ctx, player.ID, slot); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writePlayerDetail(ctx, w, player)
+}
@@ -2,12 +2,96 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"tribble/models"
 
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 )
 
+// domainErrors are repository errors caused by the request itself.
+var domainErrors = []error{
+	models.ErrInventoryFull,
+	models.ErrInvalidSlot,
+	models.ErrEmptySlot,
+	models.ErrSlotOccupied,
+	models.ErrNotEnoughItems,
+	models.ErrItemChanged,
+	models.ErrNotEquippable,
+	models.ErrWrongClass,
+	models.ErrLevelTooLow,
+	models.ErrNotEquipped,
+	models.ErrNotDead,
+	models.ErrQuestLocked,
+	models.ErrQuestAccepted,
+	models.ErrQuestCompleted,
+	models.ErrQuestNotActive,
+	models.ErrQuestIncomplete,
+	models.ErrTooManyQuests,
+	models.ErrUnknownChannel,
+	models.ErrEmptyMessage,
+	models.ErrMessageTooLong,
+	models.ErrChatRateLimited,
+	models.ErrMuted,
+	models.ErrNotInParty,
+	models.ErrPlayerOffline,
+	models.ErrBlocked,
+	models.ErrSelfReference,
+	models.ErrAlreadyFriends,
+	models.ErrFriendRequestExists,
+	models.ErrNotFriends,
+	models.ErrTooManyFriends,
+	models.ErrAlreadyInParty,
+	models.ErrNotPartyLeader,
+	models.ErrNotPartyMember,
+	models.ErrPartyFull,
+	models.ErrNoPartyInvite,
+	models.ErrAlreadyInGuild,
+	models.ErrNotInGuild,
+	models.ErrNotGuildMember,
+	models.ErrGuildPermission,
+	models.ErrGuildRankTooLow,
+	models.ErrGuildFull,
+	models.ErrNoGuildInvite,
+	models.ErrGuildMaster,
+	models.ErrInvalidGuildRank,
+	models.ErrGuildBankFull,
+	models.ErrGuildNotEmpty,
+	models.ErrCharacterLimit,
+	models.ErrAlreadyTrading,
+	models.ErrNotTrading,
+	models.ErrNoTradeRequest,
+	models.ErrTradeNotLocked,
+	models.ErrTradeBusy,
+	models.ErrTooManyTradeItems,
+	models.ErrEmptyTrade,
+	models.ErrInvalidAmount,
+	models.ErrInsufficientFunds,
+	models.ErrIdempotencyConflict,
+	models.ErrNotSold,
+	models.ErrNotBought,
+	models.ErrOutOfStock,
+	models.ErrVendorTooFar,
+	models.ErrMailClaimed,
+	models.ErrNoAttachments,
+	models.ErrMailAttached,
+	models.ErrInvalidBuyout,
+	models.ErrInvalidDuration,
+	models.ErrNotTradable,
+	models.ErrTooManyAuctions,
+	models.ErrAuctionClosed,
+	models.ErrOwnAuction,
+	models.ErrHighestBidder,
+	models.ErrBidTooLow,
+	models.ErrNoBuyout,
+	models.ErrAuctionHasBids,
+	models.ErrProfessionTooLow,
+	models.ErrTooManyCrafts,
+	models.ErrCraftNotReady,
+}
+
 func HandleApiErrors(w http.ResponseWriter, status int, message string) {
 	if message == "" {
 		message = http.StatusText(status)
@@ -35,3 +119,23 @@ func HandleDatabaseErrors(w http.ResponseWriter, pgErr *pgconn.PgError) {
 		HandleApiErrors(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 	}
 }
+
+func HandleRepositoryErrors(w http.ResponseWriter, err error) {
+	log.Println(err.Error())
+	for _, domainErr := range domainErrors {
+		if errors.Is(err, domainErr) {
+			HandleApiErrors(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		HandleDatabaseErrors(w, pgErr)
+		return
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		HandleApiErrors(w, http.StatusNotFound, "")
+		return
+	}
+	HandleApiErrors(w, http.StatusInternalServerError, "")
+}
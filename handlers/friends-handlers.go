@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/gateway"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/social"
+	"tribble/storages"
+
+	"github.com/gorilla/mux"
+)
+
+func currentUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userId, err := strconv.Atoi(r.Context().Value(settings.I).(string))
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return 0, false
+	}
+	return userId, true
+}
+
+func pathID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	ID, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid "+name)
+		return 0, false
+	}
+	return ID, true
+}
+
+// decodeUserName resolves the user named in the request body.
+func decodeUserName(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	var name models.UserName
+	if err := json.NewDecoder(r.Body).Decode(&name); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return nil, false
+	}
+	if validationErr := validate.Struct(name); validationErr != nil {
+		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
+		return nil, false
+	}
+	user, err := storages.DB.GetUserByUsername(ctx, name.Username)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	response, err := json.Marshal(v)
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(response)
+}
+
+func GetFriendList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	friends, err := social.Friends(ctx, storages.DB, gateway.Default, userID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, friends)
+}
+
+func RemoveFriend(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	friendID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := storages.DB.RemoveFriend(ctx, userID, friendID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetFriendRequests(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	requests, err := storages.DB.GetFriendRequests(ctx, userID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, requests)
+}
+
+func SendFriendRequest(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	recipient, ok := decodeUserName(ctx, w, r)
+	if !ok {
+		return
+	}
+	request, err := storages.DB.SendFriendRequest(ctx, userID, recipient.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, request)
+}
+
+// answerFriendRequest runs one of the repository operations a user can do
+// on a pending request.
+func answerFriendRequest(w http.ResponseWriter, r *http.Request, answer func(ctx context.Context, userID, requestID int) error) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	requestID, ok := pathID(w, r, "request")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := answer(ctx, userID, requestID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func AcceptFriendRequest(w http.ResponseWriter, r *http.Request) {
+	answerFriendRequest(w, r, storages.DB.AcceptFriendRequest)
+}
+
+func DeclineFriendRequest(w http.ResponseWriter, r *http.Request) {
+	answerFriendRequest(w, r, storages.DB.DeclineFriendRequest)
+}
+
+func CancelFriendRequest(w http.ResponseWriter, r *http.Request) {
+	answerFriendRequest(w, r, storages.DB.CancelFriendRequest)
+}
+
+func GetBlockList(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	blocks, err := storages.DB.GetBlocks(ctx, userID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blocks)
+}
+
+func BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	blocked, ok := decodeUserName(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := storages.DB.BlockUser(ctx, userID, blocked.ID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	blockedID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if err := storages.DB.UnblockUser(ctx, userID, blockedID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+	"tribble/classes"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/gateway"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/party"
+	"tribble/stats"
+	"tribble/status"
+	"tribble/storages"
+
+	"github.com/gorilla/websocket"
+)
+
+// ConnectPlayer upgrades the request to the real-time connection of the
+// player and puts it into the simulation of its map.
+func ConnectPlayer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	// a previous session of the player finishes leaving, its status effects
+	// saved, before this one reads them
+	release := gateway.Default.Hold(player.ID)
+	conn, session, ok := joinWorld(ctx, w, r, player)
+	release()
+	if ok {
+		gateway.Default.Serve(conn, session)
+	}
+}
+
+func joinWorld(ctx context.Context, w http.ResponseWriter, r *http.Request, player *models.Player) (*websocket.Conn, *gateway.Session, bool) {
+	equipment, err := storages.DB.GetEquipment(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return nil, nil, false
+	}
+	effects, err := storages.DB.GetPlayerEffects(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return nil, nil, false
+	}
+	loop, ok := gateway.Default.Loop(player.Map)
+	if !ok {
+		HandleApiErrors(w, http.StatusConflict, "map is not simulated")
+		return nil, nil, false
+	}
+
+	conn, err := gateway.Upgrade(w, r)
+	if err != nil {
+		// the upgrader already replied to the client
+		log.Printf("could not upgrade connection of player %v: %v", player.ID, err.Error())
+		return nil, nil, false
+	}
+
+	levels.Apply(player)
+	sheet := stats.Calculate(player, equipment, effects)
+	attackRange := 1
+	if class, ok := classes.Default.Get(player.Sprite); ok {
+		attackRange = class.AttackRange
+	}
+
+	session := gateway.NewSession(player.ID, player.UserID, player.Name, player.Map)
+	gateway.Default.Register(session)
+	party.Default.Connect(player.ID)
+	events.Publish(events.Event{
+		Type:     events.PlayerConnected,
+		UserID:   player.UserID,
+		PlayerID: player.ID,
+		Data:     events.PlayerConnectedData{Map: player.Map},
+	})
+	session.Send("welcome", models.PlayerDetail{Player: *player, Equipment: equipment, StatSheet: sheet})
+	combatant := combat.FromPlayer(player, sheet, status.Default.Restore(effects, time.Now()))
+	loop.Join(combatant, attackRange, player.PositionX, player.PositionY)
+	return conn, session, true
+}
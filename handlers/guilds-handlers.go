@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"tribble/gateway"
+	"tribble/guilds"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/social"
+	"tribble/storages"
+)
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return false
+	}
+	if validationErr := validate.Struct(v); validationErr != nil {
+		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
+		return false
+	}
+	return true
+}
+
+// notifyGuild sends the event to the connected members, the change itself
+// already succeeded so failures are only logged.
+func notifyGuild(ctx context.Context, e guilds.Event) {
+	if err := guilds.Notify(ctx, storages.DB, gateway.Default, e); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func GetGuild(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := pathID(w, r, "guild")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	guild, err := storages.DB.GetGuild(ctx, guildID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, guild)
+}
+
+func GetGuildMembers(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := pathID(w, r, "guild")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	members, err := guilds.Roster(ctx, storages.DB, gateway.Default, guildID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	for _, m := range members {
+		levels.Apply(&m.Player)
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+type playerGuild struct {
+	Guild  *models.Guild       `json:"guild"`
+	Member *models.GuildMember `json:"member"`
+}
+
+func GetPlayerGuild(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, member, err := storages.DB.GetPlayerGuild(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	_, member.Online = gateway.Default.Session(player.ID)
+	levels.Apply(&member.Player)
+	writeJSON(w, http.StatusOK, playerGuild{Guild: guild, Member: member})
+}
+
+func CreateGuild(w http.ResponseWriter, r *http.Request) {
+	var name models.GuildName
+	if !decodeBody(w, r, &name) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, err := storages.DB.CreateGuild(ctx, player.ID, name.Name, guilds.DefaultRanks)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, guild)
+}
+
+// LeaveGuild takes the player out of its guild, the guild master can only
+// leave once alone, disbanding the guild.
+func LeaveGuild(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := storages.DB.LeaveGuild(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	e := guilds.Event{Event: guilds.EventLeft, GuildID: member.GuildID, PlayerID: player.ID, Name: player.Name}
+	if member.Rank == 0 {
+		e.Event = guilds.EventDisbanded
+	}
+	notifyGuild(ctx, e)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func KickGuildMember(w http.ResponseWriter, r *http.Request) {
+	memberID, ok := pathID(w, r, "member")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := storages.DB.KickGuildMember(ctx, player.ID, memberID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventKicked, GuildID: member.GuildID, PlayerID: member.ID, Name: member.Name})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetGuildMemberRank promotes or demotes a member, giving rank 0 passes
+// the lead on.
+func SetGuildMemberRank(w http.ResponseWriter, r *http.Request) {
+	memberID, ok := pathID(w, r, "member")
+	if !ok {
+		return
+	}
+	var update models.GuildRankUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := storages.DB.SetGuildMemberRank(ctx, player.ID, memberID, update.Rank)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventRank, GuildID: member.GuildID, PlayerID: member.ID, Name: member.Name, Rank: &member.Rank})
+	if member.Rank == 0 {
+		demoted := 1
+		notifyGuild(ctx, guilds.Event{Event: guilds.EventRank, GuildID: member.GuildID, PlayerID: player.ID, Name: player.Name, Rank: &demoted})
+	}
+	levels.Apply(&member.Player)
+	writeJSON(w, http.StatusOK, member)
+}
+
+func SetGuildRanks(w http.ResponseWriter, r *http.Request) {
+	var ranks []models.GuildRank
+	if err := json.NewDecoder(r.Body).Decode(&ranks); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return
+	}
+	ranks, err := guilds.ValidateRanks(ranks)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, err := storages.DB.SetGuildRanks(ctx, player.ID, ranks)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventRanks, GuildID: guild.ID, Guild: guild})
+	writeJSON(w, http.StatusOK, guild)
+}
+
+func SetGuildMOTD(w http.ResponseWriter, r *http.Request) {
+	var update models.GuildMOTDUpdate
+	if !decodeBody(w, r, &update) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, err := storages.DB.SetGuildMOTD(ctx, player.ID, update.MOTD)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventMOTD, GuildID: guild.ID, Guild: guild})
+	writeJSON(w, http.StatusOK, guild)
+}
+
+func GetGuildInvites(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	invites, err := storages.DB.GetGuildInvites(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// InviteToGuild invites the player named in the body, users that blocked
+// each other can't invite each other.
+func InviteToGuild(w http.ResponseWriter, r *http.Request) {
+	var name models.PlayerName
+	if !decodeBody(w, r, &name) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	invited, err := storages.DB.GetPlayerByName(ctx, name.Name)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if err = social.CheckBlocked(ctx, storages.DB, player.UserID, invited.UserID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	invite, err := storages.DB.InviteToGuild(ctx, player.ID, invited.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventInvited, GuildID: invite.GuildID, PlayerID: invited.ID, Name: invited.Name})
+	writeJSON(w, http.StatusCreated, invite)
+}
+
+func AcceptGuildInvite(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := pathID(w, r, "guild")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := storages.DB.AcceptGuildInvite(ctx, player.ID, guildID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyGuild(ctx, guilds.Event{Event: guilds.EventJoined, GuildID: guildID, PlayerID: player.ID, Name: player.Name, Rank: &member.Rank})
+	levels.Apply(&member.Player)
+	writeJSON(w, http.StatusOK, member)
+}
+
+func DeclineGuildInvite(w http.ResponseWriter, r *http.Request) {
+	guildID, ok := pathID(w, r, "guild")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := storages.DB.DeclineGuildInvite(ctx, player.ID, guildID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func GetGuildBank(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, _, err := storages.DB.GetPlayerGuild(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	bank, err := storages.DB.GetGuildBank(ctx, guild.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bank)
+}
+
+func DepositGuildItem(w http.ResponseWriter, r *http.Request) {
+	var move models.GuildBankMove
+	if !decodeBody(w, r, &move) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	bank, err := storages.DB.DepositGuildItem(ctx, player.ID, move.Slot, move.Quantity)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bank)
+}
+
+// WithdrawGuildItem moves items from a bank slot to the inventory, it
+// needs the withdraw permission.
+func WithdrawGuildItem(w http.ResponseWriter, r *http.Request) {
+	var move models.GuildBankMove
+	if !decodeBody(w, r, &move) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	bank, err := storages.DB.WithdrawGuildItem(ctx, player.ID, move.Slot, move.Quantity)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, bank)
+}
+
+type guildBankLog struct {
+	Entries []*models.GuildBankEntry `json:"entries"`
+	// Next is the cursor of the following page, nil on the last one.
+	Next *int64 `json:"next"`
+}
+
+func GetGuildBankLog(w http.ResponseWriter, r *http.Request) {
+	before, limit, ok := pageQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, _, err := storages.DB.GetPlayerGuild(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	// one more entry than asked tells whether there is a next page
+	entries, err := storages.DB.GetGuildBankLog(ctx, guild.ID, before, limit+1)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	page := guildBankLog{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.Next = &entries[limit-1].ID
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func GetGuildCurrency(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	guild, _, err := storages.DB.GetPlayerGuild(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	balance, err := storages.DB.GetGuildBalance(ctx, guild.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.Wallet{Balance: balance})
+}
+
+func DepositGuildCurrency(w http.ResponseWriter, r *http.Request) {
+	moveGuildCurrency(w, r, "guild-deposit", storages.DB.DepositGuildCurrency)
+}
+
+// WithdrawGuildCurrency needs the withdraw permission.
+func WithdrawGuildCurrency(w http.ResponseWriter, r *http.Request) {
+	moveGuildCurrency(w, r, "guild-withdrawal", storages.DB.WithdrawGuildCurrency)
+}
+
+func moveGuildCurrency(w http.ResponseWriter, r *http.Request, scope string, move func(ctx context.Context, playerID int, amount int64, key string) (*models.LedgerTransaction, error)) {
+	var amount models.CurrencyAmount
+	if !decodeBody(w, r, &amount) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("%v:%d", scope, player.ID))
+	if !ok {
+		return
+	}
+	transaction, err := move(ctx, player.ID, amount.Amount, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeTransaction(w, transaction)
+}
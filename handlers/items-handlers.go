@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/storages"
+
+	"github.com/gorilla/mux"
+)
+
+func GetItemList(w http.ResponseWriter, r *http.Request) {
+	response, err := json.Marshal(items.Default.List())
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func writeInventory(w http.ResponseWriter, slots []*models.InventorySlot) {
+	response, err := json.Marshal(struct {
+		Capacity int                     `json:"capacity"`
+		Slots    []*models.InventorySlot `json:"slots"`
+	}{settings.InventoryCapacity, slots})
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func GetInventory(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := storages.DB.GetInventory(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeInventory(w, slots)
+}
+
+func decodeInventoryMove(w http.ResponseWriter, r *http.Request) (*models.InventoryMove, bool) {
+	var move models.InventoryMove
+	if err := json.NewDecoder(r.Body).Decode(&move); err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "unable to decode request body")
+		return nil, false
+	}
+	if validationErr := validate.Struct(move); validationErr != nil {
+		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
+		return nil, false
+	}
+	return &move, true
+}
+
+func MoveInventoryItem(w http.ResponseWriter, r *http.Request) {
+	move, ok := decodeInventoryMove(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := storages.DB.MoveItem(ctx, player.ID, move.From, move.To)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeInventory(w, slots)
+}
+
+func SplitInventoryStack(w http.ResponseWriter, r *http.Request) {
+	move, ok := decodeInventoryMove(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	slots, err := storages.DB.SplitStack(ctx, player.ID, move.From, move.To, move.Quantity)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeInventory(w, slots)
+}
+
+func DeleteInventoryItem(w http.ResponseWriter, r *http.Request) {
+	slot, err := strconv.Atoi(mux.Vars(r)["slot"])
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid slot")
+		return
+	}
+	quantity := 1
+	if q := r.URL.Query().Get("quantity"); q != "" {
+		if quantity, err = strconv.Atoi(q); err != nil {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid quantity")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	if err = storages.DB.RemoveItem(ctx, player.ID, slot, quantity); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+	"tribble/classes"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/storages"
+)
+
+// leaderboardClass reads the ?class= filter, empty for every class.
+func leaderboardClass(w http.ResponseWriter, r *http.Request) (string, bool) {
+	class := r.URL.Query().Get("class")
+	if class == "" {
+		return "", true
+	}
+	if _, ok := classes.Default.Get(class); !ok {
+		HandleApiErrors(w, http.StatusBadRequest, "unknown class")
+		return "", false
+	}
+	return class, true
+}
+
+func writeLeaderboard(w http.ResponseWriter, board *models.Leaderboard) {
+	for _, e := range board.Entries {
+		e.Level = levels.Default.Level(e.XP)
+	}
+	writeJSON(w, http.StatusOK, board)
+}
+
+// GetXPLeaderboard pages through the players ranked by XP, ?after_xp= and
+// ?after_player= take the next cursor of the previous page.
+func GetXPLeaderboard(w http.ResponseWriter, r *http.Request) {
+	class, ok := leaderboardClass(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	var after *models.LeaderboardCursor
+	limit := 50
+	var err error
+	if query.Get("after_xp") != "" || query.Get("after_player") != "" {
+		after = &models.LeaderboardCursor{}
+		if after.XP, err = strconv.Atoi(query.Get("after_xp")); err != nil || after.XP < 0 {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid after_xp")
+			return
+		}
+		if after.PlayerID, err = strconv.Atoi(query.Get("after_player")); err != nil || after.PlayerID < 0 {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid after_player")
+			return
+		}
+	}
+	if l := query.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > 100 {
+			HandleApiErrors(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	board, err := storages.DB.GetXPLeaderboard(ctx, class, after, limit)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeLeaderboard(w, board)
+}
+
+// GetMyXPLeaderboard shows the rank of the ?player= of the caller with the
+// players ranked around it. Players created since the last refresh are
+// not ranked yet.
+func GetMyXPLeaderboard(w http.ResponseWriter, r *http.Request) {
+	class, ok := leaderboardClass(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	playerID, err := strconv.Atoi(r.URL.Query().Get("player"))
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid player")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, err := storages.DB.GetPlayer(ctx, playerID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if player.UserID != userID {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	board, err := storages.DB.GetXPLeaderboardAround(ctx, player.ID, class, settings.LeaderboardNeighbours)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeLeaderboard(w, board)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"tribble/gateway"
+	"tribble/items"
+	"tribble/models"
+	"tribble/social"
+	"tribble/storages"
+)
+
+// GetMail pages through the mailbox of the player, newest mail first.
+func GetMail(w http.ResponseWriter, r *http.Request) {
+	before, limit, ok := pageQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	mail, err := storages.DB.GetMail(ctx, player.ID, before, limit)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mail)
+}
+
+// ClaimMail moves the item and currency attached to a mail to the player.
+func ClaimMail(w http.ResponseWriter, r *http.Request) {
+	mailID, ok := pathID(w, r, "mail")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	mail, err := storages.DB.ClaimMail(ctx, player.ID, int64(mailID))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mail)
+}
+
+// notifyMail tells the recipient, if connected, that mail arrived.
+func notifyMail(recipientID int, mail *models.Mail) {
+	if s, ok := gateway.Default.Session(recipientID); ok {
+		s.Send("mail", mail)
+	}
+}
+
+// ReadMail returns a mail of the player, marking it read.
+func ReadMail(w http.ResponseWriter, r *http.Request) {
+	mailID, ok := pathID(w, r, "mail")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	mail, err := storages.DB.ReadMail(ctx, player.ID, int64(mailID))
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, mail)
+}
+
+// SendMail sends mail to the player named as recipient, the items of an
+// inventory slot and currency can be attached. Mail with currency needs
+// an Idempotency-Key header.
+func SendMail(w http.ResponseWriter, r *http.Request) {
+	var newMail models.NewMail
+	if !decodeBody(w, r, &newMail) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	var key string
+	if newMail.Currency > 0 || r.Header.Get("Idempotency-Key") != "" {
+		if key, ok = idempotencyKey(w, r, fmt.Sprintf("mail:%d", player.ID)); !ok {
+			return
+		}
+	}
+	recipient, err := storages.DB.GetPlayerByName(ctx, newMail.Recipient)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if err = social.CheckBlocked(ctx, storages.DB, player.UserID, recipient.UserID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	mail, err := storages.DB.SendMail(ctx, player.ID, recipient.ID, newMail, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyMail(recipient.ID, mail)
+	writeJSON(w, http.StatusCreated, mail)
+}
+
+// SendSystemMail lets admins send mail from the game, with created items
+// and currency attached.
+func SendSystemMail(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	playerID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("system-mail:%d", playerID))
+	if !ok {
+		return
+	}
+	var systemMail models.SystemMail
+	if !decodeBody(w, r, &systemMail) {
+		return
+	}
+	if _, ok = items.Default.Get(systemMail.ItemID); systemMail.ItemID != "" && !ok {
+		HandleApiErrors(w, http.StatusBadRequest, "unknown item")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	mail, err := storages.DB.SendSystemMail(ctx, playerID, systemMail, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	notifyMail(playerID, mail)
+	writeJSON(w, http.StatusCreated, mail)
+}
+
+// DeleteMail deletes a mail once its attachments are claimed.
+func DeleteMail(w http.ResponseWriter, r *http.Request) {
+	mailID, ok := pathID(w, r, "mail")
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := storages.DB.DeleteMail(ctx, player.ID, int64(mailID)); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -8,10 +8,18 @@ import (
 	"net/http"
 	"strconv"
 	"time"
+	"tribble/classes"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/levels"
 	"tribble/models"
+	"tribble/names"
 	"tribble/settings"
+	"tribble/stats"
 	"tribble/storages"
+	"tribble/world"
 
+	"github.com/gorilla/mux"
 	"github.com/jackc/pgconn"
 )
 
@@ -31,6 +39,12 @@ func CreatePlayer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	name, ok := checkName(w, names.PlayerNames, player.Name)
+	if !ok {
+		return
+	}
+	player.Name = name
+
 	userId, err := strconv.Atoi(r.Context().Value(settings.I).(string))
 	if err != nil {
 		log.Println(err.Error())
@@ -38,9 +52,15 @@ func CreatePlayer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	player.UserID = userId
-	// TODO: position cannot be hardcoded
-	player.PositionX = 8
-	player.PositionY = 8
+	player.XP = 0
+	class, _ := classes.Default.Get(player.Sprite)
+	player.Stats = class.Stats(1)
+	player.HP = player.Stats.HP
+	player.Mana = player.Stats.Mana
+	start := world.Maps.Start()
+	player.Map = start.Name
+	player.PositionX = start.Spawn.X
+	player.PositionY = start.Spawn.Y
 
 	// TODO: insert player on database
 
@@ -50,20 +70,48 @@ func CreatePlayer(w http.ResponseWriter, r *http.Request) {
 	player, err = storages.DB.CreatePlayer(ctx, *player)
 
 	if err != nil {
-		log.Println(err.Error())
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			HandleDatabaseErrors(w, pgErr)
-			return
-		}
-		HandleApiErrors(w, http.StatusInternalServerError, "")
+		HandleRepositoryErrors(w, err)
 		return
 	}
 
+	levels.Apply(player)
+	events.Publish(events.Event{Type: events.PlayerCreated, UserID: player.UserID, PlayerID: player.ID})
+
 	response, _ := json.Marshal(player)
 	_, _ = w.Write(response)
 }
 
+// ValidatePlayerName checks a name against the name policy and whether it
+// is still available.
+func ValidatePlayerName(w http.ResponseWriter, r *http.Request) {
+	var body models.PlayerName
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	name, ok := checkName(w, names.PlayerNames, body.Name)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	taken, err := storages.DB.PlayerNameTaken(ctx, name)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if taken {
+		HandleApiErrors(w, http.StatusBadRequest, "name not available")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Ok   bool   `json:"ok"`
+		Name string `json:"name"`
+	}{Ok: true, Name: name})
+}
+
 func GetPlayerList(w http.ResponseWriter, r *http.Request) {
 
 	userId, err := strconv.Atoi(r.Context().Value(settings.I).(string))
@@ -89,6 +137,10 @@ func GetPlayerList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for _, player := range players {
+		levels.Apply(player)
+	}
+
 	response, err := json.Marshal(players)
 	if err != nil {
 		log.Println(err.Error())
@@ -97,3 +149,60 @@ func GetPlayerList(w http.ResponseWriter, r *http.Request) {
 	}
 	_, _ = w.Write(response)
 }
+
+// getOwnedPlayer loads the player in the url and makes sure it belongs
+// to the authenticated user.
+func getOwnedPlayer(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Player, bool) {
+	userId, err := strconv.Atoi(r.Context().Value(settings.I).(string))
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return nil, false
+	}
+	playerId, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, "invalid player id")
+		return nil, false
+	}
+
+	player, err := storages.DB.GetPlayer(ctx, playerId)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return nil, false
+	}
+	if player.UserID != userId {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return nil, false
+	}
+	return player, true
+}
+
+func RespawnPlayer(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	equipment, err := storages.DB.GetEquipment(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	effects, err := storages.DB.GetPlayerEffects(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	levels.Apply(player)
+	sheet := stats.Calculate(player, equipment, effects)
+
+	player, err = combat.Respawn(ctx, storages.DB, player, sheet, combat.DefaultConfig)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writePlayerDetail(ctx, w, player)
+}
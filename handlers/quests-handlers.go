@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+	"tribble/models"
+	"tribble/quests"
+	"tribble/storages"
+
+	"github.com/gorilla/mux"
+)
+
+func GetQuestList(w http.ResponseWriter, r *http.Request) {
+	response, err := json.Marshal(quests.Default.List())
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func writeQuest(w http.ResponseWriter, status int, quest *models.PlayerQuest) {
+	response, err := json.Marshal(quest)
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(response)
+}
+
+func getQuest(w http.ResponseWriter, r *http.Request) (*quests.Quest, bool) {
+	quest, ok := quests.Default.Get(mux.Vars(r)["quest"])
+	if !ok {
+		HandleApiErrors(w, http.StatusNotFound, "unknown quest")
+		return nil, false
+	}
+	return quest, true
+}
+
+// GetQuestJournal lists the available, active and completed quests of the
+// player.
+func GetQuestJournal(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	accepted, err := storages.DB.GetQuests(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+
+	response, err := json.Marshal(quests.NewJournal(quests.Default, player, accepted))
+	if err != nil {
+		log.Println(err.Error())
+		HandleApiErrors(w, http.StatusInternalServerError, "")
+		return
+	}
+	_, _ = w.Write(response)
+}
+
+func AcceptQuest(w http.ResponseWriter, r *http.Request) {
+	quest, ok := getQuest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	accepted, err := quests.Accept(ctx, storages.DB, player, quest)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeQuest(w, http.StatusCreated, accepted)
+}
+
+func AbandonQuest(w http.ResponseWriter, r *http.Request) {
+	quest, ok := getQuest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := storages.DB.AbandonQuest(ctx, player.ID, quest.ID); err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TurnInQuest(w http.ResponseWriter, r *http.Request) {
+	quest, ok := getQuest(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	completed, err := quests.TurnIn(ctx, storages.DB, player, quest)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeQuest(w, http.StatusOK, completed)
+}
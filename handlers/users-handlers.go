@@ -7,6 +7,7 @@ import (
 	"log"
 	"strconv"
 	"time"
+	"tribble/names"
 	"tribble/settings"
 	"tribble/storages"
 
@@ -39,6 +40,16 @@ func verifyPassword(userPassword string, providedPassword string) bool {
 	return err == nil
 }
 
+// checkName applies the name policy, returning the name to store.
+func checkName(w http.ResponseWriter, policy *names.Policy, name string) (string, bool) {
+	name, err := policy.Check(name)
+	if err != nil {
+		HandleApiErrors(w, http.StatusBadRequest, err.Error())
+		return "", false
+	}
+	return name, true
+}
+
 func ValidateUsername(w http.ResponseWriter, r *http.Request) {
 	var user *models.User
 	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
@@ -51,12 +62,20 @@ func ValidateUsername(w http.ResponseWriter, r *http.Request) {
 		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
 		return
 	}
+	username, ok := checkName(w, names.Usernames, user.Username)
+	if !ok {
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := storages.DB.GetUserByUsername(ctx, user.Username)
-	if err == nil {
+	taken, err := storages.DB.UsernameTaken(ctx, username)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	if taken {
 		HandleApiErrors(w, http.StatusBadRequest, "username not available")
 		return
 	}
@@ -137,6 +156,11 @@ func CreateUser(w http.ResponseWriter, r *http.Request) {
 		HandleApiErrors(w, http.StatusBadRequest, validationErr.Error())
 		return
 	}
+	username, ok := checkName(w, names.Usernames, user.Username)
+	if !ok {
+		return
+	}
+	user.Username = username
 
 	password, err := hashPassword(user.Password)
 	if err != nil {
@@ -203,6 +227,20 @@ func UpdateUser(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
+	current, err := storages.DB.GetUser(ctx, userId)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	// names allowed before the policy changed are kept
+	if user.Username != current.Username {
+		username, ok := checkName(w, names.Usernames, user.Username)
+		if !ok {
+			return
+		}
+		user.Username = username
+	}
+
 	_, err = storages.DB.UpdateUser(ctx, user)
 
 	if err != nil {
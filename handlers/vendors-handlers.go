@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"tribble/models"
+	"tribble/storages"
+	"tribble/vendors"
+
+	"github.com/gorilla/mux"
+)
+
+func getVendor(w http.ResponseWriter, r *http.Request) (*vendors.Vendor, bool) {
+	vendor, ok := vendors.Default.Get(mux.Vars(r)["vendor"])
+	if !ok {
+		HandleApiErrors(w, http.StatusNotFound, "unknown vendor")
+		return nil, false
+	}
+	return vendor, true
+}
+
+// GetVendorList lists the vendors, only those of a map with ?map=.
+func GetVendorList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, vendors.Default.List(r.URL.Query().Get("map")))
+}
+
+// GetVendor returns the vendor with the current stock of its limited
+// offers.
+func GetVendor(w http.ResponseWriter, r *http.Request) {
+	vendor, ok := getVendor(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stored, err := storages.DB.GetVendorStock(ctx, vendor.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, struct {
+		*vendors.Vendor
+		Stock map[string]int `json:"stock"`
+	}{vendor, vendor.Available(stored, time.Now())})
+}
+
+// vendorOrder reads the owned player, which must stand by the vendor, and
+// the idempotency key of a purchase or sale.
+func vendorOrder(ctx context.Context, w http.ResponseWriter, r *http.Request) (*models.Player, *vendors.Vendor, string, bool) {
+	vendor, ok := getVendor(w, r)
+	if !ok {
+		return nil, nil, "", false
+	}
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return nil, nil, "", false
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("vendor:%d", player.ID))
+	if !ok {
+		return nil, nil, "", false
+	}
+	if !vendor.InRange(player) {
+		HandleRepositoryErrors(w, models.ErrVendorTooFar)
+		return nil, nil, "", false
+	}
+	return player, vendor, key, true
+}
+
+func BuyFromVendor(w http.ResponseWriter, r *http.Request) {
+	var purchase models.VendorPurchase
+	if !decodeBody(w, r, &purchase) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, vendor, key, ok := vendorOrder(ctx, w, r)
+	if !ok {
+		return
+	}
+	order, err := vendor.BuyOrder(purchase.ItemID, purchase.Quantity)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	transaction, err := storages.DB.BuyFromVendor(ctx, player.ID, order, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeVendorTransaction(w, transaction)
+}
+
+// SellToVendor sells items of an inventory slot at the sellback price of
+// the item it holds.
+func SellToVendor(w http.ResponseWriter, r *http.Request) {
+	var sale models.VendorSale
+	if !decodeBody(w, r, &sale) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, vendor, key, ok := vendorOrder(ctx, w, r)
+	if !ok {
+		return
+	}
+	inventory, err := storages.DB.GetInventory(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	var slot *models.InventorySlot
+	for _, s := range inventory {
+		if s.Slot == sale.Slot {
+			slot = s
+		}
+	}
+	if slot == nil {
+		HandleRepositoryErrors(w, models.ErrEmptySlot)
+		return
+	}
+	order, err := vendor.SellOrder(slot, sale.Quantity)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	transaction, err := storages.DB.SellToVendor(ctx, player.ID, order, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeVendorTransaction(w, transaction)
+}
+
+func writeVendorTransaction(w http.ResponseWriter, transaction *models.VendorTransaction) {
+	status := http.StatusCreated
+	if transaction.Replayed {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, transaction)
+}
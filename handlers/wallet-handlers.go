@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+	"tribble/models"
+	"tribble/storages"
+)
+
+// idempotencyKey reads the Idempotency-Key header every request moving
+// currency must send, scoped so keys of different callers never collide.
+func idempotencyKey(w http.ResponseWriter, r *http.Request, scope string) (string, bool) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || len(key) > 64 {
+		HandleApiErrors(w, http.StatusBadRequest, "missing or invalid Idempotency-Key header")
+		return "", false
+	}
+	return scope + ":" + key, true
+}
+
+func writeTransaction(w http.ResponseWriter, transaction *models.LedgerTransaction) {
+	status := http.StatusCreated
+	if transaction.Replayed {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, transaction)
+}
+
+func GetWallet(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	balance, err := storages.DB.GetBalance(ctx, player.ID)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, models.Wallet{Balance: balance})
+}
+
+// GetLedgerEntries pages through the currency history of the player, the
+// newest entries first.
+func GetLedgerEntries(w http.ResponseWriter, r *http.Request) {
+	before, limit, ok := pageQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	player, ok := getOwnedPlayer(ctx, w, r)
+	if !ok {
+		return
+	}
+	entries, err := storages.DB.GetLedgerEntries(ctx, player.ID, before, limit)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+// GrantCurrency lets admins credit a player from the world account.
+func GrantCurrency(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+	playerID, ok := pathID(w, r, "id")
+	if !ok {
+		return
+	}
+	key, ok := idempotencyKey(w, r, fmt.Sprintf("admin-grant:%d", playerID))
+	if !ok {
+		return
+	}
+	var amount models.CurrencyAmount
+	if !decodeBody(w, r, &amount) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	transaction, err := storages.DB.GrantCurrency(ctx, playerID, amount.Amount, models.ReasonAdminGrant, key)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeTransaction(w, transaction)
+}
+
+// CheckLedger reports the ledger invariants that are broken, a currency
+// duplication shows up as unbalanced transactions or mismatched snapshots.
+func CheckLedger(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		HandleApiErrors(w, http.StatusForbidden, "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := storages.DB.CheckLedger(ctx)
+	if err != nil {
+		HandleRepositoryErrors(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
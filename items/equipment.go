@@ -0,0 +1,43 @@
+package items
+
+import "tribble/models"
+
+const (
+	SlotHead    = "head"
+	SlotChest   = "chest"
+	SlotLegs    = "legs"
+	SlotFeet    = "feet"
+	SlotHands   = "hands"
+	SlotWeapon  = "weapon"
+	SlotOffhand = "offhand"
+)
+
+var Slots = []string{SlotHead, SlotChest, SlotLegs, SlotFeet, SlotHands, SlotWeapon, SlotOffhand}
+
+func ValidSlot(slot string) bool {
+	for _, s := range Slots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+// CanEquip checks the slot, class and level requirements of the item.
+func CanEquip(item *models.Item, class string, level int) error {
+	if item.Slot == "" {
+		return models.ErrNotEquippable
+	}
+	if level < item.Level {
+		return models.ErrLevelTooLow
+	}
+	if len(item.Classes) == 0 {
+		return nil
+	}
+	for _, c := range item.Classes {
+		if c == class {
+			return nil
+		}
+	}
+	return models.ErrWrongClass
+}
@@ -0,0 +1,117 @@
+package items
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"tribble/data"
+	"tribble/models"
+	"tribble/settings"
+)
+
+const (
+	TypeWeapon     = "weapon"
+	TypeArmor      = "armor"
+	TypeConsumable = "consumable"
+	TypeMaterial   = "material"
+	TypeQuest      = "quest"
+)
+
+var Rarities = []string{"common", "uncommon", "rare", "epic", "legendary"}
+
+var types = map[string]bool{
+	TypeWeapon:     true,
+	TypeArmor:      true,
+	TypeConsumable: true,
+	TypeMaterial:   true,
+	TypeQuest:      true,
+}
+
+type Catalog struct {
+	items []*models.Item
+	byID  map[string]*models.Item
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Items []*models.Item `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Items) == 0 {
+		return nil, errors.New("item catalog is empty")
+	}
+
+	catalog := &Catalog{items: file.Items, byID: make(map[string]*models.Item)}
+	for _, item := range file.Items {
+		if item.ID == "" {
+			return nil, errors.New("item without id")
+		}
+		if _, ok := catalog.byID[item.ID]; ok {
+			return nil, fmt.Errorf("duplicated item %v", item.ID)
+		}
+		if !ValidType(item.Type) {
+			return nil, fmt.Errorf("item %v has invalid type %v", item.ID, item.Type)
+		}
+		if RarityRank(item.Rarity) < 0 {
+			return nil, fmt.Errorf("item %v has invalid rarity %v", item.ID, item.Rarity)
+		}
+		if item.StackSize < 1 {
+			return nil, fmt.Errorf("item %v must have a stack size", item.ID)
+		}
+		if item.Slot != "" && (!ValidSlot(item.Slot) || item.StackSize != 1) {
+			return nil, fmt.Errorf("item %v cannot be equipped in slot %v", item.ID, item.Slot)
+		}
+		catalog.byID[item.ID] = item
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*models.Item, bool) {
+	item, ok := c.byID[ID]
+	return item, ok
+}
+
+func (c *Catalog) List() []*models.Item {
+	return c.items
+}
+
+func ValidType(t string) bool {
+	return types[t]
+}
+
+// Tradable tells whether players can pass the item to each other, quest
+// items stay with the player that got them.
+func Tradable(itemID string) bool {
+	item, ok := Default.Get(itemID)
+	return ok && item.Type != TypeQuest
+}
+
+// RarityRank returns the position of the rarity from common upwards, or
+// -1 for unknown rarities.
+func RarityRank(rarity string) int {
+	for i, r := range Rarities {
+		if r == rarity {
+			return i
+		}
+	}
+	return -1
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("items.json", settings.ItemCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open item catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load item catalog: %v", err)
+	}
+	return catalog
+}
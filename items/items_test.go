@@ -0,0 +1,44 @@
+package items
+
+import (
+	"strings"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestLoadValidatesItems(t *testing.T) {
+	for _, c := range []string{
+		`{"items": []}`,
+		`{"items": [{"id": "", "type": "weapon", "rarity": "common", "stack_size": 1}]}`,
+		`{"items": [{"id": "sword", "type": "spaceship", "rarity": "common", "stack_size": 1}]}`,
+		`{"items": [{"id": "sword", "type": "weapon", "rarity": "mythic", "stack_size": 1}]}`,
+		`{"items": [{"id": "sword", "type": "weapon", "rarity": "common", "stack_size": 0}]}`,
+	} {
+		if _, err := Load(strings.NewReader(c)); err == nil {
+			t.Errorf("%s FAILED: want error for %s", t.Name(), c)
+		}
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	potion, ok := Default.Get("health_potion")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, potion.StackSize, 20)
+	assert.Equal(t, RarityRank("epic") > RarityRank("rare"), true)
+	assert.Equal(t, Tradable("iron_sword"), true)
+	assert.Equal(t, Tradable("gold"), false)
+}
+
+func TestCanEquip(t *testing.T) {
+	plate, _ := Default.Get("templar_plate")
+	helmet, _ := Default.Get("leather_cap")
+	potion, _ := Default.Get("health_potion")
+
+	assert.Equal(t, CanEquip(plate, "templar", 20), nil)
+	assert.Equal(t, CanEquip(plate, "templar", 19), models.ErrLevelTooLow)
+	assert.Equal(t, CanEquip(plate, "mage", 20), models.ErrWrongClass)
+	assert.Equal(t, CanEquip(helmet, "mage", 1), nil)
+	assert.Equal(t, CanEquip(potion, "mage", 1), models.ErrNotEquippable)
+}
@@ -0,0 +1,40 @@
+package items
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/models"
+)
+
+// PickUpLoot puts the loot of killed monsters into the killer's inventory.
+// Loot that doesn't fit is lost.
+func PickUpLoot(repo models.InventoryRepository) events.Handler {
+	return func(e events.Event) {
+		kill, ok := e.Data.(events.MonsterKilledData)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		for _, stack := range kill.Loot {
+			item, ok := Default.Get(stack.ItemID)
+			if !ok {
+				continue
+			}
+			if _, err := repo.AddItem(ctx, e.PlayerID, *item, stack.Quantity); err != nil {
+				log.Printf("could not give %v to player %v: %v", item.ID, e.PlayerID, err.Error())
+				continue
+			}
+			events.Publish(events.Event{
+				Type:     events.ItemAcquired,
+				UserID:   e.UserID,
+				PlayerID: e.PlayerID,
+				Data:     events.ItemAcquiredData{Item: item.ID, Quantity: stack.Quantity, Source: "loot"},
+			})
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"log"
+	"sync"
+	"time"
+	"tribble/settings"
+)
+
+// Func is the work of a job, run with a context bound by JobTimeout.
+type Func func(ctx context.Context) error
+
+type entry struct {
+	name     string
+	at       time.Time
+	periodic bool
+}
+
+type queue []entry
+
+func (q queue) Len() int            { return len(q) }
+func (q queue) Less(i, j int) bool  { return q[i].at.Before(q[j].at) }
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(entry)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// Scheduler runs named jobs at given times or periodically. A job never
+// runs twice at once, a job due while it runs runs again right after.
+// Jobs are kept in memory, work that must survive restarts is stored by
+// the jobs themselves and picked up by a periodic run.
+type Scheduler struct {
+	Now func() time.Time
+
+	mu       sync.Mutex
+	handlers map[string]Func
+	every    map[string]time.Duration
+	queue    queue
+	running  map[string]bool
+	// again holds the jobs due while running, true when a periodic run
+	// was among them.
+	again map[string]bool
+	wake  chan struct{}
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		Now:      time.Now,
+		handlers: make(map[string]Func),
+		every:    make(map[string]time.Duration),
+		running:  make(map[string]bool),
+		again:    make(map[string]bool),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Handle registers the work of the job called name.
+func (s *Scheduler) Handle(name string, run Func) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[name] = run
+}
+
+// Every runs the job every interval, the first run after one interval.
+// Runs scheduled with At in between don't move the periodic runs.
+func (s *Scheduler) Every(name string, interval time.Duration) {
+	s.mu.Lock()
+	s.every[name] = interval
+	s.mu.Unlock()
+	s.push(entry{name, s.Now().Add(interval), true})
+}
+
+// At runs the job once at the given time, right away when it is past.
+func (s *Scheduler) At(name string, at time.Time) {
+	s.push(entry{name, at, false})
+}
+
+func (s *Scheduler) push(e entry) {
+	s.mu.Lock()
+	heap.Push(&s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// due takes the jobs due now out of the queue, marking them running. Jobs
+// already running are flagged to run again instead.
+func (s *Scheduler) due() []entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.Now()
+	var entries []entry
+	for len(s.queue) > 0 && !s.queue[0].at.After(now) {
+		e := heap.Pop(&s.queue).(entry)
+		if s.running[e.name] {
+			s.again[e.name] = s.again[e.name] || e.periodic
+			continue
+		}
+		s.running[e.name] = true
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func (s *Scheduler) run(e entry) {
+	name, periodic := e.name, e.periodic
+	for {
+		s.mu.Lock()
+		handler, ok := s.handlers[name]
+		s.mu.Unlock()
+		if ok {
+			ctx, cancel := context.WithTimeout(context.Background(), settings.JobTimeout)
+			if err := handler(ctx); err != nil {
+				log.Printf("job %v failed: %v", name, err)
+			}
+			cancel()
+		} else {
+			log.Printf("job %v has no handler", name)
+		}
+
+		s.mu.Lock()
+		if p, ok := s.again[name]; ok {
+			periodic = periodic || p
+			delete(s.again, name)
+			s.mu.Unlock()
+			continue
+		}
+		delete(s.running, name)
+		if interval, ok := s.every[name]; ok && periodic {
+			heap.Push(&s.queue, entry{name, s.Now().Add(interval), true})
+		}
+		s.mu.Unlock()
+		return
+	}
+}
+
+// RunDue runs the jobs due now and waits for them, returning how many
+// ran.
+func (s *Scheduler) RunDue() int {
+	entries := s.due()
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e entry) {
+			defer wg.Done()
+			s.run(e)
+		}(e)
+	}
+	wg.Wait()
+	return len(entries)
+}
+
+// next is how long to wait for the next job.
+func (s *Scheduler) next() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return time.Hour
+	}
+	return s.queue[0].at.Sub(s.Now())
+}
+
+// Start runs the jobs in the background until Stop.
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			timer := time.NewTimer(s.next())
+			select {
+			case <-s.stop:
+				timer.Stop()
+				return
+			case <-s.wake:
+			case <-timer.C:
+			}
+			timer.Stop()
+			for _, e := range s.due() {
+				s.wg.Add(1)
+				go func(e entry) {
+					defer s.wg.Done()
+					s.run(e)
+				}(e)
+			}
+		}
+	}()
+}
+
+// Stop waits for the running jobs.
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+var Default = NewScheduler()
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func testScheduler() (*Scheduler, *time.Time) {
+	now := time.Unix(0, 0)
+	s := NewScheduler()
+	s.Now = func() time.Time { return now }
+	return s, &now
+}
+
+func TestAt(t *testing.T) {
+	s, now := testScheduler()
+	runs := 0
+	s.Handle("job", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+	s.At("job", now.Add(time.Minute))
+	s.At("job", now.Add(-time.Minute))
+	assert.Equal(t, s.RunDue(), 1)
+	assert.Equal(t, s.RunDue(), 0)
+	*now = now.Add(time.Minute)
+	assert.Equal(t, s.RunDue(), 1)
+	assert.Equal(t, runs, 2)
+	// unknown jobs are dropped
+	s.At("unknown", *now)
+	assert.Equal(t, s.RunDue(), 1)
+	assert.Equal(t, len(s.queue), 0)
+}
+
+func TestEvery(t *testing.T) {
+	s, now := testScheduler()
+	runs := 0
+	s.Handle("job", func(ctx context.Context) error {
+		runs++
+		return nil
+	})
+	s.Every("job", 10*time.Second)
+	assert.Equal(t, s.RunDue(), 0)
+	for i := 0; i < 3; i++ {
+		*now = now.Add(10 * time.Second)
+		assert.Equal(t, s.RunDue(), 1)
+	}
+	assert.Equal(t, runs, 3)
+	assert.Equal(t, len(s.queue), 1)
+
+	// runs in between don't add periodic runs
+	s.At("job", *now)
+	assert.Equal(t, s.RunDue(), 1)
+	assert.Equal(t, len(s.queue), 1)
+}
+
+// A job due while it runs runs once more right after instead of twice at
+// once.
+func TestDueWhileRunning(t *testing.T) {
+	s, now := testScheduler()
+	var mu sync.Mutex
+	runs := 0
+	started := make(chan bool)
+	release := make(chan bool)
+	s.Handle("job", func(ctx context.Context) error {
+		mu.Lock()
+		runs++
+		first := runs == 1
+		mu.Unlock()
+		if first {
+			started <- true
+			<-release
+		}
+		return nil
+	})
+	s.At("job", *now)
+	done := make(chan int)
+	go func() { done <- s.RunDue() }()
+	<-started
+	s.At("job", *now)
+	s.At("job", *now)
+	assert.Equal(t, s.RunDue(), 0)
+	release <- true
+	assert.Equal(t, <-done, 1)
+	assert.Equal(t, runs, 2)
+}
+
+func TestStart(t *testing.T) {
+	s := NewScheduler()
+	ran := make(chan bool, 1)
+	s.Handle("job", func(ctx context.Context) error {
+		ran <- true
+		return nil
+	})
+	s.Start()
+	s.At("job", time.Now())
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Errorf("%s FAILED: job did not run", t.Name())
+	}
+	s.Stop()
+}
@@ -0,0 +1,74 @@
+package levels
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/models"
+)
+
+// GrantXP adds XP to the player atomically and publishes an XPGained
+// event, followed by a LevelUp event when a level boundary was crossed.
+func GrantXP(ctx context.Context, repo models.PlayerRepository, playerID, amount int) (*models.Player, error) {
+	if amount <= 0 {
+		return nil, errors.New("xp amount must be positive")
+	}
+	player, err := repo.GrantXP(ctx, playerID, amount)
+	if err != nil {
+		return nil, err
+	}
+	PublishXP(player, amount)
+	return player, nil
+}
+
+// PublishXP publishes the events of XP the player was granted, once the
+// grant is saved.
+func PublishXP(player *models.Player, amount int) {
+	Apply(player)
+	events.Publish(events.Event{
+		Type:     events.XPGained,
+		UserID:   player.UserID,
+		PlayerID: player.ID,
+		Data:     events.XPGainedData{Amount: amount, XP: player.XP},
+	})
+	if from := Default.Level(player.XP - amount); player.Level > from {
+		events.Publish(events.Event{
+			Type:     events.LevelUp,
+			UserID:   player.UserID,
+			PlayerID: player.ID,
+			Data:     events.LevelUpData{From: from, To: player.Level},
+		})
+	}
+}
+
+// Shares splits the XP of a kill, returning the XP of each player.
+type Shares func(killerID int, xp int, mapName string) map[int]int
+
+// RewardKills grants the XP of killed monsters to their killers, or to the
+// players share returns.
+func RewardKills(repo models.PlayerRepository, share Shares) events.Handler {
+	return func(e events.Event) {
+		kill, ok := e.Data.(events.MonsterKilledData)
+		if !ok || kill.XP <= 0 {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		shares := map[int]int{e.PlayerID: kill.XP}
+		if share != nil {
+			shares = share(e.PlayerID, kill.XP, kill.Map)
+		}
+		for playerID, xp := range shares {
+			if xp <= 0 {
+				continue
+			}
+			if _, err := GrantXP(ctx, repo, playerID, xp); err != nil {
+				log.Printf("could not reward kill to player %v: %v", playerID, err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package levels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"tribble/data"
+	"tribble/models"
+	"tribble/settings"
+)
+
+// Curve maps accumulated XP to levels. thresholds[i] is the total XP
+// required to reach level i+1, so thresholds[0] is always 0.
+type Curve struct {
+	thresholds []int
+}
+
+type Progress struct {
+	Level          int `json:"level"`
+	CurrentLevelXP int `json:"current_level_xp"`
+	NextLevelXP    int `json:"next_level_xp"`
+}
+
+// Formula generates a curve where reaching level n costs
+// base * (n-1)^exponent total XP.
+type Formula struct {
+	MaxLevel int     `json:"max_level"`
+	Base     float64 `json:"base"`
+	Exponent float64 `json:"exponent"`
+}
+
+type config struct {
+	Table   []int    `json:"table"`
+	Formula *Formula `json:"formula"`
+}
+
+func NewCurve(table []int) (*Curve, error) {
+	if len(table) == 0 || table[0] != 0 {
+		return nil, errors.New("leveling table must start at 0 xp")
+	}
+	for i := 1; i < len(table); i++ {
+		if table[i] <= table[i-1] {
+			return nil, fmt.Errorf("leveling table must be increasing: level %d", i+1)
+		}
+	}
+	thresholds := make([]int, len(table))
+	copy(thresholds, table)
+	return &Curve{thresholds}, nil
+}
+
+func (f Formula) Curve() (*Curve, error) {
+	if f.MaxLevel < 1 || f.Base <= 0 || f.Exponent <= 0 {
+		return nil, errors.New("invalid leveling formula")
+	}
+	table := make([]int, f.MaxLevel)
+	for i := 1; i < f.MaxLevel; i++ {
+		table[i] = int(math.Round(f.Base * math.Pow(float64(i), f.Exponent)))
+	}
+	return NewCurve(table)
+}
+
+// Load reads a curve defined either as a table or as a formula.
+func Load(r io.Reader) (*Curve, error) {
+	var c config
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, err
+	}
+	switch {
+	case c.Table != nil && c.Formula != nil:
+		return nil, errors.New("leveling curve must define either a table or a formula")
+	case c.Table != nil:
+		return NewCurve(c.Table)
+	case c.Formula != nil:
+		return c.Formula.Curve()
+	}
+	return nil, errors.New("leveling curve is empty")
+}
+
+func (c *Curve) MaxLevel() int {
+	return len(c.thresholds)
+}
+
+// XP returns the total XP required to reach the level.
+func (c *Curve) XP(level int) int {
+	if level <= 1 {
+		return 0
+	}
+	if level > len(c.thresholds) {
+		level = len(c.thresholds)
+	}
+	return c.thresholds[level-1]
+}
+
+func (c *Curve) Level(xp int) int {
+	return sort.Search(len(c.thresholds), func(i int) bool {
+		return c.thresholds[i] > xp
+	})
+}
+
+// Progress reports the XP boundaries of the current level. At the max
+// level NextLevelXP equals CurrentLevelXP.
+func (c *Curve) Progress(xp int) Progress {
+	level := c.Level(xp)
+	next := level + 1
+	if level == c.MaxLevel() {
+		next = level
+	}
+	return Progress{
+		Level:          level,
+		CurrentLevelXP: c.XP(level),
+		NextLevelXP:    c.XP(next),
+	}
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Curve {
+	f, err := data.Open("levels.json", settings.LevelCurveFile)
+	if err != nil {
+		log.Fatalf("Unable to open leveling curve: %v", err)
+	}
+	defer f.Close()
+	curve, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load leveling curve: %v", err)
+	}
+	return curve
+}
+
+// Apply fills the level fields of the player from its XP.
+func Apply(player *models.Player) {
+	p := Default.Progress(player.XP)
+	player.Level = p.Level
+	player.CurrentLevelXP = p.CurrentLevelXP
+	player.NextLevelXP = p.NextLevelXP
+}
@@ -0,0 +1,54 @@
+package levels
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestTableCurve(t *testing.T) {
+	curve, err := Load(strings.NewReader(`{"table": [0, 100, 250, 500]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, curve.MaxLevel(), 4)
+	assert.Equal(t, curve.Level(0), 1)
+	assert.Equal(t, curve.Level(99), 1)
+	assert.Equal(t, curve.Level(100), 2)
+	assert.Equal(t, curve.Level(499), 3)
+	assert.Equal(t, curve.Level(100000), 4)
+
+	assert.Equal(t, curve.Progress(120), Progress{Level: 2, CurrentLevelXP: 100, NextLevelXP: 250})
+	assert.Equal(t, curve.Progress(900), Progress{Level: 4, CurrentLevelXP: 500, NextLevelXP: 500})
+}
+
+func TestFormulaCurve(t *testing.T) {
+	curve, err := Load(strings.NewReader(`{"formula": {"max_level": 5, "base": 100, "exponent": 2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, curve.thresholds, []int{0, 100, 400, 900, 1600})
+}
+
+func TestInvalidCurves(t *testing.T) {
+	for _, c := range []string{
+		`{}`,
+		`{"table": []}`,
+		`{"table": [10, 20]}`,
+		`{"table": [0, 100, 100]}`,
+		`{"formula": {"max_level": 0, "base": 100, "exponent": 2}}`,
+		`{"table": [0], "formula": {"max_level": 5, "base": 100, "exponent": 2}}`,
+	} {
+		if _, err := Load(strings.NewReader(c)); err == nil {
+			t.Errorf("%s FAILED: want error for %s", t.Name(), c)
+		}
+	}
+}
+
+func TestDefaultCurve(t *testing.T) {
+	assert.Equal(t, Default.Level(0), 1)
+	assert.Equal(t, Default.MaxLevel(), 60)
+}
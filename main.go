@@ -1,14 +1,35 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"tribble/achievements"
+	"tribble/chat"
+	"tribble/classes"
+	"tribble/combat"
+	"tribble/crafting"
+	"tribble/events"
+	"tribble/gateway"
 	"tribble/handlers"
+	"tribble/items"
+	"tribble/jobs"
+	"tribble/levels"
 	"tribble/middlewares"
+	"tribble/party"
+	"tribble/quests"
+	"tribble/settings"
+	"tribble/simulation"
+	"tribble/status"
 	"tribble/storages"
 	"tribble/storages/postgres"
+	"tribble/trade"
+	"tribble/world"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -30,10 +51,85 @@ func main() {
 	}
 	log.Println("successfully connected to database")
 
+	events.Subscribe(events.LevelUp, classes.ApplyGrowth(storages.DB))
+	events.Subscribe(events.MonsterKilled, levels.RewardKills(storages.DB, party.Default.Shares(world.Spaces)))
+	events.Subscribe(events.PlayerDied, combat.RecordDeath(storages.DB))
+	events.Subscribe(events.MonsterKilled, items.PickUpLoot(storages.DB))
+	events.Subscribe(events.PlayerLeft, status.Persist(storages.DB))
+
+	trackQuests := quests.TrackProgress(quests.Default, storages.DB)
+	events.Subscribe(events.MonsterKilled, trackQuests)
+	events.Subscribe(events.ItemAcquired, trackQuests)
+	events.Subscribe(events.LandmarkEntered, trackQuests)
+
+	trackAchievements := achievements.Track(achievements.Default, storages.DB)
+	events.Subscribe(events.PlayerCreated, trackAchievements)
+	events.Subscribe(events.XPGained, trackAchievements)
+	events.Subscribe(events.LevelUp, trackAchievements)
+	events.Subscribe(events.MonsterKilled, trackAchievements)
+	events.Subscribe(events.QuestCompleted, trackAchievements)
+	events.Subscribe(events.PlayerConnected, trackAchievements)
+
+	queue := events.NewQueue(events.Default, 1024)
+	defer queue.Close()
+
+	loops, err := simulation.NewManager(world.Maps.List(), world.Spaces, simulation.DefaultConfig(), queue.Publish)
+	if err != nil {
+		log.Fatalf("Unable to start simulation: %v", err)
+	}
+	gateway.Default.Attach(loops)
+	// after ApplyGrowth, which saves the stats
+	events.Subscribe(events.LevelUp, loops.RefreshStats(storages.DB))
+	loops.Start(simulation.RealClock{})
+	defer loops.Stop()
+
+	chat.Default = chat.NewService(storages.DB, gateway.Default)
+	chat.Default.PartyOf = party.Default.PartyOf
+	chat.Default.Register()
+	party.Default.Register(gateway.Default, storages.DB)
+	trade.Default.Register(gateway.Default, storages.DB)
+
+	jobs.Default.Handle("sweep", func(ctx context.Context) error {
+		party.Default.Sweep()
+		trade.Default.Sweep()
+		return nil
+	})
+	jobs.Default.Every("sweep", 10*time.Second)
+	jobs.Default.Handle("leaderboards.refresh", storages.DB.RefreshLeaderboards)
+	jobs.Default.Every("leaderboards.refresh", settings.LeaderboardRefresh)
+	jobs.Default.Handle("auctions.settle", func(ctx context.Context) error {
+		n, err := storages.DB.SettleAuctions(ctx, settings.AuctionSettleBatch)
+		if n > 0 {
+			log.Printf("settled %v auctions", n)
+		}
+		return err
+	})
+	jobs.Default.Every("auctions.settle", settings.AuctionSettleInterval)
+	jobs.Default.Handle("mail.expire", func(ctx context.Context) error {
+		_, err := storages.DB.ExpireMail(ctx, settings.MailExpireBatch)
+		return err
+	})
+	jobs.Default.Every("mail.expire", settings.MailExpireInterval)
+	jobs.Default.Handle("names.skeletons", func(ctx context.Context) error {
+		n, err := storages.DB.FoldNames(ctx)
+		if n > 0 {
+			log.Printf("folded %v names", n)
+		}
+		return err
+	})
+	// names taken before their skeletons were stored
+	jobs.Default.At("names.skeletons", time.Now())
+	jobs.Default.Handle(crafting.FinishJob, crafting.Finish(storages.DB, gateway.Default))
+	jobs.Default.Every(crafting.FinishJob, settings.CraftSweepInterval)
+	// crafts finished while the server was down
+	jobs.Default.At(crafting.FinishJob, time.Now())
+	jobs.Default.Start()
+	defer jobs.Default.Stop()
+
 	r := mux.NewRouter()
 	handler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodOptions},
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
 		AllowedHeaders:   []string{"*"},
 		AllowCredentials: true,
 	}).Handler(r)
@@ -49,9 +145,128 @@ func main() {
 	r.HandleFunc("/users/validate/username/", handlers.ValidateUsername).Methods("POST")
 	r.HandleFunc("/users/refresh/", handlers.RefreshToken).Methods("POST")
 	r.HandleFunc("/users/login/", handlers.Login).Methods("POST")
+	r.HandleFunc("/users/{id:[0-9]+}/entitlements/", middlewares.Authentication(handlers.GetEntitlements)).Methods("GET")
+	r.HandleFunc("/users/{id:[0-9]+}/entitlements/", middlewares.Authentication(handlers.GrantEntitlement)).Methods("POST")
+
+	r.HandleFunc("/friends/", middlewares.Authentication(handlers.GetFriendList)).Methods("GET")
+	r.HandleFunc("/friends/{id:[0-9]+}/", middlewares.Authentication(handlers.RemoveFriend)).Methods("DELETE")
+	r.HandleFunc("/friends/requests/", middlewares.Authentication(handlers.GetFriendRequests)).Methods("GET")
+	r.HandleFunc("/friends/requests/", middlewares.Authentication(handlers.SendFriendRequest)).Methods("POST")
+	r.HandleFunc("/friends/requests/{request:[0-9]+}/accept/", middlewares.Authentication(handlers.AcceptFriendRequest)).Methods("POST")
+	r.HandleFunc("/friends/requests/{request:[0-9]+}/decline/", middlewares.Authentication(handlers.DeclineFriendRequest)).Methods("POST")
+	r.HandleFunc("/friends/requests/{request:[0-9]+}/", middlewares.Authentication(handlers.CancelFriendRequest)).Methods("DELETE")
+	r.HandleFunc("/blocks/", middlewares.Authentication(handlers.GetBlockList)).Methods("GET")
+	r.HandleFunc("/blocks/", middlewares.Authentication(handlers.BlockUser)).Methods("POST")
+	r.HandleFunc("/blocks/{id:[0-9]+}/", middlewares.Authentication(handlers.UnblockUser)).Methods("DELETE")
 
 	r.HandleFunc("/players/", middlewares.Authentication(handlers.CreatePlayer)).Methods("POST")
 	r.HandleFunc("/players/", middlewares.Authentication(handlers.GetPlayerList)).Methods("GET")
+	r.HandleFunc("/players/validate/name/", handlers.ValidatePlayerName).Methods("POST")
+	r.HandleFunc("/players/slots/", middlewares.Authentication(handlers.GetCharacterSlots)).Methods("GET")
+
+	r.HandleFunc("/players/{id:[0-9]+}/", middlewares.Authentication(handlers.GetPlayerDetail)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/wallet/", middlewares.Authentication(handlers.GetWallet)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/wallet/ledger/", middlewares.Authentication(handlers.GetLedgerEntries)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/wallet/grant/", middlewares.Authentication(handlers.GrantCurrency)).Methods("POST")
+	r.HandleFunc("/ledger/check/", middlewares.Authentication(handlers.CheckLedger)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/respawn/", middlewares.Authentication(handlers.RespawnPlayer)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/equipment/", middlewares.Authentication(handlers.EquipItem)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/equipment/{slot}/", middlewares.Authentication(handlers.UnequipItem)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/inventory/", middlewares.Authentication(handlers.GetInventory)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/inventory/move/", middlewares.Authentication(handlers.MoveInventoryItem)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/inventory/split/", middlewares.Authentication(handlers.SplitInventoryStack)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/inventory/{slot:[0-9]+}/", middlewares.Authentication(handlers.DeleteInventoryItem)).Methods("DELETE")
+
+	r.HandleFunc("/players/{id:[0-9]+}/connect/", middlewares.Authentication(handlers.ConnectPlayer)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/chat/{channel}/", middlewares.Authentication(handlers.GetChatHistory)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/achievements/", middlewares.Authentication(handlers.GetPlayerAchievements)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/quests/", middlewares.Authentication(handlers.GetQuestJournal)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/quests/{quest}/", middlewares.Authentication(handlers.AcceptQuest)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/quests/{quest}/", middlewares.Authentication(handlers.AbandonQuest)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/quests/{quest}/turn-in/", middlewares.Authentication(handlers.TurnInQuest)).Methods("POST")
+
+	r.HandleFunc("/players/{id:[0-9]+}/vendors/{vendor}/buy/", middlewares.Authentication(handlers.BuyFromVendor)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/vendors/{vendor}/sell/", middlewares.Authentication(handlers.SellToVendor)).Methods("POST")
+
+	r.HandleFunc("/players/{id:[0-9]+}/mail/", middlewares.Authentication(handlers.GetMail)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/mail/", middlewares.Authentication(handlers.SendMail)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/mail/system/", middlewares.Authentication(handlers.SendSystemMail)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/mail/{mail:[0-9]+}/", middlewares.Authentication(handlers.ReadMail)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/mail/{mail:[0-9]+}/", middlewares.Authentication(handlers.DeleteMail)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/mail/{mail:[0-9]+}/claim/", middlewares.Authentication(handlers.ClaimMail)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/abilities/", middlewares.Authentication(handlers.GetPlayerAbilities)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/professions/", middlewares.Authentication(handlers.GetProfessions)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/recipes/", middlewares.Authentication(handlers.GetKnownRecipes)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/crafts/", middlewares.Authentication(handlers.GetCrafts)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/crafts/", middlewares.Authentication(handlers.StartCraft)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/crafts/{craft:[0-9]+}/collect/", middlewares.Authentication(handlers.CollectCraft)).Methods("POST")
+
+	r.HandleFunc("/players/{id:[0-9]+}/auctions/", middlewares.Authentication(handlers.GetPlayerAuctions)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/auctions/", middlewares.Authentication(handlers.CreateAuction)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/auctions/{auction:[0-9]+}/", middlewares.Authentication(handlers.CancelAuction)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/auctions/{auction:[0-9]+}/bid/", middlewares.Authentication(handlers.BidOnAuction)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/auctions/{auction:[0-9]+}/buyout/", middlewares.Authentication(handlers.BuyoutAuction)).Methods("POST")
+
+	r.HandleFunc("/players/{id:[0-9]+}/guild/", middlewares.Authentication(handlers.GetPlayerGuild)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/", middlewares.Authentication(handlers.CreateGuild)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/", middlewares.Authentication(handlers.LeaveGuild)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/motd/", middlewares.Authentication(handlers.SetGuildMOTD)).Methods("PUT")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/ranks/", middlewares.Authentication(handlers.SetGuildRanks)).Methods("PUT")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/members/{member:[0-9]+}/", middlewares.Authentication(handlers.SetGuildMemberRank)).Methods("PUT")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/members/{member:[0-9]+}/", middlewares.Authentication(handlers.KickGuildMember)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/invites/", middlewares.Authentication(handlers.GetGuildInvites)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/invites/", middlewares.Authentication(handlers.InviteToGuild)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/invites/{guild:[0-9]+}/accept/", middlewares.Authentication(handlers.AcceptGuildInvite)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/invites/{guild:[0-9]+}/", middlewares.Authentication(handlers.DeclineGuildInvite)).Methods("DELETE")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/", middlewares.Authentication(handlers.GetGuildBank)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/deposit/", middlewares.Authentication(handlers.DepositGuildItem)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/withdraw/", middlewares.Authentication(handlers.WithdrawGuildItem)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/log/", middlewares.Authentication(handlers.GetGuildBankLog)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/currency/", middlewares.Authentication(handlers.GetGuildCurrency)).Methods("GET")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/currency/deposit/", middlewares.Authentication(handlers.DepositGuildCurrency)).Methods("POST")
+	r.HandleFunc("/players/{id:[0-9]+}/guild/bank/currency/withdraw/", middlewares.Authentication(handlers.WithdrawGuildCurrency)).Methods("POST")
+
+	r.HandleFunc("/guilds/{guild:[0-9]+}/", handlers.GetGuild).Methods("GET")
+	r.HandleFunc("/guilds/{guild:[0-9]+}/members/", handlers.GetGuildMembers).Methods("GET")
+
+	r.HandleFunc("/chat/mutes/", middlewares.Authentication(handlers.MuteUser)).Methods("POST")
+	r.HandleFunc("/chat/mutes/{id:[0-9]+}/", middlewares.Authentication(handlers.UnmuteUser)).Methods("DELETE")
+
+	r.HandleFunc("/classes/", handlers.GetClassList).Methods("GET")
+	r.HandleFunc("/abilities/", handlers.GetAbilityList).Methods("GET")
+	r.HandleFunc("/status-effects/", handlers.GetStatusEffectList).Methods("GET")
+	r.HandleFunc("/items/", handlers.GetItemList).Methods("GET")
+	r.HandleFunc("/quests/", handlers.GetQuestList).Methods("GET")
+	r.HandleFunc("/vendors/", handlers.GetVendorList).Methods("GET")
+	r.HandleFunc("/recipes/", handlers.GetRecipeList).Methods("GET")
+	r.HandleFunc("/auctions/", handlers.GetAuctionList).Methods("GET")
+	r.HandleFunc("/auctions/{auction:[0-9]+}/", handlers.GetAuction).Methods("GET")
+	r.HandleFunc("/vendors/{vendor}/", handlers.GetVendor).Methods("GET")
+
+	r.HandleFunc("/leaderboards/xp/", handlers.GetXPLeaderboard).Methods("GET")
+	r.HandleFunc("/leaderboards/xp/me/", middlewares.Authentication(handlers.GetMyXPLeaderboard)).Methods("GET")
+
+	if settings.Debug {
+		r.HandleFunc("/debug/maps/{map}/path/", handlers.GetMapPath).Methods("GET")
+	}
+
+	server := &http.Server{
+		Addr:    ":" + os.Getenv("PORT"),
+		Handler: middlewares.LogRequest(middlewares.SetHeaders(handler)),
+	}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
 
-	_ = http.ListenAndServe(":"+os.Getenv("PORT"), middlewares.LogRequest(middlewares.SetHeaders(handler)))
+	log.Println("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+	gateway.Default.Close()
 }
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInventoryFull  = errors.New("inventory is full")
+	ErrInvalidSlot    = errors.New("invalid inventory slot")
+	ErrEmptySlot      = errors.New("inventory slot is empty")
+	ErrSlotOccupied   = errors.New("inventory slot is occupied")
+	ErrNotEnoughItems = errors.New("not enough items")
+	ErrItemChanged    = errors.New("inventory slot changed")
+
+	ErrNotEquippable = errors.New("item cannot be equipped")
+	ErrWrongClass    = errors.New("item cannot be equipped by this class")
+	ErrLevelTooLow   = errors.New("level too low to equip item")
+	ErrNotEquipped   = errors.New("equipment slot is empty")
+
+	ErrNotDead = errors.New("player is not dead")
+
+	ErrQuestLocked     = errors.New("quest requirements are not met")
+	ErrQuestAccepted   = errors.New("quest already accepted")
+	ErrQuestCompleted  = errors.New("quest already completed")
+	ErrQuestNotActive  = errors.New("quest is not active")
+	ErrQuestIncomplete = errors.New("quest objectives are not complete")
+	ErrTooManyQuests   = errors.New("too many active quests")
+
+	ErrUnknownChannel  = errors.New("unknown chat channel")
+	ErrEmptyMessage    = errors.New("chat message is empty")
+	ErrMessageTooLong  = errors.New("chat message is too long")
+	ErrChatRateLimited = errors.New("sending chat messages too fast")
+	ErrMuted           = errors.New("muted in chat")
+	ErrNotInParty      = errors.New("not in a party")
+	ErrPlayerOffline   = errors.New("player is not online")
+
+	ErrBlocked             = errors.New("user is blocked")
+	ErrSelfReference       = errors.New("cannot do this with yourself")
+	ErrAlreadyFriends      = errors.New("already friends")
+	ErrFriendRequestExists = errors.New("friend request already pending")
+	ErrNotFriends          = errors.New("not friends")
+	ErrTooManyFriends      = errors.New("friends list is full")
+
+	ErrAlreadyInParty = errors.New("player is already in a party")
+	ErrNotPartyLeader = errors.New("only the party leader can do this")
+	ErrNotPartyMember = errors.New("player is not in the party")
+	ErrPartyFull      = errors.New("party is full")
+	ErrNoPartyInvite  = errors.New("no pending party invite")
+
+	ErrAlreadyInGuild   = errors.New("player is already in a guild")
+	ErrNotInGuild       = errors.New("not in a guild")
+	ErrNotGuildMember   = errors.New("player is not in the guild")
+	ErrGuildPermission  = errors.New("guild rank lacks the permission")
+	ErrGuildRankTooLow  = errors.New("can only manage members of a lower rank")
+	ErrGuildFull        = errors.New("guild is full")
+	ErrNoGuildInvite    = errors.New("no pending guild invite")
+	ErrGuildMaster      = errors.New("guild master must pass on the lead first")
+	ErrInvalidGuildRank = errors.New("invalid guild rank")
+	ErrGuildBankFull    = errors.New("guild bank is full")
+	ErrGuildNotEmpty    = errors.New("guild bank and currency must be withdrawn before disbanding")
+
+	ErrCharacterLimit = errors.New("character slot limit reached")
+
+	ErrAlreadyTrading    = errors.New("player is already trading")
+	ErrNotTrading        = errors.New("not trading")
+	ErrNoTradeRequest    = errors.New("no pending trade request")
+	ErrTradeNotLocked    = errors.New("both offers must be locked first")
+	ErrTradeBusy         = errors.New("trade is being completed")
+	ErrTooManyTradeItems = errors.New("too many items offered")
+	ErrEmptyTrade        = errors.New("nothing offered")
+
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrInsufficientFunds   = errors.New("not enough currency")
+	ErrIdempotencyConflict = errors.New("idempotency key already used for another operation")
+
+	ErrNotSold      = errors.New("vendor doesn't sell this item")
+	ErrNotBought    = errors.New("vendor doesn't buy this item")
+	ErrOutOfStock   = errors.New("vendor is out of stock")
+	ErrVendorTooFar = errors.New("too far from the vendor")
+
+	ErrMailClaimed     = errors.New("mail attachments already claimed")
+	ErrNoAttachments   = errors.New("mail has no attachments")
+	ErrMailAttached    = errors.New("mail attachments must be claimed first")
+	ErrInvalidBuyout   = errors.New("buyout must not be below the starting bid")
+	ErrInvalidDuration = errors.New("invalid auction duration")
+	ErrNotTradable     = errors.New("item cannot be traded")
+	ErrTooManyAuctions = errors.New("too many active auctions")
+	ErrAuctionClosed   = errors.New("auction is closed")
+	ErrOwnAuction      = errors.New("cannot bid on own auction")
+	ErrHighestBidder   = errors.New("already the highest bidder")
+	ErrBidTooLow       = errors.New("bid is too low")
+	ErrNoBuyout        = errors.New("auction has no buyout")
+	ErrAuctionHasBids  = errors.New("auction already has bids")
+
+	ErrProfessionTooLow = errors.New("profession level too low")
+	ErrTooManyCrafts    = errors.New("too many crafts in progress")
+	ErrCraftNotReady    = errors.New("craft is not finished")
+)
+
+// CharacterLimitError is ErrCharacterLimit with the slots of the account.
+type CharacterLimitError struct {
+	Current int
+	Max     int
+}
+
+func (e *CharacterLimitError) Error() string {
+	return fmt.Sprintf("%v: %d of %d characters", ErrCharacterLimit, e.Current, e.Max)
+}
+
+func (e *CharacterLimitError) Is(target error) bool {
+	return target == ErrCharacterLimit
+}
@@ -22,12 +22,622 @@ type Player struct {
 	UserID    int    `json:"user_id,omitempty"`
 	Name      string `json:"name"`
 	XP        int    `json:"xp"`
-	Sprite    string `json:"sprite" validate:"oneof=assassin warrior templar archer mage"`
+	Sprite    string `json:"sprite" validate:"class"`
 	PositionX int    `json:"position_x"`
 	PositionY int    `json:"position_y"`
+	Stats     Stats  `json:"stats"`
+	Map       string `json:"map"`
+	HP        int    `json:"hp"`
+	Mana      int    `json:"mana"`
+
+	Level          int `json:"level"`
+	CurrentLevelXP int `json:"current_level_xp"`
+	NextLevelXP    int `json:"next_level_xp"`
+}
+
+type Stats struct {
+	HP            int `json:"hp"`
+	Mana          int `json:"mana"`
+	Strength      int `json:"strength"`
+	Agility       int `json:"agility"`
+	Intellect     int `json:"intellect"`
+	MovementSpeed int `json:"movement_speed"`
+}
+
+const (
+	Strength  = "strength"
+	Agility   = "agility"
+	Intellect = "intellect"
+)
+
+func IsAttribute(name string) bool {
+	return name == Strength || name == Agility || name == Intellect
+}
+
+// Attribute returns the value of the primary attribute with the given name.
+func (s Stats) Attribute(name string) int {
+	switch name {
+	case Strength:
+		return s.Strength
+	case Agility:
+		return s.Agility
+	case Intellect:
+		return s.Intellect
+	}
+	return 0
+}
+
+func (s Stats) Add(o Stats) Stats {
+	return Stats{
+		HP:            s.HP + o.HP,
+		Mana:          s.Mana + o.Mana,
+		Strength:      s.Strength + o.Strength,
+		Agility:       s.Agility + o.Agility,
+		Intellect:     s.Intellect + o.Intellect,
+		MovementSpeed: s.MovementSpeed + o.MovementSpeed,
+	}
+}
+
+func (s Stats) Scale(n int) Stats {
+	return Stats{
+		HP:            s.HP * n,
+		Mana:          s.Mana * n,
+		Strength:      s.Strength * n,
+		Agility:       s.Agility * n,
+		Intellect:     s.Intellect * n,
+		MovementSpeed: s.MovementSpeed * n,
+	}
+}
+
+type Item struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Rarity    string `json:"rarity"`
+	StackSize int    `json:"stack_size"`
+	Value     int    `json:"value"`
+	Stats     Stats  `json:"stats"`
+
+	Slot    string   `json:"slot,omitempty"`
+	Level   int      `json:"level,omitempty"`
+	Classes []string `json:"classes,omitempty"`
+}
+
+type InventorySlot struct {
+	Slot     int    `json:"slot"`
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
+
+type InventoryMove struct {
+	From     int `json:"from" validate:"gte=0"`
+	To       int `json:"to" validate:"gte=0"`
+	Quantity int `json:"quantity,omitempty" validate:"gte=0"`
+}
+
+type EquippedItem struct {
+	Slot   string `json:"slot"`
+	ItemID string `json:"item_id"`
+}
+
+// ItemStack is a quantity of an item outside of the inventory, like a
+// reward or a cost.
+type ItemStack struct {
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
+
+type Equip struct {
+	InventorySlot int `json:"inventory_slot" validate:"gte=0"`
+}
+
+// StatSheet breaks the derived stats of a player down by source.
+type StatSheet struct {
+	Base      Stats `json:"base"`
+	Equipment Stats `json:"equipment"`
+	Effects   Stats `json:"effects"`
+	Total     Stats `json:"total"`
+}
+
+type PlayerDetail struct {
+	Player
+	Equipment  []*EquippedItem `json:"equipment"`
+	StatSheet  StatSheet       `json:"stat_sheet"`
+	UnreadMail int             `json:"unread_mail"`
+}
+
+const (
+	QuestActive    = "active"
+	QuestCompleted = "completed"
+)
+
+// PlayerQuest is the state of a quest accepted by a player, Progress holds
+// the count of each objective in definition order.
+type PlayerQuest struct {
+	QuestID     string     `json:"quest_id"`
+	Status      string     `json:"status"`
+	Progress    []int      `json:"progress"`
+	AcceptedAt  time.Time  `json:"accepted_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// PlayerAchievement is the progress of a player towards an achievement.
+type PlayerAchievement struct {
+	AchievementID string     `json:"achievement_id"`
+	Progress      int        `json:"progress"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	UnlockedAt    *time.Time `json:"unlocked_at"`
+}
+
+// UserName names another user in social requests.
+type UserName struct {
+	Username string `json:"username" validate:"required"`
+}
+
+// EntitlementCharacterSlot grants extra character slots to an account.
+const EntitlementCharacterSlot = "character_slot"
+
+type Entitlement struct {
+	ID        int       `json:"id"`
+	Kind      string    `json:"kind" validate:"required,oneof=character_slot"`
+	Quantity  int       `json:"quantity" validate:"required,gte=1"`
+	Reason    string    `json:"reason" validate:"lte=128"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// CharacterSlots are the characters an account has and may have.
+type CharacterSlots struct {
+	Used int `json:"used"`
+	Max  int `json:"max"`
+}
+
+// PlayerName names another player in requests.
+type PlayerName struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// OnlinePlayer is the player a user is currently connected with.
+type OnlinePlayer struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Map  string `json:"map"`
+}
+
+type Friend struct {
+	UserID   int           `json:"user_id"`
+	Username string        `json:"username"`
+	Since    time.Time     `json:"since"`
+	Online   bool          `json:"online"`
+	Player   *OnlinePlayer `json:"player,omitempty"`
+}
+
+type FriendRequest struct {
+	ID            int       `json:"id"`
+	SenderID      int       `json:"sender_id"`
+	SenderName    string    `json:"sender_name"`
+	RecipientID   int       `json:"recipient_id"`
+	RecipientName string    `json:"recipient_name"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type BlockedUser struct {
+	UserID   int       `json:"user_id"`
+	Username string    `json:"username"`
+	Since    time.Time `json:"since"`
+}
+
+type ChatMessage struct {
+	ID          int64     `json:"id"`
+	Channel     string    `json:"channel"`
+	SenderID    int       `json:"sender_id"`
+	SenderName  string    `json:"sender_name"`
+	RecipientID *int      `json:"recipient_id,omitempty"`
+	Text        string    `json:"text"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// ChatMute silences every player of a user until the given time.
+type ChatMute struct {
+	UserID int       `json:"user_id" validate:"required"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason" validate:"lte=256"`
 }
 
 type Tokens struct {
 	Token        string `json:"token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+const (
+	GuildInvite   = "invite"
+	GuildKick     = "kick"
+	GuildPromote  = "promote"
+	GuildWithdraw = "withdraw"
+	GuildMOTD     = "motd"
+)
+
+var GuildPermissions = []string{GuildInvite, GuildKick, GuildPromote, GuildWithdraw, GuildMOTD}
+
+// GuildRank is a rank of a guild, lower ranks come first. Rank 0 leads the
+// guild and holds every permission.
+type GuildRank struct {
+	Rank        int      `json:"rank"`
+	Name        string   `json:"name" validate:"required,lte=32"`
+	Permissions []string `json:"permissions"`
+}
+
+func (r GuildRank) Can(permission string) bool {
+	if r.Rank == 0 {
+		return true
+	}
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+type Guild struct {
+	ID        int         `json:"id"`
+	Name      string      `json:"name"`
+	MOTD      string      `json:"motd"`
+	Members   int         `json:"members"`
+	Ranks     []GuildRank `json:"ranks"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type GuildName struct {
+	Name string `json:"name" validate:"required,gte=3,lte=32"`
+}
+
+type GuildMOTDUpdate struct {
+	MOTD string `json:"motd" validate:"lte=512"`
+}
+
+type GuildRankUpdate struct {
+	Rank int `json:"rank" validate:"gte=0"`
+}
+
+// GuildMember is a player of the guild roster.
+type GuildMember struct {
+	Player
+	GuildID  int       `json:"guild_id"`
+	Rank     int       `json:"rank"`
+	JoinedAt time.Time `json:"joined_at"`
+	Online   bool      `json:"online"`
+}
+
+type GuildInvitation struct {
+	GuildID     int       `json:"guild_id"`
+	GuildName   string    `json:"guild_name"`
+	PlayerID    int       `json:"player_id"`
+	InviterID   int       `json:"inviter_id"`
+	InviterName string    `json:"inviter_name"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+const (
+	GuildDeposit    = "deposit"
+	GuildWithdrawal = "withdraw"
+)
+
+// GuildBankEntry records items or currency moved in or out of the guild
+// bank. The player is nil once deleted, its name is kept.
+type GuildBankEntry struct {
+	ID         int64     `json:"id"`
+	PlayerID   *int      `json:"player_id"`
+	PlayerName string    `json:"player_name"`
+	Action     string    `json:"action"`
+	ItemID     string    `json:"item_id,omitempty"`
+	Quantity   int       `json:"quantity,omitempty"`
+	Currency   int64     `json:"currency,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// GuildBankMove moves items between a slot of the player inventory and a
+// slot of the guild bank.
+type GuildBankMove struct {
+	Slot     int `json:"slot" validate:"gte=0"`
+	Quantity int `json:"quantity" validate:"gte=1"`
+}
+
+// LeaderboardEntry is a ranked player, players with the same score share
+// the rank while the position orders them.
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	Position int    `json:"position"`
+	PlayerID int    `json:"player_id"`
+	Name     string `json:"name"`
+	Sprite   string `json:"sprite"`
+	XP       int    `json:"xp"`
+	Level    int    `json:"level"`
+}
+
+// LeaderboardCursor is the last entry of a page. Unlike the positions it
+// still points to the same place after a refresh.
+type LeaderboardCursor struct {
+	XP       int `json:"xp"`
+	PlayerID int `json:"player_id"`
+}
+
+// Leaderboard is a page of the ranking as of the last refresh.
+type Leaderboard struct {
+	Entries     []*LeaderboardEntry `json:"entries"`
+	RefreshedAt *time.Time          `json:"refreshed_at"`
+	// Next is the cursor of the following page, nil on the last one.
+	Next *LeaderboardCursor `json:"next"`
+}
+
+// TradeItem is an inventory stack offered in a trade. ItemID is what the
+// slot held when offered, the trade fails if it changed since.
+type TradeItem struct {
+	Slot     int    `json:"slot"`
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity"`
+}
+
+// TradeOffer is what a player gives to the other side of a trade.
+type TradeOffer struct {
+	PlayerID int         `json:"player_id"`
+	Items    []TradeItem `json:"items"`
+	Currency int64       `json:"currency"`
+}
+
+// Reasons of ledger transactions.
+const (
+	ReasonAdminGrant      = "admin_grant"
+	ReasonTrade           = "trade"
+	ReasonGuildDeposit    = "guild_deposit"
+	ReasonGuildWithdrawal = "guild_withdrawal"
+	ReasonVendorBuy       = "vendor_buy"
+	ReasonVendorSell      = "vendor_sell"
+	ReasonAuctionBid      = "auction_bid"
+	ReasonAuctionRefund   = "auction_refund"
+	ReasonAuctionSale     = "auction_sale"
+	ReasonMailClaim       = "mail_claim"
+	ReasonMailSend        = "mail_send"
+	ReasonMailReturn      = "mail_return"
+	ReasonSystemMail      = "system_mail"
+)
+
+// LedgerTransaction moves currency between accounts. Replayed is set when
+// the idempotency key was already used and nothing moved this time.
+type LedgerTransaction struct {
+	ID             int64     `json:"id"`
+	Reason         string    `json:"reason"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	CreatedAt      time.Time `json:"created_at"`
+	Replayed       bool      `json:"replayed"`
+}
+
+// LedgerEntry is a credit, or a debit when negative, of an account.
+type LedgerEntry struct {
+	ID            int64     `json:"id"`
+	TransactionID int64     `json:"transaction_id"`
+	Reason        string    `json:"reason"`
+	Amount        int64     `json:"amount"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type Wallet struct {
+	Balance int64 `json:"balance"`
+}
+
+type CurrencyAmount struct {
+	Amount int64 `json:"amount" validate:"required,gte=1"`
+}
+
+// LedgerReport lists the ledger invariants that don't hold, at most a
+// page of IDs each. Total is the sum of every entry and must be zero.
+type LedgerReport struct {
+	OK           bool    `json:"ok"`
+	Transactions int64   `json:"transactions"`
+	Total        int64   `json:"total"`
+	Unbalanced   []int64 `json:"unbalanced_transactions"`
+	Mismatched   []int64 `json:"mismatched_snapshots"`
+	Overdrawn    []int64 `json:"overdrawn_accounts"`
+}
+
+const (
+	VendorBuy  = "buy"
+	VendorSell = "sell"
+)
+
+// VendorPurchase and VendorSale are what players send to buy from and sell
+// to a vendor.
+type VendorPurchase struct {
+	ItemID   string `json:"item_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"gte=1,lte=1000"`
+}
+
+type VendorSale struct {
+	Slot     int `json:"slot" validate:"gte=0"`
+	Quantity int `json:"quantity" validate:"gte=1,lte=1000"`
+}
+
+// VendorOrder is a purchase or sale priced by the vendor catalog. Stock is
+// how many items a limited offer holds when full, one more coming back
+// every Restock, and is 0 for unlimited offers. Slot is the inventory slot
+// sold from.
+type VendorOrder struct {
+	VendorID string
+	ItemID   string
+	Slot     int
+	Quantity int
+	Price    int64
+	Stock    int
+	Restock  time.Duration
+}
+
+// VendorStock is the stored stock of a limited offer, restocking is
+// counted from RestockedAt.
+type VendorStock struct {
+	ItemID      string    `json:"item_id"`
+	Stock       int       `json:"stock"`
+	RestockedAt time.Time `json:"restocked_at"`
+}
+
+// VendorTransaction records a purchase or sale for the economy analytics,
+// TransactionID is the ledger transaction that moved the currency.
+type VendorTransaction struct {
+	ID            int64     `json:"id"`
+	TransactionID int64     `json:"transaction_id"`
+	PlayerID      int       `json:"player_id"`
+	VendorID      string    `json:"vendor_id"`
+	Action        string    `json:"action"`
+	ItemID        string    `json:"item_id"`
+	Quantity      int       `json:"quantity"`
+	Price         int64     `json:"price"`
+	Total         int64     `json:"total"`
+	CreatedAt     time.Time `json:"created_at"`
+	Replayed      bool      `json:"replayed"`
+}
+
+// Mail is delivered to a player, its item and currency are claimed
+// separately from reading it. System mail has no sender ID. Returned is
+// set on mail bringing back the attachments of expired mail.
+type Mail struct {
+	ID         int64      `json:"id"`
+	SenderID   int        `json:"sender_id,omitempty"`
+	SenderName string     `json:"sender_name"`
+	Subject    string     `json:"subject"`
+	Body       string     `json:"body"`
+	ItemID     string     `json:"item_id,omitempty"`
+	Quantity   int        `json:"quantity,omitempty"`
+	Currency   int64      `json:"currency,omitempty"`
+	Returned   bool       `json:"returned"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReadAt     *time.Time `json:"read_at,omitempty"`
+	ClaimedAt  *time.Time `json:"claimed_at,omitempty"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// Attached tells whether the mail holds attachments not claimed yet.
+func (m *Mail) Attached() bool {
+	return m.ClaimedAt == nil && (m.ItemID != "" || m.Currency > 0)
+}
+
+// NewMail is mail sent by a player, with the items of an inventory slot
+// and currency attached when Slot and Currency are set.
+type NewMail struct {
+	Recipient string `json:"recipient" validate:"required"`
+	Subject   string `json:"subject" validate:"required,lte=64"`
+	Body      string `json:"body" validate:"lte=1024"`
+	Slot      *int   `json:"slot" validate:"omitempty,gte=0"`
+	Quantity  int    `json:"quantity" validate:"gte=0"`
+	Currency  int64  `json:"currency" validate:"gte=0"`
+}
+
+// SystemMail is mail sent by admins, its attachments are created.
+type SystemMail struct {
+	Subject  string `json:"subject" validate:"required,lte=64"`
+	Body     string `json:"body" validate:"lte=1024"`
+	ItemID   string `json:"item_id"`
+	Quantity int    `json:"quantity" validate:"gte=0"`
+	Currency int64  `json:"currency" validate:"gte=0"`
+}
+
+const (
+	AuctionActive    = "active"
+	AuctionSold      = "sold"
+	AuctionExpired   = "expired"
+	AuctionCancelled = "cancelled"
+)
+
+// Auction is a listing of the auction house. The item and the highest bid
+// are held in escrow until it is settled.
+type Auction struct {
+	ID         int64     `json:"id"`
+	SellerID   int       `json:"seller_id,omitempty"`
+	SellerName string    `json:"seller_name"`
+	ItemID     string    `json:"item_id"`
+	ItemType   string    `json:"item_type"`
+	Rarity     string    `json:"rarity"`
+	Quantity   int       `json:"quantity"`
+	StartBid   int64     `json:"start_bid"`
+	Buyout     int64     `json:"buyout,omitempty"`
+	Bid        int64     `json:"bid"`
+	BidderID   int       `json:"bidder_id,omitempty"`
+	Bids       int       `json:"bids"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+// NewAuction lists items of an inventory slot, Buyout is 0 when the items
+// can only be bid on.
+type NewAuction struct {
+	Slot     int   `json:"slot" validate:"gte=0"`
+	Quantity int   `json:"quantity" validate:"gte=1"`
+	StartBid int64 `json:"start_bid" validate:"gte=1"`
+	Buyout   int64 `json:"buyout" validate:"gte=0"`
+	Hours    int   `json:"hours" validate:"required"`
+}
+
+// AuctionQuery filters active auctions, empty fields match everything.
+// Prices compare with the current price, the highest bid or the starting
+// bid.
+type AuctionQuery struct {
+	ItemID   string
+	Type     string
+	Rarity   string
+	MinPrice int64
+	MaxPrice int64
+	SellerID int
+	Before   int64
+	Limit    int
+}
+
+const (
+	CraftActive    = "crafting"
+	CraftReady     = "ready"
+	CraftCollected = "collected"
+)
+
+// Profession is the progress of a player in a crafting profession.
+type Profession struct {
+	Profession string `json:"profession"`
+	XP         int    `json:"xp"`
+	Level      int    `json:"level"`
+}
+
+// Craft is a recipe crafted Count times. Its ingredients are taken when it
+// starts, the output is collected once it is ready.
+type Craft struct {
+	ID          int64      `json:"id"`
+	PlayerID    int        `json:"player_id"`
+	RecipeID    string     `json:"recipe_id"`
+	Profession  string     `json:"profession"`
+	ItemID      string     `json:"item_id"`
+	Quantity    int        `json:"quantity"`
+	XP          int        `json:"xp"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishesAt  time.Time  `json:"finishes_at"`
+	CollectedAt *time.Time `json:"collected_at,omitempty"`
+}
+
+type NewCraft struct {
+	RecipeID string `json:"recipe_id" validate:"required"`
+	Count    int    `json:"count" validate:"gte=0"`
+}
+
+// CraftOrder is a craft priced from its recipe, MinXP is the profession XP
+// the recipe requires. Orders without duration finish right away.
+type CraftOrder struct {
+	RecipeID    string
+	Profession  string
+	MinXP       int
+	Ingredients []ItemStack
+	Output      ItemStack
+	XP          int
+	Duration    time.Duration
+}
+
+// PlayerEffect is a status effect saved while its player is offline.
+type PlayerEffect struct {
+	EffectID  string    `json:"effect_id"`
+	Stacks    int       `json:"stacks"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -1,6 +1,9 @@
 package models
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type UserRepository interface {
 	GetUser(ctx context.Context, ID int) (*User, error)
@@ -10,14 +13,236 @@ type UserRepository interface {
 	DeleteUser(ctx context.Context, ID int) error
 
 	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	// UsernameTaken also matches the names that look like username.
+	UsernameTaken(ctx context.Context, username string) (bool, error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	GetUserByRefresh(ctx context.Context, refresh string) (*User, error)
 	UpdateUserTokens(ctx context.Context, ID int, token, refresh string) error
 }
 
+type EntitlementRepository interface {
+	GetEntitlements(ctx context.Context, userID int) ([]*Entitlement, error)
+	GrantEntitlement(ctx context.Context, userID int, entitlement Entitlement) (*Entitlement, error)
+	GetCharacterSlots(ctx context.Context, userID int) (*CharacterSlots, error)
+}
+
+type FriendRepository interface {
+	GetFriends(ctx context.Context, userID int) ([]*Friend, error)
+	// GetFriendRequests returns the pending requests sent and received.
+	GetFriendRequests(ctx context.Context, userID int) ([]*FriendRequest, error)
+	SendFriendRequest(ctx context.Context, senderID, recipientID int) (*FriendRequest, error)
+	AcceptFriendRequest(ctx context.Context, recipientID, requestID int) error
+	DeclineFriendRequest(ctx context.Context, recipientID, requestID int) error
+	CancelFriendRequest(ctx context.Context, senderID, requestID int) error
+	RemoveFriend(ctx context.Context, userID, friendID int) error
+}
+
+type BlockRepository interface {
+	GetBlocks(ctx context.Context, userID int) ([]*BlockedUser, error)
+	// BlockUser also ends the friendship and pending requests of both users.
+	BlockUser(ctx context.Context, userID, blockedID int) error
+	UnblockUser(ctx context.Context, userID, blockedID int) error
+	// IsBlocked reports whether either user blocked the other.
+	IsBlocked(ctx context.Context, a, b int) (bool, error)
+}
+
 type PlayerRepository interface {
+	GetPlayer(ctx context.Context, ID int) (*Player, error)
 	GetPlayerList(ctx context.Context, ID int) ([]*Player, error)
+	// CreatePlayer fails with a *CharacterLimitError when the account has
+	// no free character slot.
 	CreatePlayer(ctx context.Context, player Player) (*Player, error)
+	GrantXP(ctx context.Context, ID int, amount int) (*Player, error)
+	UpdatePlayerStats(ctx context.Context, ID int, stats Stats) error
+	UpdatePlayerVitals(ctx context.Context, ID int, hp, mana int) error
+	// RespawnPlayer restores the HP and mana of a dead player, which its
+	// derived stats give.
+	RespawnPlayer(ctx context.Context, ID int, mapName string, x, y, hp, mana int, xpLoss int) (*Player, error)
+	GetPlayerByName(ctx context.Context, name string) (*Player, error)
+	// PlayerNameTaken also matches the names that look like name.
+	PlayerNameTaken(ctx context.Context, name string) (bool, error)
+	// FoldNames stores the skeletons of the names from before they were.
+	FoldNames(ctx context.Context) (int, error)
+}
+
+type InventoryRepository interface {
+	GetInventory(ctx context.Context, playerID int) ([]*InventorySlot, error)
+	AddItem(ctx context.Context, playerID int, item Item, quantity int) ([]*InventorySlot, error)
+	RemoveItem(ctx context.Context, playerID int, slot int, quantity int) error
+	MoveItem(ctx context.Context, playerID int, from int, to int) ([]*InventorySlot, error)
+	SplitStack(ctx context.Context, playerID int, from int, to int, quantity int) ([]*InventorySlot, error)
+}
+
+type EquipmentRepository interface {
+	GetEquipment(ctx context.Context, playerID int) ([]*EquippedItem, error)
+	EquipItem(ctx context.Context, playerID int, inventorySlot int, item Item) ([]*EquippedItem, error)
+	UnequipItem(ctx context.Context, playerID int, slot string) ([]*EquippedItem, error)
+}
+
+type QuestRepository interface {
+	GetQuests(ctx context.Context, playerID int) ([]*PlayerQuest, error)
+	AcceptQuest(ctx context.Context, playerID int, questID string, progress []int) (*PlayerQuest, error)
+	AbandonQuest(ctx context.Context, playerID int, questID string) error
+	AddQuestProgress(ctx context.Context, playerID int, questID string, objective, amount, required int) error
+	// CompleteQuest also grants the XP reward, returning the player with
+	// its new XP when there is one.
+	CompleteQuest(ctx context.Context, playerID int, questID string, cost, rewards []ItemStack, xp int) (*PlayerQuest, *Player, error)
+}
+
+// AchievementRepository caps progress at the goal of the achievement and
+// unlocks it once reached, the updates report whether they unlocked it.
+type AchievementRepository interface {
+	GetAchievements(ctx context.Context, playerID int) ([]*PlayerAchievement, error)
+	AddAchievementProgress(ctx context.Context, playerID int, achievementID string, amount, goal int) (bool, error)
+	// RaiseAchievementProgress keeps the highest value, like a level.
+	RaiseAchievementProgress(ctx context.Context, playerID int, achievementID string, value, goal int) (bool, error)
+	// ExtendAchievementStreak counts consecutive days, a missed day starts
+	// over.
+	ExtendAchievementStreak(ctx context.Context, playerID int, achievementID string, day time.Time, goal int) (bool, error)
+}
+
+type ChatRepository interface {
+	SaveChatMessage(ctx context.Context, message ChatMessage) (*ChatMessage, error)
+	// GetChatMessages pages through the history of a channel, newest first,
+	// starting before the given message ID or at the newest when it is 0.
+	GetChatMessages(ctx context.Context, channel string, before int64, limit int) ([]*ChatMessage, error)
+	// GetChatMute returns nil when the user was never muted.
+	GetChatMute(ctx context.Context, userID int) (*ChatMute, error)
+	MuteUser(ctx context.Context, mute ChatMute) error
+	UnmuteUser(ctx context.Context, userID int) error
+}
+
+type GuildRepository interface {
+	GetGuild(ctx context.Context, ID int) (*Guild, error)
+	// GetPlayerGuild returns the guild of the player and its membership.
+	GetPlayerGuild(ctx context.Context, playerID int) (*Guild, *GuildMember, error)
+	GetGuildMembers(ctx context.Context, guildID int) ([]*GuildMember, error)
+	CreateGuild(ctx context.Context, playerID int, name string, ranks []GuildRank) (*Guild, error)
+	// LeaveGuild disbands the guild when the last member leaves, which
+	// fails with ErrGuildNotEmpty until its bank and currency are withdrawn.
+	LeaveGuild(ctx context.Context, playerID int) (*GuildMember, error)
+	KickGuildMember(ctx context.Context, actorID, playerID int) (*GuildMember, error)
+	// SetGuildMemberRank passes the lead on when setting rank 0, the guild
+	// master taking the next rank.
+	SetGuildMemberRank(ctx context.Context, actorID, playerID, rank int) (*GuildMember, error)
+	SetGuildRanks(ctx context.Context, actorID int, ranks []GuildRank) (*Guild, error)
+	SetGuildMOTD(ctx context.Context, actorID int, motd string) (*Guild, error)
+
+	GetGuildInvites(ctx context.Context, playerID int) ([]*GuildInvitation, error)
+	InviteToGuild(ctx context.Context, actorID, playerID int) (*GuildInvitation, error)
+	AcceptGuildInvite(ctx context.Context, playerID, guildID int) (*GuildMember, error)
+	DeclineGuildInvite(ctx context.Context, playerID, guildID int) error
+
+	GetGuildBank(ctx context.Context, guildID int) ([]*InventorySlot, error)
+	DepositGuildItem(ctx context.Context, playerID, slot, quantity int) ([]*InventorySlot, error)
+	WithdrawGuildItem(ctx context.Context, playerID, bankSlot, quantity int) ([]*InventorySlot, error)
+	// GetGuildBankLog pages through the bank log, newest first, starting
+	// before the given entry ID or at the newest when it is 0.
+	GetGuildBankLog(ctx context.Context, guildID int, before int64, limit int) ([]*GuildBankEntry, error)
+
+	GetGuildBalance(ctx context.Context, guildID int) (int64, error)
+	DepositGuildCurrency(ctx context.Context, playerID int, amount int64, key string) (*LedgerTransaction, error)
+	WithdrawGuildCurrency(ctx context.Context, playerID int, amount int64, key string) (*LedgerTransaction, error)
+}
+
+// LedgerRepository moves currency with balanced transactions. A key
+// already used returns the first transaction without moving anything.
+type LedgerRepository interface {
+	GetBalance(ctx context.Context, playerID int) (int64, error)
+	// GetLedgerEntries pages through the entries of the player, newest
+	// first, starting before the given entry ID or at the newest when 0.
+	GetLedgerEntries(ctx context.Context, playerID int, before int64, limit int) ([]*LedgerEntry, error)
+	GrantCurrency(ctx context.Context, playerID int, amount int64, reason, key string) (*LedgerTransaction, error)
+	SpendCurrency(ctx context.Context, playerID int, amount int64, reason, key string) (*LedgerTransaction, error)
+	TransferCurrency(ctx context.Context, fromID, toID int, amount int64, reason, key string) (*LedgerTransaction, error)
+	CheckLedger(ctx context.Context) (*LedgerReport, error)
+}
+
+// VendorRepository settles vendor orders against the ledger and the
+// inventory in one transaction, recording every purchase and sale.
+type VendorRepository interface {
+	GetVendorStock(ctx context.Context, vendorID string) ([]*VendorStock, error)
+	BuyFromVendor(ctx context.Context, playerID int, order VendorOrder, key string) (*VendorTransaction, error)
+	SellToVendor(ctx context.Context, playerID int, order VendorOrder, key string) (*VendorTransaction, error)
+}
+
+// MailRepository delivers mail between players and from the game. Mail
+// expires after a while, unclaimed attachments of player mail going back
+// to the sender.
+type MailRepository interface {
+	// GetMail pages through the mailbox of the player, newest first,
+	// starting before the given mail ID or at the newest when it is 0.
+	GetMail(ctx context.Context, playerID int, before int64, limit int) ([]*Mail, error)
+	// ReadMail returns the mail, marking it read.
+	ReadMail(ctx context.Context, playerID int, mailID int64) (*Mail, error)
+	CountUnreadMail(ctx context.Context, playerID int) (int, error)
+	// SendMail takes the attachments from the sender. A key already used
+	// by the sender returns the mail sent with it.
+	SendMail(ctx context.Context, senderID, recipientID int, mail NewMail, key string) (*Mail, error)
+	SendSystemMail(ctx context.Context, recipientID int, mail SystemMail, key string) (*Mail, error)
+	// ClaimMail moves the attachments of the mail to the player.
+	ClaimMail(ctx context.Context, playerID int, mailID int64) (*Mail, error)
+	// DeleteMail refuses to delete mail with unclaimed attachments.
+	DeleteMail(ctx context.Context, playerID int, mailID int64) error
+	// ExpireMail processes at most limit expired mail, returning how many
+	// were.
+	ExpireMail(ctx context.Context, limit int) (int, error)
+}
+
+// AuctionRepository escrows the listed items and the highest bid of each
+// auction. Outbid players and the sides of settled auctions are paid
+// through mail.
+type AuctionRepository interface {
+	GetAuctions(ctx context.Context, query AuctionQuery) ([]*Auction, error)
+	GetAuction(ctx context.Context, auctionID int64) (*Auction, error)
+	CreateAuction(ctx context.Context, playerID int, listing NewAuction) (*Auction, error)
+	// CancelAuction returns the items to the seller while nobody bid.
+	CancelAuction(ctx context.Context, playerID int, auctionID int64) (*Auction, error)
+	BidOnAuction(ctx context.Context, playerID int, auctionID int64, amount int64, key string) (*Auction, error)
+	BuyoutAuction(ctx context.Context, playerID int, auctionID int64, key string) (*Auction, error)
+	// SettleAuctions settles at most limit expired auctions, returning
+	// how many were.
+	SettleAuctions(ctx context.Context, limit int) (int, error)
+}
+
+// CraftingRepository takes the ingredients of a craft when it starts and
+// gives its output and profession XP once it finishes.
+type CraftingRepository interface {
+	GetProfessions(ctx context.Context, playerID int) ([]*Profession, error)
+	// GetCrafts returns the crafts of the player not collected yet.
+	GetCrafts(ctx context.Context, playerID int) ([]*Craft, error)
+	// StartCraft collects orders without duration right away.
+	StartCraft(ctx context.Context, playerID int, order CraftOrder) (*Craft, error)
+	// FinishCrafts marks at most limit crafts past their time ready,
+	// granting their XP, and returns them.
+	FinishCrafts(ctx context.Context, limit int) ([]*Craft, error)
+	CollectCraft(ctx context.Context, playerID int, craftID int64) (*Craft, error)
+}
+
+// StatusEffectRepository keeps the status effects of players that outlast
+// their sessions.
+type StatusEffectRepository interface {
+	// GetPlayerEffects returns the saved effects not expired yet.
+	GetPlayerEffects(ctx context.Context, playerID int) ([]*PlayerEffect, error)
+	// SavePlayerEffects replaces the saved effects of the player.
+	SavePlayerEffects(ctx context.Context, playerID int, effects []*PlayerEffect) error
+}
+
+type TradeRepository interface {
+	// ExecuteTrade swaps the offers in a single transaction and records
+	// the trade, returning its ID.
+	ExecuteTrade(ctx context.Context, offer, counter TradeOffer) (int64, error)
+}
+
+type LeaderboardRepository interface {
+	// GetXPLeaderboard pages through the XP ranking after the given
+	// cursor, from the top when nil. A class ranks the players of that
+	// class only.
+	GetXPLeaderboard(ctx context.Context, class string, after *LeaderboardCursor, limit int) (*Leaderboard, error)
+	// GetXPLeaderboardAround returns the player with the players ranked
+	// right above and below.
+	GetXPLeaderboardAround(ctx context.Context, playerID int, class string, radius int) (*Leaderboard, error)
+	RefreshLeaderboards(ctx context.Context) error
 }
 
 type TokenRepository interface {
@@ -0,0 +1,108 @@
+package monsters
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"tribble/data"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+)
+
+type Definition struct {
+	ID               string       `json:"id"`
+	Name             string       `json:"name"`
+	Level            int          `json:"level"`
+	Attribute        string       `json:"attribute"`
+	Stats            models.Stats `json:"stats"`
+	AggroRadius      int          `json:"aggro_radius"`
+	LeashDistance    int          `json:"leash_distance"`
+	WanderRadius     int          `json:"wander_radius"`
+	AttackRange      int          `json:"attack_range"`
+	AttackIntervalMS int          `json:"attack_interval_ms"`
+	RespawnSeconds   int          `json:"respawn_seconds"`
+	XP               int          `json:"xp"`
+	Loot             []Drop       `json:"loot,omitempty"`
+}
+
+// Drop is an entry of a loot table, the item drops with the given chance
+// in a quantity between min and max.
+type Drop struct {
+	Item   string  `json:"item"`
+	Chance float64 `json:"chance"`
+	Min    int     `json:"min"`
+	Max    int     `json:"max"`
+}
+
+type Catalog struct {
+	monsters []*Definition
+	byID     map[string]*Definition
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Monsters []*Definition `json:"monsters"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Monsters) == 0 {
+		return nil, errors.New("monster catalog is empty")
+	}
+
+	catalog := &Catalog{monsters: file.Monsters, byID: make(map[string]*Definition)}
+	for _, m := range file.Monsters {
+		if m.ID == "" {
+			return nil, errors.New("monster without id")
+		}
+		if _, ok := catalog.byID[m.ID]; ok {
+			return nil, fmt.Errorf("duplicated monster %v", m.ID)
+		}
+		if !models.IsAttribute(m.Attribute) {
+			return nil, fmt.Errorf("monster %v has invalid attribute %v", m.ID, m.Attribute)
+		}
+		if m.Level < 1 || m.Stats.HP < 1 || m.Stats.MovementSpeed < 1 || m.AttackRange < 1 || m.AttackIntervalMS < 1 {
+			return nil, fmt.Errorf("monster %v must have level, hp, movement speed and attack", m.ID)
+		}
+		if m.LeashDistance < m.AggroRadius {
+			return nil, fmt.Errorf("monster %v leash distance is shorter than its aggro radius", m.ID)
+		}
+		for _, drop := range m.Loot {
+			if _, ok := items.Default.Get(drop.Item); !ok {
+				return nil, fmt.Errorf("monster %v drops unknown item %v", m.ID, drop.Item)
+			}
+			if drop.Chance <= 0 || drop.Chance > 1 || drop.Min < 1 || drop.Max < drop.Min {
+				return nil, fmt.Errorf("monster %v has an invalid %v drop", m.ID, drop.Item)
+			}
+		}
+		catalog.byID[m.ID] = m
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Definition, bool) {
+	m, ok := c.byID[ID]
+	return m, ok
+}
+
+func (c *Catalog) List() []*Definition {
+	return c.monsters
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("monsters.json", settings.MonsterCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open monster catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load monster catalog: %v", err)
+	}
+	return catalog
+}
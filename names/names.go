@@ -0,0 +1,228 @@
+package names
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"tribble/data"
+	"tribble/settings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrNameLength    = errors.New("name has an invalid length")
+	ErrNameCharacter = errors.New("name contains an invalid character")
+	ErrNameFormat    = errors.New("name is badly formed")
+	ErrNameReserved  = errors.New("name is reserved")
+	ErrNameBlocked   = errors.New("name contains a blocked word")
+)
+
+// confusables fold characters that look alike to the one they imitate.
+var confusables = map[rune]rune{
+	'0': 'o', '1': 'l', 'i': 'l', '|': 'l', '!': 'l',
+	'2': 'z', '3': 'e', '4': 'a', '5': 's', '6': 'g', '7': 't', '8': 'b', '9': 'g',
+	'@': 'a', '$': 's', '€': 'e',
+	// cyrillic and greek
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p',
+	'с': 'c', 'т': 't', 'у': 'y', 'х': 'x', 'і': 'l', 'ј': 'j', 'ѕ': 's',
+	'α': 'a', 'β': 'b', 'ε': 'e', 'ι': 'l', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p',
+	'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+var sequences = strings.NewReplacer("rn", "m", "vv", "w")
+
+// Skeleton is the form names are compared in: accents stripped, lower
+// case, confusable characters folded and separators dropped, so "Ädm1n"
+// and "a_d_m_i_n" both become the skeleton of "admin".
+func Skeleton(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if folded, ok := confusables[r]; ok {
+			r = folded
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return sequences.Replace(b.String())
+}
+
+// Lists are the reserved names, which may not be used as a name or a word
+// of a name, and the blocked words, which may not appear anywhere in one.
+type Lists struct {
+	reserved map[string]bool
+	blocked  []string
+}
+
+func NewLists(reserved, blocked []string) *Lists {
+	l := &Lists{reserved: make(map[string]bool, len(reserved))}
+	for _, word := range reserved {
+		if s := Skeleton(word); s != "" {
+			l.reserved[s] = true
+		}
+	}
+	for _, word := range blocked {
+		if s := Skeleton(word); s != "" {
+			l.blocked = append(l.blocked, s)
+		}
+	}
+	return l
+}
+
+// LoadList reads a word list file.
+func LoadList(r io.Reader) ([]string, error) {
+	var file struct {
+		Words []string `json:"words"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	return file.Words, nil
+}
+
+// Policy is the rules a kind of name follows. Names are made of letters of
+// Script, digits when allowed, and single Separators between them.
+type Policy struct {
+	Min        int
+	Max        int
+	Script     *unicode.RangeTable
+	Digits     bool
+	Separators string
+	Lists      *Lists
+}
+
+// Check validates the name, returning its normalized form to store.
+func (p *Policy) Check(name string) (string, error) {
+	name = norm.NFKC.String(strings.TrimSpace(name))
+	runes := []rune(name)
+	if len(runes) < p.Min || len(runes) > p.Max {
+		return "", fmt.Errorf("%w: must be %d to %d characters", ErrNameLength, p.Min, p.Max)
+	}
+
+	separator := true
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) && unicode.Is(p.Script, r):
+			separator = false
+		case p.Digits && r >= '0' && r <= '9':
+			if i == 0 {
+				return "", fmt.Errorf("%w: must start with a letter", ErrNameFormat)
+			}
+			separator = false
+		case strings.ContainsRune(p.Separators, r):
+			if separator {
+				return "", fmt.Errorf("%w: %q must be between letters", ErrNameFormat, r)
+			}
+			separator = true
+		default:
+			return "", fmt.Errorf("%w: %q", ErrNameCharacter, r)
+		}
+	}
+	if separator {
+		return "", fmt.Errorf("%w: %q must be between letters", ErrNameFormat, runes[len(runes)-1])
+	}
+
+	if err := p.Lists.check(name, p.Separators); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// check matches the whole name and each of its words against the reserved
+// names, so "GM Bob" is refused but "Badminton" is not. Blocked words are
+// refused anywhere in the name.
+func (l *Lists) check(name, separators string) error {
+	skeleton := Skeleton(name)
+	for _, word := range l.blocked {
+		if strings.Contains(skeleton, word) {
+			return ErrNameBlocked
+		}
+	}
+	if l.reserved[skeleton] {
+		return ErrNameReserved
+	}
+	for _, word := range words(name, separators) {
+		if l.reserved[Skeleton(word)] {
+			return ErrNameReserved
+		}
+	}
+	return nil
+}
+
+// words splits the name at separators and at lower to upper case changes.
+func words(name, separators string) []string {
+	words := make([]string, 0)
+	var word []rune
+	var last rune
+	for _, r := range name {
+		if strings.ContainsRune(separators, r) || (unicode.IsUpper(r) && unicode.IsLower(last)) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = word[:0]
+		}
+		if !strings.ContainsRune(separators, r) {
+			word = append(word, r)
+		}
+		last = r
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+func mustLoadList(name, path string) []string {
+	f, err := data.Open(name, path)
+	if err != nil {
+		log.Fatalf("Unable to open name list %v: %v", name, err)
+	}
+	defer f.Close()
+	words, err := LoadList(f)
+	if err != nil {
+		log.Fatalf("Unable to load name list %v: %v", name, err)
+	}
+	return words
+}
+
+func mustLoadLists() *Lists {
+	reserved := mustLoadList("reserved_names.json", settings.ReservedNamesFile)
+	// staff names can't be impersonated either, their own accounts keep
+	// them as long as they don't rename
+	for _, name := range settings.StaffNames {
+		if name = strings.TrimSpace(name); name != "" {
+			reserved = append(reserved, name)
+		}
+	}
+	return NewLists(reserved, mustLoadList("blocked_names.json", settings.BlockedNamesFile))
+}
+
+var defaultLists = mustLoadLists()
+
+// Usernames are the account names, plain ASCII.
+var Usernames = &Policy{
+	Min:        3,
+	Max:        32,
+	Script:     &unicode.RangeTable{R16: []unicode.Range16{{Lo: 'A', Hi: 'Z', Stride: 1}, {Lo: 'a', Hi: 'z', Stride: 1}}},
+	Digits:     true,
+	Separators: "_-.",
+	Lists:      defaultLists,
+}
+
+// PlayerNames are the character names, latin letters with accents allowed.
+var PlayerNames = &Policy{
+	Min:        3,
+	Max:        16,
+	Script:     unicode.Latin,
+	Separators: " '-",
+	Lists:      defaultLists,
+}
@@ -0,0 +1,67 @@
+package names
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestSkeleton(t *testing.T) {
+	assert.Equal(t, Skeleton("Ädm1n"), Skeleton("admin"))
+	assert.Equal(t, Skeleton("a_d.m-i n"), Skeleton("admin"))
+	assert.Equal(t, Skeleton("ａｄｍｉｎ"), Skeleton("admin"))
+	assert.Equal(t, Skeleton("аdmin"), Skeleton("admin"))
+	assert.Equal(t, Skeleton("arnold"), Skeleton("amold"))
+}
+
+func TestCheck(t *testing.T) {
+	lists := NewLists([]string{"admin", "gm", "staff", "system", "tribble"}, []string{"idiot"})
+	users := &Policy{Min: 3, Max: 12, Script: Usernames.Script, Digits: true, Separators: "_-.", Lists: lists}
+	players := &Policy{Min: 3, Max: 16, Script: PlayerNames.Script, Separators: " '-", Lists: lists}
+
+	tests := []struct {
+		policy *Policy
+		name   string
+		err    error
+	}{
+		{users, "frodo_99", nil},
+		{users, "fr", ErrNameLength},
+		{users, "frodobagginsxx", ErrNameLength},
+		{users, "9frodo", ErrNameFormat},
+		{users, "frodo__b", ErrNameFormat},
+		{users, "_frodo", ErrNameFormat},
+		{users, "frodo.", ErrNameFormat},
+		{users, "fródo", ErrNameCharacter},
+		{users, "frоdo", ErrNameCharacter},
+		{users, "Adm1n", ErrNameReserved},
+		{users, "xx_admin_xx", ErrNameReserved},
+		{users, "xxadminxx", nil},
+		{users, "Badminton", nil},
+		{users, "Systemic", nil},
+		{users, "Madmind", nil},
+		{users, "Tribbles", nil},
+		{users, "gm_frodo", ErrNameReserved},
+		{users, "the1d10t", ErrNameBlocked},
+		{players, "Frodo", nil},
+		{players, "Éowyn", nil},
+		{players, "Gandalf Grey", nil},
+		{players, "Sam  Grey", ErrNameFormat},
+		{players, "Frodo2", ErrNameCharacter},
+		{players, "Frodo GM", ErrNameReserved},
+		{players, "Frodo Gm", ErrNameReserved},
+		{players, "Sam SamGm", ErrNameReserved},
+		{players, "Gmork", nil},
+		{players, "Staffordshire", nil},
+	}
+	for _, test := range tests {
+		_, err := test.policy.Check(test.name)
+		if !errors.Is(err, test.err) && !(err == nil && test.err == nil) {
+			t.Errorf("%s FAILED: %q got %v want %v", t.Name(), test.name, err, test.err)
+		}
+	}
+
+	name, err := players.Check("  Ｆrodo ")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, name, "Frodo")
+}
@@ -0,0 +1,69 @@
+package party
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"tribble/gateway"
+	"tribble/models"
+	"tribble/social"
+)
+
+type inviteData struct {
+	Name string `json:"name"`
+}
+
+type playerData struct {
+	PlayerID int `json:"player_id"`
+}
+
+// Register routes the party messages of clients to the manager and sends
+// party events to their sessions. Invites between users that blocked each
+// other are refused.
+func (m *Manager) Register(hub *gateway.Hub, blocks models.BlockRepository) {
+	m.Send = func(playerID int, e Event) {
+		if s, ok := hub.Session(playerID); ok {
+			s.Send("party", e)
+		}
+	}
+
+	hub.Handle("party.invite", func(s *gateway.Session, data json.RawMessage) error {
+		var invite inviteData
+		if err := json.Unmarshal(data, &invite); err != nil {
+			return err
+		}
+		to, ok := hub.Find(invite.Name)
+		if !ok {
+			return models.ErrPlayerOffline
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := social.CheckBlocked(ctx, blocks, s.UserID, to.UserID); err != nil {
+			return err
+		}
+		return m.Invite(s.PlayerID, to.PlayerID)
+	})
+
+	withPlayer := func(op func(playerID, other int) error) gateway.Handler {
+		return func(s *gateway.Session, data json.RawMessage) error {
+			var p playerData
+			if err := json.Unmarshal(data, &p); err != nil {
+				return err
+			}
+			return op(s.PlayerID, p.PlayerID)
+		}
+	}
+	hub.Handle("party.accept", withPlayer(m.Accept))
+	hub.Handle("party.decline", withPlayer(m.Decline))
+	hub.Handle("party.kick", withPlayer(m.Kick))
+	hub.Handle("party.promote", withPlayer(m.Promote))
+	hub.Handle("party.leave", func(s *gateway.Session, data json.RawMessage) error {
+		return m.Leave(s.PlayerID)
+	})
+
+	hub.OnDisconnect(func(s *gateway.Session) {
+		m.Disconnect(s.PlayerID)
+	})
+}
+
+var Default = NewManager()
@@ -0,0 +1,366 @@
+package party
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+	"tribble/models"
+	"tribble/settings"
+)
+
+type Party struct {
+	ID     int `json:"id"`
+	Leader int `json:"leader"`
+	// Members are player IDs in the order they joined.
+	Members []int `json:"members"`
+	// Offline members keep their place until they reconnect or the grace
+	// period ends.
+	Offline []int `json:"offline"`
+}
+
+const (
+	EventInvited   = "invited"
+	EventJoined    = "joined"
+	EventLeft      = "left"
+	EventKicked    = "kicked"
+	EventPromoted  = "promoted"
+	EventDisbanded = "disbanded"
+	EventState     = "state"
+)
+
+// Event is sent to players through the gateway when their party changes.
+type Event struct {
+	Event    string `json:"event"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Party    *Party `json:"party,omitempty"`
+}
+
+type party struct {
+	id int
+	// channel keys the chat history of the party. IDs start over when the
+	// server restarts, channels are never given twice.
+	channel string
+	leader  int
+	members []int
+	offline map[int]time.Time
+}
+
+func newChannel() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func (p *party) view() *Party {
+	v := &Party{ID: p.id, Leader: p.leader, Members: append([]int(nil), p.members...), Offline: make([]int, 0)}
+	for ID := range p.offline {
+		v.Offline = append(v.Offline, ID)
+	}
+	sort.Ints(v.Offline)
+	return v
+}
+
+func (p *party) remove(playerID int) {
+	for i, ID := range p.members {
+		if ID == playerID {
+			p.members = append(p.members[:i:i], p.members[i+1:]...)
+			break
+		}
+	}
+	delete(p.offline, playerID)
+}
+
+// successor is the first connected member other than the leader.
+func (p *party) successor() int {
+	for _, ID := range p.members {
+		if _, offline := p.offline[ID]; ID != p.leader && !offline {
+			return ID
+		}
+	}
+	return 0
+}
+
+type inviteKey struct {
+	from, to int
+}
+
+// Manager keeps every party in memory. All operations take player IDs and
+// are safe to call from any goroutine.
+type Manager struct {
+	// Send delivers an event to a connected player.
+	Send func(playerID int, e Event)
+	Now  func() time.Time
+
+	mu       sync.Mutex
+	nextID   int
+	parties  map[int]*party
+	byPlayer map[int]*party
+	invites  map[inviteKey]time.Time
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		Send:     func(int, Event) {},
+		Now:      time.Now,
+		parties:  make(map[int]*party),
+		byPlayer: make(map[int]*party),
+		invites:  make(map[inviteKey]time.Time),
+	}
+}
+
+func (m *Manager) notify(p *party, e Event) {
+	for _, ID := range p.members {
+		if _, offline := p.offline[ID]; !offline {
+			m.Send(ID, e)
+		}
+	}
+}
+
+// Get returns the party of the player.
+func (m *Manager) Get(playerID int) (*Party, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	p, ok := m.byPlayer[playerID]
+	if !ok {
+		return nil, false
+	}
+	return p.view(), true
+}
+
+// PartyOf matches the party lookup of the chat, returning the channel of
+// the party and its members.
+func (m *Manager) PartyOf(playerID int) (string, []int, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+	p, ok := m.byPlayer[playerID]
+	if !ok {
+		return "", nil, false
+	}
+	return p.channel, append([]int(nil), p.members...), true
+}
+
+func (m *Manager) Invite(from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	if from == to {
+		return models.ErrSelfReference
+	}
+	if _, ok := m.byPlayer[to]; ok {
+		return models.ErrAlreadyInParty
+	}
+	if p, ok := m.byPlayer[from]; ok {
+		if p.leader != from {
+			return models.ErrNotPartyLeader
+		}
+		if len(p.members) >= settings.MaxPartySize {
+			return models.ErrPartyFull
+		}
+	}
+	m.invites[inviteKey{from, to}] = m.Now().Add(settings.PartyInviteTTL)
+	m.Send(to, Event{Event: EventInvited, PlayerID: from})
+	return nil
+}
+
+// Accept joins the party of the inviter, creating it when the inviter is
+// not in a party yet.
+func (m *Manager) Accept(to, from int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	expires, ok := m.invites[inviteKey{from, to}]
+	if !ok || !m.Now().Before(expires) {
+		delete(m.invites, inviteKey{from, to})
+		return models.ErrNoPartyInvite
+	}
+	if _, ok = m.byPlayer[to]; ok {
+		return models.ErrAlreadyInParty
+	}
+
+	p, ok := m.byPlayer[from]
+	switch {
+	case !ok:
+		m.nextID++
+		p = &party{id: m.nextID, channel: newChannel(), leader: from, members: []int{from}, offline: make(map[int]time.Time)}
+		m.parties[p.id] = p
+		m.byPlayer[from] = p
+	case p.leader != from:
+		// the inviter lost the lead since inviting
+		return models.ErrNotPartyLeader
+	case len(p.members) >= settings.MaxPartySize:
+		return models.ErrPartyFull
+	}
+
+	for key := range m.invites {
+		if key.to == to {
+			delete(m.invites, key)
+		}
+	}
+	p.members = append(p.members, to)
+	m.byPlayer[to] = p
+	m.notify(p, Event{Event: EventJoined, PlayerID: to, Party: p.view()})
+	return nil
+}
+
+func (m *Manager) Decline(to, from int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invites[inviteKey{from, to}]; !ok {
+		return models.ErrNoPartyInvite
+	}
+	delete(m.invites, inviteKey{from, to})
+	return nil
+}
+
+func (m *Manager) Leave(playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	p, ok := m.byPlayer[playerID]
+	if !ok {
+		return models.ErrNotInParty
+	}
+	m.remove(p, playerID, EventLeft)
+	return nil
+}
+
+func (m *Manager) Kick(leader, playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	p, ok := m.byPlayer[leader]
+	if !ok {
+		return models.ErrNotInParty
+	}
+	if p.leader != leader {
+		return models.ErrNotPartyLeader
+	}
+	if target, ok := m.byPlayer[playerID]; !ok || target != p || playerID == leader {
+		return models.ErrNotPartyMember
+	}
+	m.remove(p, playerID, EventKicked)
+	return nil
+}
+
+func (m *Manager) Promote(leader, playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	p, ok := m.byPlayer[leader]
+	if !ok {
+		return models.ErrNotInParty
+	}
+	if p.leader != leader {
+		return models.ErrNotPartyLeader
+	}
+	if target, ok := m.byPlayer[playerID]; !ok || target != p || playerID == leader {
+		return models.ErrNotPartyMember
+	}
+	p.leader = playerID
+	m.notify(p, Event{Event: EventPromoted, PlayerID: playerID, Party: p.view()})
+	return nil
+}
+
+// Disconnect keeps the player in its party for the reconnect grace period.
+// A disconnected leader hands the lead to the first connected member.
+func (m *Manager) Disconnect(playerID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	for key := range m.invites {
+		if key.from == playerID || key.to == playerID {
+			delete(m.invites, key)
+		}
+	}
+	p, ok := m.byPlayer[playerID]
+	if !ok {
+		return
+	}
+	p.offline[playerID] = m.Now().Add(settings.PartyReconnectGrace)
+	if p.leader == playerID {
+		if next := p.successor(); next != 0 {
+			p.leader = next
+			m.notify(p, Event{Event: EventPromoted, PlayerID: next, Party: p.view()})
+			return
+		}
+	}
+	m.notify(p, Event{Event: EventState, Party: p.view()})
+}
+
+// Connect restores the party of a reconnecting player and sends it the
+// party state.
+func (m *Manager) Connect(playerID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	p, ok := m.byPlayer[playerID]
+	if !ok {
+		return
+	}
+	delete(p.offline, playerID)
+	m.notify(p, Event{Event: EventState, Party: p.view()})
+}
+
+// Sweep drops the members that didn't reconnect in time.
+func (m *Manager) Sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+}
+
+func (m *Manager) sweep() {
+	now := m.Now()
+	for key, expires := range m.invites {
+		if !now.Before(expires) {
+			delete(m.invites, key)
+		}
+	}
+	for _, p := range m.parties {
+		for ID, deadline := range p.offline {
+			if !now.Before(deadline) {
+				m.remove(p, ID, EventLeft)
+			}
+		}
+	}
+}
+
+// remove takes the player out of the party, passing the lead on and
+// disbanding parties left with a single member.
+func (m *Manager) remove(p *party, playerID int, reason string) {
+	_, offline := p.offline[playerID]
+	p.remove(playerID)
+	delete(m.byPlayer, playerID)
+	if !offline {
+		m.Send(playerID, Event{Event: reason, PlayerID: playerID})
+	}
+
+	if len(p.members) < 2 {
+		for _, ID := range p.members {
+			delete(m.byPlayer, ID)
+		}
+		m.notify(p, Event{Event: EventDisbanded})
+		delete(m.parties, p.id)
+		p.members = nil
+		return
+	}
+	if p.leader == playerID {
+		p.leader = p.successor()
+		if p.leader == 0 {
+			p.leader = p.members[0]
+		}
+	}
+	m.notify(p, Event{Event: reason, PlayerID: playerID, Party: p.view()})
+}
@@ -0,0 +1,289 @@
+package party
+
+import (
+	"sync"
+	"testing"
+	"time"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type inbox struct {
+	mu     sync.Mutex
+	events map[int][]Event
+}
+
+func (i *inbox) send(playerID int, e Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.events[playerID] = append(i.events[playerID], e)
+}
+
+func (i *inbox) last(playerID int) Event {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	events := i.events[playerID]
+	if len(events) == 0 {
+		return Event{}
+	}
+	return events[len(events)-1]
+}
+
+func testManager() (*Manager, *clock, *inbox) {
+	c := &clock{now: time.Unix(0, 0)}
+	i := &inbox{events: make(map[int][]Event)}
+	m := NewManager()
+	m.Now = c.Now
+	m.Send = i.send
+	return m, c, i
+}
+
+// form makes a party led by the first player.
+func form(t *testing.T, m *Manager, players ...int) {
+	for _, ID := range players[1:] {
+		if err := m.Invite(players[0], ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.Accept(ID, players[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestInviteAndAccept(t *testing.T) {
+	m, c, events := testManager()
+
+	assert.Equal(t, m.Invite(1, 1), models.ErrSelfReference)
+	assert.Equal(t, m.Accept(2, 1), models.ErrNoPartyInvite)
+
+	assert.Equal(t, m.Invite(1, 2), nil)
+	assert.Equal(t, events.last(2), Event{Event: EventInvited, PlayerID: 1})
+	assert.Equal(t, m.Accept(2, 1), nil)
+	p, ok := m.Get(1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, p.Leader, 1)
+	assert.Equal(t, p.Members, []int{1, 2})
+	assert.Equal(t, events.last(1).Event, EventJoined)
+
+	// only the leader invites
+	assert.Equal(t, m.Invite(2, 3), models.ErrNotPartyLeader)
+	assert.Equal(t, m.Invite(3, 2), models.ErrAlreadyInParty)
+
+	// invites expire
+	assert.Equal(t, m.Invite(1, 3), nil)
+	c.Advance(settings.PartyInviteTTL)
+	assert.Equal(t, m.Accept(3, 1), models.ErrNoPartyInvite)
+}
+
+func TestPartySize(t *testing.T) {
+	m, _, _ := testManager()
+	players := make([]int, settings.MaxPartySize)
+	for i := range players {
+		players[i] = i + 1
+	}
+	form(t, m, players...)
+	assert.Equal(t, m.Invite(1, 100), models.ErrPartyFull)
+}
+
+func TestKickPromoteAndLeave(t *testing.T) {
+	m, _, events := testManager()
+	form(t, m, 1, 2, 3)
+
+	assert.Equal(t, m.Kick(2, 3), models.ErrNotPartyLeader)
+	assert.Equal(t, m.Kick(1, 9), models.ErrNotPartyMember)
+	assert.Equal(t, m.Kick(1, 3), nil)
+	assert.Equal(t, events.last(3), Event{Event: EventKicked, PlayerID: 3})
+	_, ok := m.Get(3)
+	assert.Equal(t, ok, false)
+
+	form(t, m, 1, 3)
+	assert.Equal(t, m.Promote(1, 3), nil)
+	p, _ := m.Get(1)
+	assert.Equal(t, p.Leader, 3)
+
+	// the leader leaving passes the lead on
+	assert.Equal(t, m.Leave(3), nil)
+	p, _ = m.Get(1)
+	assert.Equal(t, p.Leader, 1)
+	assert.Equal(t, p.Members, []int{1, 2})
+
+	// parties of one are disbanded
+	assert.Equal(t, m.Leave(1), nil)
+	_, ok = m.Get(2)
+	assert.Equal(t, ok, false)
+	assert.Equal(t, events.last(2).Event, EventDisbanded)
+	assert.Equal(t, m.Leave(2), models.ErrNotInParty)
+}
+
+func TestLeaderDisconnect(t *testing.T) {
+	m, c, events := testManager()
+	form(t, m, 1, 2, 3)
+
+	m.Disconnect(1)
+	p, _ := m.Get(2)
+	assert.Equal(t, p.Leader, 2)
+	assert.Equal(t, p.Offline, []int{1})
+	assert.Equal(t, events.last(3).Event, EventPromoted)
+	assert.Equal(t, m.Kick(1, 3), models.ErrNotPartyLeader)
+
+	// reconnecting in time restores the membership, not the lead
+	c.Advance(settings.PartyReconnectGrace / 2)
+	m.Connect(1)
+	p, _ = m.Get(1)
+	assert.Equal(t, p.Leader, 2)
+	assert.Equal(t, p.Offline, []int{})
+	assert.Equal(t, events.last(1).Event, EventState)
+
+	// staying away too long drops the player
+	m.Disconnect(2)
+	c.Advance(settings.PartyReconnectGrace)
+	m.Sweep()
+	p, _ = m.Get(1)
+	assert.Equal(t, p.Members, []int{1, 3})
+	assert.Equal(t, p.Leader, 1)
+}
+
+func TestWholePartyDisconnects(t *testing.T) {
+	m, c, _ := testManager()
+	form(t, m, 1, 2)
+
+	m.Disconnect(1)
+	m.Disconnect(2)
+	p, _ := m.Get(1)
+	// nobody connected can take over from the last leader
+	assert.Equal(t, p.Leader, 2)
+
+	c.Advance(settings.PartyReconnectGrace)
+	_, ok := m.Get(2)
+	assert.Equal(t, ok, false)
+}
+
+func race(fns ...func() error) []error {
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		wg.Add(1)
+		go func(i int, fn func() error) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn()
+		}(i, fn)
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func count(errs []error, err error) int {
+	n := 0
+	for _, e := range errs {
+		if e == err {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRacingInvitesForLastSlot(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		m, _, _ := testManager()
+		players := make([]int, settings.MaxPartySize-1)
+		for i := range players {
+			players[i] = i + 1
+		}
+		form(t, m, players...)
+		assert.Equal(t, m.Invite(1, 100), nil)
+		assert.Equal(t, m.Invite(1, 200), nil)
+
+		errs := race(func() error { return m.Accept(100, 1) }, func() error { return m.Accept(200, 1) })
+		assert.Equal(t, count(errs, nil), 1)
+		assert.Equal(t, count(errs, models.ErrPartyFull), 1)
+		p, _ := m.Get(1)
+		assert.Equal(t, len(p.Members), settings.MaxPartySize)
+	}
+}
+
+func TestRacingInvitesToSamePlayer(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		m, _, _ := testManager()
+		assert.Equal(t, m.Invite(1, 3), nil)
+		assert.Equal(t, m.Invite(2, 3), nil)
+
+		errs := race(func() error { return m.Accept(3, 1) }, func() error { return m.Accept(3, 2) })
+		// accepting one invite drops the others
+		assert.Equal(t, count(errs, nil), 1)
+		assert.Equal(t, count(errs, models.ErrNoPartyInvite), 1)
+		p, _ := m.Get(3)
+		assert.Equal(t, len(p.Members), 2)
+	}
+}
+
+func TestRacingMutualInvites(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		m, _, _ := testManager()
+		assert.Equal(t, m.Invite(1, 2), nil)
+		assert.Equal(t, m.Invite(2, 1), nil)
+
+		errs := race(func() error { return m.Accept(2, 1) }, func() error { return m.Accept(1, 2) })
+		assert.Equal(t, count(errs, nil), 1)
+		assert.Equal(t, count(errs, models.ErrAlreadyInParty)+count(errs, models.ErrNoPartyInvite), 1)
+		p, _ := m.Get(1)
+		assert.Equal(t, len(p.Members), 2)
+	}
+}
+
+func TestShares(t *testing.T) {
+	assert.Equal(t, Split(100, []int{1, 2, 3}), map[int]int{1: 34, 2: 33, 3: 33})
+
+	m, _, _ := testManager()
+	form(t, m, 1, 2, 3, 4)
+	m.Disconnect(4)
+	spaces := world.New(12)
+	space := spaces.Space("village")
+	for ID, x := range map[int]int{1: 0, 2: settings.PartyXPRadius, 3: settings.PartyXPRadius + 1, 4: 1} {
+		space.Join(world.Entity{Ref: world.Ref{Kind: world.KindPlayer, ID: ID}, PositionX: x}, true)
+	}
+
+	share := m.Shares(spaces)
+	assert.Equal(t, share(1, 100, "village"), map[int]int{1: 50, 2: 50})
+	// players out of a party or not in the map keep it all
+	assert.Equal(t, share(9, 100, "village"), map[int]int{9: 100})
+	assert.Equal(t, share(1, 100, "forest"), map[int]int{1: 100})
+}
+
+func TestChannelOutlivesRestart(t *testing.T) {
+	before, _, _ := testManager()
+	form(t, before, 1, 2)
+	after, _, _ := testManager()
+	form(t, after, 3, 4)
+
+	first, _ := before.Get(1)
+	second, _ := after.Get(3)
+	assert.Equal(t, first.ID, second.ID)
+	old, _, _ := before.PartyOf(1)
+	channel, members, ok := after.PartyOf(3)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, members, []int{3, 4})
+	assert.NotEqual(t, channel, old)
+}
@@ -0,0 +1,62 @@
+package party
+
+import (
+	"tribble/settings"
+	"tribble/world"
+)
+
+// Split divides xp evenly among the players, the remainder goes to the
+// killer which is always the first player.
+func Split(xp int, players []int) map[int]int {
+	shares := make(map[int]int, len(players))
+	each := xp / len(players)
+	for _, ID := range players {
+		shares[ID] = each
+	}
+	shares[players[0]] += xp - each*len(players)
+	return shares
+}
+
+// Shares splits the XP of kills among the connected party members on the
+// same map within PartyXPRadius of the killer.
+func (m *Manager) Shares(spaces *world.World) func(killerID, xp int, mapName string) map[int]int {
+	return func(killerID, xp int, mapName string) map[int]int {
+		players := []int{killerID}
+		p, ok := m.Get(killerID)
+		space := spaces.Space(mapName)
+		killer, found := space.Get(world.Ref{Kind: world.KindPlayer, ID: killerID})
+		if !ok || !found {
+			return Split(xp, players)
+		}
+
+		offline := make(map[int]bool, len(p.Offline))
+		for _, ID := range p.Offline {
+			offline[ID] = true
+		}
+		for _, ID := range p.Members {
+			if ID == killerID || offline[ID] {
+				continue
+			}
+			member, ok := space.Get(world.Ref{Kind: world.KindPlayer, ID: ID})
+			if !ok || distance(killer, member) > settings.PartyXPRadius {
+				continue
+			}
+			players = append(players, ID)
+		}
+		return Split(xp, players)
+	}
+}
+
+func distance(a, b world.Entity) int {
+	dx, dy := a.PositionX-b.PositionX, a.PositionY-b.PositionY
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
@@ -0,0 +1,181 @@
+package pathfinding
+
+import (
+	"container/heap"
+	"errors"
+	"tribble/world"
+)
+
+var (
+	ErrBlocked        = errors.New("start or goal is not walkable")
+	ErrNoPath         = errors.New("no path between points")
+	ErrBudgetExceeded = errors.New("path search exceeded its node budget")
+)
+
+type Grid interface {
+	Walkable(x, y int) bool
+}
+
+type Options struct {
+	// Diagonal allows moving diagonally, never cutting the corner of a
+	// blocked tile: both orthogonal neighbours must be walkable.
+	Diagonal bool
+	// MaxNodes is how many nodes a search may expand before giving up.
+	MaxNodes int
+}
+
+const (
+	straightCost = 10
+	diagonalCost = 14
+)
+
+var neighbours = [8]world.Point{
+	{X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 1}, {X: 0, Y: -1},
+	{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1},
+}
+
+type node struct {
+	point world.Point
+	g, f  int
+	index int
+}
+
+type openSet []*node
+
+func (s openSet) Len() int { return len(s) }
+
+func (s openSet) Less(i, j int) bool {
+	if s[i].f != s[j].f {
+		return s[i].f < s[j].f
+	}
+	// prefer nodes closer to the goal to keep the search narrow
+	return s[i].g > s[j].g
+}
+
+func (s openSet) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *openSet) Push(x interface{}) {
+	n := x.(*node)
+	n.index = len(*s)
+	*s = append(*s, n)
+}
+
+func (s *openSet) Pop() interface{} {
+	old := *s
+	n := old[len(old)-1]
+	*s = old[:len(old)-1]
+	return n
+}
+
+// heuristic is the octile distance, exact on an empty grid.
+func heuristic(a, b world.Point, diagonal bool) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if !diagonal {
+		return straightCost * (dx + dy)
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return straightCost*(dx-dy) + diagonalCost*dy
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Find returns the shortest path from start to goal, both included, and
+// how many nodes were expanded to find it.
+func Find(grid Grid, start, goal world.Point, options Options) ([]world.Point, int, error) {
+	if !grid.Walkable(start.X, start.Y) || !grid.Walkable(goal.X, goal.Y) {
+		return nil, 0, ErrBlocked
+	}
+	if start == goal {
+		return []world.Point{start}, 0, nil
+	}
+
+	directions := neighbours[:4]
+	if options.Diagonal {
+		directions = neighbours[:]
+	}
+
+	nodes := map[world.Point]*node{start: {point: start, f: heuristic(start, goal, options.Diagonal)}}
+	parents := make(map[world.Point]world.Point)
+	closed := make(map[world.Point]bool)
+	open := &openSet{nodes[start]}
+
+	expanded := 0
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*node)
+		if current.point == goal {
+			return walkBack(parents, start, goal), expanded, nil
+		}
+		closed[current.point] = true
+		expanded++
+		if options.MaxNodes > 0 && expanded > options.MaxNodes {
+			return nil, expanded, ErrBudgetExceeded
+		}
+
+		for _, d := range directions {
+			p := world.Point{X: current.point.X + d.X, Y: current.point.Y + d.Y}
+			if closed[p] || !grid.Walkable(p.X, p.Y) {
+				continue
+			}
+			cost := straightCost
+			if d.X != 0 && d.Y != 0 {
+				if !grid.Walkable(current.point.X+d.X, current.point.Y) || !grid.Walkable(current.point.X, current.point.Y+d.Y) {
+					continue
+				}
+				cost = diagonalCost
+			}
+
+			g := current.g + cost
+			n, seen := nodes[p]
+			if seen && g >= n.g {
+				continue
+			}
+			parents[p] = current.point
+			if !seen {
+				n = &node{point: p}
+				nodes[p] = n
+				n.g, n.f = g, g+heuristic(p, goal, options.Diagonal)
+				heap.Push(open, n)
+				continue
+			}
+			n.g, n.f = g, g+heuristic(p, goal, options.Diagonal)
+			heap.Fix(open, n.index)
+		}
+	}
+	return nil, expanded, ErrNoPath
+}
+
+func walkBack(parents map[world.Point]world.Point, start, goal world.Point) []world.Point {
+	path := []world.Point{goal}
+	for p := goal; p != start; {
+		p = parents[p]
+		path = append(path, p)
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// Step reports whether moving from a to the adjacent b follows the same
+// movement rules used by Find.
+func Step(grid Grid, a, b world.Point, diagonal bool) bool {
+	dx, dy := b.X-a.X, b.Y-a.Y
+	if abs(dx) > 1 || abs(dy) > 1 || (dx == 0 && dy == 0) || !grid.Walkable(b.X, b.Y) {
+		return false
+	}
+	if dx != 0 && dy != 0 {
+		return diagonal && grid.Walkable(a.X+dx, a.Y) && grid.Walkable(a.X, a.Y+dy)
+	}
+	return true
+}
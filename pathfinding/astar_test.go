@@ -0,0 +1,131 @@
+package pathfinding
+
+import (
+	"strings"
+	"testing"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func grid(t testing.TB, rows ...string) *world.Map {
+	m := &world.Map{Name: "test", Width: len(rows[0]), Height: len(rows)}
+	if err := m.SetCollision(strings.NewReader(strings.Join(rows, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func pt(x, y int) world.Point {
+	return world.Point{X: x, Y: y}
+}
+
+func TestFindAroundWall(t *testing.T) {
+	m := grid(t,
+		".....",
+		".###.",
+		"...#.",
+		"...#.",
+		".....",
+	)
+	path, _, err := Find(m, pt(0, 2), pt(4, 2), Options{Diagonal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, path[0], pt(0, 2))
+	assert.Equal(t, path[len(path)-1], pt(4, 2))
+	for i := 1; i < len(path); i++ {
+		if !Step(m, path[i-1], path[i], true) {
+			t.Errorf("%s FAILED: invalid step %v -> %v", t.Name(), path[i-1], path[i])
+		}
+	}
+	// down and around the bottom of the wall
+	assert.Equal(t, len(path), 7)
+}
+
+func TestNoCornerCutting(t *testing.T) {
+	m := grid(t,
+		".#",
+		"..",
+	)
+	path, _, err := Find(m, pt(0, 0), pt(1, 1), Options{Diagonal: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, path, []world.Point{pt(0, 0), pt(0, 1), pt(1, 1)})
+	assert.Equal(t, Step(m, pt(0, 0), pt(1, 1), true), false)
+	assert.Equal(t, Step(m, pt(0, 1), pt(1, 0), true), false)
+}
+
+func TestWithoutDiagonals(t *testing.T) {
+	m := grid(t, "...", "...", "...")
+	path, _, err := Find(m, pt(0, 0), pt(2, 2), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(path), 5)
+	assert.Equal(t, Step(m, pt(0, 0), pt(1, 1), false), false)
+}
+
+func TestFindErrors(t *testing.T) {
+	m := grid(t,
+		"..#..",
+		"..#..",
+		"..#..",
+	)
+	tests := []struct {
+		name     string
+		from, to world.Point
+		options  Options
+		want     error
+	}{
+		{"blocked goal", pt(0, 0), pt(2, 0), Options{Diagonal: true}, ErrBlocked},
+		{"outside of map", pt(0, 0), pt(9, 9), Options{Diagonal: true}, ErrBlocked},
+		{"unreachable", pt(0, 0), pt(4, 0), Options{Diagonal: true}, ErrNoPath},
+		{"budget", pt(0, 0), pt(4, 0), Options{Diagonal: true, MaxNodes: 3}, ErrBudgetExceeded},
+	}
+	for _, tt := range tests {
+		if _, _, err := Find(m, tt.from, tt.to, tt.options); err != tt.want {
+			t.Errorf("%s FAILED: %s want %v got %v", t.Name(), tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestFinderCache(t *testing.T) {
+	m := grid(t, "....", "....")
+	f := NewFinder(m, Options{Diagonal: true}, 2)
+
+	a, _ := f.Path(pt(0, 0), pt(3, 1))
+	b, _ := f.Path(pt(0, 0), pt(3, 1))
+	assert.Equal(t, &a[0] == &b[0], true)
+	assert.Equal(t, f.Len(), 1)
+
+	_, err := f.Path(pt(0, 0), pt(9, 9))
+	assert.Equal(t, err, ErrBlocked)
+	assert.Equal(t, f.Len(), 2)
+
+	// the least recently used query is evicted
+	_, _ = f.Path(pt(0, 0), pt(3, 1))
+	_, _ = f.Path(pt(1, 0), pt(2, 1))
+	assert.Equal(t, f.Len(), 2)
+	c, _ := f.Path(pt(0, 0), pt(3, 1))
+	assert.Equal(t, &a[0] == &c[0], true)
+
+	next, err := f.Next(pt(0, 0), pt(3, 0))
+	assert.Equal(t, err, nil)
+	assert.Equal(t, next, pt(1, 0))
+}
+
+func BenchmarkFindForest(b *testing.B) {
+	m, _ := world.Maps.Get("forest")
+	from, to := m.Spawn, pt(m.Width-m.Spawn.X-1, m.Height-m.Spawn.Y-1)
+	for !m.Walkable(to.X, to.Y) {
+		to.X--
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := Find(m, from, to, Options{Diagonal: true}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
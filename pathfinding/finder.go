@@ -0,0 +1,107 @@
+package pathfinding
+
+import (
+	"container/list"
+	"sync"
+	"tribble/settings"
+	"tribble/world"
+)
+
+type query struct {
+	from, to world.Point
+}
+
+type entry struct {
+	query query
+	path  []world.Point
+	err   error
+}
+
+// Finder searches paths on a single grid, remembering the most recent
+// queries. Failed searches are cached too so unreachable goals do not burn
+// the node budget on every tick.
+type Finder struct {
+	grid    Grid
+	options Options
+	size    int
+
+	mu      sync.Mutex
+	entries map[query]*list.Element
+	recent  *list.List
+}
+
+func NewFinder(grid Grid, options Options, size int) *Finder {
+	return &Finder{
+		grid:    grid,
+		options: options,
+		size:    size,
+		entries: make(map[query]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+// Path returns the path from one point to another, both included. The
+// returned slice is shared with the cache and must not be modified.
+func (f *Finder) Path(from, to world.Point) ([]world.Point, error) {
+	q := query{from: from, to: to}
+	f.mu.Lock()
+	if e, ok := f.entries[q]; ok {
+		f.recent.MoveToFront(e)
+		cached := e.Value.(*entry)
+		f.mu.Unlock()
+		return cached.path, cached.err
+	}
+	f.mu.Unlock()
+
+	path, _, err := Find(f.grid, from, to, f.options)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.entries[q]; !ok && f.size > 0 {
+		f.entries[q] = f.recent.PushFront(&entry{query: q, path: path, err: err})
+		if f.recent.Len() > f.size {
+			oldest := f.recent.Back()
+			f.recent.Remove(oldest)
+			delete(f.entries, oldest.Value.(*entry).query)
+		}
+	}
+	return path, err
+}
+
+// Next returns the tile to step on to get closer to the goal.
+func (f *Finder) Next(from, to world.Point) (world.Point, error) {
+	path, err := f.Path(from, to)
+	if err != nil {
+		return from, err
+	}
+	if len(path) < 2 {
+		return from, nil
+	}
+	return path[1], nil
+}
+
+func (f *Finder) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.recent.Len()
+}
+
+var (
+	finders   = make(map[*world.Map]*Finder)
+	findersMu sync.Mutex
+)
+
+// DefaultOptions are the movement rules shared by monsters and players.
+var DefaultOptions = Options{Diagonal: true, MaxNodes: settings.PathNodeBudget}
+
+// For returns the shared finder of the map.
+func For(m *world.Map) *Finder {
+	findersMu.Lock()
+	defer findersMu.Unlock()
+	f, ok := finders[m]
+	if !ok {
+		f = NewFinder(m, DefaultOptions, settings.PathCacheSize)
+		finders[m] = f
+	}
+	return f
+}
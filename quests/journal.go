@@ -0,0 +1,154 @@
+package quests
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/levels"
+	"tribble/models"
+)
+
+type Repository interface {
+	models.PlayerRepository
+	models.InventoryRepository
+	models.QuestRepository
+}
+
+// Journal sorts the quests of a player by what can be done with them.
+type Journal struct {
+	Available []*Quest              `json:"available"`
+	Active    []*models.PlayerQuest `json:"active"`
+	Completed []*models.PlayerQuest `json:"completed"`
+}
+
+func NewJournal(catalog *Catalog, player *models.Player, accepted []*models.PlayerQuest) Journal {
+	journal := Journal{
+		Available: make([]*Quest, 0),
+		Active:    make([]*models.PlayerQuest, 0),
+		Completed: make([]*models.PlayerQuest, 0),
+	}
+	for _, q := range accepted {
+		if q.Status == models.QuestCompleted {
+			journal.Completed = append(journal.Completed, q)
+		} else {
+			journal.Active = append(journal.Active, q)
+		}
+	}
+	states := byID(accepted)
+	for _, q := range catalog.List() {
+		if q.CanAccept(player, states) == nil {
+			journal.Available = append(journal.Available, q)
+		}
+	}
+	return journal
+}
+
+func byID(accepted []*models.PlayerQuest) map[string]*models.PlayerQuest {
+	m := make(map[string]*models.PlayerQuest, len(accepted))
+	for _, q := range accepted {
+		m[q.QuestID] = q
+	}
+	return m
+}
+
+// Accept starts the quest for the player. Collect objectives start with
+// the items the player already carries.
+func Accept(ctx context.Context, repo Repository, player *models.Player, quest *Quest) (*models.PlayerQuest, error) {
+	accepted, err := repo.GetQuests(ctx, player.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = quest.CanAccept(player, byID(accepted)); err != nil {
+		return nil, err
+	}
+
+	slots, err := repo.GetInventory(ctx, player.ID)
+	if err != nil {
+		return nil, err
+	}
+	progress := make([]int, len(quest.Objectives))
+	for i, o := range quest.Objectives {
+		if o.Type != Collect {
+			continue
+		}
+		for _, s := range slots {
+			if s.ItemID == o.Target {
+				progress[i] += s.Quantity
+			}
+		}
+		if progress[i] > o.Count {
+			progress[i] = o.Count
+		}
+	}
+	return repo.AcceptQuest(ctx, player.ID, quest.ID, progress)
+}
+
+// TurnIn completes the quest, taking the collected items and granting its
+// rewards.
+func TurnIn(ctx context.Context, repo Repository, player *models.Player, quest *Quest) (*models.PlayerQuest, error) {
+	accepted, err := repo.GetQuests(ctx, player.ID)
+	if err != nil {
+		return nil, err
+	}
+	state, ok := byID(accepted)[quest.ID]
+	if !ok || state.Status != models.QuestActive {
+		return nil, models.ErrQuestNotActive
+	}
+	if !quest.Complete(state.Progress) {
+		return nil, models.ErrQuestIncomplete
+	}
+
+	completed, rewarded, err := repo.CompleteQuest(ctx, player.ID, quest.ID, quest.Cost(), quest.Rewards.Items, quest.Rewards.XP)
+	if err != nil {
+		return nil, err
+	}
+	events.Publish(events.Event{
+		Type:     events.QuestCompleted,
+		UserID:   player.UserID,
+		PlayerID: player.ID,
+		Data:     events.QuestCompletedData{Quest: quest.ID},
+	})
+	for _, stack := range quest.Rewards.Items {
+		events.Publish(events.Event{
+			Type:     events.ItemAcquired,
+			UserID:   player.UserID,
+			PlayerID: player.ID,
+			Data:     events.ItemAcquiredData{Item: stack.ItemID, Quantity: stack.Quantity, Source: "quest"},
+		})
+	}
+	if rewarded != nil {
+		levels.PublishXP(rewarded, quest.Rewards.XP)
+	}
+	return completed, nil
+}
+
+// TrackProgress advances the active quests of players from kill, item
+// and landmark events.
+func TrackProgress(catalog *Catalog, repo models.QuestRepository) events.Handler {
+	return func(e events.Event) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		accepted, err := repo.GetQuests(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not track quests of player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		for _, state := range accepted {
+			quest, ok := catalog.Get(state.QuestID)
+			if !ok || state.Status != models.QuestActive {
+				continue
+			}
+			for i, o := range quest.Objectives {
+				amount := o.Progress(e)
+				if amount == 0 || (i < len(state.Progress) && state.Progress[i] >= o.Count) {
+					continue
+				}
+				if err = repo.AddQuestProgress(ctx, e.PlayerID, quest.ID, i, amount, o.Count); err != nil {
+					log.Printf("could not track quest %v of player %v: %v", quest.ID, e.PlayerID, err.Error())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package quests
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"tribble/data"
+	"tribble/events"
+	"tribble/items"
+	"tribble/levels"
+	"tribble/models"
+	"tribble/monsters"
+	"tribble/settings"
+	"tribble/world"
+)
+
+const (
+	Kill    = "kill"
+	Collect = "collect"
+	Visit   = "visit"
+)
+
+// Objective counts monsters killed, items collected or landmarks visited.
+// Collected items are handed in when the quest is turned in.
+type Objective struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Map    string `json:"map,omitempty"`
+	Count  int    `json:"count"`
+}
+
+// Progress returns how much the event advances the objective.
+func (o Objective) Progress(e events.Event) int {
+	switch data := e.Data.(type) {
+	case events.MonsterKilledData:
+		if o.Type == Kill && o.Target == data.Monster && (o.Map == "" || o.Map == data.Map) {
+			return 1
+		}
+	case events.ItemAcquiredData:
+		if o.Type == Collect && o.Target == data.Item {
+			return data.Quantity
+		}
+	case events.LandmarkEnteredData:
+		if o.Type == Visit && o.Target == data.Landmark && o.Map == data.Map {
+			return 1
+		}
+	}
+	return 0
+}
+
+type Rewards struct {
+	XP    int                `json:"xp"`
+	Items []models.ItemStack `json:"items,omitempty"`
+}
+
+type Quest struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Description   string      `json:"description"`
+	Level         int         `json:"level"`
+	Prerequisites []string    `json:"prerequisites,omitempty"`
+	Objectives    []Objective `json:"objectives"`
+	Rewards       Rewards     `json:"rewards"`
+}
+
+// Cost is what the player hands in when turning the quest in.
+func (q *Quest) Cost() []models.ItemStack {
+	cost := make([]models.ItemStack, 0)
+	for _, o := range q.Objectives {
+		if o.Type == Collect {
+			cost = append(cost, models.ItemStack{ItemID: o.Target, Quantity: o.Count})
+		}
+	}
+	return cost
+}
+
+func (q *Quest) Complete(progress []int) bool {
+	if len(progress) != len(q.Objectives) {
+		return false
+	}
+	for i, o := range q.Objectives {
+		if progress[i] < o.Count {
+			return false
+		}
+	}
+	return true
+}
+
+// CanAccept checks the quest against the level of the player and the
+// quests it already accepted or completed.
+func (q *Quest) CanAccept(player *models.Player, accepted map[string]*models.PlayerQuest) error {
+	if state, ok := accepted[q.ID]; ok {
+		if state.Status == models.QuestCompleted {
+			return models.ErrQuestCompleted
+		}
+		return models.ErrQuestAccepted
+	}
+	if levels.Default.Level(player.XP) < q.Level {
+		return models.ErrQuestLocked
+	}
+	for _, ID := range q.Prerequisites {
+		if state, ok := accepted[ID]; !ok || state.Status != models.QuestCompleted {
+			return models.ErrQuestLocked
+		}
+	}
+	return nil
+}
+
+func (q *Quest) validate(catalog *Catalog) error {
+	if q.Level < 1 {
+		q.Level = 1
+	}
+	for _, ID := range q.Prerequisites {
+		// prerequisites must be defined earlier, which also rules out cycles
+		if _, ok := catalog.byID[ID]; !ok {
+			return fmt.Errorf("quest %v requires unknown or later quest %v", q.ID, ID)
+		}
+	}
+	if len(q.Objectives) == 0 {
+		return fmt.Errorf("quest %v has no objectives", q.ID)
+	}
+	for _, o := range q.Objectives {
+		if o.Count < 1 {
+			return fmt.Errorf("quest %v has an objective without count", q.ID)
+		}
+		switch o.Type {
+		case Kill:
+			if _, ok := monsters.Default.Get(o.Target); !ok {
+				return fmt.Errorf("quest %v kills unknown monster %v", q.ID, o.Target)
+			}
+		case Collect:
+			if _, ok := items.Default.Get(o.Target); !ok {
+				return fmt.Errorf("quest %v collects unknown item %v", q.ID, o.Target)
+			}
+		case Visit:
+			m, ok := world.Maps.Get(o.Map)
+			if !ok {
+				return fmt.Errorf("quest %v visits unknown map %v", q.ID, o.Map)
+			}
+			if _, ok = m.Landmark(o.Target); !ok {
+				return fmt.Errorf("quest %v visits unknown landmark %v", q.ID, o.Target)
+			}
+		default:
+			return fmt.Errorf("quest %v has invalid objective %v", q.ID, o.Type)
+		}
+	}
+	if q.Rewards.XP < 0 {
+		return fmt.Errorf("quest %v has negative xp reward", q.ID)
+	}
+	for _, stack := range q.Rewards.Items {
+		if _, ok := items.Default.Get(stack.ItemID); !ok || stack.Quantity < 1 {
+			return fmt.Errorf("quest %v rewards invalid item %v", q.ID, stack.ItemID)
+		}
+	}
+	return nil
+}
+
+type Catalog struct {
+	quests []*Quest
+	byID   map[string]*Quest
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Quests []*Quest `json:"quests"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Quests) == 0 {
+		return nil, errors.New("quest catalog is empty")
+	}
+
+	catalog := &Catalog{quests: file.Quests, byID: make(map[string]*Quest)}
+	for _, q := range file.Quests {
+		if q.ID == "" {
+			return nil, errors.New("quest without id")
+		}
+		if _, ok := catalog.byID[q.ID]; ok {
+			return nil, fmt.Errorf("duplicated quest %v", q.ID)
+		}
+		if err := q.validate(catalog); err != nil {
+			return nil, err
+		}
+		catalog.byID[q.ID] = q
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Quest, bool) {
+	q, ok := c.byID[ID]
+	return q, ok
+}
+
+func (c *Catalog) List() []*Quest {
+	return c.quests
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("quests.json", settings.QuestCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open quest catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load quest catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,92 @@
+package quests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"tribble/events"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	q, ok := Default.Get("proof_of_deed")
+	assert.Equal(t, ok, true)
+	assert.Equal(t, q.Cost(), []models.ItemStack{{ItemID: "goblin_ear", Quantity: 4}})
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"later prerequisite": `{"quests": [
+			{"id": "a", "prerequisites": ["b"], "objectives": [{"type": "kill", "target": "goblin", "count": 1}]},
+			{"id": "b", "objectives": [{"type": "kill", "target": "goblin", "count": 1}]}]}`,
+		"unknown monster":  `{"quests": [{"id": "a", "objectives": [{"type": "kill", "target": "dragon", "count": 1}]}]}`,
+		"unknown landmark": `{"quests": [{"id": "a", "objectives": [{"type": "visit", "target": "moon", "map": "village", "count": 1}]}]}`,
+		"no objectives":    `{"quests": [{"id": "a", "objectives": []}]}`,
+		"reward item":      `{"quests": [{"id": "a", "objectives": [{"type": "collect", "target": "bread", "count": 1}], "rewards": {"items": [{"item_id": "gold"}]}}]}`,
+	}
+	for name, file := range tests {
+		if _, err := Load(strings.NewReader(file)); err == nil {
+			t.Errorf("%s FAILED: %s want error", t.Name(), name)
+		}
+	}
+}
+
+func TestCanAccept(t *testing.T) {
+	woods, _ := Default.Get("into_the_woods")
+	low := &models.Player{XP: 0}
+	high := &models.Player{XP: 100000}
+	prayed := map[string]*models.PlayerQuest{"evening_prayer": {QuestID: "evening_prayer", Status: models.QuestCompleted}}
+
+	assert.Equal(t, woods.CanAccept(low, prayed), models.ErrQuestLocked)
+	assert.Equal(t, woods.CanAccept(high, map[string]*models.PlayerQuest{}), models.ErrQuestLocked)
+	assert.Equal(t, woods.CanAccept(high, prayed), nil)
+
+	prayed["into_the_woods"] = &models.PlayerQuest{Status: models.QuestActive}
+	assert.Equal(t, woods.CanAccept(high, prayed), models.ErrQuestAccepted)
+}
+
+func TestJournal(t *testing.T) {
+	player := &models.Player{ID: 1}
+	journal := NewJournal(Default, player, []*models.PlayerQuest{
+		{QuestID: "goblin_trouble", Status: models.QuestCompleted},
+		{QuestID: "evening_prayer", Status: models.QuestActive},
+	})
+	available := make([]string, 0)
+	for _, q := range journal.Available {
+		available = append(available, q.ID)
+	}
+	assert.Equal(t, available, []string{"proof_of_deed"})
+	assert.Equal(t, len(journal.Active), 1)
+	assert.Equal(t, len(journal.Completed), 1)
+}
+
+type progressRepo struct {
+	models.QuestRepository
+	quests []*models.PlayerQuest
+	added  []int
+}
+
+func (r *progressRepo) GetQuests(ctx context.Context, playerID int) ([]*models.PlayerQuest, error) {
+	return r.quests, nil
+}
+
+func (r *progressRepo) AddQuestProgress(ctx context.Context, playerID int, questID string, objective, amount, required int) error {
+	r.added = append(r.added, objective, amount)
+	return nil
+}
+
+func TestTrackProgress(t *testing.T) {
+	repo := &progressRepo{quests: []*models.PlayerQuest{
+		{QuestID: "into_the_woods", Status: models.QuestActive, Progress: []int{0, 1}},
+		{QuestID: "goblin_trouble", Status: models.QuestCompleted, Progress: []int{5}},
+	}}
+	track := TrackProgress(Default, repo)
+
+	track(events.Event{PlayerID: 1, Data: events.MonsterKilledData{Map: "forest", Monster: "wolf"}})
+	track(events.Event{PlayerID: 1, Data: events.MonsterKilledData{Map: "village", Monster: "goblin"}})
+	// the camp was already visited
+	track(events.Event{PlayerID: 1, Data: events.LandmarkEnteredData{Map: "forest", Landmark: "bandit_camp"}})
+	assert.Equal(t, repo.added, []int{0, 1})
+}
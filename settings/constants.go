@@ -1,7 +1,10 @@
 package settings
 
 import (
+	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,3 +20,221 @@ var JWTSecretKey = os.Getenv("JWT_SECRET_KEY")
 
 const AccessTokenLifetime = time.Minute * time.Duration(10)
 const RefreshTokenLifetime = time.Hour * time.Duration(24)
+
+// InterestRadius is how many tiles around a player are broadcast to its client.
+const InterestRadius = 12
+
+// LevelCurveFile overrides the leveling curve shipped in tribble/data.
+var LevelCurveFile = os.Getenv("LEVEL_CURVE_FILE")
+
+// ClassCatalogFile overrides the class catalog shipped in tribble/data.
+var ClassCatalogFile = os.Getenv("CLASS_CATALOG_FILE")
+
+// ItemCatalogFile overrides the item catalog shipped in tribble/data.
+var ItemCatalogFile = os.Getenv("ITEM_CATALOG_FILE")
+
+// InventoryCapacity is the number of inventory slots of each player.
+const InventoryCapacity = 30
+
+// MapCatalogFile overrides the map definitions shipped in tribble/data.
+var MapCatalogFile = os.Getenv("MAP_CATALOG_FILE")
+
+// MonsterCatalogFile overrides the monster definitions shipped in tribble/data.
+var MonsterCatalogFile = os.Getenv("MONSTER_CATALOG_FILE")
+
+// SimulationTickRate is the fixed step of the per map simulation loops.
+const SimulationTickRate = 100 * time.Millisecond
+
+// PlayerAttackInterval is the minimum time between basic attacks of a player.
+const PlayerAttackInterval = time.Second
+
+// AbilityCatalogFile overrides the class abilities shipped in tribble/data.
+var AbilityCatalogFile = os.Getenv("ABILITY_CATALOG_FILE")
+
+// StatusEffectCatalogFile overrides the status effects shipped in
+// tribble/data.
+var StatusEffectCatalogFile = os.Getenv("STATUS_EFFECT_CATALOG_FILE")
+
+// ManaRegenPercent is the share of their maximum mana players regain every
+// second, at least 1.
+const ManaRegenPercent = 2
+
+// PathNodeBudget is how many nodes a single path search may expand.
+const PathNodeBudget = 4000
+
+// PathCacheSize is how many recent path queries are kept per map.
+const PathCacheSize = 1024
+
+// Debug enables the debug endpoints.
+var Debug = os.Getenv("DEBUG") == "true"
+
+// QuestCatalogFile overrides the quest definitions shipped in tribble/data.
+var QuestCatalogFile = os.Getenv("QUEST_CATALOG_FILE")
+
+// AchievementCatalogFile overrides the achievements shipped in tribble/data.
+var AchievementCatalogFile = os.Getenv("ACHIEVEMENT_CATALOG_FILE")
+
+// MaxActiveQuests is how many quests a player may have accepted at once.
+const MaxActiveQuests = 20
+
+// GatewaySendBuffer is how many messages may wait for a slow client before
+// it is disconnected.
+const GatewaySendBuffer = 256
+
+// GatewayMaxMessageSize is the largest message a client may send, in bytes.
+const GatewayMaxMessageSize = 4096
+
+// ChatMaxLength is the longest chat message in characters.
+const ChatMaxLength = 256
+
+// ChatBurst and ChatRefill rate limit chat per user: a burst of messages
+// is allowed, then one more every refill interval.
+const ChatBurst = 5
+const ChatRefill = time.Second
+
+// ChatHistorySize is how many messages are kept per channel.
+const ChatHistorySize = 500
+
+// ChatFilterFile overrides the word filter list shipped in tribble/data.
+var ChatFilterFile = os.Getenv("CHAT_FILTER_FILE")
+
+// Admins are the user IDs allowed to grant entitlements, comma separated.
+// IDs rather than usernames, which anyone can pick once they are free.
+var Admins = envIDs("ADMINS")
+
+// ChatModerators are the user IDs allowed to mute users, comma separated.
+var ChatModerators = envIDs("CHAT_MODERATORS")
+
+// StaffNames are reserved so players can't impersonate the staff, comma
+// separated.
+var StaffNames = strings.Split(os.Getenv("STAFF_NAMES"), ",")
+
+// ReservedNamesFile and BlockedNamesFile override the name lists shipped
+// in tribble/data.
+var ReservedNamesFile = os.Getenv("RESERVED_NAMES_FILE")
+var BlockedNamesFile = os.Getenv("BLOCKED_NAMES_FILE")
+
+// MaxFriends is the size of the friends list of a user.
+const MaxFriends = 100
+
+// MaxPartySize is how many players a party can hold.
+const MaxPartySize = 5
+
+// PartyInviteTTL is how long a party invite can be accepted.
+const PartyInviteTTL = time.Minute
+
+// PartyReconnectGrace is how long a disconnected player keeps its place in
+// its party.
+const PartyReconnectGrace = 2 * time.Minute
+
+// PartyXPRadius is how close to the killer party members must be to share
+// the XP of a kill.
+const PartyXPRadius = 30
+
+// TradeRequestTTL is how long a trade request can be accepted.
+const TradeRequestTTL = time.Minute
+
+// MaxTradeItems is how many inventory stacks a player can offer in a trade.
+const MaxTradeItems = 12
+
+// VendorCatalogFile overrides the vendor definitions shipped in tribble/data.
+var VendorCatalogFile = os.Getenv("VENDOR_CATALOG_FILE")
+
+// VendorRange is how close to a vendor players must stand to trade with it.
+const VendorRange = 5
+
+// VendorSellbackPercent is the share of the item value vendors pay for the
+// items players sell them.
+const VendorSellbackPercent = 25
+
+// MailExpiry is how long mail is kept, unclaimed attachments of mail sent
+// by players are then returned to the sender. The attachments of system
+// and returned mail are delivered to the recipient instead, or kept for
+// another MailExpiry when its inventory is full.
+const MailExpiry = 30 * 24 * time.Hour
+
+// MailExpireInterval is how often expired mail is processed, at most
+// MailExpireBatch at a time.
+const MailExpireInterval = time.Minute
+const MailExpireBatch = 100
+
+// MaxAuctions is how many active listings a player can have.
+const MaxAuctions = 20
+
+// AuctionBidIncrementPercent is how much a bid must raise the highest bid,
+// at least 1.
+const AuctionBidIncrementPercent = 5
+
+// AuctionFeePercent is the share of the winning bid the auction house
+// keeps, taken out of the economy.
+const AuctionFeePercent = 5
+
+// AuctionSettleInterval is how often expired auctions are settled, at most
+// AuctionSettleBatch at a time.
+const AuctionSettleInterval = 30 * time.Second
+const AuctionSettleBatch = 100
+
+// RecipeCatalogFile overrides the crafting recipes shipped in tribble/data.
+var RecipeCatalogFile = os.Getenv("RECIPE_CATALOG_FILE")
+
+// MaxCrafts is how many timed crafts a player can run at once.
+const MaxCrafts = 3
+
+// MaxCraftCount is how many times a recipe can be crafted in one go.
+const MaxCraftCount = 20
+
+// CraftSweepInterval is how often finished crafts are looked for, at most
+// CraftFinishBatch at a time, in case a scheduled finish was lost.
+const CraftSweepInterval = time.Minute
+const CraftFinishBatch = 100
+
+// MaxGuildMembers is how many players a guild can hold.
+const MaxGuildMembers = 100
+
+// MaxGuildRanks is how many ranks a guild can define, the guild master
+// included.
+const MaxGuildRanks = 10
+
+// GuildBankCapacity is the number of guild bank slots.
+const GuildBankCapacity = 60
+
+// JobTimeout bounds each run of a scheduled job.
+const JobTimeout = time.Minute
+
+// LeaderboardRefresh is how often the leaderboards are recomputed.
+const LeaderboardRefresh = time.Minute
+
+// LeaderboardNeighbours is how many players above and below the player
+// the leaderboard of a player shows.
+const LeaderboardNeighbours = 5
+
+// CharacterSlots is how many characters an account can create before
+// entitlements, CHARACTER_SLOTS overrides it.
+var CharacterSlots = envInt("CHARACTER_SLOTS", 3)
+
+func envInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Fatalf("Invalid %v: %v", name, value)
+	}
+	return n
+}
+
+func envIDs(name string) []int {
+	IDs := make([]int, 0)
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		ID, err := strconv.Atoi(value)
+		if err != nil {
+			log.Fatalf("Invalid %v: %v", name, value)
+		}
+		IDs = append(IDs, ID)
+	}
+	return IDs
+}
@@ -0,0 +1,144 @@
+package simulation
+
+import (
+	"time"
+	"tribble/abilities"
+	"tribble/combat"
+	"tribble/settings"
+	"tribble/status"
+	"tribble/world"
+)
+
+// Cast is attached to the update events of the entities reached by an
+// ability.
+type Cast struct {
+	Caster  world.Ref `json:"caster"`
+	Ability string    `json:"ability"`
+	abilities.Outcome
+}
+
+// CastResult tells the caster how its cast went.
+type CastResult struct {
+	Ability string `json:"ability"`
+	Error   string `json:"error,omitempty"`
+	Mana    int    `json:"mana"`
+	// CooldownMS is how long until the ability can be cast again.
+	CooldownMS int64 `json:"cooldown_ms"`
+}
+
+// Cast makes the player use an ability, on a monster when the ability
+// targets enemies.
+func (l *Loop) Cast(playerID int, abilityID string, monsterID int) {
+	l.Submit(func() {
+		l.cast(playerID, abilityID, monsterID)
+	})
+}
+
+func (l *Loop) cast(playerID int, abilityID string, monsterID int) error {
+	p, ok := l.players[playerID]
+	if !ok {
+		return abilities.ErrDead
+	}
+	err := l.castAbility(p, abilityID, monsterID)
+	result := CastResult{Ability: abilityID, Mana: p.combatant.Mana}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	if ready := p.cooldowns[abilityID]; ready > l.tick {
+		result.CooldownMS = (time.Duration(ready-l.tick) * l.config.TickRate).Milliseconds()
+	}
+	l.Notify(playerID, "cast", result)
+	return err
+}
+
+func (l *Loop) castAbility(p *player, abilityID string, monsterID int) error {
+	a, ok := abilities.Default.Get(abilityID)
+	if !ok {
+		return abilities.ErrUnknownAbility
+	}
+	if err := a.Check(p.combatant, l.tick, p.cooldowns[a.ID]); err != nil {
+		return err
+	}
+	targets, err := l.targets(p, a, monsterID)
+	if err != nil {
+		return err
+	}
+
+	p.combatant.Mana -= a.Mana
+	p.cooldowns[a.ID] = l.tick + l.ticks(a.Cooldown())
+	cast := &abilities.Cast{
+		Ability:  a,
+		Caster:   p.combatant,
+		Resolver: l.resolver,
+		AddEffect: func(target *combat.Combatant, d *status.Definition) {
+			l.addEffect(target, d, p.combatant.Ref)
+		},
+	}
+	for _, outcome := range a.Apply(cast, targets) {
+		l.touch(outcome.Target, Cast{Caster: p.combatant.Ref, Ability: a.ID, Outcome: outcome})
+		if outcome.Target.Kind != world.KindMonster {
+			continue
+		}
+		m := l.monster(outcome.Target.ID)
+		if outcome.Killed {
+			l.kill(m, p)
+		} else if m.State != StateDead && m.target == 0 {
+			m.target = p.combatant.Ref.ID
+			m.State = StateChase
+		}
+	}
+	return nil
+}
+
+// targets returns the living monsters around the targeted one for enemy
+// abilities, the players around the caster for the others.
+func (l *Loop) targets(p *player, a *abilities.Ability, monsterID int) ([]*combat.Combatant, error) {
+	if a.Target == abilities.TargetSelf {
+		targets := []*combat.Combatant{p.combatant}
+		if a.Radius == 0 {
+			return targets, nil
+		}
+		for _, e := range l.space.Nearby(p.x, p.y, a.Radius) {
+			other, ok := l.players[e.ID]
+			if e.Kind == world.KindPlayer && ok && other != p && other.combatant.Alive() {
+				targets = append(targets, other.combatant)
+			}
+		}
+		return targets, nil
+	}
+
+	m := l.monster(monsterID)
+	if m == nil || m.State == StateDead {
+		return nil, abilities.ErrInvalidTarget
+	}
+	if distance(p.x, p.y, m.X, m.Y) > a.Range {
+		return nil, abilities.ErrOutOfRange
+	}
+	targets := []*combat.Combatant{&m.Combatant}
+	if a.Radius == 0 {
+		return targets, nil
+	}
+	for _, e := range l.space.Nearby(m.X, m.Y, a.Radius) {
+		other := l.monster(e.ID)
+		if e.Kind == world.KindMonster && other != nil && other != m && other.State != StateDead {
+			targets = append(targets, &other.Combatant)
+		}
+	}
+	return targets, nil
+}
+
+// regenerate gives the player back a share of its mana.
+func (l *Loop) regenerate(p *player) {
+	c := p.combatant
+	if !c.Alive() || c.Mana >= c.Stats.Mana {
+		return
+	}
+	amount := c.Stats.Mana * settings.ManaRegenPercent / 100
+	if amount < 1 {
+		amount = 1
+	}
+	c.Mana += amount
+	if c.Mana > c.Stats.Mana {
+		c.Mana = c.Stats.Mana
+	}
+}
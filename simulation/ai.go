@@ -0,0 +1,255 @@
+package simulation
+
+import (
+	"time"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/models"
+	"tribble/monsters"
+	"tribble/world"
+)
+
+type State string
+
+const (
+	StateIdle   State = "idle"
+	StateWander State = "wander"
+	StateChase  State = "chase"
+	StateAttack State = "attack"
+	StateReturn State = "return"
+	StateDead   State = "dead"
+)
+
+type Monster struct {
+	combat.Combatant
+	Definition *monsters.Definition
+	State      State
+	X, Y       int
+	Home       world.Point
+
+	spawn      *world.Spawn
+	target     int
+	goal       world.Point
+	nextMove   uint64
+	nextAttack uint64
+	nextWander uint64
+	respawnAt  uint64
+}
+
+func newMonster(ID int, definition *monsters.Definition, spawn *world.Spawn) *Monster {
+	return &Monster{
+		Combatant: combat.Combatant{
+			Ref:       world.Ref{Kind: world.KindMonster, ID: ID},
+			Level:     definition.Level,
+			Attribute: definition.Attribute,
+			Stats:     definition.Stats,
+		},
+		Definition: definition,
+		State:      StateDead,
+		spawn:      spawn,
+	}
+}
+
+func (l *Loop) respawn(m *Monster) {
+	m.X, m.Y = l.spawnPoint(m.spawn.Area)
+	m.Home = world.Point{X: m.X, Y: m.Y}
+	m.HP, m.Mana = m.Stats.HP, m.Stats.Mana
+	m.target = 0
+	l.idle(m)
+	l.broadcast(l.space.Join(world.Entity{Ref: m.Ref, PositionX: m.X, PositionY: m.Y}, false))
+}
+
+// spawnPoint picks a random walkable tile of the area, falling back to the
+// first walkable one when the random picks keep hitting walls.
+func (l *Loop) spawnPoint(a world.Area) (int, int) {
+	for i := 0; i < 8; i++ {
+		x, y := a.X+l.rng.Intn(a.Width), a.Y+l.rng.Intn(a.Height)
+		if l.Map.Walkable(x, y) {
+			return x, y
+		}
+	}
+	for y := a.Y; y < a.Y+a.Height; y++ {
+		for x := a.X; x < a.X+a.Width; x++ {
+			if l.Map.Walkable(x, y) {
+				return x, y
+			}
+		}
+	}
+	return a.X, a.Y
+}
+
+func (l *Loop) idle(m *Monster) {
+	m.State = StateIdle
+	m.nextWander = l.tick + l.ticks(2*time.Second) + uint64(l.rng.Intn(int(l.ticks(4*time.Second))))
+}
+
+func (l *Loop) kill(m *Monster, killer *player) {
+	l.die(m)
+	l.Publish(events.Event{
+		Type:     events.MonsterKilled,
+		PlayerID: killer.combatant.Ref.ID,
+		Data: events.MonsterKilledData{
+			Map:       l.Map.Name,
+			Monster:   m.Definition.ID,
+			MonsterID: m.Ref.ID,
+			XP:        m.Definition.XP,
+			Loot:      l.loot(m.Definition),
+		},
+	})
+}
+
+// die removes the monster from the map until it respawns.
+func (l *Loop) die(m *Monster) {
+	m.State = StateDead
+	m.target = 0
+	m.respawnAt = l.tick + l.ticks(time.Duration(m.Definition.RespawnSeconds)*time.Second)
+	m.ClearEffects()
+	l.broadcast(l.space.Leave(m.Ref))
+}
+
+func (l *Loop) loot(definition *monsters.Definition) []models.ItemStack {
+	var loot []models.ItemStack
+	for _, drop := range definition.Loot {
+		if l.rng.Float64() >= drop.Chance {
+			continue
+		}
+		loot = append(loot, models.ItemStack{ItemID: drop.Item, Quantity: drop.Min + l.rng.Intn(drop.Max-drop.Min+1)})
+	}
+	return loot
+}
+
+// think runs one tick of the monster state machine.
+func (l *Loop) think(m *Monster) {
+	switch m.State {
+	case StateDead:
+		if l.tick >= m.respawnAt {
+			l.respawn(m)
+		}
+
+	case StateIdle:
+		if l.aggro(m) || l.tick < m.nextWander {
+			return
+		}
+		r := m.Definition.WanderRadius
+		goal := world.Point{X: m.Home.X + l.rng.Intn(2*r+1) - r, Y: m.Home.Y + l.rng.Intn(2*r+1) - r}
+		if !l.Map.Walkable(goal.X, goal.Y) {
+			l.idle(m)
+			return
+		}
+		m.goal = goal
+		m.State = StateWander
+
+	case StateWander:
+		if l.aggro(m) {
+			return
+		}
+		if m.X == m.goal.X && m.Y == m.goal.Y {
+			l.idle(m)
+			return
+		}
+		if !l.step(m, m.goal) {
+			l.idle(m)
+		}
+
+	case StateChase, StateAttack:
+		target := l.players[m.target]
+		if target == nil || !target.combatant.Alive() ||
+			distance(m.X, m.Y, m.Home.X, m.Home.Y) > m.Definition.LeashDistance {
+			l.disengage(m)
+			return
+		}
+		if distance(m.X, m.Y, target.x, target.y) > m.Definition.AttackRange {
+			m.State = StateChase
+			if !l.step(m, world.Point{X: target.x, Y: target.y}) {
+				l.disengage(m)
+			}
+			return
+		}
+		m.State = StateAttack
+		l.attack(m, target)
+
+	case StateReturn:
+		if m.X == m.Home.X && m.Y == m.Home.Y {
+			m.HP = m.Stats.HP
+			l.idle(m)
+			l.broadcast(l.space.Touch(m.Ref))
+			return
+		}
+		if !l.step(m, m.Home) {
+			// nothing walks home when the way is lost, snap back instead
+			m.X, m.Y = m.Home.X, m.Home.Y
+			l.broadcast(l.space.Move(m.Ref, m.X, m.Y))
+		}
+	}
+}
+
+// aggro starts chasing the closest living player inside the aggro radius,
+// ties are broken by the lowest player ID.
+func (l *Loop) aggro(m *Monster) bool {
+	best, bestDistance := 0, 0
+	for _, e := range l.space.Nearby(m.X, m.Y, m.Definition.AggroRadius) {
+		p, ok := l.players[e.ID]
+		if e.Kind != world.KindPlayer || !ok || !p.combatant.Alive() {
+			continue
+		}
+		d := distance(m.X, m.Y, p.x, p.y)
+		if best == 0 || d < bestDistance || (d == bestDistance && e.ID < best) {
+			best, bestDistance = e.ID, d
+		}
+	}
+	if best == 0 {
+		return false
+	}
+	m.target = best
+	m.State = StateChase
+	return true
+}
+
+func (l *Loop) disengage(m *Monster) {
+	m.target = 0
+	m.State = StateReturn
+}
+
+func (l *Loop) attack(m *Monster, target *player) {
+	if l.tick < m.nextAttack {
+		return
+	}
+	m.nextAttack = l.tick + l.ticks(time.Duration(m.Definition.AttackIntervalMS)*time.Millisecond)
+
+	result := l.resolver.Attack(&m.Combatant, target.combatant)
+	l.hit(m.Ref, target.combatant.Ref, result)
+	if result.Killed {
+		l.playerDied(target, m.Ref)
+		l.disengage(m)
+	}
+}
+
+func (l *Loop) playerDied(p *player, killer world.Ref) {
+	if p.combatant.ClearEffects() {
+		l.effectsChanged(p.combatant)
+	}
+	l.Publish(events.Event{
+		Type:     events.PlayerDied,
+		PlayerID: p.combatant.Ref.ID,
+		Data:     events.PlayerDiedData{Map: l.Map.Name, KillerKind: string(killer.Kind), KillerID: killer.ID},
+	})
+}
+
+// step moves the monster one tile along the path to the goal when its
+// movement speed allows it. It reports false when the goal can't be reached.
+func (l *Loop) step(m *Monster, goal world.Point) bool {
+	if l.tick < m.nextMove {
+		return true
+	}
+	next, err := l.paths.Next(world.Point{X: m.X, Y: m.Y}, goal)
+	if err != nil {
+		return false
+	}
+	m.nextMove = l.tick + l.moveTicks(m.Stats.MovementSpeed)
+	if next.X == m.X && next.Y == m.Y {
+		return true
+	}
+	m.X, m.Y = next.X, next.Y
+	l.broadcast(l.space.Move(m.Ref, m.X, m.Y))
+	return true
+}
@@ -0,0 +1,102 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock drives the simulation loops, tests use a ManualClock to step
+// them deterministically.
+type Clock interface {
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type RealClock struct{}
+
+func (RealClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTicker) Stop() {
+	t.t.Stop()
+}
+
+// ManualClock only moves when Advance is called. Ticks are delivered on
+// unbuffered channels, so once Advance returns every due tick has been
+// received by its loop.
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*manualTicker
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) NewTicker(d time.Duration) Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &manualTicker{
+		period:  d,
+		next:    c.now.Add(d),
+		c:       make(chan time.Time),
+		stopped: make(chan struct{}),
+	}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+	tickers := make([]*manualTicker, len(c.tickers))
+	copy(tickers, c.tickers)
+	c.mu.Unlock()
+
+	for _, t := range tickers {
+		for !t.next.After(now) {
+			select {
+			case t.c <- t.next:
+			case <-t.stopped:
+			}
+			t.next = t.next.Add(t.period)
+		}
+	}
+}
+
+type manualTicker struct {
+	period  time.Duration
+	next    time.Time
+	c       chan time.Time
+	once    sync.Once
+	stopped chan struct{}
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTicker) Stop() {
+	t.once.Do(func() { close(t.stopped) })
+}
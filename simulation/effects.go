@@ -0,0 +1,59 @@
+package simulation
+
+import (
+	"tribble/combat"
+	"tribble/status"
+	"tribble/world"
+)
+
+// Effects is attached to the update events of an entity whose status
+// effects changed, the ticks of its effects are attached as
+// combat.EffectTick.
+type Effects struct {
+	Effects []status.State `json:"effects"`
+}
+
+func (l *Loop) effectsChanged(c *combat.Combatant) {
+	l.touch(c.Ref, Effects{Effects: c.Effects.States()})
+}
+
+// addEffect applies a status effect cast by the source.
+func (l *Loop) addEffect(target *combat.Combatant, d *status.Definition, source world.Ref) {
+	if target.AddEffect(d, source) {
+		l.effectsChanged(target)
+	}
+}
+
+func (l *Loop) tickEffects(c *combat.Combatant) []combat.EffectTick {
+	ticks, ended := c.AdvanceEffects(l.config.TickRate)
+	for _, tick := range ticks {
+		l.touch(c.Ref, tick)
+	}
+	if ended {
+		l.effectsChanged(c)
+	}
+	return ticks
+}
+
+func (l *Loop) tickPlayerEffects(p *player) {
+	for _, tick := range l.tickEffects(p.combatant) {
+		if tick.Killed {
+			l.playerDied(p, tick.Source)
+		}
+	}
+}
+
+// tickMonsterEffects credits the kills of damage over time to the player
+// that applied the effect, when still on the map.
+func (l *Loop) tickMonsterEffects(m *Monster) {
+	for _, tick := range l.tickEffects(&m.Combatant) {
+		if !tick.Killed {
+			continue
+		}
+		if killer, ok := l.players[tick.Source.ID]; ok && tick.Source.Kind == world.KindPlayer {
+			l.kill(m, killer)
+		} else {
+			l.die(m)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package simulation
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/monsters"
+	"tribble/pathfinding"
+	"tribble/settings"
+	"tribble/world"
+)
+
+type Config struct {
+	TickRate time.Duration
+	Seed     int64
+	Combat   combat.Config
+}
+
+func DefaultConfig() Config {
+	return Config{
+		TickRate: settings.SimulationTickRate,
+		Seed:     time.Now().UnixNano(),
+		Combat:   combat.DefaultConfig,
+	}
+}
+
+// Hit is attached to the update events of an entity that was attacked.
+type Hit struct {
+	Attacker world.Ref `json:"attacker"`
+	combat.Result
+}
+
+type player struct {
+	combatant   *combat.Combatant
+	attackRange int
+	x, y        int
+	landmark    string
+	nextMove    uint64
+	nextAttack  uint64
+	// cooldowns holds the tick each ability used is ready again.
+	cooldowns map[string]uint64
+}
+
+// Loop runs the simulation of a single map at a fixed rate. All the state
+// is owned by the loop goroutine, other goroutines talk to it through
+// commands that run at the beginning of the next tick.
+type Loop struct {
+	Map *world.Map
+
+	// Publish receives the domain events of the map, like kills and deaths.
+	Publish func(events.Event)
+	// Broadcast receives the events addressed to the observers of the map.
+	Broadcast func([]world.Event)
+	// Notify receives the messages addressed to a single player, like the
+	// outcome of its casts.
+	Notify func(playerID int, msgType string, data interface{})
+
+	space    *world.Space
+	config   Config
+	resolver *combat.Resolver
+	paths    *pathfinding.Finder
+	rng      *rand.Rand
+	tick     uint64
+	monsters []*Monster
+	players  map[int]*player
+	commands chan func()
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+func NewLoop(m *world.Map, space *world.Space, config Config) (*Loop, error) {
+	l := &Loop{
+		Map:       m,
+		Publish:   events.Publish,
+		Broadcast: func([]world.Event) {},
+		Notify:    func(int, string, interface{}) {},
+		space:     space,
+		config:    config,
+		resolver:  combat.NewResolver(config.Seed, config.Combat),
+		paths:     pathfinding.For(m),
+		rng:       rand.New(rand.NewSource(config.Seed + 1)),
+		players:   make(map[int]*player),
+		commands:  make(chan func(), 1024),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	for i := range m.Spawns {
+		spawn := &m.Spawns[i]
+		definition, ok := monsters.Default.Get(spawn.Monster)
+		if !ok {
+			return nil, fmt.Errorf("map %v spawns unknown monster %v", m.Name, spawn.Monster)
+		}
+		for n := 0; n < spawn.Count; n++ {
+			monster := newMonster(len(l.monsters)+1, definition, spawn)
+			l.monsters = append(l.monsters, monster)
+			l.respawn(monster)
+		}
+	}
+	return l, nil
+}
+
+// Start runs the loop on its own goroutine, ticking with the clock.
+func (l *Loop) Start(clock Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.started || l.stopped {
+		return
+	}
+	l.started = true
+
+	ticker := clock.NewTicker(l.config.TickRate)
+	go func() {
+		defer close(l.done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				l.shutdown()
+				return
+			case <-ticker.C():
+				l.Step()
+			}
+		}
+	}()
+}
+
+// Stop ends the loop and waits for the tick in progress to finish. The
+// players still on the map leave it, their PlayerLeft events published.
+func (l *Loop) Stop() {
+	l.mu.Lock()
+	if l.stopped {
+		l.mu.Unlock()
+		<-l.done
+		return
+	}
+	l.stopped = true
+	close(l.stop)
+	started := l.started
+	l.mu.Unlock()
+	if !started {
+		l.shutdown()
+		close(l.done)
+	}
+	<-l.done
+}
+
+// shutdown runs the commands queued before the loop stopped, then removes
+// the remaining players.
+func (l *Loop) shutdown() {
+	for n := len(l.commands); n > 0; n-- {
+		(<-l.commands)()
+	}
+	for playerID := range l.players {
+		if e, ok := l.leave(playerID); ok {
+			l.Publish(e)
+		}
+	}
+}
+
+func (l *Loop) Tick() uint64 {
+	return atomic.LoadUint64(&l.tick)
+}
+
+// Submit queues a command to run on the loop goroutine, commands submitted
+// once the loop stopped are dropped.
+func (l *Loop) Submit(command func()) {
+	select {
+	case <-l.stop:
+		return
+	default:
+	}
+	select {
+	case <-l.stop:
+	case l.commands <- command:
+	}
+}
+
+// Step advances the simulation by one tick. It is called by the running
+// loop and may be called directly by tests while the loop is not started.
+func (l *Loop) Step() {
+	atomic.AddUint64(&l.tick, 1)
+	for n := len(l.commands); n > 0; n-- {
+		(<-l.commands)()
+	}
+	second := l.tick%l.ticks(time.Second) == 0
+	for _, p := range l.players {
+		l.tickPlayerEffects(p)
+		if second {
+			l.regenerate(p)
+		}
+	}
+	for _, m := range l.monsters {
+		if m.State != StateDead {
+			l.tickMonsterEffects(m)
+		}
+		l.think(m)
+	}
+}
+
+func (l *Loop) ticks(d time.Duration) uint64 {
+	n := uint64((d + l.config.TickRate - 1) / l.config.TickRate)
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (l *Loop) moveTicks(speed int) uint64 {
+	if speed < 1 {
+		speed = 1
+	}
+	return l.ticks(time.Second / time.Duration(speed))
+}
+
+func (l *Loop) broadcast(events []world.Event) {
+	if len(events) > 0 {
+		l.Broadcast(events)
+	}
+}
+
+// hit broadcasts the outcome of an attack.
+func (l *Loop) hit(attacker, target world.Ref, result combat.Result) {
+	l.touch(target, Hit{Attacker: attacker, Result: result})
+}
+
+// touch broadcasts the data to everyone seeing the target, the target
+// itself included.
+func (l *Loop) touch(target world.Ref, data interface{}) {
+	events := l.space.Touch(target)
+	for i := range events {
+		events[i].Data = data
+	}
+	if target.Kind == world.KindPlayer {
+		if entity, ok := l.space.Get(target); ok {
+			events = append(events, world.Event{Type: world.EventUpdate, Observer: target, Entity: entity, Data: data})
+		}
+	}
+	l.broadcast(events)
+}
+
+func distance(x1, y1, x2, y2 int) int {
+	dx, dy := x1-x2, y1-y2
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx > dy {
+		return dx
+	}
+	return dy
+}
@@ -0,0 +1,446 @@
+package simulation
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tribble/abilities"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/models"
+	"tribble/status"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func testMap() *world.Map {
+	return &world.Map{
+		Name:   "test",
+		Width:  50,
+		Height: 50,
+		Spawn:  world.Point{X: 1, Y: 1},
+		Spawns: []world.Spawn{
+			{Monster: "goblin", Count: 1, Area: world.Area{X: 10, Y: 10, Width: 1, Height: 1}},
+		},
+	}
+}
+
+func testLoop(t *testing.T, seed int64) (*Loop, *[]events.Event) {
+	loop, err := NewLoop(testMap(), world.NewSpace(12), Config{
+		TickRate: 100 * time.Millisecond,
+		Seed:     seed,
+		Combat:   combat.DefaultConfig,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	published := make([]events.Event, 0)
+	loop.Publish = func(e events.Event) {
+		published = append(published, e)
+	}
+	return loop, &published
+}
+
+func hero(ID, hp int) *combat.Combatant {
+	return &combat.Combatant{
+		Ref:       world.Ref{Kind: world.KindPlayer, ID: ID},
+		Level:     1,
+		Attribute: models.Strength,
+		Stats:     models.Stats{HP: hp, Strength: 200, MovementSpeed: 10},
+		HP:        hp,
+	}
+}
+
+func steps(loop *Loop, n int) {
+	for i := 0; i < n; i++ {
+		loop.Step()
+	}
+}
+
+func TestClockDrivesLoop(t *testing.T) {
+	loop, _ := testLoop(t, 1)
+	clock := NewManualClock(time.Unix(0, 0))
+	loop.Start(clock)
+
+	clock.Advance(time.Second)
+	clock.Advance(250 * time.Millisecond)
+	loop.Stop()
+	assert.Equal(t, loop.Tick(), uint64(12))
+
+	// ticks after stopping are dropped and stopping twice is harmless
+	clock.Advance(time.Second)
+	loop.Stop()
+	assert.Equal(t, loop.Tick(), uint64(12))
+}
+
+func TestStopWithoutStart(t *testing.T) {
+	loop, _ := testLoop(t, 1)
+	loop.Stop()
+	loop.Start(NewManualClock(time.Unix(0, 0)))
+	assert.Equal(t, loop.Tick(), uint64(0))
+}
+
+func TestManagerStopsEveryLoop(t *testing.T) {
+	maps := []*world.Map{testMap(), testMap()}
+	maps[1].Name = "other"
+	manager, err := NewManager(maps, world.New(12), Config{TickRate: 100 * time.Millisecond, Seed: 1}, func(events.Event) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := NewManualClock(time.Unix(0, 0))
+	manager.Start(clock)
+	clock.Advance(500 * time.Millisecond)
+	manager.Stop()
+
+	for _, name := range []string{"test", "other"} {
+		loop, ok := manager.Loop(name)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, loop.Tick(), uint64(5))
+	}
+}
+
+func TestUnknownMonster(t *testing.T) {
+	m := testMap()
+	m.Spawns[0].Monster = "dragon"
+	if _, err := NewLoop(m, world.NewSpace(12), Config{TickRate: time.Second}); err == nil {
+		t.Errorf("%s FAILED: want error for unknown monster", t.Name())
+	}
+}
+
+func TestWanderIsDeterministic(t *testing.T) {
+	positions := func(seed int64) [][2]int {
+		loop, _ := testLoop(t, seed)
+		result := make([][2]int, 0)
+		for i := 0; i < 300; i++ {
+			loop.Step()
+			m := loop.monsters[0]
+			result = append(result, [2]int{m.X, m.Y})
+		}
+		return result
+	}
+
+	a := positions(7)
+	assert.Equal(t, positions(7), a)
+
+	moved := false
+	for _, p := range a {
+		if p != [2]int{10, 10} {
+			moved = true
+		}
+		if distance(p[0], p[1], 10, 10) > 4 {
+			t.Fatalf("%s FAILED: wandered outside of radius: %v", t.Name(), p)
+		}
+	}
+	assert.Equal(t, moved, true)
+}
+
+func TestChaseAttackAndKillPlayer(t *testing.T) {
+	loop, published := testLoop(t, 3)
+	goblin := loop.monsters[0]
+
+	loop.Join(hero(1, 30), 1, 14, 10)
+	loop.Step()
+	assert.Equal(t, goblin.State, StateChase)
+
+	// the goblin walks to the player and starts hitting
+	steps(loop, 20)
+	assert.Equal(t, distance(goblin.X, goblin.Y, 14, 10), 1)
+	assert.Equal(t, goblin.State, StateAttack)
+
+	steps(loop, 300)
+	assert.Equal(t, loop.players[1].combatant.HP, 0)
+	assert.Equal(t, goblin.State == StateReturn || goblin.State == StateIdle || goblin.State == StateWander, true)
+	assert.Equal(t, len(*published), 1)
+	assert.Equal(t, (*published)[0].Type, events.PlayerDied)
+	assert.Equal(t, (*published)[0].PlayerID, 1)
+}
+
+func TestLeashReturnsHomeAndHeals(t *testing.T) {
+	loop, _ := testLoop(t, 3)
+	goblin := loop.monsters[0]
+
+	loop.Join(hero(1, 1000), 1, 15, 10)
+	loop.Step()
+	assert.Equal(t, goblin.State, StateChase)
+
+	// the player runs away faster than the goblin can follow
+	p := loop.players[1]
+	for x := 16; x < 40; x++ {
+		p.x = x
+		loop.space.Move(p.combatant.Ref, x, 10)
+		steps(loop, 4)
+	}
+	assert.Equal(t, goblin.State, StateReturn)
+
+	goblin.HP = 1
+	for i := 0; i < 200 && goblin.State == StateReturn; i++ {
+		loop.Step()
+	}
+	assert.Equal(t, goblin.State, StateIdle)
+	assert.Equal(t, goblin.X, goblin.Home.X)
+	assert.Equal(t, goblin.Y, goblin.Home.Y)
+	assert.Equal(t, goblin.HP, goblin.Stats.HP)
+}
+
+func TestPlayerKillsMonsterAndItRespawns(t *testing.T) {
+	loop, published := testLoop(t, 5)
+	loop.config.Combat.BaseEvasion = 0
+	loop.config.Combat.EvasionPerAgility = 0
+	loop.resolver = combat.NewResolver(5, loop.config.Combat)
+	goblin := loop.monsters[0]
+
+	loop.Join(hero(1, 1000), 1, 11, 10)
+	loop.Attack(1, goblin.Ref.ID)
+	loop.Step()
+
+	assert.Equal(t, goblin.State, StateDead)
+	assert.Equal(t, loop.space.Len(), 1)
+	assert.Equal(t, len(*published), 1)
+	assert.Equal(t, (*published)[0].Type, events.MonsterKilled)
+	assert.Equal(t, (*published)[0].Data, events.MonsterKilledData{
+		Map: "test", Monster: "goblin", MonsterID: 1, XP: 20,
+		Loot: []models.ItemStack{{ItemID: "goblin_ear", Quantity: 1}},
+	})
+
+	// goblins respawn after 30 seconds
+	steps(loop, 299)
+	assert.Equal(t, goblin.State, StateDead)
+	loop.Step()
+	assert.Equal(t, goblin.State == StateDead, false)
+	assert.Equal(t, goblin.HP, goblin.Stats.HP)
+	assert.Equal(t, loop.space.Len(), 2)
+}
+
+func TestPlayerMovementRules(t *testing.T) {
+	loop, _ := testLoop(t, 1)
+	loop.Join(hero(1, 100), 1, 1, 1)
+	loop.Step()
+
+	assert.Equal(t, loop.move(1, 3, 1), false)
+	assert.Equal(t, loop.move(1, 0, 0), true)
+	// movement speed 10 allows one tile per tick
+	assert.Equal(t, loop.move(1, 0, 1), false)
+	loop.Step()
+	assert.Equal(t, loop.move(1, -1, 1), false)
+	assert.Equal(t, loop.move(1, 1, 1), true)
+
+	loop.leave(1)
+	assert.Equal(t, loop.move(1, 2, 1), false)
+}
+
+func TestMonsterWalksAroundWalls(t *testing.T) {
+	m := testMap()
+	rows := make([]string, m.Height)
+	for y := range rows {
+		row := []byte(strings.Repeat(".", m.Width))
+		if y >= 7 && y <= 13 {
+			row[12] = '#'
+		}
+		rows[y] = string(row)
+	}
+	if err := m.SetCollision(strings.NewReader(strings.Join(rows, "\n"))); err != nil {
+		t.Fatal(err)
+	}
+	loop, err := NewLoop(m, world.NewSpace(12), Config{TickRate: 100 * time.Millisecond, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	goblin := loop.monsters[0]
+
+	loop.Join(hero(1, 1000), 1, 14, 10)
+	loop.Step()
+	assert.Equal(t, goblin.State, StateChase)
+	for i := 0; i < 100 && goblin.State == StateChase; i++ {
+		loop.Step()
+		if !m.Walkable(goblin.X, goblin.Y) {
+			t.Fatalf("%s FAILED: goblin walked into a wall at %d,%d", t.Name(), goblin.X, goblin.Y)
+		}
+	}
+	assert.Equal(t, goblin.State, StateAttack)
+
+	// players can't walk through walls nor cut their corners
+	loop.players[1].x, loop.players[1].y = 13, 7
+	assert.Equal(t, loop.move(1, 12, 7), false)
+	assert.Equal(t, loop.move(1, 12, 6), false)
+	assert.Equal(t, loop.move(1, 13, 6), true)
+}
+
+func TestEnteringLandmarks(t *testing.T) {
+	loop, published := testLoop(t, 1)
+	loop.Map.Landmarks = []world.Landmark{{Name: "well", Area: world.Area{X: 2, Y: 1, Width: 2, Height: 1}}}
+	loop.Join(hero(1, 100), 1, 1, 1)
+	loop.Step()
+
+	for x := 2; x <= 4; x++ {
+		assert.Equal(t, loop.move(1, x, 1), true)
+		loop.Step()
+	}
+	// walking inside the landmark doesn't publish again
+	assert.Equal(t, len(*published), 1)
+	assert.Equal(t, (*published)[0].Type, events.LandmarkEntered)
+	assert.Equal(t, (*published)[0].Data, events.LandmarkEnteredData{Map: "test", Landmark: "well"})
+}
+
+func TestCastAbility(t *testing.T) {
+	loop, _ := testLoop(t, 5)
+	loop.config.Combat.BaseEvasion = 0
+	loop.config.Combat.EvasionPerAgility = 0
+	loop.resolver = combat.NewResolver(5, loop.config.Combat)
+	results := make([]CastResult, 0)
+	loop.Notify = func(playerID int, msgType string, data interface{}) {
+		assert.Equal(t, msgType, "cast")
+		results = append(results, data.(CastResult))
+	}
+	goblin := loop.monsters[0]
+	warrior := hero(1, 1000)
+	warrior.Class, warrior.Stats.Strength = "warrior", 1
+	warrior.Stats.Mana, warrior.Mana = 100, 100
+
+	loop.Join(warrior, 1, 14, 10)
+	loop.Step()
+	loop.Cast(1, "heavy_strike", goblin.Ref.ID)
+	loop.Cast(1, "whirlwind", goblin.Ref.ID)
+	loop.Step()
+	assert.Equal(t, results[0].Error, abilities.ErrOutOfRange.Error())
+	assert.Equal(t, results[1].Error, abilities.ErrAbilityLocked.Error())
+
+	loop.Move(1, 13, 10)
+	steps(loop, 2)
+	loop.Move(1, 12, 10)
+	steps(loop, 2)
+	loop.Cast(1, "heavy_strike", goblin.Ref.ID)
+	loop.Cast(1, "heavy_strike", goblin.Ref.ID)
+	loop.Step()
+	assert.Equal(t, results[2].Error, "")
+	assert.Equal(t, results[2].Mana, 95)
+	assert.Equal(t, results[2].CooldownMS, int64(5000))
+	assert.Equal(t, results[3].Error, abilities.ErrOnCooldown.Error())
+	assert.Equal(t, goblin.HP < goblin.Stats.HP, true)
+	assert.Equal(t, goblin.target, 1)
+
+	// the cooldown is over after 5 seconds, mana regenerates meanwhile
+	steps(loop, 49)
+	loop.Cast(1, "heavy_strike", goblin.Ref.ID)
+	loop.Step()
+	assert.Equal(t, results[4].Error, "")
+	assert.Equal(t, results[4].Mana, 100-5)
+}
+
+func TestRefreshUnlocksAbilities(t *testing.T) {
+	loop, _ := testLoop(t, 5)
+	results := make([]CastResult, 0)
+	loop.Notify = func(playerID int, msgType string, data interface{}) {
+		results = append(results, data.(CastResult))
+	}
+	goblin := loop.monsters[0]
+	warrior := hero(1, 1000)
+	warrior.Class = "warrior"
+	warrior.Stats.Mana, warrior.Mana = 100, 100
+
+	loop.Join(warrior, 1, 11, 10)
+	loop.Cast(1, "whirlwind", goblin.Ref.ID)
+	loop.Step()
+	assert.Equal(t, results[0].Error, abilities.ErrAbilityLocked.Error())
+
+	loop.Refresh(1, 6, models.Stats{HP: 1200, Mana: 120, Strength: 200, MovementSpeed: 10})
+	loop.Cast(1, "whirlwind", goblin.Ref.ID)
+	loop.Step()
+	assert.Equal(t, results[1].Error, "")
+	assert.Equal(t, warrior.Level, 6)
+	assert.Equal(t, warrior.Stats.HP, 1200)
+	assert.Equal(t, warrior.HP, 1000)
+}
+
+func TestStatusEffects(t *testing.T) {
+	loop, published := testLoop(t, 5)
+	broadcast := make([]world.Event, 0)
+	loop.Broadcast = func(events []world.Event) {
+		broadcast = append(broadcast, events...)
+	}
+	goblin := loop.monsters[0]
+	templar := hero(1, 1000)
+	loop.Join(templar, 1, 1, 1)
+	loop.Step()
+
+	// damage over time kills are credited to the player
+	poison, _ := status.Default.Get("poison")
+	loop.addEffect(&goblin.Combatant, poison, templar.Ref)
+	goblin.HP = poison.Damage
+	steps(loop, int(poison.Interval()/loop.config.TickRate))
+	assert.Equal(t, goblin.State, StateDead)
+	assert.Equal(t, len(goblin.Effects.States()), 0)
+	assert.Equal(t, (*published)[len(*published)-1].Type, events.MonsterKilled)
+
+	// the player sees its effects change
+	broadcast = broadcast[:0]
+	blessed, _ := status.Default.Get("blessed")
+	loop.addEffect(templar, blessed, templar.Ref)
+	assert.Equal(t, len(broadcast), 1)
+	assert.Equal(t, broadcast[0].Observer, templar.Ref)
+	assert.Equal(t, broadcast[0].Data.(Effects).Effects[0].ID, "blessed")
+	assert.Equal(t, templar.Stats.Intellect, blessed.Stats.Intellect)
+
+	// persisted effects are given when the player leaves
+	left, ok := loop.leave(1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, left.Type, events.PlayerLeft)
+	saved := left.Data.(events.PlayerLeftData).Effects
+	assert.Equal(t, len(saved), 1)
+	assert.Equal(t, saved[0].EffectID, "blessed")
+}
+
+func TestSubmitAfterStop(t *testing.T) {
+	loop, _ := testLoop(t, 1)
+	loop.Start(NewManualClock(time.Unix(0, 0)))
+	loop.Stop()
+
+	// a full queue doesn't block callers of a stopped loop
+	for i := 0; i < cap(loop.commands)+1; i++ {
+		loop.Move(1, 1, 1)
+	}
+	assert.Equal(t, len(loop.commands), 0)
+}
+
+// leaveTicking leaves the loop while advancing its clock, until the loop
+// ran the command.
+func leaveTicking(loop *Loop, clock *ManualClock, playerID int) (events.Event, bool) {
+	type result struct {
+		e  events.Event
+		ok bool
+	}
+	left := make(chan result, 1)
+	go func() {
+		e, ok := loop.Leave(playerID)
+		left <- result{e, ok}
+	}()
+	for {
+		select {
+		case r := <-left:
+			return r.e, r.ok
+		case <-time.After(time.Millisecond):
+			clock.Advance(100 * time.Millisecond)
+		}
+	}
+}
+
+func TestStopLeavesPlayers(t *testing.T) {
+	loop, published := testLoop(t, 1)
+	clock := NewManualClock(time.Unix(0, 0))
+	loop.Start(clock)
+	loop.Join(hero(1, 100), 1, 1, 1)
+	loop.Join(hero(2, 100), 1, 1, 1)
+
+	left, ok := leaveTicking(loop, clock, 1)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, left.PlayerID, 1)
+	_, ok = leaveTicking(loop, clock, 1)
+	assert.Equal(t, ok, false)
+
+	loop.Stop()
+	last := (*published)[len(*published)-1]
+	assert.Equal(t, last.Type, events.PlayerLeft)
+	assert.Equal(t, last.PlayerID, 2)
+	_, ok = loop.Leave(2)
+	assert.Equal(t, ok, false)
+}
@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/models"
+	"tribble/stats"
+	"tribble/world"
+)
+
+type Repository interface {
+	models.PlayerRepository
+	models.EquipmentRepository
+}
+
+// Manager owns the simulation loop of every map.
+type Manager struct {
+	loops map[string]*Loop
+	order []*Loop
+}
+
+func NewManager(maps []*world.Map, spaces *world.World, config Config, publish func(events.Event)) (*Manager, error) {
+	manager := &Manager{loops: make(map[string]*Loop)}
+	for i, m := range maps {
+		c := config
+		c.Seed = config.Seed + int64(i)*2
+		loop, err := NewLoop(m, spaces.Space(m.Name), c)
+		if err != nil {
+			return nil, err
+		}
+		loop.Publish = publish
+		manager.loops[m.Name] = loop
+		manager.order = append(manager.order, loop)
+	}
+	return manager, nil
+}
+
+func (m *Manager) Loop(mapName string) (*Loop, bool) {
+	loop, ok := m.loops[mapName]
+	return loop, ok
+}
+
+func (m *Manager) Loops() []*Loop {
+	return m.order
+}
+
+func (m *Manager) Start(clock Clock) {
+	for _, loop := range m.order {
+		loop.Start(clock)
+	}
+}
+
+// Stop stops every loop and waits for all of them to finish.
+func (m *Manager) Stop() {
+	for _, loop := range m.order {
+		loop.Stop()
+	}
+}
+
+// RefreshStats gives the players leveling up while in a map their new
+// level and class growth. It is subscribed to LevelUp after the growth is
+// saved.
+func (m *Manager) RefreshStats(repo Repository) events.Handler {
+	return func(e events.Event) {
+		levelUp, ok := e.Data.(events.LevelUpData)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		player, err := repo.GetPlayer(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not refresh stats of player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		equipment, err := repo.GetEquipment(ctx, e.PlayerID)
+		if err != nil {
+			log.Printf("could not refresh stats of player %v: %v", e.PlayerID, err.Error())
+			return
+		}
+		// the loops own the status effects, they add their modifiers
+		sheet := stats.Calculate(player, equipment, nil)
+		for _, loop := range m.order {
+			loop.Refresh(e.PlayerID, levelUp.To, sheet.Total)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package simulation
+
+import (
+	"time"
+	"tribble/combat"
+	"tribble/events"
+	"tribble/models"
+	"tribble/pathfinding"
+	"tribble/settings"
+	"tribble/world"
+)
+
+// Join adds a connected player to the map at the given position.
+func (l *Loop) Join(c *combat.Combatant, attackRange, x, y int) {
+	l.Submit(func() {
+		l.join(c, attackRange, x, y)
+	})
+}
+
+// Leave removes the player from the map, monsters chasing it go home. It
+// waits for the loop and returns the PlayerLeft event for the caller to
+// publish, so the status effects are saved before the player can join
+// again. It is false when the player wasn't on the map or the loop stopped.
+func (l *Loop) Leave(playerID int) (events.Event, bool) {
+	left := make(chan events.Event, 1)
+	l.Submit(func() {
+		if e, ok := l.leave(playerID); ok {
+			left <- e
+		}
+		close(left)
+	})
+	select {
+	case e, ok := <-left:
+		return e, ok
+	case <-l.done:
+		// the command may have run before the loop stopped
+		select {
+		case e, ok := <-left:
+			return e, ok
+		default:
+			return events.Event{}, false
+		}
+	}
+}
+
+// Refresh gives the player on the map its new level and stats without
+// status effects.
+func (l *Loop) Refresh(playerID, level int, stats models.Stats) {
+	l.Submit(func() {
+		if p, ok := l.players[playerID]; ok {
+			p.combatant.SetStats(level, stats)
+		}
+	})
+}
+
+// Move walks the player to an adjacent tile, respecting its movement speed.
+func (l *Loop) Move(playerID, x, y int) {
+	l.Submit(func() {
+		l.move(playerID, x, y)
+	})
+}
+
+// Attack makes the player hit a monster in range.
+func (l *Loop) Attack(playerID, monsterID int) {
+	l.Submit(func() {
+		l.attackMonster(playerID, monsterID)
+	})
+}
+
+func (l *Loop) join(c *combat.Combatant, attackRange, x, y int) {
+	// a reconnecting player starts over so its client gets the whole view,
+	// its cooldowns and status effects are kept
+	cooldowns := make(map[string]uint64)
+	if old, ok := l.players[c.Ref.ID]; ok {
+		cooldowns = old.cooldowns
+		c.SetEffects(old.combatant.Effects)
+		l.remove(old)
+	}
+	if !l.Map.Walkable(x, y) {
+		x, y = l.Map.Spawn.X, l.Map.Spawn.Y
+	}
+	p := &player{combatant: c, attackRange: attackRange, x: x, y: y, cooldowns: cooldowns}
+	l.players[c.Ref.ID] = p
+	l.broadcast(l.space.Join(world.Entity{Ref: c.Ref, PositionX: x, PositionY: y}, true))
+	l.effectsChanged(c)
+	l.enterLandmark(p)
+}
+
+// enterLandmark publishes when the player steps into a landmark it wasn't
+// already in.
+func (l *Loop) enterLandmark(p *player) {
+	landmark := l.Map.LandmarkAt(p.x, p.y)
+	if landmark == p.landmark {
+		return
+	}
+	p.landmark = landmark
+	if landmark == "" {
+		return
+	}
+	l.Publish(events.Event{
+		Type:     events.LandmarkEntered,
+		PlayerID: p.combatant.Ref.ID,
+		Data:     events.LandmarkEnteredData{Map: l.Map.Name, Landmark: landmark},
+	})
+}
+
+// leave returns the PlayerLeft event of the player, with the status
+// effects that outlast its session.
+func (l *Loop) leave(playerID int) (events.Event, bool) {
+	p, ok := l.players[playerID]
+	if !ok {
+		return events.Event{}, false
+	}
+	l.remove(p)
+	return events.Event{
+		Type:     events.PlayerLeft,
+		PlayerID: playerID,
+		Data:     events.PlayerLeftData{Map: l.Map.Name, Effects: p.combatant.Effects.Persisted(time.Now())},
+	}, true
+}
+
+func (l *Loop) remove(p *player) {
+	playerID := p.combatant.Ref.ID
+	delete(l.players, playerID)
+	for _, m := range l.monsters {
+		if m.target == playerID {
+			l.disengage(m)
+		}
+	}
+	l.broadcast(l.space.Leave(p.combatant.Ref))
+}
+
+func (l *Loop) move(playerID, x, y int) bool {
+	p, ok := l.players[playerID]
+	if !ok || !p.combatant.Alive() || l.tick < p.nextMove {
+		return false
+	}
+	from, to := world.Point{X: p.x, Y: p.y}, world.Point{X: x, Y: y}
+	if !pathfinding.Step(l.Map, from, to, pathfinding.DefaultOptions.Diagonal) {
+		return false
+	}
+	p.x, p.y = x, y
+	p.nextMove = l.tick + l.moveTicks(p.combatant.Stats.MovementSpeed)
+	l.broadcast(l.space.Move(p.combatant.Ref, x, y))
+	l.enterLandmark(p)
+	return true
+}
+
+func (l *Loop) monster(ID int) *Monster {
+	if ID < 1 || ID > len(l.monsters) {
+		return nil
+	}
+	return l.monsters[ID-1]
+}
+
+func (l *Loop) attackMonster(playerID, monsterID int) bool {
+	p, ok := l.players[playerID]
+	m := l.monster(monsterID)
+	if !ok || m == nil || !p.combatant.Alive() || m.State == StateDead || l.tick < p.nextAttack {
+		return false
+	}
+	if distance(p.x, p.y, m.X, m.Y) > p.attackRange {
+		return false
+	}
+	p.nextAttack = l.tick + l.ticks(settings.PlayerAttackInterval)
+
+	result := l.resolver.Attack(p.combatant, &m.Combatant)
+	l.hit(p.combatant.Ref, m.Ref, result)
+	if result.Killed {
+		l.kill(m, p)
+		return true
+	}
+	// monsters fight back when attacked, even while returning home
+	if m.target == 0 {
+		m.target = playerID
+		m.State = StateChase
+	}
+	return true
+}
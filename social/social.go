@@ -0,0 +1,36 @@
+package social
+
+import (
+	"context"
+	"tribble/gateway"
+	"tribble/models"
+)
+
+// CheckBlocked fails with ErrBlocked when either user blocked the other.
+// Whispers, party invites and trade requests go through it.
+func CheckBlocked(ctx context.Context, repo models.BlockRepository, a, b int) error {
+	blocked, err := repo.IsBlocked(ctx, a, b)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return models.ErrBlocked
+	}
+	return nil
+}
+
+// Friends lists the friends of the user with the player each one is
+// connected with, if any.
+func Friends(ctx context.Context, repo models.FriendRepository, hub *gateway.Hub, userID int) ([]*models.Friend, error) {
+	friends, err := repo.GetFriends(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range friends {
+		if s, ok := hub.User(f.UserID); ok {
+			f.Online = true
+			f.Player = &models.OnlinePlayer{ID: s.PlayerID, Name: s.Name, Map: s.Map}
+		}
+	}
+	return friends, nil
+}
@@ -0,0 +1,42 @@
+package social
+
+import (
+	"context"
+	"testing"
+	"tribble/gateway"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type fakeRepo struct {
+	models.FriendRepository
+	models.BlockRepository
+	blocked bool
+}
+
+func (r fakeRepo) GetFriends(ctx context.Context, userID int) ([]*models.Friend, error) {
+	return []*models.Friend{{UserID: 2, Username: "bren"}, {UserID: 3, Username: "cato"}}, nil
+}
+
+func (r fakeRepo) IsBlocked(ctx context.Context, a, b int) (bool, error) {
+	return r.blocked, nil
+}
+
+func TestFriendsOnlineStatus(t *testing.T) {
+	hub := gateway.NewHub()
+	hub.Register(gateway.NewSession(20, 2, "Bren", "forest"))
+
+	friends, err := Friends(context.Background(), fakeRepo{}, hub, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, friends[0].Online, true)
+	assert.Equal(t, *friends[0].Player, models.OnlinePlayer{ID: 20, Name: "Bren", Map: "forest"})
+	assert.Equal(t, friends[1].Online, false)
+	assert.Equal(t, friends[1].Player == nil, true)
+}
+
+func TestCheckBlocked(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, CheckBlocked(ctx, fakeRepo{}, 1, 2), nil)
+	assert.Equal(t, CheckBlocked(ctx, fakeRepo{blocked: true}, 1, 2), models.ErrBlocked)
+}
@@ -0,0 +1,24 @@
+package stats
+
+import (
+	"time"
+	"tribble/items"
+	"tribble/models"
+	"tribble/status"
+)
+
+// Calculate combines the class stats stored on the player with the
+// bonuses of the equipped items and the modifiers of its saved status
+// effects.
+func Calculate(player *models.Player, equipment []*models.EquippedItem, effects []*models.PlayerEffect) models.StatSheet {
+	sheet := models.StatSheet{Base: player.Stats}
+	for _, equipped := range equipment {
+		if item, ok := items.Default.Get(equipped.ItemID); ok {
+			sheet.Equipment = sheet.Equipment.Add(item.Stats)
+		}
+	}
+	restored := status.Default.Restore(effects, time.Now())
+	sheet.Effects = restored.Stats()
+	sheet.Total = sheet.Base.Add(sheet.Equipment).Add(sheet.Effects)
+	return sheet
+}
@@ -0,0 +1,27 @@
+package status
+
+import (
+	"context"
+	"log"
+	"time"
+	"tribble/events"
+	"tribble/models"
+)
+
+// Persist saves the status effects players leave the world with, in place
+// of the ones saved before.
+func Persist(repo models.StatusEffectRepository) events.Handler {
+	return func(e events.Event) {
+		left, ok := e.Data.(events.PlayerLeftData)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		if err := repo.SavePlayerEffects(ctx, e.PlayerID, left.Effects); err != nil {
+			log.Printf("could not save status effects of player %v: %v", e.PlayerID, err.Error())
+		}
+	}
+}
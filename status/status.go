@@ -0,0 +1,297 @@
+package status
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"tribble/data"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/world"
+)
+
+const (
+	TypeBuff   = "buff"
+	TypeDebuff = "debuff"
+)
+
+// Stacking policies, what applying an effect already active does.
+const (
+	// StackRefresh restarts the duration of the effect.
+	StackRefresh = "refresh"
+	// StackStack adds a stack up to MaxStacks and restarts the duration.
+	StackStack = "stack"
+	// StackIgnore keeps the effect as it is.
+	StackIgnore = "ignore"
+)
+
+// Definition is a status effect. Stats, Damage and Heal apply per stack,
+// damage and healing every tick interval. Persisted effects are saved
+// when their player logs out and keep running while it is away.
+type Definition struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Type       string       `json:"type"`
+	DurationMS int          `json:"duration_ms"`
+	TickMS     int          `json:"tick_ms,omitempty"`
+	Stacking   string       `json:"stacking"`
+	MaxStacks  int          `json:"max_stacks,omitempty"`
+	Stats      models.Stats `json:"stats,omitempty"`
+	Damage     int          `json:"damage,omitempty"`
+	Heal       int          `json:"heal,omitempty"`
+	Persist    bool         `json:"persist,omitempty"`
+}
+
+func (d *Definition) Duration() time.Duration {
+	return time.Duration(d.DurationMS) * time.Millisecond
+}
+
+func (d *Definition) Interval() time.Duration {
+	return time.Duration(d.TickMS) * time.Millisecond
+}
+
+func (d *Definition) validate() error {
+	if d.Type != TypeBuff && d.Type != TypeDebuff {
+		return fmt.Errorf("status effect %v has invalid type %v", d.ID, d.Type)
+	}
+	if d.DurationMS <= 0 || d.TickMS < 0 {
+		return fmt.Errorf("status effect %v has invalid timings", d.ID)
+	}
+	switch d.Stacking {
+	case StackStack:
+		if d.MaxStacks < 2 {
+			return fmt.Errorf("status effect %v stacks without max stacks", d.ID)
+		}
+	case StackRefresh, StackIgnore:
+		d.MaxStacks = 1
+	default:
+		return fmt.Errorf("status effect %v has invalid stacking %v", d.ID, d.Stacking)
+	}
+	if d.Damage < 0 || d.Heal < 0 || (d.Damage > 0 || d.Heal > 0) != (d.TickMS > 0) {
+		return fmt.Errorf("status effect %v must tick exactly when it damages or heals", d.ID)
+	}
+	if d.Damage > 0 && d.Type == TypeBuff || d.Heal > 0 && d.Type == TypeDebuff {
+		return fmt.Errorf("status effect %v damages or heals the wrong way", d.ID)
+	}
+	return nil
+}
+
+// Effect is a status effect active on an entity, Source is the entity
+// that applied it.
+type Effect struct {
+	Definition *Definition
+	Source     world.Ref
+	Stacks     int
+	Remaining  time.Duration
+
+	sinceTick time.Duration
+}
+
+// Tick is the damage or healing of an effect on one of its ticks.
+type Tick struct {
+	Effect string
+	Source world.Ref
+	Damage int
+	Heal   int
+}
+
+// State is an active effect as sent to clients.
+type State struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Stacks      int       `json:"stacks"`
+	RemainingMS int64     `json:"remaining_ms"`
+	Source      world.Ref `json:"source"`
+}
+
+// List holds the effects active on an entity. It is owned by the
+// simulation loop of the entity and not safe for concurrent use.
+type List struct {
+	effects []*Effect
+}
+
+func (l *List) get(ID string) *Effect {
+	for _, e := range l.effects {
+		if e.Definition.ID == ID {
+			return e
+		}
+	}
+	return nil
+}
+
+// Add applies the effect following its stacking policy, reporting whether
+// the list changed.
+func (l *List) Add(d *Definition, source world.Ref) bool {
+	e := l.get(d.ID)
+	if e == nil {
+		l.effects = append(l.effects, &Effect{Definition: d, Source: source, Stacks: 1, Remaining: d.Duration()})
+		return true
+	}
+	switch d.Stacking {
+	case StackIgnore:
+		return false
+	case StackStack:
+		if e.Stacks < d.MaxStacks {
+			e.Stacks++
+		}
+	}
+	e.Source = source
+	e.Remaining = d.Duration()
+	return true
+}
+
+// Clear removes every effect, reporting whether there was any.
+func (l *List) Clear() bool {
+	cleared := len(l.effects) > 0
+	l.effects = nil
+	return cleared
+}
+
+// Stats is the sum of the stat modifiers of the effects.
+func (l *List) Stats() models.Stats {
+	var stats models.Stats
+	for _, e := range l.effects {
+		stats = stats.Add(e.Definition.Stats.Scale(e.Stacks))
+	}
+	return stats
+}
+
+// Advance runs the effects for dt, returning their ticks and whether some
+// effects ended.
+func (l *List) Advance(dt time.Duration) ([]Tick, bool) {
+	var ticks []Tick
+	kept := l.effects[:0]
+	for _, e := range l.effects {
+		// effects don't tick past their end
+		elapsed := dt
+		if elapsed > e.Remaining {
+			elapsed = e.Remaining
+		}
+		e.Remaining -= elapsed
+		if interval := e.Definition.Interval(); interval > 0 {
+			for e.sinceTick += elapsed; e.sinceTick >= interval; e.sinceTick -= interval {
+				ticks = append(ticks, Tick{
+					Effect: e.Definition.ID,
+					Source: e.Source,
+					Damage: e.Definition.Damage * e.Stacks,
+					Heal:   e.Definition.Heal * e.Stacks,
+				})
+			}
+		}
+		if e.Remaining > 0 {
+			kept = append(kept, e)
+		}
+	}
+	ended := len(kept) < len(l.effects)
+	for i := len(kept); i < len(l.effects); i++ {
+		l.effects[i] = nil
+	}
+	l.effects = kept
+	return ticks, ended
+}
+
+func (l *List) States() []State {
+	states := make([]State, 0, len(l.effects))
+	for _, e := range l.effects {
+		states = append(states, State{
+			ID:          e.Definition.ID,
+			Type:        e.Definition.Type,
+			Stacks:      e.Stacks,
+			RemainingMS: e.Remaining.Milliseconds(),
+			Source:      e.Source,
+		})
+	}
+	return states
+}
+
+// Persisted returns the effects to save when the player logs out.
+func (l *List) Persisted(now time.Time) []*models.PlayerEffect {
+	saved := make([]*models.PlayerEffect, 0)
+	for _, e := range l.effects {
+		if e.Definition.Persist {
+			saved = append(saved, &models.PlayerEffect{
+				EffectID:  e.Definition.ID,
+				Stacks:    e.Stacks,
+				ExpiresAt: now.Add(e.Remaining),
+			})
+		}
+	}
+	return saved
+}
+
+type Catalog struct {
+	effects []*Definition
+	byID    map[string]*Definition
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Effects []*Definition `json:"effects"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Effects) == 0 {
+		return nil, errors.New("status effect catalog is empty")
+	}
+
+	catalog := &Catalog{effects: file.Effects, byID: make(map[string]*Definition)}
+	for _, d := range file.Effects {
+		if d.ID == "" {
+			return nil, errors.New("status effect without id")
+		}
+		if _, ok := catalog.byID[d.ID]; ok {
+			return nil, fmt.Errorf("duplicated status effect %v", d.ID)
+		}
+		if err := d.validate(); err != nil {
+			return nil, err
+		}
+		catalog.byID[d.ID] = d
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Definition, bool) {
+	d, ok := c.byID[ID]
+	return d, ok
+}
+
+func (c *Catalog) List() []*Definition {
+	return c.effects
+}
+
+// Restore returns the list of the saved effects still running, effects
+// removed from the catalog are dropped.
+func (c *Catalog) Restore(saved []*models.PlayerEffect, now time.Time) List {
+	var l List
+	for _, s := range saved {
+		d, ok := c.Get(s.EffectID)
+		if !ok || !s.ExpiresAt.After(now) {
+			continue
+		}
+		stacks := s.Stacks
+		if stacks > d.MaxStacks {
+			stacks = d.MaxStacks
+		}
+		l.effects = append(l.effects, &Effect{Definition: d, Stacks: stacks, Remaining: s.ExpiresAt.Sub(now)})
+	}
+	return l
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("status_effects.json", settings.StatusEffectCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open status effect catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load status effect catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tribble/models"
+	"tribble/world"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestStacking(t *testing.T) {
+	var l List
+	source := world.Ref{Kind: world.KindPlayer, ID: 1}
+	poison, _ := Default.Get("poison")
+	crippled, _ := Default.Get("crippled")
+	shout, _ := Default.Get("battle_shout")
+
+	for i := 0; i < poison.MaxStacks+2; i++ {
+		assert.Equal(t, l.Add(poison, source), true)
+	}
+	assert.Equal(t, l.States()[0].Stacks, poison.MaxStacks)
+	assert.Equal(t, l.Stats(), poison.Stats.Scale(poison.MaxStacks))
+
+	assert.Equal(t, l.Add(crippled, source), true)
+	l.Advance(time.Second)
+	assert.Equal(t, l.Add(crippled, source), false)
+	assert.Equal(t, l.States()[1].RemainingMS, crippled.Duration().Milliseconds()-1000)
+
+	assert.Equal(t, l.Add(shout, source), true)
+	l.Advance(time.Second)
+	assert.Equal(t, l.Add(shout, source), true)
+	assert.Equal(t, l.States()[2].Stacks, 1)
+	assert.Equal(t, l.States()[2].RemainingMS, shout.Duration().Milliseconds())
+}
+
+func TestAdvance(t *testing.T) {
+	var l List
+	source := world.Ref{Kind: world.KindPlayer, ID: 1}
+	poison, _ := Default.Get("poison")
+	l.Add(poison, source)
+	l.Add(poison, source)
+
+	ticks, ended := l.Advance(poison.Interval() / 2)
+	assert.Equal(t, len(ticks), 0)
+	assert.Equal(t, ended, false)
+	ticks, _ = l.Advance(poison.Interval() / 2)
+	assert.Equal(t, ticks, []Tick{{Effect: "poison", Source: source, Damage: 2 * poison.Damage}})
+
+	// one tick per interval until the effect ends, the last one included
+	ticks, ended = l.Advance(poison.Duration())
+	assert.Equal(t, len(ticks), int(poison.Duration()/poison.Interval())-1)
+	assert.Equal(t, ended, true)
+	assert.Equal(t, len(l.States()), 0)
+	assert.Equal(t, l.Stats(), models.Stats{})
+}
+
+func TestPersistence(t *testing.T) {
+	var l List
+	now := time.Unix(1000, 0)
+	blessed, _ := Default.Get("blessed")
+	shout, _ := Default.Get("battle_shout")
+	l.Add(blessed, world.Ref{})
+	l.Add(shout, world.Ref{})
+	l.Advance(time.Minute)
+
+	saved := l.Persisted(now)
+	assert.Equal(t, saved, []*models.PlayerEffect{{EffectID: "blessed", Stacks: 1, ExpiresAt: now.Add(blessed.Duration() - time.Minute)}})
+
+	restored := Default.Restore(saved, now.Add(time.Minute))
+	assert.Equal(t, restored.States()[0].RemainingMS, (blessed.Duration() - 2*time.Minute).Milliseconds())
+	assert.Equal(t, restored.Stats(), blessed.Stats)
+	restored = Default.Restore(saved, now.Add(blessed.Duration()))
+	assert.Equal(t, len(restored.States()), 0)
+}
+
+func TestInvalidEffects(t *testing.T) {
+	tests := []string{
+		`{"effects": []}`,
+		`{"effects": [{"id": "a", "type": "curse", "duration_ms": 1000, "stacking": "refresh"}]}`,
+		`{"effects": [{"id": "a", "type": "buff", "duration_ms": 0, "stacking": "refresh"}]}`,
+		`{"effects": [{"id": "a", "type": "buff", "duration_ms": 1000, "stacking": "merge"}]}`,
+		`{"effects": [{"id": "a", "type": "buff", "duration_ms": 1000, "stacking": "stack"}]}`,
+		`{"effects": [{"id": "a", "type": "debuff", "duration_ms": 1000, "stacking": "refresh", "damage": 5}]}`,
+		`{"effects": [{"id": "a", "type": "buff", "duration_ms": 1000, "tick_ms": 100, "stacking": "refresh", "damage": 5}]}`,
+	}
+	for _, test := range tests {
+		if _, err := Load(strings.NewReader(test)); err == nil {
+			t.Errorf("%s FAILED: %v loaded", t.Name(), test)
+		}
+	}
+}
@@ -4,7 +4,24 @@ import "tribble/models"
 
 type DBRepository interface {
 	models.UserRepository
+	models.EntitlementRepository
+	models.FriendRepository
+	models.BlockRepository
 	models.PlayerRepository
+	models.InventoryRepository
+	models.EquipmentRepository
+	models.QuestRepository
+	models.AchievementRepository
+	models.ChatRepository
+	models.GuildRepository
+	models.TradeRepository
+	models.LedgerRepository
+	models.VendorRepository
+	models.MailRepository
+	models.AuctionRepository
+	models.CraftingRepository
+	models.StatusEffectRepository
+	models.LeaderboardRepository
 	models.TokenRepository
 	Close()
 }
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tribble/models"
+)
+
+func (p Postgres) GetAchievements(ctx context.Context, playerID int) ([]*models.PlayerAchievement, error) {
+	sql := `SELECT achievement_id, progress, updated_at, unlocked_at FROM player_achievements WHERE player_id=$1`
+	rows, err := p.DB.Query(ctx, sql, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	achievements := make([]*models.PlayerAchievement, 0)
+	for rows.Next() {
+		var a models.PlayerAchievement
+		if err = rows.Scan(&a.AchievementID, &a.Progress, &a.UpdatedAt, &a.UnlockedAt); err != nil {
+			return nil, err
+		}
+		achievements = append(achievements, &a)
+	}
+	return achievements, rows.Err()
+}
+
+// updateAchievement upserts the progress, first being the progress of a new
+// row and next the one of an existing row a. It reports whether this
+// statement unlocked the achievement, now() being the same for the whole
+// transaction. $3 is the goal and $4 the day of streaks.
+func (p Postgres) updateAchievement(ctx context.Context, first, next string, args ...interface{}) (bool, error) {
+	sql := fmt.Sprintf(`INSERT INTO player_achievements AS a (player_id, achievement_id, progress, last_day, unlocked_at)
+			VALUES ($1, $2, LEAST(%[1]s, $3::int), $4::date, CASE WHEN %[1]s >= $3::int THEN now() END)
+			ON CONFLICT (player_id, achievement_id) DO UPDATE SET
+				progress = LEAST(%[2]s, $3::int),
+				last_day = $4::date,
+				updated_at = now(),
+				unlocked_at = COALESCE(a.unlocked_at, CASE WHEN %[2]s >= $3::int THEN now() END)
+			RETURNING COALESCE(a.unlocked_at = now(), false)`, first, next)
+	var unlocked bool
+	err := p.DB.QueryRow(ctx, sql, args...).Scan(&unlocked)
+	return unlocked, err
+}
+
+func (p Postgres) AddAchievementProgress(ctx context.Context, playerID int, achievementID string, amount, goal int) (bool, error) {
+	return p.updateAchievement(ctx, `$5::int`, `a.progress + $5::int`, playerID, achievementID, goal, nil, amount)
+}
+
+func (p Postgres) RaiseAchievementProgress(ctx context.Context, playerID int, achievementID string, value, goal int) (bool, error) {
+	return p.updateAchievement(ctx, `$5::int`, `GREATEST(a.progress, $5::int)`, playerID, achievementID, goal, nil, value)
+}
+
+func (p Postgres) ExtendAchievementStreak(ctx context.Context, playerID int, achievementID string, day time.Time, goal int) (bool, error) {
+	next := `CASE WHEN a.last_day = $4::date THEN a.progress
+				WHEN a.last_day = $4::date - 1 THEN a.progress + 1
+				ELSE 1 END`
+	return p.updateAchievement(ctx, `1`, next, playerID, achievementID, goal, day.Format("2006-01-02"))
+}
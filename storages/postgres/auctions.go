@@ -0,0 +1,395 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"tribble/auctions"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const auctionHouse = "Auction House"
+
+func auctionAccount(auctionID int64) string {
+	return fmt.Sprintf("auction:%d", auctionID)
+}
+
+const auctionColumns = `id, COALESCE(seller_id, 0), seller_name, item_id, item_type, rarity, quantity,
+		start_bid, buyout, bid, COALESCE(bidder_id, 0), bids, status, created_at, ends_at`
+
+func scanAuction(row pgx.Row) (*models.Auction, error) {
+	var a models.Auction
+	err := row.Scan(&a.ID, &a.SellerID, &a.SellerName, &a.ItemID, &a.ItemType, &a.Rarity, &a.Quantity,
+		&a.StartBid, &a.Buyout, &a.Bid, &a.BidderID, &a.Bids, &a.Status, &a.CreatedAt, &a.EndsAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func itemName(itemID string) string {
+	if item, ok := items.Default.Get(itemID); ok {
+		return item.Name
+	}
+	return itemID
+}
+
+func (p Postgres) GetAuctions(ctx context.Context, query models.AuctionQuery) ([]*models.Auction, error) {
+	sql := `SELECT ` + auctionColumns + ` FROM auctions
+			WHERE status='active' AND ends_at > now()
+			AND ($1 = '' OR item_id=$1) AND ($2 = '' OR item_type=$2) AND ($3 = '' OR rarity=$3)
+			AND ($4 = 0 OR GREATEST(bid, start_bid) >= $4) AND ($5 = 0 OR GREATEST(bid, start_bid) <= $5)
+			AND ($6 = 0 OR seller_id=$6) AND ($7 = 0 OR id < $7)
+			ORDER BY id DESC LIMIT $8`
+	rows, err := p.DB.Query(ctx, sql, query.ItemID, query.Type, query.Rarity, query.MinPrice, query.MaxPrice,
+		query.SellerID, query.Before, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := make([]*models.Auction, 0)
+	for rows.Next() {
+		a, err := scanAuction(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+func (p Postgres) GetAuction(ctx context.Context, auctionID int64) (*models.Auction, error) {
+	return scanAuction(p.DB.QueryRow(ctx, `SELECT `+auctionColumns+` FROM auctions WHERE id=$1`, auctionID))
+}
+
+// lockAuction locks the auction, every change of an auction goes through
+// this lock so concurrent bids are applied one after the other. Auctions
+// past their end are closed even before they are settled.
+func lockAuction(ctx context.Context, tx pgx.Tx, auctionID int64) (*models.Auction, error) {
+	sql := `SELECT ` + auctionColumns + `, ends_at <= now() FROM auctions WHERE id=$1 FOR UPDATE`
+	var a models.Auction
+	var ended bool
+	err := tx.QueryRow(ctx, sql, auctionID).Scan(&a.ID, &a.SellerID, &a.SellerName, &a.ItemID, &a.ItemType, &a.Rarity,
+		&a.Quantity, &a.StartBid, &a.Buyout, &a.Bid, &a.BidderID, &a.Bids, &a.Status, &a.CreatedAt, &a.EndsAt, &ended)
+	if err != nil {
+		return nil, err
+	}
+	if a.Status != models.AuctionActive || ended {
+		return &a, models.ErrAuctionClosed
+	}
+	return &a, nil
+}
+
+func (p Postgres) CreateAuction(ctx context.Context, playerID int, listing models.NewAuction) (*models.Auction, error) {
+	if err := auctions.Validate(listing); err != nil {
+		return nil, err
+	}
+	if !validSlot(listing.Slot) {
+		return nil, models.ErrInvalidSlot
+	}
+	var a *models.Auction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		var active int
+		sql := `SELECT COUNT(*) FROM auctions WHERE seller_id=$1 AND status='active'`
+		if err := tx.QueryRow(ctx, sql, playerID).Scan(&active); err != nil {
+			return err
+		}
+		if active >= settings.MaxAuctions {
+			return models.ErrTooManyAuctions
+		}
+		s, err := getSlot(ctx, tx, playerID, listing.Slot)
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return models.ErrEmptySlot
+		}
+		if !items.Tradable(s.ItemID) {
+			return models.ErrNotTradable
+		}
+		item, _ := items.Default.Get(s.ItemID)
+		if _, err = removeItem(ctx, tx, playerID, listing.Slot, listing.Quantity); err != nil {
+			return err
+		}
+
+		sql = `INSERT INTO auctions (seller_id, seller_name, item_id, item_type, rarity, quantity, start_bid, buyout, ends_at)
+				SELECT id, name, $2, $3, $4, $5, $6, $7, now() + $8::interval FROM players WHERE id=$1
+				RETURNING ` + auctionColumns
+		a, err = scanAuction(tx.QueryRow(ctx, sql, playerID, item.ID, item.Type, item.Rarity, listing.Quantity,
+			listing.StartBid, listing.Buyout, auctions.Duration(listing)))
+		return err
+	})
+	return a, err
+}
+
+// returnItems mails the items of the auction back to its seller, they are
+// lost when the seller was deleted.
+func returnItems(ctx context.Context, tx pgx.Tx, a *models.Auction, subject string) error {
+	if a.SellerID == 0 {
+		return nil
+	}
+	_, err := sendMail(ctx, tx, a.SellerID, &models.Mail{
+		SenderName: auctionHouse,
+		Subject:    fmt.Sprintf("%v: %v", subject, itemName(a.ItemID)),
+		ItemID:     a.ItemID,
+		Quantity:   a.Quantity,
+	}, "")
+	return err
+}
+
+func closeAuction(ctx context.Context, tx pgx.Tx, a *models.Auction, status string) error {
+	a.Status = status
+	_, err := tx.Exec(ctx, `UPDATE auctions SET status=$2, settled_at=now() WHERE id=$1`, a.ID, status)
+	return err
+}
+
+func (p Postgres) CancelAuction(ctx context.Context, playerID int, auctionID int64) (*models.Auction, error) {
+	var a *models.Auction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		if a, err = lockAuction(ctx, tx, auctionID); err != nil {
+			return err
+		}
+		if a.SellerID != playerID {
+			return pgx.ErrNoRows
+		}
+		if a.Bids > 0 {
+			return models.ErrAuctionHasBids
+		}
+		if err = returnItems(ctx, tx, a, "Auction cancelled"); err != nil {
+			return err
+		}
+		return closeAuction(ctx, tx, a, models.AuctionCancelled)
+	})
+	return a, err
+}
+
+// refund mails the escrowed bid back to the highest bidder, or takes it
+// out of the economy when the bidder was deleted.
+func refund(ctx context.Context, tx pgx.Tx, a *models.Auction, escrow int64) error {
+	to, err := openAccount(ctx, tx, sinkAccount)
+	if err != nil {
+		return err
+	}
+	if a.BidderID != 0 {
+		to, err = sendMail(ctx, tx, a.BidderID, &models.Mail{
+			SenderName: auctionHouse,
+			Subject:    fmt.Sprintf("Outbid: %v", itemName(a.ItemID)),
+			Body:       fmt.Sprintf("You were outbid on auction %d, your bid of %d is returned.", a.ID, a.Bid),
+			Currency:   a.Bid,
+		}, "")
+		if err != nil {
+			return err
+		}
+	}
+	key := fmt.Sprintf("auction:%d:refund:%d", a.ID, a.Bids)
+	_, err = transfer(ctx, tx, escrow, to, a.Bid, models.ReasonAuctionRefund, key)
+	return err
+}
+
+// settle mails the items to the highest bidder and the bid, less the fee,
+// to the seller. Auctions nobody bid on return the items to the seller.
+func settle(ctx context.Context, tx pgx.Tx, a *models.Auction) error {
+	if a.Bid == 0 {
+		if err := returnItems(ctx, tx, a, "Auction expired"); err != nil {
+			return err
+		}
+		return closeAuction(ctx, tx, a, models.AuctionExpired)
+	}
+
+	if a.BidderID != 0 {
+		_, err := sendMail(ctx, tx, a.BidderID, &models.Mail{
+			SenderName: auctionHouse,
+			Subject:    fmt.Sprintf("Auction won: %v", itemName(a.ItemID)),
+			Body:       fmt.Sprintf("You won auction %d for %d.", a.ID, a.Bid),
+			ItemID:     a.ItemID,
+			Quantity:   a.Quantity,
+		}, "")
+		if err != nil {
+			return err
+		}
+	} else if err := returnItems(ctx, tx, a, "Auction expired"); err != nil {
+		return err
+	}
+
+	escrow, err := openAccount(ctx, tx, auctionAccount(a.ID))
+	if err != nil {
+		return err
+	}
+	sink, err := openAccount(ctx, tx, sinkAccount)
+	if err != nil {
+		return err
+	}
+	fee := auctions.Fee(a.Bid)
+	postings := []posting{{escrow, -a.Bid}}
+	if a.SellerID != 0 && a.Bid > fee {
+		seller, err := sendMail(ctx, tx, a.SellerID, &models.Mail{
+			SenderName: auctionHouse,
+			Subject:    fmt.Sprintf("Auction sold: %v", itemName(a.ItemID)),
+			Body:       fmt.Sprintf("Auction %d sold for %d, the auction house kept %d.", a.ID, a.Bid, fee),
+			Currency:   a.Bid - fee,
+		}, "")
+		if err != nil {
+			return err
+		}
+		postings = append(postings, posting{seller, a.Bid - fee})
+	} else {
+		fee = a.Bid
+	}
+	if fee > 0 {
+		postings = append(postings, posting{sink, fee})
+	}
+	if _, err = post(ctx, tx, models.ReasonAuctionSale, fmt.Sprintf("auction:%d:sale", a.ID), postings); err != nil {
+		return err
+	}
+	return closeAuction(ctx, tx, a, models.AuctionSold)
+}
+
+// bidReplayed tells whether the key was used, failing with
+// ErrIdempotencyConflict unless it was by the same bid.
+func bidReplayed(ctx context.Context, tx pgx.Tx, key string, auctionID int64, playerID int, amount int64, buyout bool) (bool, error) {
+	used, err := keyUsed(ctx, tx, key)
+	if err != nil || !used {
+		return false, err
+	}
+	var same bool
+	sql := `SELECT COALESCE(auction_id=$2 AND bidder_id=$3 AND asked=$4 AND buyout=$5, false)
+			FROM auction_bids WHERE idempotency_key=$1`
+	err = tx.QueryRow(ctx, sql, key, auctionID, playerID, amount, buyout).Scan(&same)
+	if err == pgx.ErrNoRows {
+		// used by something other than a bid
+		return true, models.ErrIdempotencyConflict
+	}
+	if err != nil {
+		return true, err
+	}
+	if !same {
+		return true, models.ErrIdempotencyConflict
+	}
+	return true, nil
+}
+
+// bid escrows the bid of the player, refunds the previous highest bidder
+// and settles the auction right away when the bid reaches the buyout. A
+// key already used by the same bid returns the auction as it is now, so
+// keys must be scoped to the auction.
+func (p Postgres) bid(ctx context.Context, playerID int, auctionID int64, amount int64, buyout bool, key string) (*models.Auction, error) {
+	asked, askedBuyout := amount, buyout
+	var a *models.Auction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var closed error
+		a, closed = lockAuction(ctx, tx, auctionID)
+		if closed != nil && closed != models.ErrAuctionClosed {
+			return closed
+		}
+		// a retried bid replays even when it closed the auction
+		replayed, err := bidReplayed(ctx, tx, key, auctionID, playerID, asked, askedBuyout)
+		if err != nil || replayed {
+			return err
+		}
+		if closed != nil {
+			return closed
+		}
+
+		if buyout {
+			if a.Buyout == 0 {
+				return models.ErrNoBuyout
+			}
+			amount = a.Buyout
+		}
+		if amount, buyout, err = auctions.Bid(a, playerID, amount); err != nil {
+			return err
+		}
+		player, err := openPlayerAccount(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		escrow, err := openAccount(ctx, tx, auctionAccount(a.ID))
+		if err != nil {
+			return err
+		}
+		if _, err = transfer(ctx, tx, player, escrow, amount, models.ReasonAuctionBid, key); err != nil {
+			return err
+		}
+		if a.Bid > 0 {
+			if err = refund(ctx, tx, a, escrow); err != nil {
+				return err
+			}
+		}
+
+		sql := `INSERT INTO auction_bids (auction_id, bidder_id, amount, idempotency_key, asked, buyout)
+				VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err = tx.Exec(ctx, sql, a.ID, playerID, amount, key, asked, askedBuyout); err != nil {
+			return err
+		}
+		a.Bid, a.BidderID, a.Bids = amount, playerID, a.Bids+1
+		sql = `UPDATE auctions SET bid=$2, bidder_id=$3, bids=$4 WHERE id=$1`
+		if _, err = tx.Exec(ctx, sql, a.ID, a.Bid, a.BidderID, a.Bids); err != nil {
+			return err
+		}
+		if buyout {
+			return settle(ctx, tx, a)
+		}
+		return nil
+	})
+	return a, err
+}
+
+func (p Postgres) BidOnAuction(ctx context.Context, playerID int, auctionID int64, amount int64, key string) (*models.Auction, error) {
+	return p.bid(ctx, playerID, auctionID, amount, false, key)
+}
+
+func (p Postgres) BuyoutAuction(ctx context.Context, playerID int, auctionID int64, key string) (*models.Auction, error) {
+	return p.bid(ctx, playerID, auctionID, 0, true, key)
+}
+
+// SettleAuctions skips the auctions locked by bids or other workers, they
+// are settled on a later run. Each auction is settled in a savepoint, one
+// that fails is logged and retried on the next run without holding back
+// the others.
+func (p Postgres) SettleAuctions(ctx context.Context, limit int) (int, error) {
+	settled := 0
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `SELECT ` + auctionColumns + ` FROM auctions
+				WHERE status='active' AND ends_at <= now()
+				ORDER BY ends_at LIMIT $1 FOR UPDATE SKIP LOCKED`
+		rows, err := tx.Query(ctx, sql, limit)
+		if err != nil {
+			return err
+		}
+		expired := make([]*models.Auction, 0)
+		for rows.Next() {
+			a, err := scanAuction(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			expired = append(expired, a)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for _, a := range expired {
+			err = tx.BeginFunc(ctx, func(savepoint pgx.Tx) error {
+				return settle(ctx, savepoint, a)
+			})
+			if err != nil {
+				log.Printf("could not settle auction %v: %v", a.ID, err.Error())
+				continue
+			}
+			settled++
+		}
+		return nil
+	})
+	return settled, err
+}
@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestBidReplay(t *testing.T) {
+	ctx := context.Background()
+	seller, bidder := createTestPlayer(t), createTestPlayer(t)
+	addTestItem(t, seller.ID, "iron_sword", 1)
+	grantTestCurrency(t, bidder.ID, 100)
+	a, err := pg.CreateAuction(ctx, seller.ID, models.NewAuction{Slot: 0, Quantity: 1, StartBid: 10, Buyout: 50, Hours: 12})
+	assert.Equal(t, err, nil)
+	key := fmt.Sprintf("test:auction:%d:%d", a.ID, bidder.ID)
+
+	_, err = pg.BidOnAuction(ctx, bidder.ID, a.ID, 20, key)
+	assert.Equal(t, err, nil)
+	replayed, err := pg.BidOnAuction(ctx, bidder.ID, a.ID, 20, key)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, replayed.Bid, int64(20))
+	assert.Equal(t, replayed.Bids, 1)
+
+	_, err = pg.BidOnAuction(ctx, bidder.ID, a.ID, 30, key)
+	assert.Equal(t, err, models.ErrIdempotencyConflict)
+	_, err = pg.BuyoutAuction(ctx, bidder.ID, a.ID, key)
+	assert.Equal(t, err, models.ErrIdempotencyConflict)
+
+	balance, err := pg.GetBalance(ctx, bidder.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, int64(80))
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const chatColumns = `id, channel, sender_id, sender_name, recipient_id, text, sent_at`
+
+func scanChatMessage(row pgx.Row, m *models.ChatMessage) error {
+	return row.Scan(&m.ID, &m.Channel, &m.SenderID, &m.SenderName, &m.RecipientID, &m.Text, &m.SentAt)
+}
+
+// SaveChatMessage stores the message and trims the channel history to the
+// most recent messages.
+func (p Postgres) SaveChatMessage(ctx context.Context, message models.ChatMessage) (*models.ChatMessage, error) {
+	var saved models.ChatMessage
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `INSERT INTO chat_messages (channel, sender_id, sender_name, recipient_id, text)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING ` + chatColumns
+		row := tx.QueryRow(ctx, sql, message.Channel, message.SenderID, message.SenderName, message.RecipientID, message.Text)
+		if err := scanChatMessage(row, &saved); err != nil {
+			return err
+		}
+
+		sql = `DELETE FROM chat_messages WHERE channel=$1 AND id <= (
+					SELECT id FROM chat_messages WHERE channel=$1 ORDER BY id DESC OFFSET $2 LIMIT 1)`
+		_, err := tx.Exec(ctx, sql, message.Channel, settings.ChatHistorySize)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &saved, nil
+}
+
+func (p Postgres) GetChatMessages(ctx context.Context, channel string, before int64, limit int) ([]*models.ChatMessage, error) {
+	sql := `SELECT ` + chatColumns + ` FROM chat_messages
+			WHERE channel=$1 AND ($2 = 0 OR id < $2)
+			ORDER BY id DESC LIMIT $3`
+	rows, err := p.DB.Query(ctx, sql, channel, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]*models.ChatMessage, 0)
+	for rows.Next() {
+		var m models.ChatMessage
+		if err = scanChatMessage(rows, &m); err != nil {
+			return nil, err
+		}
+		messages = append(messages, &m)
+	}
+	return messages, rows.Err()
+}
+
+func (p Postgres) GetChatMute(ctx context.Context, userID int) (*models.ChatMute, error) {
+	var mute models.ChatMute
+	sql := `SELECT user_id, until, reason FROM chat_mutes WHERE user_id=$1`
+	err := p.DB.QueryRow(ctx, sql, userID).Scan(&mute.UserID, &mute.Until, &mute.Reason)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+func (p Postgres) MuteUser(ctx context.Context, mute models.ChatMute) error {
+	sql := `INSERT INTO chat_mutes (user_id, until, reason) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id) DO UPDATE SET until=$2, reason=$3, created_at=now()`
+	_, err := p.DB.Exec(ctx, sql, mute.UserID, mute.Until, mute.Reason)
+	return err
+}
+
+func (p Postgres) UnmuteUser(ctx context.Context, userID int) error {
+	tag, err := p.DB.Exec(ctx, `DELETE FROM chat_mutes WHERE user_id=$1`, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const craftColumns = `id, player_id, recipe_id, profession, item_id, quantity, xp, status, started_at, finishes_at, collected_at`
+
+func scanCraft(row pgx.Row) (*models.Craft, error) {
+	var c models.Craft
+	err := row.Scan(&c.ID, &c.PlayerID, &c.RecipeID, &c.Profession, &c.ItemID, &c.Quantity, &c.XP,
+		&c.Status, &c.StartedAt, &c.FinishesAt, &c.CollectedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func scanCrafts(rows pgx.Rows) ([]*models.Craft, error) {
+	defer rows.Close()
+	crafts := make([]*models.Craft, 0)
+	for rows.Next() {
+		c, err := scanCraft(rows)
+		if err != nil {
+			return nil, err
+		}
+		crafts = append(crafts, c)
+	}
+	return crafts, rows.Err()
+}
+
+func addProfessionXP(ctx context.Context, tx pgx.Tx, playerID int, profession string, xp int) error {
+	sql := `INSERT INTO player_professions (player_id, profession, xp) VALUES ($1, $2, $3)
+			ON CONFLICT (player_id, profession) DO UPDATE SET xp = player_professions.xp + EXCLUDED.xp`
+	_, err := tx.Exec(ctx, sql, playerID, profession, xp)
+	return err
+}
+
+// collect gives the output of the craft to the player.
+func collect(ctx context.Context, tx pgx.Tx, craft *models.Craft) (*models.Craft, error) {
+	item, ok := items.Default.Get(craft.ItemID)
+	if !ok {
+		return nil, fmt.Errorf("unknown item %v", craft.ItemID)
+	}
+	if err := addItem(ctx, tx, craft.PlayerID, *item, craft.Quantity); err != nil {
+		return nil, err
+	}
+	sql := `UPDATE crafts SET status=$2, collected_at=now() WHERE id=$1 RETURNING ` + craftColumns
+	return scanCraft(tx.QueryRow(ctx, sql, craft.ID, models.CraftCollected))
+}
+
+func (p Postgres) GetProfessions(ctx context.Context, playerID int) ([]*models.Profession, error) {
+	rows, err := p.DB.Query(ctx, `SELECT profession, xp FROM player_professions WHERE player_id=$1 ORDER BY profession`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	professions := make([]*models.Profession, 0)
+	for rows.Next() {
+		var prof models.Profession
+		if err = rows.Scan(&prof.Profession, &prof.XP); err != nil {
+			return nil, err
+		}
+		professions = append(professions, &prof)
+	}
+	return professions, rows.Err()
+}
+
+func (p Postgres) GetCrafts(ctx context.Context, playerID int) ([]*models.Craft, error) {
+	sql := `SELECT ` + craftColumns + ` FROM crafts WHERE player_id=$1 AND status<>$2 ORDER BY id`
+	rows, err := p.DB.Query(ctx, sql, playerID, models.CraftCollected)
+	if err != nil {
+		return nil, err
+	}
+	return scanCrafts(rows)
+}
+
+// StartCraft takes the ingredients under the player lock, crafts not
+// collected yet count against MaxCrafts.
+func (p Postgres) StartCraft(ctx context.Context, playerID int, order models.CraftOrder) (*models.Craft, error) {
+	var craft *models.Craft
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		var xp int
+		err := tx.QueryRow(ctx, `SELECT xp FROM player_professions WHERE player_id=$1 AND profession=$2`,
+			playerID, order.Profession).Scan(&xp)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+		if xp < order.MinXP {
+			return models.ErrProfessionTooLow
+		}
+		if order.Duration > 0 {
+			var active int
+			err = tx.QueryRow(ctx, `SELECT count(*) FROM crafts WHERE player_id=$1 AND status<>$2`,
+				playerID, models.CraftCollected).Scan(&active)
+			if err != nil {
+				return err
+			}
+			if active >= settings.MaxCrafts {
+				return models.ErrTooManyCrafts
+			}
+		}
+
+		for _, stack := range order.Ingredients {
+			if err = takeItems(ctx, tx, playerID, stack.ItemID, stack.Quantity); err != nil {
+				return err
+			}
+		}
+		sql := `INSERT INTO crafts (player_id, recipe_id, profession, item_id, quantity, xp, finishes_at)
+				VALUES ($1, $2, $3, $4, $5, $6, now() + $7 * interval '1 second')
+				RETURNING ` + craftColumns
+		craft, err = scanCraft(tx.QueryRow(ctx, sql, playerID, order.RecipeID, order.Profession,
+			order.Output.ItemID, order.Output.Quantity, order.XP, int(order.Duration.Seconds())))
+		if err != nil || order.Duration > 0 {
+			return err
+		}
+
+		if err = addProfessionXP(ctx, tx, playerID, order.Profession, order.XP); err != nil {
+			return err
+		}
+		craft, err = collect(ctx, tx, craft)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return craft, nil
+}
+
+// FinishCrafts skips the crafts locked by a collect, the collect finishes
+// them itself.
+func (p Postgres) FinishCrafts(ctx context.Context, limit int) ([]*models.Craft, error) {
+	var crafts []*models.Craft
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `UPDATE crafts SET status=$1 WHERE id IN (
+					SELECT id FROM crafts WHERE status=$2 AND finishes_at <= now()
+					ORDER BY finishes_at LIMIT $3 FOR UPDATE SKIP LOCKED)
+				RETURNING ` + craftColumns
+		rows, err := tx.Query(ctx, sql, models.CraftReady, models.CraftActive, limit)
+		if err != nil {
+			return err
+		}
+		if crafts, err = scanCrafts(rows); err != nil {
+			return err
+		}
+
+		for _, c := range crafts {
+			if err = addProfessionXP(ctx, tx, c.PlayerID, c.Profession, c.XP); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return crafts, nil
+}
+
+// CollectCraft finishes crafts past their time the finish job didn't get
+// to yet.
+func (p Postgres) CollectCraft(ctx context.Context, playerID int, craftID int64) (*models.Craft, error) {
+	var craft *models.Craft
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		sql := `SELECT ` + craftColumns + `, finishes_at <= now() FROM crafts
+				WHERE id=$1 AND player_id=$2 AND status<>$3 FOR UPDATE`
+		var c models.Craft
+		var due bool
+		err := tx.QueryRow(ctx, sql, craftID, playerID, models.CraftCollected).Scan(&c.ID, &c.PlayerID,
+			&c.RecipeID, &c.Profession, &c.ItemID, &c.Quantity, &c.XP, &c.Status, &c.StartedAt,
+			&c.FinishesAt, &c.CollectedAt, &due)
+		if err != nil {
+			return err
+		}
+		if c.Status == models.CraftActive {
+			if !due {
+				return models.ErrCraftNotReady
+			}
+			if err = addProfessionXP(ctx, tx, playerID, c.Profession, c.XP); err != nil {
+				return err
+			}
+		}
+		craft, err = collect(ctx, tx, &c)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return craft, nil
+}
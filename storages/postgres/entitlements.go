@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+	"tribble/settings"
+)
+
+func (p Postgres) GetEntitlements(ctx context.Context, userID int) ([]*models.Entitlement, error) {
+	sql := `SELECT id, kind, quantity, reason, granted_at FROM user_entitlements
+			WHERE user_id=$1 ORDER BY granted_at, id`
+	rows, err := p.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entitlements := make([]*models.Entitlement, 0)
+	for rows.Next() {
+		var e models.Entitlement
+		if err = rows.Scan(&e.ID, &e.Kind, &e.Quantity, &e.Reason, &e.GrantedAt); err != nil {
+			return nil, err
+		}
+		entitlements = append(entitlements, &e)
+	}
+	return entitlements, rows.Err()
+}
+
+func (p Postgres) GrantEntitlement(ctx context.Context, userID int, entitlement models.Entitlement) (*models.Entitlement, error) {
+	sql := `INSERT INTO user_entitlements (user_id, kind, quantity, reason)
+			VALUES ($1, $2, $3, $4) RETURNING id, granted_at`
+	err := p.DB.QueryRow(ctx, sql, userID, entitlement.Kind, entitlement.Quantity, entitlement.Reason).
+		Scan(&entitlement.ID, &entitlement.GrantedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &entitlement, nil
+}
+
+// characterSlots counts the characters of the user against the default
+// slots plus the ones granted as entitlements.
+func characterSlots(ctx context.Context, q queryRower, userID int) (*models.CharacterSlots, error) {
+	sql := `SELECT
+				(SELECT COUNT(*) FROM players WHERE user_id=$1),
+				(SELECT COALESCE(SUM(quantity), 0) FROM user_entitlements WHERE user_id=$1 AND kind=$2)`
+	var extra int
+	slots := models.CharacterSlots{}
+	if err := q.QueryRow(ctx, sql, userID, models.EntitlementCharacterSlot).Scan(&slots.Used, &extra); err != nil {
+		return nil, err
+	}
+	slots.Max = settings.CharacterSlots + extra
+	return &slots, nil
+}
+
+func (p Postgres) GetCharacterSlots(ctx context.Context, userID int) (*models.CharacterSlots, error) {
+	return characterSlots(ctx, p.DB, userID)
+}
@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"tribble/items"
+	"tribble/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func getEquipment(ctx context.Context, q querier, playerID int) ([]*models.EquippedItem, error) {
+	sql := `SELECT slot, item_id FROM equipment WHERE player_id=$1 ORDER BY slot`
+	rows, err := q.Query(ctx, sql, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	equipment := make([]*models.EquippedItem, 0)
+	for rows.Next() {
+		var e models.EquippedItem
+		if err = rows.Scan(&e.Slot, &e.ItemID); err != nil {
+			return nil, err
+		}
+		equipment = append(equipment, &e)
+	}
+	return equipment, rows.Err()
+}
+
+// unequip moves the item in the equipment slot back to the inventory.
+func unequip(ctx context.Context, tx pgx.Tx, playerID int, slot string) error {
+	var itemID string
+	sql := `DELETE FROM equipment WHERE player_id=$1 AND slot=$2 RETURNING item_id`
+	err := tx.QueryRow(ctx, sql, playerID, slot).Scan(&itemID)
+	if err == pgx.ErrNoRows {
+		return models.ErrNotEquipped
+	}
+	if err != nil {
+		return err
+	}
+	item, ok := items.Default.Get(itemID)
+	if !ok {
+		return fmt.Errorf("unknown item %v", itemID)
+	}
+	return addItem(ctx, tx, playerID, *item, 1)
+}
+
+func (p Postgres) GetEquipment(ctx context.Context, playerID int) ([]*models.EquippedItem, error) {
+	return getEquipment(ctx, p.DB, playerID)
+}
+
+// EquipItem takes the item out of the inventory slot and equips it, the
+// item previously in the same equipment slot goes back to the inventory.
+func (p Postgres) EquipItem(ctx context.Context, playerID int, inventorySlot int, item models.Item) ([]*models.EquippedItem, error) {
+	if !validSlot(inventorySlot) {
+		return nil, models.ErrInvalidSlot
+	}
+	var equipment []*models.EquippedItem
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		removed, err := removeItem(ctx, tx, playerID, inventorySlot, 1)
+		if err != nil {
+			return err
+		}
+		if removed.ItemID != item.ID {
+			return models.ErrItemChanged
+		}
+		if err = unequip(ctx, tx, playerID, item.Slot); err != nil && err != models.ErrNotEquipped {
+			return err
+		}
+
+		sql := `INSERT INTO equipment (player_id, slot, item_id) VALUES ($1, $2, $3)`
+		if _, err = tx.Exec(ctx, sql, playerID, item.Slot, item.ID); err != nil {
+			return err
+		}
+		equipment, err = getEquipment(ctx, tx, playerID)
+		return err
+	})
+	return equipment, err
+}
+
+func (p Postgres) UnequipItem(ctx context.Context, playerID int, slot string) ([]*models.EquippedItem, error) {
+	var equipment []*models.EquippedItem
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		if err := unequip(ctx, tx, playerID, slot); err != nil {
+			return err
+		}
+		var err error
+		equipment, err = getEquipment(ctx, tx, playerID)
+		return err
+	})
+	return equipment, err
+}
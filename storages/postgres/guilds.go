@@ -0,0 +1,692 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type guildQuerier interface {
+	querier
+	queryRower
+}
+
+func getGuild(ctx context.Context, q guildQuerier, ID int) (*models.Guild, error) {
+	sql := `SELECT id, name, motd, created_at,
+				(SELECT count(*) FROM guild_members WHERE guild_id=$1)
+			FROM guilds WHERE id=$1`
+	var guild models.Guild
+	if err := q.QueryRow(ctx, sql, ID).Scan(&guild.ID, &guild.Name, &guild.MOTD, &guild.CreatedAt, &guild.Members); err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, `SELECT rank, name, permissions FROM guild_ranks WHERE guild_id=$1 ORDER BY rank`, ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	guild.Ranks = make([]models.GuildRank, 0)
+	for rows.Next() {
+		var rank models.GuildRank
+		if err = rows.Scan(&rank.Rank, &rank.Name, &rank.Permissions); err != nil {
+			return nil, err
+		}
+		guild.Ranks = append(guild.Ranks, rank)
+	}
+	return &guild, rows.Err()
+}
+
+const guildMemberSelect = `SELECT ` + playerColumns + `, guild_id, rank, joined_at
+		FROM players JOIN guild_members ON player_id = id`
+
+func scanGuildMember(row pgx.Row, member *models.GuildMember) error {
+	return row.Scan(append(playerFields(&member.Player), &member.GuildID, &member.Rank, &member.JoinedAt)...)
+}
+
+// getGuildMember returns nil when the player is not in a guild.
+func getGuildMember(ctx context.Context, q queryRower, playerID int) (*models.GuildMember, error) {
+	var member models.GuildMember
+	err := scanGuildMember(q.QueryRow(ctx, guildMemberSelect+` WHERE player_id=$1`, playerID), &member)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func countGuildMembers(ctx context.Context, tx pgx.Tx, guildID int) (int, error) {
+	var n int
+	err := tx.QueryRow(ctx, `SELECT count(*) FROM guild_members WHERE guild_id=$1`, guildID).Scan(&n)
+	return n, err
+}
+
+func lockGuild(ctx context.Context, tx pgx.Tx, guildID int) error {
+	var id int
+	return tx.QueryRow(ctx, `SELECT id FROM guilds WHERE id=$1 FOR UPDATE`, guildID).Scan(&id)
+}
+
+// lockMembership locks the guild of the player and returns it with the
+// rank the player holds. Membership, rank and bank changes of a guild run
+// one at a time, locking the guild before any player.
+func lockMembership(ctx context.Context, tx pgx.Tx, playerID int) (int, models.GuildRank, error) {
+	var guildID int
+	var rank models.GuildRank
+	err := tx.QueryRow(ctx, `SELECT guild_id FROM guild_members WHERE player_id=$1`, playerID).Scan(&guildID)
+	if err == pgx.ErrNoRows {
+		return 0, rank, models.ErrNotInGuild
+	}
+	if err != nil {
+		return 0, rank, err
+	}
+	if err = lockGuild(ctx, tx, guildID); err != nil {
+		return 0, rank, err
+	}
+
+	// the player may have left while waiting for the lock
+	sql := `SELECT r.rank, r.name, r.permissions
+			FROM guild_members m JOIN guild_ranks r ON r.guild_id = m.guild_id AND r.rank = m.rank
+			WHERE m.player_id=$1 AND m.guild_id=$2`
+	err = tx.QueryRow(ctx, sql, playerID, guildID).Scan(&rank.Rank, &rank.Name, &rank.Permissions)
+	if err == pgx.ErrNoRows {
+		return 0, rank, models.ErrNotInGuild
+	}
+	return guildID, rank, err
+}
+
+// lockTarget returns the member of the guild the actor manages.
+func lockTarget(ctx context.Context, tx pgx.Tx, guildID int, actor models.GuildRank, playerID int) (*models.GuildMember, error) {
+	target, err := getGuildMember(ctx, tx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil || target.GuildID != guildID {
+		return nil, models.ErrNotGuildMember
+	}
+	if target.Rank <= actor.Rank {
+		return nil, models.ErrGuildRankTooLow
+	}
+	return target, nil
+}
+
+func insertGuildRanks(ctx context.Context, tx pgx.Tx, guildID int, ranks []models.GuildRank) error {
+	for i, rank := range ranks {
+		permissions := rank.Permissions
+		if permissions == nil {
+			permissions = []string{}
+		}
+		sql := `INSERT INTO guild_ranks (guild_id, rank, name, permissions) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(ctx, sql, guildID, i, rank.Name, permissions); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p Postgres) GetGuild(ctx context.Context, ID int) (*models.Guild, error) {
+	return getGuild(ctx, p.DB, ID)
+}
+
+func (p Postgres) GetPlayerGuild(ctx context.Context, playerID int) (*models.Guild, *models.GuildMember, error) {
+	member, err := getGuildMember(ctx, p.DB, playerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member == nil {
+		return nil, nil, models.ErrNotInGuild
+	}
+	guild, err := getGuild(ctx, p.DB, member.GuildID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return guild, member, nil
+}
+
+func (p Postgres) GetGuildMembers(ctx context.Context, guildID int) ([]*models.GuildMember, error) {
+	rows, err := p.DB.Query(ctx, guildMemberSelect+` WHERE guild_id=$1 ORDER BY rank, joined_at`, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make([]*models.GuildMember, 0)
+	for rows.Next() {
+		var m models.GuildMember
+		if err = scanGuildMember(rows, &m); err != nil {
+			return nil, err
+		}
+		members = append(members, &m)
+	}
+	return members, rows.Err()
+}
+
+func (p Postgres) CreateGuild(ctx context.Context, playerID int, name string, ranks []models.GuildRank) (*models.Guild, error) {
+	var guild *models.Guild
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		member, err := getGuildMember(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if member != nil {
+			return models.ErrAlreadyInGuild
+		}
+
+		var guildID int
+		if err = tx.QueryRow(ctx, `INSERT INTO guilds (name) VALUES ($1) RETURNING id`, name).Scan(&guildID); err != nil {
+			return err
+		}
+		if err = insertGuildRanks(ctx, tx, guildID, ranks); err != nil {
+			return err
+		}
+		sql := `INSERT INTO guild_members (player_id, guild_id, rank) VALUES ($1, $2, 0)`
+		if _, err = tx.Exec(ctx, sql, playerID, guildID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM guild_invites WHERE player_id=$1`, playerID); err != nil {
+			return err
+		}
+		guild, err = getGuild(ctx, tx, guildID)
+		return err
+	})
+	return guild, err
+}
+
+func (p Postgres) LeaveGuild(ctx context.Context, playerID int) (*models.GuildMember, error) {
+	var member *models.GuildMember
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if member, err = getGuildMember(ctx, tx, playerID); err != nil {
+			return err
+		}
+		if rank.Rank != 0 {
+			_, err = tx.Exec(ctx, `DELETE FROM guild_members WHERE player_id=$1`, playerID)
+			return err
+		}
+
+		n, err := countGuildMembers(ctx, tx, guildID)
+		if err != nil {
+			return err
+		}
+		if n > 1 {
+			return models.ErrGuildMaster
+		}
+		// the bank and the currency of a disbanded guild would be lost
+		var items bool
+		if err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM guild_bank_items WHERE guild_id=$1)`, guildID).Scan(&items); err != nil {
+			return err
+		}
+		amount, err := balance(ctx, tx, guildAccount(guildID))
+		if err != nil {
+			return err
+		}
+		if items || amount != 0 {
+			return models.ErrGuildNotEmpty
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM guilds WHERE id=$1`, guildID)
+		return err
+	})
+	return member, err
+}
+
+func (p Postgres) KickGuildMember(ctx context.Context, actorID, playerID int) (*models.GuildMember, error) {
+	if actorID == playerID {
+		return nil, models.ErrSelfReference
+	}
+	var target *models.GuildMember
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, actorID)
+		if err != nil {
+			return err
+		}
+		if !rank.Can(models.GuildKick) {
+			return models.ErrGuildPermission
+		}
+		if target, err = lockTarget(ctx, tx, guildID, rank, playerID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM guild_members WHERE player_id=$1`, playerID)
+		return err
+	})
+	return target, err
+}
+
+func (p Postgres) SetGuildMemberRank(ctx context.Context, actorID, playerID, newRank int) (*models.GuildMember, error) {
+	if actorID == playerID {
+		return nil, models.ErrSelfReference
+	}
+	var target *models.GuildMember
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, actorID)
+		if err != nil {
+			return err
+		}
+		if !rank.Can(models.GuildPromote) {
+			return models.ErrGuildPermission
+		}
+		if target, err = lockTarget(ctx, tx, guildID, rank, playerID); err != nil {
+			return err
+		}
+
+		var exists bool
+		sql := `SELECT EXISTS (SELECT 1 FROM guild_ranks WHERE guild_id=$1 AND rank=$2)`
+		if err = tx.QueryRow(ctx, sql, guildID, newRank).Scan(&exists); err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrInvalidGuildRank
+		}
+		if newRank <= rank.Rank && rank.Rank != 0 {
+			return models.ErrGuildRankTooLow
+		}
+		if newRank == 0 {
+			sql = `UPDATE guild_members SET rank=1 WHERE player_id=$1`
+			if _, err = tx.Exec(ctx, sql, actorID); err != nil {
+				return err
+			}
+		}
+		if _, err = tx.Exec(ctx, `UPDATE guild_members SET rank=$2 WHERE player_id=$1`, playerID, newRank); err != nil {
+			return err
+		}
+		target.Rank = newRank
+		return nil
+	})
+	return target, err
+}
+
+// SetGuildRanks replaces the ranks of the guild, members of ranks that no
+// longer exist take the last rank.
+func (p Postgres) SetGuildRanks(ctx context.Context, actorID int, ranks []models.GuildRank) (*models.Guild, error) {
+	if len(ranks) < 2 || len(ranks) > settings.MaxGuildRanks {
+		return nil, models.ErrInvalidGuildRank
+	}
+	var guild *models.Guild
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, actorID)
+		if err != nil {
+			return err
+		}
+		if rank.Rank != 0 {
+			return models.ErrGuildPermission
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM guild_ranks WHERE guild_id=$1`, guildID); err != nil {
+			return err
+		}
+		if err = insertGuildRanks(ctx, tx, guildID, ranks); err != nil {
+			return err
+		}
+		sql := `UPDATE guild_members SET rank=$2 WHERE guild_id=$1 AND rank > $2`
+		if _, err = tx.Exec(ctx, sql, guildID, len(ranks)-1); err != nil {
+			return err
+		}
+		guild, err = getGuild(ctx, tx, guildID)
+		return err
+	})
+	return guild, err
+}
+
+func (p Postgres) SetGuildMOTD(ctx context.Context, actorID int, motd string) (*models.Guild, error) {
+	var guild *models.Guild
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, actorID)
+		if err != nil {
+			return err
+		}
+		if !rank.Can(models.GuildMOTD) {
+			return models.ErrGuildPermission
+		}
+		if _, err = tx.Exec(ctx, `UPDATE guilds SET motd=$2 WHERE id=$1`, guildID, motd); err != nil {
+			return err
+		}
+		guild, err = getGuild(ctx, tx, guildID)
+		return err
+	})
+	return guild, err
+}
+
+const guildInvitationSelect = `SELECT i.guild_id, g.name, i.player_id, i.inviter_id, p.name, i.created_at
+		FROM guild_invites i
+		JOIN guilds g ON g.id = i.guild_id
+		JOIN players p ON p.id = i.inviter_id`
+
+func scanGuildInvitation(row pgx.Row, i *models.GuildInvitation) error {
+	return row.Scan(&i.GuildID, &i.GuildName, &i.PlayerID, &i.InviterID, &i.InviterName, &i.CreatedAt)
+}
+
+func (p Postgres) GetGuildInvites(ctx context.Context, playerID int) ([]*models.GuildInvitation, error) {
+	rows, err := p.DB.Query(ctx, guildInvitationSelect+` WHERE i.player_id=$1 ORDER BY i.created_at`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := make([]*models.GuildInvitation, 0)
+	for rows.Next() {
+		var i models.GuildInvitation
+		if err = scanGuildInvitation(rows, &i); err != nil {
+			return nil, err
+		}
+		invites = append(invites, &i)
+	}
+	return invites, rows.Err()
+}
+
+func (p Postgres) InviteToGuild(ctx context.Context, actorID, playerID int) (*models.GuildInvitation, error) {
+	if actorID == playerID {
+		return nil, models.ErrSelfReference
+	}
+	var invite models.GuildInvitation
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, actorID)
+		if err != nil {
+			return err
+		}
+		if !rank.Can(models.GuildInvite) {
+			return models.ErrGuildPermission
+		}
+		member, err := getGuildMember(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if member != nil {
+			return models.ErrAlreadyInGuild
+		}
+		n, err := countGuildMembers(ctx, tx, guildID)
+		if err != nil {
+			return err
+		}
+		if n >= settings.MaxGuildMembers {
+			return models.ErrGuildFull
+		}
+
+		sql := `INSERT INTO guild_invites (guild_id, player_id, inviter_id) VALUES ($1, $2, $3)
+				ON CONFLICT (guild_id, player_id) DO UPDATE SET inviter_id=$3, created_at=now()`
+		if _, err = tx.Exec(ctx, sql, guildID, playerID, actorID); err != nil {
+			return err
+		}
+		sql = guildInvitationSelect + ` WHERE i.guild_id=$1 AND i.player_id=$2`
+		return scanGuildInvitation(tx.QueryRow(ctx, sql, guildID, playerID), &invite)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// AcceptGuildInvite joins the guild at its last rank.
+func (p Postgres) AcceptGuildInvite(ctx context.Context, playerID, guildID int) (*models.GuildMember, error) {
+	var member *models.GuildMember
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		err := lockGuild(ctx, tx, guildID)
+		if err == pgx.ErrNoRows {
+			return models.ErrNoGuildInvite
+		}
+		if err != nil {
+			return err
+		}
+		if err = lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM guild_invites WHERE guild_id=$1 AND player_id=$2`, guildID, playerID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrNoGuildInvite
+		}
+		if member, err = getGuildMember(ctx, tx, playerID); err != nil {
+			return err
+		}
+		if member != nil {
+			return models.ErrAlreadyInGuild
+		}
+		n, err := countGuildMembers(ctx, tx, guildID)
+		if err != nil {
+			return err
+		}
+		if n >= settings.MaxGuildMembers {
+			return models.ErrGuildFull
+		}
+
+		sql := `INSERT INTO guild_members (player_id, guild_id, rank)
+				SELECT $1, $2, max(rank) FROM guild_ranks WHERE guild_id=$2`
+		if _, err = tx.Exec(ctx, sql, playerID, guildID); err != nil {
+			return err
+		}
+		if _, err = tx.Exec(ctx, `DELETE FROM guild_invites WHERE player_id=$1`, playerID); err != nil {
+			return err
+		}
+		member, err = getGuildMember(ctx, tx, playerID)
+		return err
+	})
+	return member, err
+}
+
+func (p Postgres) DeclineGuildInvite(ctx context.Context, playerID, guildID int) error {
+	tag, err := p.DB.Exec(ctx, `DELETE FROM guild_invites WHERE guild_id=$1 AND player_id=$2`, guildID, playerID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNoGuildInvite
+	}
+	return nil
+}
+
+func getGuildBank(ctx context.Context, q querier, guildID int) ([]*models.InventorySlot, error) {
+	sql := `SELECT slot, item_id, quantity FROM guild_bank_items WHERE guild_id=$1 ORDER BY slot`
+	rows, err := q.Query(ctx, sql, guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := make([]*models.InventorySlot, 0)
+	for rows.Next() {
+		var slot models.InventorySlot
+		if err = rows.Scan(&slot.Slot, &slot.ItemID, &slot.Quantity); err != nil {
+			return nil, err
+		}
+		slots = append(slots, &slot)
+	}
+	return slots, rows.Err()
+}
+
+func setBankSlot(ctx context.Context, tx pgx.Tx, guildID, slot int, itemID string, quantity int) error {
+	if quantity == 0 {
+		_, err := tx.Exec(ctx, `DELETE FROM guild_bank_items WHERE guild_id=$1 AND slot=$2`, guildID, slot)
+		return err
+	}
+	sql := `INSERT INTO guild_bank_items (guild_id, slot, item_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (guild_id, slot) DO UPDATE SET item_id=$3, quantity=$4`
+	_, err := tx.Exec(ctx, sql, guildID, slot, itemID, quantity)
+	return err
+}
+
+func logGuildBank(ctx context.Context, tx pgx.Tx, guildID, playerID int, action, itemID string, quantity int) error {
+	sql := `INSERT INTO guild_bank_log (guild_id, player_id, player_name, action, item_id, quantity)
+			SELECT $1, id, name, $3, $4, $5 FROM players WHERE id=$2`
+	_, err := tx.Exec(ctx, sql, guildID, playerID, action, itemID, quantity)
+	return err
+}
+
+func logGuildCurrency(ctx context.Context, tx pgx.Tx, guildID, playerID int, action string, amount int64) error {
+	sql := `INSERT INTO guild_bank_log (guild_id, player_id, player_name, action, quantity, currency)
+			SELECT $1, id, name, $3, 0, $4 FROM players WHERE id=$2`
+	_, err := tx.Exec(ctx, sql, guildID, playerID, action, amount)
+	return err
+}
+
+func (p Postgres) GetGuildBank(ctx context.Context, guildID int) ([]*models.InventorySlot, error) {
+	return getGuildBank(ctx, p.DB, guildID)
+}
+
+// DepositGuildItem moves items from an inventory slot to the guild bank,
+// any member can deposit.
+func (p Postgres) DepositGuildItem(ctx context.Context, playerID, slot, quantity int) ([]*models.InventorySlot, error) {
+	if !validSlot(slot) {
+		return nil, models.ErrInvalidSlot
+	}
+	if quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	var bank []*models.InventorySlot
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, _, err := lockMembership(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if err = lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		s, err := removeItem(ctx, tx, playerID, slot, quantity)
+		if err != nil {
+			return err
+		}
+
+		if bank, err = getGuildBank(ctx, tx, guildID); err != nil {
+			return err
+		}
+		item := models.Item{ID: s.ItemID, StackSize: stackSize(s.ItemID)}
+		changes, left := fill(bank, settings.GuildBankCapacity, item, quantity)
+		if left > 0 {
+			return models.ErrGuildBankFull
+		}
+		for _, c := range changes {
+			if err = setBankSlot(ctx, tx, guildID, c.Slot, c.ItemID, c.Quantity); err != nil {
+				return err
+			}
+		}
+		if err = logGuildBank(ctx, tx, guildID, playerID, models.GuildDeposit, s.ItemID, quantity); err != nil {
+			return err
+		}
+		bank, err = getGuildBank(ctx, tx, guildID)
+		return err
+	})
+	return bank, err
+}
+
+func (p Postgres) WithdrawGuildItem(ctx context.Context, playerID, bankSlot, quantity int) ([]*models.InventorySlot, error) {
+	if bankSlot < 0 || bankSlot >= settings.GuildBankCapacity {
+		return nil, models.ErrInvalidSlot
+	}
+	if quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	var bank []*models.InventorySlot
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if !rank.Can(models.GuildWithdraw) {
+			return models.ErrGuildPermission
+		}
+		if err = lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		var s models.InventorySlot
+		sql := `SELECT item_id, quantity FROM guild_bank_items WHERE guild_id=$1 AND slot=$2`
+		err = tx.QueryRow(ctx, sql, guildID, bankSlot).Scan(&s.ItemID, &s.Quantity)
+		if err == pgx.ErrNoRows {
+			return models.ErrEmptySlot
+		}
+		if err != nil {
+			return err
+		}
+		if s.Quantity < quantity {
+			return models.ErrNotEnoughItems
+		}
+		if err = setBankSlot(ctx, tx, guildID, bankSlot, s.ItemID, s.Quantity-quantity); err != nil {
+			return err
+		}
+		item := models.Item{ID: s.ItemID, StackSize: stackSize(s.ItemID)}
+		if err = addItem(ctx, tx, playerID, item, quantity); err != nil {
+			return err
+		}
+		if err = logGuildBank(ctx, tx, guildID, playerID, models.GuildWithdrawal, s.ItemID, quantity); err != nil {
+			return err
+		}
+		bank, err = getGuildBank(ctx, tx, guildID)
+		return err
+	})
+	return bank, err
+}
+
+func (p Postgres) GetGuildBankLog(ctx context.Context, guildID int, before int64, limit int) ([]*models.GuildBankEntry, error) {
+	sql := `SELECT id, player_id, player_name, action, COALESCE(item_id, ''), quantity, currency, created_at FROM guild_bank_log
+			WHERE guild_id=$1 AND ($2 = 0 OR id < $2)
+			ORDER BY id DESC LIMIT $3`
+	rows, err := p.DB.Query(ctx, sql, guildID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.GuildBankEntry, 0)
+	for rows.Next() {
+		var e models.GuildBankEntry
+		if err = rows.Scan(&e.ID, &e.PlayerID, &e.PlayerName, &e.Action, &e.ItemID, &e.Quantity, &e.Currency, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+func (p Postgres) GetGuildBalance(ctx context.Context, guildID int) (int64, error) {
+	return balance(ctx, p.DB, guildAccount(guildID))
+}
+
+// moveGuildCurrency moves currency between the player and its guild, the
+// guild locked first like the other guild changes.
+func (p Postgres) moveGuildCurrency(ctx context.Context, playerID int, amount int64, action, reason, key string) (*models.LedgerTransaction, error) {
+	var transaction *models.LedgerTransaction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		guildID, rank, err := lockMembership(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		if action == models.GuildWithdrawal && !rank.Can(models.GuildWithdraw) {
+			return models.ErrGuildPermission
+		}
+		player, err := openPlayerAccount(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		guild, err := openAccount(ctx, tx, guildAccount(guildID))
+		if err != nil {
+			return err
+		}
+
+		if action == models.GuildDeposit {
+			transaction, err = transfer(ctx, tx, player, guild, amount, reason, key)
+		} else {
+			transaction, err = transfer(ctx, tx, guild, player, amount, reason, key)
+		}
+		if err != nil || transaction.Replayed {
+			return err
+		}
+		return logGuildCurrency(ctx, tx, guildID, playerID, action, amount)
+	})
+	return transaction, err
+}
+
+func (p Postgres) DepositGuildCurrency(ctx context.Context, playerID int, amount int64, key string) (*models.LedgerTransaction, error) {
+	return p.moveGuildCurrency(ctx, playerID, amount, models.GuildDeposit, models.ReasonGuildDeposit, key)
+}
+
+// WithdrawGuildCurrency needs the withdraw permission, like items.
+func (p Postgres) WithdrawGuildCurrency(ctx context.Context, playerID int, amount int64, key string) (*models.LedgerTransaction, error) {
+	return p.moveGuildCurrency(ctx, playerID, amount, models.GuildWithdrawal, models.ReasonGuildWithdrawal, key)
+}
@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"tribble/guilds"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestLeaveGuildNotEmpty(t *testing.T) {
+	ctx := context.Background()
+	master := createTestPlayer(t)
+	grantTestCurrency(t, master.ID, 100)
+	addTestItem(t, master.ID, "iron_sword", 1)
+	guild, err := pg.CreateGuild(ctx, master.ID, testName(), guilds.DefaultRanks)
+	assert.Equal(t, err, nil)
+
+	_, err = pg.DepositGuildCurrency(ctx, master.ID, 40, fmt.Sprintf("test:guild:%d:deposit", guild.ID))
+	assert.Equal(t, err, nil)
+	_, err = pg.DepositGuildItem(ctx, master.ID, 0, 1)
+	assert.Equal(t, err, nil)
+	_, err = pg.LeaveGuild(ctx, master.ID)
+	assert.Equal(t, err, models.ErrGuildNotEmpty)
+
+	_, err = pg.WithdrawGuildItem(ctx, master.ID, 0, 1)
+	assert.Equal(t, err, nil)
+	_, err = pg.LeaveGuild(ctx, master.ID)
+	assert.Equal(t, err, models.ErrGuildNotEmpty)
+
+	_, err = pg.WithdrawGuildCurrency(ctx, master.ID, 40, fmt.Sprintf("test:guild:%d:withdrawal", guild.ID))
+	assert.Equal(t, err, nil)
+	_, err = pg.LeaveGuild(ctx, master.ID)
+	assert.Equal(t, err, nil)
+	_, err = pg.GetGuild(ctx, guild.ID)
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,318 @@
+package postgres
+
+import (
+	"context"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+type querier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+}
+
+// lockPlayer serializes every transaction that changes what a player owns.
+func lockPlayer(ctx context.Context, tx pgx.Tx, playerID int) error {
+	var id int
+	sql := `SELECT id FROM players WHERE id=$1 FOR UPDATE`
+	return tx.QueryRow(ctx, sql, playerID).Scan(&id)
+}
+
+func getInventory(ctx context.Context, q querier, playerID int) ([]*models.InventorySlot, error) {
+	sql := `SELECT slot, item_id, quantity FROM inventory_items WHERE player_id=$1 ORDER BY slot`
+	rows, err := q.Query(ctx, sql, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := make([]*models.InventorySlot, 0)
+	for rows.Next() {
+		var slot models.InventorySlot
+		if err = rows.Scan(&slot.Slot, &slot.ItemID, &slot.Quantity); err != nil {
+			return nil, err
+		}
+		slots = append(slots, &slot)
+	}
+	return slots, rows.Err()
+}
+
+func getSlot(ctx context.Context, tx pgx.Tx, playerID, slot int) (*models.InventorySlot, error) {
+	sql := `SELECT slot, item_id, quantity FROM inventory_items WHERE player_id=$1 AND slot=$2`
+	var s models.InventorySlot
+	err := tx.QueryRow(ctx, sql, playerID, slot).Scan(&s.Slot, &s.ItemID, &s.Quantity)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func setSlot(ctx context.Context, tx pgx.Tx, playerID, slot int, itemID string, quantity int) error {
+	if quantity == 0 {
+		_, err := tx.Exec(ctx, `DELETE FROM inventory_items WHERE player_id=$1 AND slot=$2`, playerID, slot)
+		return err
+	}
+	sql := `INSERT INTO inventory_items (player_id, slot, item_id, quantity)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (player_id, slot) DO UPDATE SET item_id=$3, quantity=$4`
+	_, err := tx.Exec(ctx, sql, playerID, slot, itemID, quantity)
+	return err
+}
+
+func validSlot(slot int) bool {
+	return slot >= 0 && slot < settings.InventoryCapacity
+}
+
+func stackSize(itemID string) int {
+	if item, ok := items.Default.Get(itemID); ok {
+		return item.StackSize
+	}
+	return 1
+}
+
+// fill plans putting quantity items into the slots, topping up existing
+// stacks of the item before taking free slots. It returns the slots to
+// write and how many items didn't fit.
+func fill(slots []*models.InventorySlot, capacity int, item models.Item, quantity int) ([]models.InventorySlot, int) {
+	var changes []models.InventorySlot
+	used := make(map[int]bool, len(slots))
+	for _, s := range slots {
+		used[s.Slot] = true
+		if quantity == 0 || s.ItemID != item.ID || s.Quantity >= item.StackSize {
+			continue
+		}
+		n := item.StackSize - s.Quantity
+		if n > quantity {
+			n = quantity
+		}
+		changes = append(changes, models.InventorySlot{Slot: s.Slot, ItemID: item.ID, Quantity: s.Quantity + n})
+		quantity -= n
+	}
+
+	for slot := 0; quantity > 0 && slot < capacity; slot++ {
+		if used[slot] {
+			continue
+		}
+		n := item.StackSize
+		if n > quantity {
+			n = quantity
+		}
+		changes = append(changes, models.InventorySlot{Slot: slot, ItemID: item.ID, Quantity: n})
+		quantity -= n
+	}
+	return changes, quantity
+}
+
+// addItem puts the items in the inventory. The player must be locked by
+// the caller.
+func addItem(ctx context.Context, tx pgx.Tx, playerID int, item models.Item, quantity int) error {
+	slots, err := getInventory(ctx, tx, playerID)
+	if err != nil {
+		return err
+	}
+	changes, left := fill(slots, settings.InventoryCapacity, item, quantity)
+	if left > 0 {
+		return models.ErrInventoryFull
+	}
+	for _, s := range changes {
+		if err = setSlot(ctx, tx, playerID, s.Slot, s.ItemID, s.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeItem takes quantity items out of the slot. The player must be
+// locked by the caller.
+func removeItem(ctx context.Context, tx pgx.Tx, playerID, slot, quantity int) (*models.InventorySlot, error) {
+	s, err := getSlot(ctx, tx, playerID, slot)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, models.ErrEmptySlot
+	}
+	if s.Quantity < quantity {
+		return nil, models.ErrNotEnoughItems
+	}
+	if err = setSlot(ctx, tx, playerID, slot, s.ItemID, s.Quantity-quantity); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// takeItems removes quantity items from every stack of the item, the
+// last slots first. The player must be locked by the caller.
+func takeItems(ctx context.Context, tx pgx.Tx, playerID int, itemID string, quantity int) error {
+	slots, err := getInventory(ctx, tx, playerID)
+	if err != nil {
+		return err
+	}
+	owned := 0
+	for _, s := range slots {
+		if s.ItemID == itemID {
+			owned += s.Quantity
+		}
+	}
+	if owned < quantity {
+		return models.ErrNotEnoughItems
+	}
+
+	for i := len(slots) - 1; i >= 0 && quantity > 0; i-- {
+		s := slots[i]
+		if s.ItemID != itemID {
+			continue
+		}
+		n := s.Quantity
+		if n > quantity {
+			n = quantity
+		}
+		if err = setSlot(ctx, tx, playerID, s.Slot, itemID, s.Quantity-n); err != nil {
+			return err
+		}
+		quantity -= n
+	}
+	return nil
+}
+
+func (p Postgres) GetInventory(ctx context.Context, playerID int) ([]*models.InventorySlot, error) {
+	return getInventory(ctx, p.DB, playerID)
+}
+
+func (p Postgres) AddItem(ctx context.Context, playerID int, item models.Item, quantity int) ([]*models.InventorySlot, error) {
+	if quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	var slots []*models.InventorySlot
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		if err := addItem(ctx, tx, playerID, item, quantity); err != nil {
+			return err
+		}
+		var err error
+		slots, err = getInventory(ctx, tx, playerID)
+		return err
+	})
+	return slots, err
+}
+
+func (p Postgres) RemoveItem(ctx context.Context, playerID int, slot int, quantity int) error {
+	if !validSlot(slot) {
+		return models.ErrInvalidSlot
+	}
+	if quantity < 1 {
+		return models.ErrNotEnoughItems
+	}
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		_, err := removeItem(ctx, tx, playerID, slot, quantity)
+		return err
+	})
+}
+
+// MoveItem moves the stack to an empty slot, merges it into a stack of the
+// same item or swaps both slots otherwise.
+func (p Postgres) MoveItem(ctx context.Context, playerID int, from int, to int) ([]*models.InventorySlot, error) {
+	if !validSlot(from) || !validSlot(to) || from == to {
+		return nil, models.ErrInvalidSlot
+	}
+	var slots []*models.InventorySlot
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		source, err := getSlot(ctx, tx, playerID, from)
+		if err != nil {
+			return err
+		}
+		if source == nil {
+			return models.ErrEmptySlot
+		}
+		target, err := getSlot(ctx, tx, playerID, to)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case target == nil:
+			err = setSlot(ctx, tx, playerID, from, source.ItemID, 0)
+			if err == nil {
+				err = setSlot(ctx, tx, playerID, to, source.ItemID, source.Quantity)
+			}
+		case target.ItemID == source.ItemID && target.Quantity < stackSize(target.ItemID):
+			n := stackSize(target.ItemID) - target.Quantity
+			if n > source.Quantity {
+				n = source.Quantity
+			}
+			err = setSlot(ctx, tx, playerID, to, target.ItemID, target.Quantity+n)
+			if err == nil {
+				err = setSlot(ctx, tx, playerID, from, source.ItemID, source.Quantity-n)
+			}
+		default:
+			err = setSlot(ctx, tx, playerID, from, target.ItemID, target.Quantity)
+			if err == nil {
+				err = setSlot(ctx, tx, playerID, to, source.ItemID, source.Quantity)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		slots, err = getInventory(ctx, tx, playerID)
+		return err
+	})
+	return slots, err
+}
+
+func (p Postgres) SplitStack(ctx context.Context, playerID int, from int, to int, quantity int) ([]*models.InventorySlot, error) {
+	if !validSlot(from) || !validSlot(to) || from == to {
+		return nil, models.ErrInvalidSlot
+	}
+	if quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	var slots []*models.InventorySlot
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		target, err := getSlot(ctx, tx, playerID, to)
+		if err != nil {
+			return err
+		}
+		if target != nil {
+			return models.ErrSlotOccupied
+		}
+		source, err := getSlot(ctx, tx, playerID, from)
+		if err != nil {
+			return err
+		}
+		if source == nil {
+			return models.ErrEmptySlot
+		}
+		// splitting the whole stack would be a move
+		if source.Quantity <= quantity {
+			return models.ErrNotEnoughItems
+		}
+
+		if err = setSlot(ctx, tx, playerID, from, source.ItemID, source.Quantity-quantity); err != nil {
+			return err
+		}
+		if err = setSlot(ctx, tx, playerID, to, source.ItemID, quantity); err != nil {
+			return err
+		}
+
+		slots, err = getInventory(ctx, tx, playerID)
+		return err
+	})
+	return slots, err
+}
@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"tribble/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// xpRanking returns the columns of the ranking, the overall one or the one
+// of a class, and the filter of its rows taking the class as parameter n.
+func xpRanking(class string, n int) (string, string, string) {
+	if class == "" {
+		return "rank", "position", ""
+	}
+	return "class_rank", "class_position", fmt.Sprintf(" AND sprite=$%d", n)
+}
+
+func getLeaderboard(ctx context.Context, q querier, sql string, args ...interface{}) (*models.Leaderboard, error) {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	board := models.Leaderboard{Entries: make([]*models.LeaderboardEntry, 0)}
+	for rows.Next() {
+		var e models.LeaderboardEntry
+		var refreshedAt time.Time
+		if err = rows.Scan(&e.Rank, &e.Position, &e.PlayerID, &e.Name, &e.Sprite, &e.XP, &refreshedAt); err != nil {
+			return nil, err
+		}
+		board.RefreshedAt = &refreshedAt
+		board.Entries = append(board.Entries, &e)
+	}
+	return &board, rows.Err()
+}
+
+// GetXPLeaderboard reads one more entry than asked to tell whether there is
+// a next page. It pages by xp and player id rather than by position, which
+// a refresh shifts between two pages.
+func (p Postgres) GetXPLeaderboard(ctx context.Context, class string, after *models.LeaderboardCursor, limit int) (*models.Leaderboard, error) {
+	rank, position, filter := xpRanking(class, 2)
+	args := []interface{}{limit + 1}
+	if class != "" {
+		args = append(args, class)
+	}
+	if after != nil {
+		filter += fmt.Sprintf(" AND (xp < $%d OR (xp = $%d AND player_id > $%d))", len(args)+1, len(args)+1, len(args)+2)
+		args = append(args, after.XP, after.PlayerID)
+	}
+	sql := `SELECT ` + rank + `, ` + position + `, player_id, name, sprite, xp, refreshed_at
+			FROM xp_leaderboard
+			WHERE true` + filter + `
+			ORDER BY xp DESC, player_id LIMIT $1`
+	board, err := getLeaderboard(ctx, p.DB, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(board.Entries) > limit {
+		board.Entries = board.Entries[:limit]
+		last := board.Entries[limit-1]
+		board.Next = &models.LeaderboardCursor{XP: last.XP, PlayerID: last.PlayerID}
+	}
+	return board, nil
+}
+
+func (p Postgres) GetXPLeaderboardAround(ctx context.Context, playerID int, class string, radius int) (*models.Leaderboard, error) {
+	_, position, filter := xpRanking(class, 2)
+	args := []interface{}{playerID}
+	if class != "" {
+		args = append(args, class)
+	}
+	var at int
+	sql := `SELECT ` + position + ` FROM xp_leaderboard WHERE player_id=$1` + filter
+	if err := p.DB.QueryRow(ctx, sql, args...).Scan(&at); err != nil {
+		return nil, err
+	}
+
+	rank, position, filter := xpRanking(class, 3)
+	sql = `SELECT ` + rank + `, ` + position + `, player_id, name, sprite, xp, refreshed_at
+			FROM xp_leaderboard
+			WHERE ` + position + ` BETWEEN $1 AND $2` + filter + `
+			ORDER BY ` + position
+	args = []interface{}{at - radius, at + radius}
+	if class != "" {
+		args = append(args, class)
+	}
+	board, err := getLeaderboard(ctx, p.DB, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(board.Entries) == 0 {
+		// the player was deleted since the last refresh
+		return nil, pgx.ErrNoRows
+	}
+	return board, nil
+}
+
+// RefreshLeaderboards recomputes the rankings without blocking readers.
+func (p Postgres) RefreshLeaderboards(ctx context.Context) error {
+	_, err := p.DB.Exec(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY xp_leaderboard`)
+	return err
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestXPLeaderboardPages(t *testing.T) {
+	ctx := context.Background()
+	// the other tests play warriors, the assassins ranked are the test's own
+	class := "assassin"
+	user := createTestUser(t)
+	_, err := pg.GrantEntitlement(ctx, user.ID, models.Entitlement{Kind: models.EntitlementCharacterSlot, Quantity: 4})
+	assert.Equal(t, err, nil)
+	createPlayer := func(xp int) int {
+		player := newTestPlayer(user.ID)
+		player.Sprite, player.XP = class, xp
+		created, err := pg.CreatePlayer(ctx, player)
+		assert.Equal(t, err, nil)
+		return created.ID
+	}
+	IDs := []int{createPlayer(100), createPlayer(100), createPlayer(50)}
+	assert.Equal(t, pg.RefreshLeaderboards(ctx), nil)
+
+	board, err := pg.GetXPLeaderboard(ctx, class, nil, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(board.Entries), 2)
+	assert.Equal(t, board.Entries[0].PlayerID, IDs[0])
+	assert.Equal(t, board.Entries[0].Rank, 1)
+	assert.Equal(t, board.Entries[1].Rank, 1)
+	assert.Equal(t, board.Entries[1].Position, 2)
+	assert.Equal(t, *board.Next, models.LeaderboardCursor{XP: 100, PlayerID: IDs[1]})
+
+	// a player ranked above the cursor by the refresh between two pages
+	// shifts the positions but not the next page
+	createPlayer(200)
+	assert.Equal(t, pg.RefreshLeaderboards(ctx), nil)
+
+	board, err = pg.GetXPLeaderboard(ctx, class, board.Next, 2)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(board.Entries), 1)
+	assert.Equal(t, board.Entries[0].PlayerID, IDs[2])
+	assert.Equal(t, board.Entries[0].Rank, 4)
+	assert.Equal(t, board.Next, nil)
+
+	// a full last page has no next one
+	board, err = pg.GetXPLeaderboard(ctx, class, nil, 4)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(board.Entries), 4)
+	assert.Equal(t, board.Next, nil)
+
+	board, err = pg.GetXPLeaderboardAround(ctx, IDs[2], class, 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(board.Entries), 2)
+	assert.Equal(t, board.Entries[0].Position, 3)
+	assert.Equal(t, board.Entries[1].PlayerID, IDs[2])
+}
@@ -0,0 +1,359 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"tribble/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	worldAccount = "system:world"
+	sinkAccount  = "system:sink"
+)
+
+func playerAccount(playerID int) string {
+	return fmt.Sprintf("player:%d", playerID)
+}
+
+func guildAccount(guildID int) string {
+	return fmt.Sprintf("guild:%d", guildID)
+}
+
+// openAccount returns the ID of the account, opening player and guild
+// accounts on first use.
+func openAccount(ctx context.Context, tx pgx.Tx, key string) (int64, error) {
+	var ID int64
+	err := tx.QueryRow(ctx, `SELECT id FROM ledger_accounts WHERE key=$1`, key).Scan(&ID)
+	if err != pgx.ErrNoRows {
+		return ID, err
+	}
+
+	sql := `INSERT INTO ledger_accounts (key) VALUES ($1) ON CONFLICT (key) DO NOTHING RETURNING id`
+	err = tx.QueryRow(ctx, sql, key).Scan(&ID)
+	if err == pgx.ErrNoRows {
+		// opened by a concurrent transaction
+		err = tx.QueryRow(ctx, `SELECT id FROM ledger_accounts WHERE key=$1`, key).Scan(&ID)
+	}
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(ctx, `INSERT INTO ledger_snapshots (account_id) VALUES ($1) ON CONFLICT DO NOTHING`, ID)
+	return ID, err
+}
+
+// openPlayerAccount checks the player exists before opening its account.
+func openPlayerAccount(ctx context.Context, tx pgx.Tx, playerID int) (int64, error) {
+	var ID int
+	if err := tx.QueryRow(ctx, `SELECT id FROM players WHERE id=$1 FOR KEY SHARE`, playerID).Scan(&ID); err != nil {
+		return 0, err
+	}
+	return openAccount(ctx, tx, playerAccount(playerID))
+}
+
+func balance(ctx context.Context, q queryRower, key string) (int64, error) {
+	sql := `SELECT s.balance + COALESCE((SELECT SUM(e.amount) FROM ledger_entries e
+				WHERE e.account_id = a.id AND e.id > s.entry_id), 0)
+			FROM ledger_accounts a JOIN ledger_snapshots s ON s.account_id = a.id
+			WHERE a.key=$1`
+	var amount int64
+	err := q.QueryRow(ctx, sql, key).Scan(&amount)
+	if err == pgx.ErrNoRows {
+		// accounts open on their first transaction
+		return 0, nil
+	}
+	return amount, err
+}
+
+type posting struct {
+	account int64
+	amount  int64
+}
+
+// samePostings tells whether the entries of the transaction are the
+// postings, in any order.
+func samePostings(ctx context.Context, tx pgx.Tx, transactionID int64, postings []posting) (bool, error) {
+	rows, err := tx.Query(ctx, `SELECT account_id, amount FROM ledger_entries WHERE transaction_id=$1`, transactionID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	missing := make(map[posting]int, len(postings))
+	for _, p := range postings {
+		missing[p]++
+	}
+	entries := 0
+	for rows.Next() {
+		var p posting
+		if err = rows.Scan(&p.account, &p.amount); err != nil {
+			return false, err
+		}
+		missing[p]--
+		entries++
+	}
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+	if entries != len(postings) {
+		return false, nil
+	}
+	for _, n := range missing {
+		if n != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// post records a balanced transaction. The snapshots of the accounts
+// involved are locked in ID order, except for system accounts which are
+// never locked so grants don't queue behind each other, then brought up to
+// date with the new entries. Accounts other than system ones can't go
+// negative. A key already used replays its transaction when it had the
+// same reason and postings.
+func post(ctx context.Context, tx pgx.Tx, reason, key string, postings []posting) (*models.LedgerTransaction, error) {
+	var sum int64
+	deltas := make(map[int64]int64)
+	for _, p := range postings {
+		if p.amount == 0 {
+			return nil, models.ErrInvalidAmount
+		}
+		sum += p.amount
+		deltas[p.account] += p.amount
+	}
+	if sum != 0 {
+		return nil, fmt.Errorf("unbalanced ledger transaction %v: %v", key, sum)
+	}
+
+	transaction := models.LedgerTransaction{Reason: reason, IdempotencyKey: key}
+	sql := `INSERT INTO ledger_transactions (reason, idempotency_key) VALUES ($1, $2)
+			ON CONFLICT (idempotency_key) DO NOTHING RETURNING id, created_at`
+	err := tx.QueryRow(ctx, sql, reason, key).Scan(&transaction.ID, &transaction.CreatedAt)
+	if err == pgx.ErrNoRows {
+		sql = `SELECT id, reason, created_at FROM ledger_transactions WHERE idempotency_key=$1`
+		if err = tx.QueryRow(ctx, sql, key).Scan(&transaction.ID, &transaction.Reason, &transaction.CreatedAt); err != nil {
+			return nil, err
+		}
+		same, err := samePostings(ctx, tx, transaction.ID, postings)
+		if err != nil {
+			return nil, err
+		}
+		if transaction.Reason != reason || !same {
+			return nil, models.ErrIdempotencyConflict
+		}
+		transaction.Replayed = true
+		return &transaction, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]int64, 0, len(deltas))
+	for account := range deltas {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i] < accounts[j] })
+	sql = `SELECT s.account_id, s.balance + COALESCE((SELECT SUM(e.amount) FROM ledger_entries e
+				WHERE e.account_id = s.account_id AND e.id > s.entry_id), 0)
+			FROM ledger_snapshots s JOIN ledger_accounts a ON a.id = s.account_id
+			WHERE s.account_id = ANY($1) AND NOT a.system
+			ORDER BY s.account_id FOR UPDATE OF s`
+	rows, err := tx.Query(ctx, sql, accounts)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[int64]int64, len(accounts))
+	for rows.Next() {
+		var account, amount int64
+		if err = rows.Scan(&account, &amount); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		balances[account] = amount
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for account, amount := range balances {
+		if amount+deltas[account] < 0 {
+			return nil, models.ErrInsufficientFunds
+		}
+	}
+
+	lastEntry := make(map[int64]int64, len(postings))
+	for _, p := range postings {
+		var entryID int64
+		sql = `INSERT INTO ledger_entries (transaction_id, account_id, amount) VALUES ($1, $2, $3) RETURNING id`
+		if err = tx.QueryRow(ctx, sql, transaction.ID, p.account, p.amount).Scan(&entryID); err != nil {
+			return nil, err
+		}
+		lastEntry[p.account] = entryID
+	}
+	for account, amount := range balances {
+		sql = `UPDATE ledger_snapshots SET balance=$2, entry_id=$3, updated_at=now() WHERE account_id=$1`
+		if _, err = tx.Exec(ctx, sql, account, amount+deltas[account], lastEntry[account]); err != nil {
+			return nil, err
+		}
+	}
+	return &transaction, nil
+}
+
+// keyUsed tells whether a transaction was already posted with the key.
+func keyUsed(ctx context.Context, tx pgx.Tx, key string) (bool, error) {
+	var used bool
+	sql := `SELECT EXISTS (SELECT 1 FROM ledger_transactions WHERE idempotency_key=$1)`
+	err := tx.QueryRow(ctx, sql, key).Scan(&used)
+	return used, err
+}
+
+// transfer moves amount from one account to another.
+func transfer(ctx context.Context, tx pgx.Tx, from, to int64, amount int64, reason, key string) (*models.LedgerTransaction, error) {
+	if amount < 1 {
+		return nil, models.ErrInvalidAmount
+	}
+	return post(ctx, tx, reason, key, []posting{{from, -amount}, {to, amount}})
+}
+
+func (p Postgres) GetBalance(ctx context.Context, playerID int) (int64, error) {
+	return balance(ctx, p.DB, playerAccount(playerID))
+}
+
+func (p Postgres) GetLedgerEntries(ctx context.Context, playerID int, before int64, limit int) ([]*models.LedgerEntry, error) {
+	sql := `SELECT e.id, e.transaction_id, t.reason, e.amount, t.created_at
+			FROM ledger_entries e
+			JOIN ledger_accounts a ON a.id = e.account_id
+			JOIN ledger_transactions t ON t.id = e.transaction_id
+			WHERE a.key=$1 AND ($2 = 0 OR e.id < $2)
+			ORDER BY e.id DESC LIMIT $3`
+	rows, err := p.DB.Query(ctx, sql, playerAccount(playerID), before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]*models.LedgerEntry, 0)
+	for rows.Next() {
+		var e models.LedgerEntry
+		if err = rows.Scan(&e.ID, &e.TransactionID, &e.Reason, &e.Amount, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// systemTransfer moves currency between a player and a system account.
+func (p Postgres) systemTransfer(ctx context.Context, playerID int, system string, amount int64, reason, key string) (*models.LedgerTransaction, error) {
+	var transaction *models.LedgerTransaction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		player, err := openPlayerAccount(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		account, err := openAccount(ctx, tx, system)
+		if err != nil {
+			return err
+		}
+		if system == worldAccount {
+			transaction, err = transfer(ctx, tx, account, player, amount, reason, key)
+		} else {
+			transaction, err = transfer(ctx, tx, player, account, amount, reason, key)
+		}
+		return err
+	})
+	return transaction, err
+}
+
+func (p Postgres) GrantCurrency(ctx context.Context, playerID int, amount int64, reason, key string) (*models.LedgerTransaction, error) {
+	return p.systemTransfer(ctx, playerID, worldAccount, amount, reason, key)
+}
+
+func (p Postgres) SpendCurrency(ctx context.Context, playerID int, amount int64, reason, key string) (*models.LedgerTransaction, error) {
+	return p.systemTransfer(ctx, playerID, sinkAccount, amount, reason, key)
+}
+
+func (p Postgres) TransferCurrency(ctx context.Context, fromID, toID int, amount int64, reason, key string) (*models.LedgerTransaction, error) {
+	if fromID == toID {
+		return nil, models.ErrSelfReference
+	}
+	var transaction *models.LedgerTransaction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		from, err := openPlayerAccount(ctx, tx, fromID)
+		if err != nil {
+			return err
+		}
+		to, err := openPlayerAccount(ctx, tx, toID)
+		if err != nil {
+			return err
+		}
+		transaction, err = transfer(ctx, tx, from, to, amount, reason, key)
+		return err
+	})
+	return transaction, err
+}
+
+// ledgerReportLimit is how many IDs the report lists per invariant.
+const ledgerReportLimit = 100
+
+func queryIDs(ctx context.Context, tx pgx.Tx, sql string) ([]int64, error) {
+	rows, err := tx.Query(ctx, sql, ledgerReportLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	IDs := make([]int64, 0)
+	for rows.Next() {
+		var ID int64
+		if err = rows.Scan(&ID); err != nil {
+			return nil, err
+		}
+		IDs = append(IDs, ID)
+	}
+	return IDs, rows.Err()
+}
+
+// CheckLedger verifies in a single snapshot that every transaction is
+// balanced, the whole ledger sums to zero, snapshots match their entries
+// and only system accounts are negative.
+func (p Postgres) CheckLedger(ctx context.Context) (*models.LedgerReport, error) {
+	report := models.LedgerReport{}
+	options := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	err := p.DB.BeginTxFunc(ctx, options, func(tx pgx.Tx) error {
+		sql := `SELECT (SELECT COUNT(*) FROM ledger_transactions), COALESCE(SUM(amount), 0) FROM ledger_entries`
+		if err := tx.QueryRow(ctx, sql).Scan(&report.Transactions, &report.Total); err != nil {
+			return err
+		}
+
+		var err error
+		report.Unbalanced, err = queryIDs(ctx, tx, `SELECT t.id FROM ledger_transactions t
+				LEFT JOIN ledger_entries e ON e.transaction_id = t.id
+				GROUP BY t.id HAVING COALESCE(SUM(e.amount), 0) <> 0 OR COUNT(e.id) < 2
+				ORDER BY t.id LIMIT $1`)
+		if err != nil {
+			return err
+		}
+		report.Mismatched, err = queryIDs(ctx, tx, `SELECT s.account_id FROM ledger_snapshots s
+				WHERE s.balance <> COALESCE((SELECT SUM(e.amount) FROM ledger_entries e
+					WHERE e.account_id = s.account_id AND e.id <= s.entry_id), 0)
+				ORDER BY s.account_id LIMIT $1`)
+		if err != nil {
+			return err
+		}
+		report.Overdrawn, err = queryIDs(ctx, tx, `SELECT a.id FROM ledger_accounts a
+				JOIN ledger_entries e ON e.account_id = a.id
+				WHERE NOT a.system
+				GROUP BY a.id HAVING SUM(e.amount) < 0
+				ORDER BY a.id LIMIT $1`)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.OK = report.Total == 0 && len(report.Unbalanced) == 0 && len(report.Mismatched) == 0 && len(report.Overdrawn) == 0
+	return &report, nil
+}
@@ -0,0 +1,34 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestReplayComparesPostings(t *testing.T) {
+	ctx := context.Background()
+	player, friend, other := createTestPlayer(t), createTestPlayer(t), createTestPlayer(t)
+	grantTestCurrency(t, player.ID, 100)
+
+	key := fmt.Sprintf("test:transfer:%d", player.ID)
+	transaction, err := pg.TransferCurrency(ctx, player.ID, friend.ID, 30, models.ReasonTrade, key)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, transaction.Replayed, false)
+
+	transaction, err = pg.TransferCurrency(ctx, player.ID, friend.ID, 30, models.ReasonTrade, key)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, transaction.Replayed, true)
+
+	_, err = pg.TransferCurrency(ctx, player.ID, friend.ID, 40, models.ReasonTrade, key)
+	assert.Equal(t, err, models.ErrIdempotencyConflict)
+	_, err = pg.TransferCurrency(ctx, player.ID, other.ID, 30, models.ReasonTrade, key)
+	assert.Equal(t, err, models.ErrIdempotencyConflict)
+
+	balance, err := pg.GetBalance(ctx, player.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, int64(70))
+}
@@ -0,0 +1,377 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const systemSender = "System"
+
+func mailAccount(mailID int64) string {
+	return fmt.Sprintf("mail:%d", mailID)
+}
+
+const mailColumns = `id, COALESCE(sender_id, 0), sender_name, subject, body, COALESCE(item_id, ''), quantity, currency,
+		returned, created_at, read_at, claimed_at, expires_at`
+
+func scanMail(row pgx.Row) (*models.Mail, error) {
+	var m models.Mail
+	err := row.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.Subject, &m.Body, &m.ItemID, &m.Quantity, &m.Currency,
+		&m.Returned, &m.CreatedAt, &m.ReadAt, &m.ClaimedAt, &m.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// sendMail delivers the mail, returning the ledger account holding its
+// currency. The caller must post the currency to that account in the same
+// transaction.
+func sendMail(ctx context.Context, tx pgx.Tx, recipientID int, m *models.Mail, key string) (int64, error) {
+	sql := `INSERT INTO mail (recipient_id, sender_id, sender_name, subject, body, item_id, quantity, currency,
+				returned, expires_at, idempotency_key)
+			VALUES ($1, NULLIF($2, 0), $3, $4, $5, NULLIF($6, ''), $7, $8, $9, now() + $10::interval, NULLIF($11, ''))
+			RETURNING id, created_at, expires_at`
+	err := tx.QueryRow(ctx, sql, recipientID, m.SenderID, m.SenderName, m.Subject, m.Body, m.ItemID, m.Quantity,
+		m.Currency, m.Returned, settings.MailExpiry, key).Scan(&m.ID, &m.CreatedAt, &m.ExpiresAt)
+	if err != nil || m.Currency == 0 {
+		return 0, err
+	}
+	return openAccount(ctx, tx, mailAccount(m.ID))
+}
+
+// sentMail returns the mail already sent with the key, if any.
+func sentMail(ctx context.Context, tx pgx.Tx, key string) (*models.Mail, error) {
+	m, err := scanMail(tx.QueryRow(ctx, `SELECT `+mailColumns+` FROM mail WHERE idempotency_key=$1`, key))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return m, err
+}
+
+func (p Postgres) GetMail(ctx context.Context, playerID int, before int64, limit int) ([]*models.Mail, error) {
+	sql := `SELECT ` + mailColumns + ` FROM mail
+			WHERE recipient_id=$1 AND ($2 = 0 OR id < $2)
+			ORDER BY id DESC LIMIT $3`
+	rows, err := p.DB.Query(ctx, sql, playerID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mail := make([]*models.Mail, 0)
+	for rows.Next() {
+		m, err := scanMail(rows)
+		if err != nil {
+			return nil, err
+		}
+		mail = append(mail, m)
+	}
+	return mail, rows.Err()
+}
+
+func (p Postgres) ReadMail(ctx context.Context, playerID int, mailID int64) (*models.Mail, error) {
+	sql := `UPDATE mail SET read_at=COALESCE(read_at, now()) WHERE id=$1 AND recipient_id=$2 RETURNING ` + mailColumns
+	return scanMail(p.DB.QueryRow(ctx, sql, mailID, playerID))
+}
+
+func (p Postgres) CountUnreadMail(ctx context.Context, playerID int) (int, error) {
+	var count int
+	sql := `SELECT COUNT(*) FROM mail WHERE recipient_id=$1 AND read_at IS NULL`
+	err := p.DB.QueryRow(ctx, sql, playerID).Scan(&count)
+	return count, err
+}
+
+func (p Postgres) SendMail(ctx context.Context, senderID, recipientID int, mail models.NewMail, key string) (*models.Mail, error) {
+	if senderID == recipientID {
+		return nil, models.ErrSelfReference
+	}
+	if mail.Currency < 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	if mail.Slot != nil && (!validSlot(*mail.Slot) || mail.Quantity < 1) {
+		return nil, models.ErrInvalidSlot
+	}
+	var m *models.Mail
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, senderID); err != nil {
+			return err
+		}
+		var err error
+		if key != "" {
+			if m, err = sentMail(ctx, tx, key); err != nil || m != nil {
+				return err
+			}
+		}
+
+		m = &models.Mail{SenderID: senderID, Subject: mail.Subject, Body: mail.Body, Currency: mail.Currency}
+		if err = tx.QueryRow(ctx, `SELECT name FROM players WHERE id=$1`, senderID).Scan(&m.SenderName); err != nil {
+			return err
+		}
+		if mail.Slot != nil {
+			s, err := getSlot(ctx, tx, senderID, *mail.Slot)
+			if err != nil {
+				return err
+			}
+			if s == nil {
+				return models.ErrEmptySlot
+			}
+			if !items.Tradable(s.ItemID) {
+				return models.ErrNotTradable
+			}
+			if _, err = removeItem(ctx, tx, senderID, s.Slot, mail.Quantity); err != nil {
+				return err
+			}
+			m.ItemID, m.Quantity = s.ItemID, mail.Quantity
+		}
+
+		account, err := sendMail(ctx, tx, recipientID, m, key)
+		if err != nil || m.Currency == 0 {
+			return err
+		}
+		from, err := openPlayerAccount(ctx, tx, senderID)
+		if err != nil {
+			return err
+		}
+		_, err = transfer(ctx, tx, from, account, m.Currency, models.ReasonMailSend, fmt.Sprintf("mail:%d:send", m.ID))
+		return err
+	})
+	return m, err
+}
+
+// SendSystemMail creates the attached items, the currency comes from the
+// world account.
+func (p Postgres) SendSystemMail(ctx context.Context, recipientID int, mail models.SystemMail, key string) (*models.Mail, error) {
+	if mail.Currency < 0 {
+		return nil, models.ErrInvalidAmount
+	}
+	if mail.ItemID != "" && mail.Quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	var m *models.Mail
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		if m, err = sentMail(ctx, tx, key); err != nil || m != nil {
+			return err
+		}
+		m = &models.Mail{
+			SenderName: systemSender,
+			Subject:    mail.Subject,
+			Body:       mail.Body,
+			ItemID:     mail.ItemID,
+			Quantity:   mail.Quantity,
+			Currency:   mail.Currency,
+		}
+		if m.ItemID == "" {
+			m.Quantity = 0
+		}
+		account, err := sendMail(ctx, tx, recipientID, m, key)
+		if err != nil || m.Currency == 0 {
+			return err
+		}
+		world, err := openAccount(ctx, tx, worldAccount)
+		if err != nil {
+			return err
+		}
+		_, err = transfer(ctx, tx, world, account, m.Currency, models.ReasonSystemMail, fmt.Sprintf("mail:%d:send", m.ID))
+		return err
+	})
+	return m, err
+}
+
+// ClaimMail locks the player, then the mail so it is claimed once.
+func (p Postgres) ClaimMail(ctx context.Context, playerID int, mailID int64) (*models.Mail, error) {
+	var m *models.Mail
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+		var err error
+		sql := `SELECT ` + mailColumns + ` FROM mail WHERE id=$1 AND recipient_id=$2 FOR UPDATE`
+		if m, err = scanMail(tx.QueryRow(ctx, sql, mailID, playerID)); err != nil {
+			return err
+		}
+		if m.ClaimedAt != nil {
+			return models.ErrMailClaimed
+		}
+		if !m.Attached() {
+			return models.ErrNoAttachments
+		}
+
+		if err = claimAttachments(ctx, tx, m, playerID); err != nil {
+			return err
+		}
+		sql = `UPDATE mail SET claimed_at=now(), read_at=COALESCE(read_at, now()) WHERE id=$1 RETURNING claimed_at, read_at`
+		return tx.QueryRow(ctx, sql, m.ID).Scan(&m.ClaimedAt, &m.ReadAt)
+	})
+	return m, err
+}
+
+// claimAttachments moves the items and the currency of the mail to its
+// recipient, locked by the caller.
+func claimAttachments(ctx context.Context, tx pgx.Tx, m *models.Mail, playerID int) error {
+	if m.ItemID != "" {
+		item := models.Item{ID: m.ItemID, StackSize: stackSize(m.ItemID)}
+		if err := addItem(ctx, tx, playerID, item, m.Quantity); err != nil {
+			return err
+		}
+	}
+	if m.Currency > 0 {
+		from, err := openAccount(ctx, tx, mailAccount(m.ID))
+		if err != nil {
+			return err
+		}
+		to, err := openPlayerAccount(ctx, tx, playerID)
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("mail:%d:claim", m.ID)
+		if _, err = transfer(ctx, tx, from, to, m.Currency, models.ReasonMailClaim, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p Postgres) DeleteMail(ctx context.Context, playerID int, mailID int64) error {
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `SELECT ` + mailColumns + ` FROM mail WHERE id=$1 AND recipient_id=$2 FOR UPDATE`
+		m, err := scanMail(tx.QueryRow(ctx, sql, mailID, playerID))
+		if err != nil {
+			return err
+		}
+		if m.Attached() {
+			return models.ErrMailAttached
+		}
+		_, err = tx.Exec(ctx, `DELETE FROM mail WHERE id=$1`, m.ID)
+		return err
+	})
+}
+
+func returnedSubject(subject string) string {
+	s := []rune("Returned: " + subject)
+	if len(s) > 64 {
+		s = s[:64]
+	}
+	return string(s)
+}
+
+// expireMail deletes the mail, sending its unclaimed attachments back to
+// the sender. The attachments of system and returned mail have no one to
+// go back to, they are delivered to the recipient as if claimed. It
+// reports false when the mail is kept: the recipient is busy and it is
+// retried on the next run, or its inventory is full and the mail gets
+// another expiry period.
+func expireMail(ctx context.Context, tx pgx.Tx, m *models.Mail, recipientID int, recipientName string) (bool, error) {
+	switch {
+	case !m.Attached():
+	case m.SenderID != 0 && !m.Returned:
+		to, err := sendMail(ctx, tx, m.SenderID, &models.Mail{
+			SenderID:   recipientID,
+			SenderName: recipientName,
+			Subject:    returnedSubject(m.Subject),
+			Body:       m.Body,
+			ItemID:     m.ItemID,
+			Quantity:   m.Quantity,
+			Currency:   m.Currency,
+			Returned:   true,
+		}, "")
+		if err != nil {
+			return false, err
+		}
+		if m.Currency > 0 {
+			from, err := openAccount(ctx, tx, mailAccount(m.ID))
+			if err != nil {
+				return false, err
+			}
+			key := fmt.Sprintf("mail:%d:return", m.ID)
+			if _, err = transfer(ctx, tx, from, to, m.Currency, models.ReasonMailReturn, key); err != nil {
+				return false, err
+			}
+		}
+	default:
+		// claims lock the player before the mail, so waiting here could
+		// deadlock with one
+		var ID int
+		err := tx.QueryRow(ctx, `SELECT id FROM players WHERE id=$1 FOR UPDATE SKIP LOCKED`, recipientID).Scan(&ID)
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		err = claimAttachments(ctx, tx, m, recipientID)
+		if err == models.ErrInventoryFull {
+			sql := `UPDATE mail SET expires_at = now() + $2::interval WHERE id=$1`
+			_, err = tx.Exec(ctx, sql, m.ID, settings.MailExpiry)
+			return false, err
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	_, err := tx.Exec(ctx, `DELETE FROM mail WHERE id=$1`, m.ID)
+	return err == nil, err
+}
+
+// ExpireMail skips the mail locked by claims or other workers, it is
+// processed on a later run. Each mail expires in a savepoint, one that
+// fails is logged and retried on the next run without holding back the
+// others.
+func (p Postgres) ExpireMail(ctx context.Context, limit int) (int, error) {
+	expired := 0
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		sql := `SELECT ` + mailColumns + `, recipient_id, (SELECT name FROM players WHERE id = recipient_id)
+				FROM mail WHERE expires_at <= now()
+				ORDER BY expires_at LIMIT $1 FOR UPDATE SKIP LOCKED`
+		rows, err := tx.Query(ctx, sql, limit)
+		if err != nil {
+			return err
+		}
+		type recipient struct {
+			ID   int
+			Name string
+		}
+		var mail []*models.Mail
+		var recipients []recipient
+		for rows.Next() {
+			var m models.Mail
+			var r recipient
+			err = rows.Scan(&m.ID, &m.SenderID, &m.SenderName, &m.Subject, &m.Body, &m.ItemID, &m.Quantity, &m.Currency,
+				&m.Returned, &m.CreatedAt, &m.ReadAt, &m.ClaimedAt, &m.ExpiresAt, &r.ID, &r.Name)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			mail = append(mail, &m)
+			recipients = append(recipients, r)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		for i, m := range mail {
+			var deleted bool
+			err = tx.BeginFunc(ctx, func(savepoint pgx.Tx) (err error) {
+				deleted, err = expireMail(ctx, savepoint, m, recipients[i].ID, recipients[i].Name)
+				return err
+			})
+			if err != nil {
+				log.Printf("could not expire mail %v: %v", m.ID, err.Error())
+				continue
+			}
+			if deleted {
+				expired++
+			}
+		}
+		return nil
+	})
+	return expired, err
+}
@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"tribble/models"
+	"tribble/settings"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func expireNow(t *testing.T, playerID int) {
+	t.Helper()
+	sql := `UPDATE mail SET expires_at = now() - interval '1 second' WHERE recipient_id=$1`
+	if _, err := pg.DB.Exec(context.Background(), sql, playerID); err != nil {
+		t.Fatalf("%s FAILED: could not expire mail: %v", t.Name(), err)
+	}
+	for {
+		expired, err := pg.ExpireMail(context.Background(), settings.MailExpireBatch)
+		if err != nil {
+			t.Fatalf("%s FAILED: could not expire mail: %v", t.Name(), err)
+		}
+		if expired == 0 {
+			return
+		}
+	}
+}
+
+func TestExpireAuctionMail(t *testing.T) {
+	ctx := context.Background()
+	seller, first, second := createTestPlayer(t), createTestPlayer(t), createTestPlayer(t)
+	addTestItem(t, seller.ID, "iron_sword", 1)
+	grantTestCurrency(t, first.ID, 100)
+	grantTestCurrency(t, second.ID, 100)
+
+	a, err := pg.CreateAuction(ctx, seller.ID, models.NewAuction{Slot: 0, Quantity: 1, StartBid: 10, Buyout: 50, Hours: 12})
+	assert.Equal(t, err, nil)
+	_, err = pg.BidOnAuction(ctx, first.ID, a.ID, 20, fmt.Sprintf("test:auction:%d:bid", a.ID))
+	assert.Equal(t, err, nil)
+	_, err = pg.BuyoutAuction(ctx, second.ID, a.ID, fmt.Sprintf("test:auction:%d:buyout", a.ID))
+	assert.Equal(t, err, nil)
+
+	// the outbid refund, the won item and the proceeds have no sender to go
+	// back to, they are delivered
+	expireNow(t, first.ID)
+	expireNow(t, second.ID)
+	expireNow(t, seller.ID)
+	for _, player := range []*models.Player{first, second, seller} {
+		mail, err := pg.GetMail(ctx, player.ID, 0, 10)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, len(mail), 0)
+	}
+
+	balance, err := pg.GetBalance(ctx, first.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, int64(100))
+	inventory, err := pg.GetInventory(ctx, second.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(inventory), 1)
+	assert.Equal(t, inventory[0].ItemID, "iron_sword")
+	balance, err = pg.GetBalance(ctx, seller.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance > 0, true)
+}
+
+func TestExpireSystemMailFullInventory(t *testing.T) {
+	ctx := context.Background()
+	player := createTestPlayer(t)
+	for slot := 0; slot < settings.InventoryCapacity; slot++ {
+		addTestItem(t, player.ID, "iron_sword", 1)
+	}
+	_, err := pg.SendSystemMail(ctx, player.ID, models.SystemMail{Subject: "Gift", ItemID: "iron_sword", Quantity: 1, Currency: 5},
+		fmt.Sprintf("test:gift:%d", player.ID))
+	assert.Equal(t, err, nil)
+
+	// kept for another period while there is no room
+	expireNow(t, player.ID)
+	mail, err := pg.GetMail(ctx, player.ID, 0, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mail), 1)
+	assert.Equal(t, mail[0].ExpiresAt.After(time.Now().Add(settings.MailExpiry/2)), true)
+	balance, err := pg.GetBalance(ctx, player.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, int64(0))
+}
+
+func TestExpirePlayerMail(t *testing.T) {
+	ctx := context.Background()
+	sender, recipient := createTestPlayer(t), createTestPlayer(t)
+	grantTestCurrency(t, sender.ID, 100)
+
+	_, err := pg.SendMail(ctx, sender.ID, recipient.ID, models.NewMail{Subject: "Gold", Currency: 40}, "")
+	assert.Equal(t, err, nil)
+	expireNow(t, recipient.ID)
+
+	mail, err := pg.GetMail(ctx, recipient.ID, 0, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mail), 0)
+	mail, err = pg.GetMail(ctx, sender.ID, 0, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mail), 1)
+	assert.Equal(t, mail[0].Returned, true)
+	assert.Equal(t, mail[0].Currency, int64(40))
+
+	// returned mail is not returned again, it is delivered
+	expireNow(t, sender.ID)
+	mail, err = pg.GetMail(ctx, sender.ID, 0, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(mail), 0)
+	balance, err := pg.GetBalance(ctx, sender.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, balance, int64(100))
+}
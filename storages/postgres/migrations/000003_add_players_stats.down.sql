@@ -0,0 +1,7 @@
+ALTER TABLE players
+    DROP COLUMN max_hp,
+    DROP COLUMN max_mana,
+    DROP COLUMN strength,
+    DROP COLUMN agility,
+    DROP COLUMN intellect,
+    DROP COLUMN movement_speed;
@@ -0,0 +1,25 @@
+ALTER TABLE players
+    ADD COLUMN max_hp         int NOT NULL DEFAULT 0,
+    ADD COLUMN max_mana       int NOT NULL DEFAULT 0,
+    ADD COLUMN strength       int NOT NULL DEFAULT 0,
+    ADD COLUMN agility        int NOT NULL DEFAULT 0,
+    ADD COLUMN intellect      int NOT NULL DEFAULT 0,
+    ADD COLUMN movement_speed int NOT NULL DEFAULT 0;
+
+-- backfill existing players with the level 1 stats of the class catalog as
+-- shipped with this migration. It is frozen: it must not follow later edits
+-- of data/classes.json, the players created since store their own stats.
+UPDATE players
+SET max_hp         = class.max_hp,
+    max_mana       = class.max_mana,
+    strength       = class.strength,
+    agility        = class.agility,
+    intellect      = class.intellect,
+    movement_speed = class.movement_speed
+FROM (VALUES ('assassin', 90, 40, 8, 14, 5, 6),
+             ('warrior', 130, 20, 14, 7, 3, 4),
+             ('templar', 115, 60, 11, 5, 9, 4),
+             ('archer', 95, 35, 7, 13, 6, 5),
+             ('mage', 75, 120, 3, 6, 15, 4))
+         AS class (sprite, max_hp, max_mana, strength, agility, intellect, movement_speed)
+WHERE players.sprite = class.sprite;
@@ -0,0 +1,12 @@
+CREATE TABLE inventory_items
+(
+    player_id int         NOT NULL,
+    slot      smallint    NOT NULL,
+    item_id   varchar(64) NOT NULL,
+    quantity  int         NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (player_id, slot)
+);
+
+ALTER TABLE inventory_items
+    ADD CONSTRAINT inventory_items_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
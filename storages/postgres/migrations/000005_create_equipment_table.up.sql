@@ -0,0 +1,11 @@
+CREATE TABLE equipment
+(
+    player_id int         NOT NULL,
+    slot      varchar(16) NOT NULL,
+    item_id   varchar(64) NOT NULL,
+    PRIMARY KEY (player_id, slot)
+);
+
+ALTER TABLE equipment
+    ADD CONSTRAINT equipment_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
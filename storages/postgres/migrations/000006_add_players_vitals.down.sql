@@ -0,0 +1,4 @@
+ALTER TABLE players
+    DROP COLUMN map,
+    DROP COLUMN hp,
+    DROP COLUMN mana;
@@ -0,0 +1,8 @@
+ALTER TABLE players
+    ADD COLUMN map  varchar(64) NOT NULL DEFAULT 'village',
+    ADD COLUMN hp   int         NOT NULL DEFAULT 0,
+    ADD COLUMN mana int         NOT NULL DEFAULT 0;
+
+UPDATE players
+SET hp   = max_hp,
+    mana = max_mana;
@@ -0,0 +1,14 @@
+CREATE TABLE player_quests
+(
+    player_id    int         NOT NULL,
+    quest_id     varchar(64) NOT NULL,
+    status       varchar(16) NOT NULL DEFAULT 'active',
+    progress     int[]       NOT NULL,
+    accepted_at  timestamp   NOT NULL DEFAULT now(),
+    completed_at timestamp,
+    PRIMARY KEY (player_id, quest_id)
+);
+
+ALTER TABLE player_quests
+    ADD CONSTRAINT player_quests_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
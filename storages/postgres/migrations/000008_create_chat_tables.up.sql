@@ -0,0 +1,24 @@
+CREATE TABLE chat_messages
+(
+    id           bigserial PRIMARY KEY,
+    channel      varchar(64)  NOT NULL,
+    sender_id    int          NOT NULL,
+    sender_name  varchar(32)  NOT NULL,
+    recipient_id int,
+    text         varchar(512) NOT NULL,
+    sent_at      timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX chat_messages_channel_id ON chat_messages (channel, id DESC);
+
+CREATE TABLE chat_mutes
+(
+    user_id    int PRIMARY KEY,
+    until      timestamp    NOT NULL,
+    reason     varchar(256) NOT NULL DEFAULT '',
+    created_at timestamp    NOT NULL DEFAULT now()
+);
+
+ALTER TABLE chat_mutes
+    ADD CONSTRAINT chat_mutes_user_id_fk_user_id
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
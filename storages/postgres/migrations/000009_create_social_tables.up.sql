@@ -0,0 +1,47 @@
+CREATE TABLE friend_requests
+(
+    id           serial PRIMARY KEY,
+    sender_id    int       NOT NULL,
+    recipient_id int       NOT NULL,
+    created_at   timestamp NOT NULL DEFAULT now(),
+    UNIQUE (sender_id, recipient_id)
+);
+
+ALTER TABLE friend_requests
+    ADD CONSTRAINT friend_requests_sender_id_fk_user_id
+        FOREIGN KEY (sender_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT friend_requests_recipient_id_fk_user_id
+        FOREIGN KEY (recipient_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX friend_requests_recipient_id ON friend_requests (recipient_id);
+
+-- every friendship is stored once per direction
+CREATE TABLE friendships
+(
+    user_id    int       NOT NULL,
+    friend_id  int       NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, friend_id)
+);
+
+ALTER TABLE friendships
+    ADD CONSTRAINT friendships_user_id_fk_user_id
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT friendships_friend_id_fk_user_id
+        FOREIGN KEY (friend_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE TABLE blocks
+(
+    user_id    int       NOT NULL,
+    blocked_id int       NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, blocked_id)
+);
+
+ALTER TABLE blocks
+    ADD CONSTRAINT blocks_user_id_fk_user_id
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    ADD CONSTRAINT blocks_blocked_id_fk_user_id
+        FOREIGN KEY (blocked_id) REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX blocks_blocked_id ON blocks (blocked_id);
@@ -0,0 +1,6 @@
+DROP TABLE guild_bank_log;
+DROP TABLE guild_bank_items;
+DROP TABLE guild_invites;
+DROP TABLE guild_members;
+DROP TABLE guild_ranks;
+DROP TABLE guilds;
@@ -0,0 +1,92 @@
+CREATE TABLE guilds
+(
+    id         serial PRIMARY KEY,
+    name       varchar(32)  NOT NULL,
+    motd       varchar(512) NOT NULL DEFAULT '',
+    created_at timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX name_unique_guilds_idx on guilds (LOWER(name));
+
+-- rank 0 is the guild master
+CREATE TABLE guild_ranks
+(
+    guild_id    int           NOT NULL,
+    rank        smallint      NOT NULL,
+    name        varchar(32)   NOT NULL,
+    permissions varchar(16)[] NOT NULL DEFAULT '{}',
+    PRIMARY KEY (guild_id, rank)
+);
+
+ALTER TABLE guild_ranks
+    ADD CONSTRAINT guild_ranks_guild_id_fk_guild_id
+        FOREIGN KEY (guild_id) REFERENCES guilds (id) ON DELETE CASCADE;
+
+-- a player is in one guild at most
+CREATE TABLE guild_members
+(
+    player_id int       PRIMARY KEY,
+    guild_id  int       NOT NULL,
+    rank      smallint  NOT NULL,
+    joined_at timestamp NOT NULL DEFAULT now()
+);
+
+ALTER TABLE guild_members
+    ADD CONSTRAINT guild_members_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE,
+    ADD CONSTRAINT guild_members_guild_id_fk_guild_id
+        FOREIGN KEY (guild_id) REFERENCES guilds (id) ON DELETE CASCADE;
+
+CREATE INDEX guild_members_guild_id ON guild_members (guild_id);
+
+CREATE TABLE guild_invites
+(
+    guild_id   int       NOT NULL,
+    player_id  int       NOT NULL,
+    inviter_id int       NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (guild_id, player_id)
+);
+
+ALTER TABLE guild_invites
+    ADD CONSTRAINT guild_invites_guild_id_fk_guild_id
+        FOREIGN KEY (guild_id) REFERENCES guilds (id) ON DELETE CASCADE,
+    ADD CONSTRAINT guild_invites_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE,
+    ADD CONSTRAINT guild_invites_inviter_id_fk_player_id
+        FOREIGN KEY (inviter_id) REFERENCES players (id) ON DELETE CASCADE;
+
+CREATE INDEX guild_invites_player_id ON guild_invites (player_id);
+
+CREATE TABLE guild_bank_items
+(
+    guild_id int         NOT NULL,
+    slot     smallint    NOT NULL,
+    item_id  varchar(64) NOT NULL,
+    quantity int         NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY (guild_id, slot)
+);
+
+ALTER TABLE guild_bank_items
+    ADD CONSTRAINT guild_bank_items_guild_id_fk_guild_id
+        FOREIGN KEY (guild_id) REFERENCES guilds (id) ON DELETE CASCADE;
+
+CREATE TABLE guild_bank_log
+(
+    id          bigserial PRIMARY KEY,
+    guild_id    int         NOT NULL,
+    player_id   int,
+    player_name varchar(32) NOT NULL,
+    action      varchar(16) NOT NULL,
+    item_id     varchar(64) NOT NULL,
+    quantity    int         NOT NULL,
+    created_at  timestamp   NOT NULL DEFAULT now()
+);
+
+ALTER TABLE guild_bank_log
+    ADD CONSTRAINT guild_bank_log_guild_id_fk_guild_id
+        FOREIGN KEY (guild_id) REFERENCES guilds (id) ON DELETE CASCADE,
+    ADD CONSTRAINT guild_bank_log_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX guild_bank_log_guild_id ON guild_bank_log (guild_id, id);
@@ -0,0 +1,3 @@
+DROP MATERIALIZED VIEW xp_leaderboard;
+DROP INDEX players_sprite_xp;
+DROP INDEX players_xp;
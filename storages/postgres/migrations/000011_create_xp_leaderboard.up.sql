@@ -0,0 +1,21 @@
+CREATE INDEX players_xp ON players (xp DESC, id);
+CREATE INDEX players_sprite_xp ON players (sprite, xp DESC, id);
+
+-- ties share their rank, positions break them by player id so pages are
+-- stable. The server refreshes it every settings.LeaderboardRefresh.
+CREATE MATERIALIZED VIEW xp_leaderboard AS
+SELECT id                                                           AS player_id,
+       name,
+       sprite,
+       xp,
+       RANK() OVER (ORDER BY xp DESC)                               AS rank,
+       ROW_NUMBER() OVER (ORDER BY xp DESC, id)                     AS position,
+       RANK() OVER (PARTITION BY sprite ORDER BY xp DESC)           AS class_rank,
+       ROW_NUMBER() OVER (PARTITION BY sprite ORDER BY xp DESC, id) AS class_position,
+       now()                                                        AS refreshed_at
+FROM players;
+
+-- the unique index allows refreshing concurrently
+CREATE UNIQUE INDEX xp_leaderboard_player_id ON xp_leaderboard (player_id);
+CREATE INDEX xp_leaderboard_position ON xp_leaderboard (position);
+CREATE INDEX xp_leaderboard_class_position ON xp_leaderboard (sprite, class_position);
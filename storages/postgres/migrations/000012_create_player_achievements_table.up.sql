@@ -0,0 +1,15 @@
+CREATE TABLE player_achievements
+(
+    player_id      int         NOT NULL,
+    achievement_id varchar(64) NOT NULL,
+    progress       int         NOT NULL DEFAULT 0,
+    -- the last day counted by streaks
+    last_day       date,
+    updated_at     timestamp   NOT NULL DEFAULT now(),
+    unlocked_at    timestamp,
+    PRIMARY KEY (player_id, achievement_id)
+);
+
+ALTER TABLE player_achievements
+    ADD CONSTRAINT player_achievements_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
@@ -0,0 +1,15 @@
+CREATE TABLE user_entitlements
+(
+    id         serial PRIMARY KEY,
+    user_id    int          NOT NULL,
+    kind       varchar(32)  NOT NULL,
+    quantity   int          NOT NULL CHECK (quantity > 0),
+    reason     varchar(128) NOT NULL DEFAULT '',
+    granted_at timestamp    NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_entitlements_user_id_idx ON user_entitlements (user_id, kind);
+
+ALTER TABLE user_entitlements
+    ADD CONSTRAINT user_entitlements_user_id_fk_user_id
+        FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
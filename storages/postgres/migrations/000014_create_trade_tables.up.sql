@@ -0,0 +1,35 @@
+CREATE TABLE trades
+(
+    id           bigserial PRIMARY KEY,
+    player_id    int,
+    player_name  varchar(32) NOT NULL,
+    partner_id   int,
+    partner_name varchar(32) NOT NULL,
+    completed_at timestamp   NOT NULL DEFAULT now()
+);
+
+ALTER TABLE trades
+    ADD CONSTRAINT trades_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE SET NULL,
+    ADD CONSTRAINT trades_partner_id_fk_player_id
+        FOREIGN KEY (partner_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX trades_player_id ON trades (player_id);
+CREATE INDEX trades_partner_id ON trades (partner_id);
+
+-- the items each side gave, from_player tells whether the player or the
+-- partner of the trade gave them
+CREATE TABLE trade_items
+(
+    id          bigserial PRIMARY KEY,
+    trade_id    bigint      NOT NULL,
+    from_player boolean     NOT NULL,
+    item_id     varchar(64) NOT NULL,
+    quantity    int         NOT NULL
+);
+
+ALTER TABLE trade_items
+    ADD CONSTRAINT trade_items_trade_id_fk_trade_id
+        FOREIGN KEY (trade_id) REFERENCES trades (id) ON DELETE CASCADE;
+
+CREATE INDEX trade_items_trade_id ON trade_items (trade_id);
@@ -0,0 +1,13 @@
+DELETE FROM guild_bank_log WHERE item_id IS NULL;
+ALTER TABLE guild_bank_log
+    DROP COLUMN currency,
+    ALTER COLUMN item_id SET NOT NULL;
+
+ALTER TABLE trades
+    DROP COLUMN player_currency,
+    DROP COLUMN partner_currency;
+
+DROP TABLE ledger_snapshots;
+DROP TABLE ledger_entries;
+DROP TABLE ledger_transactions;
+DROP TABLE ledger_accounts;
@@ -0,0 +1,64 @@
+-- accounts are named by key: player:<id>, guild:<id> or system:<name>.
+-- System accounts are the source and sink of currency and may go negative.
+CREATE TABLE ledger_accounts
+(
+    id         bigserial PRIMARY KEY,
+    key        varchar(64) NOT NULL UNIQUE,
+    system     boolean     NOT NULL DEFAULT false,
+    created_at timestamp   NOT NULL DEFAULT now()
+);
+
+INSERT INTO ledger_accounts (key, system)
+VALUES ('system:world', true),
+       ('system:sink', true);
+
+CREATE TABLE ledger_transactions
+(
+    id              bigserial PRIMARY KEY,
+    reason          varchar(32)  NOT NULL,
+    idempotency_key varchar(128) NOT NULL UNIQUE,
+    created_at      timestamp    NOT NULL DEFAULT now()
+);
+
+-- the entries of a transaction sum to zero, credits are positive
+CREATE TABLE ledger_entries
+(
+    id             bigserial PRIMARY KEY,
+    transaction_id bigint NOT NULL,
+    account_id     bigint NOT NULL,
+    amount         bigint NOT NULL CHECK (amount <> 0)
+);
+
+ALTER TABLE ledger_entries
+    ADD CONSTRAINT ledger_entries_transaction_id_fk_transaction_id
+        FOREIGN KEY (transaction_id) REFERENCES ledger_transactions (id),
+    ADD CONSTRAINT ledger_entries_account_id_fk_account_id
+        FOREIGN KEY (account_id) REFERENCES ledger_accounts (id);
+
+CREATE INDEX ledger_entries_transaction_id ON ledger_entries (transaction_id);
+CREATE INDEX ledger_entries_account_id ON ledger_entries (account_id, id);
+
+-- the balance of an account up to entry_id, entries after it are added on
+-- read
+CREATE TABLE ledger_snapshots
+(
+    account_id bigint    PRIMARY KEY,
+    balance    bigint    NOT NULL DEFAULT 0,
+    entry_id   bigint    NOT NULL DEFAULT 0,
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+ALTER TABLE ledger_snapshots
+    ADD CONSTRAINT ledger_snapshots_account_id_fk_account_id
+        FOREIGN KEY (account_id) REFERENCES ledger_accounts (id);
+
+INSERT INTO ledger_snapshots (account_id)
+SELECT id FROM ledger_accounts;
+
+ALTER TABLE trades
+    ADD COLUMN player_currency  bigint NOT NULL DEFAULT 0,
+    ADD COLUMN partner_currency bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE guild_bank_log
+    ALTER COLUMN item_id DROP NOT NULL,
+    ADD COLUMN currency bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE vendor_transactions;
+DROP TABLE vendor_stock;
@@ -0,0 +1,34 @@
+-- the stock of limited vendor offers, rows are created on the first
+-- purchase and restocked lazily from restocked_at
+CREATE TABLE vendor_stock
+(
+    vendor_id    varchar(64) NOT NULL,
+    item_id      varchar(64) NOT NULL,
+    stock        int         NOT NULL CHECK (stock >= 0),
+    restocked_at timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (vendor_id, item_id)
+);
+
+-- every purchase and sale, kept for the economy analytics
+CREATE TABLE vendor_transactions
+(
+    id             bigserial PRIMARY KEY,
+    transaction_id bigint      NOT NULL UNIQUE,
+    player_id      int,
+    vendor_id      varchar(64) NOT NULL,
+    action         varchar(4)  NOT NULL CHECK (action IN ('buy', 'sell')),
+    item_id        varchar(64) NOT NULL,
+    quantity       int         NOT NULL,
+    price          bigint      NOT NULL,
+    total          bigint      NOT NULL,
+    created_at     timestamp   NOT NULL DEFAULT now()
+);
+
+ALTER TABLE vendor_transactions
+    ADD CONSTRAINT vendor_transactions_transaction_id_fk_transaction_id
+        FOREIGN KEY (transaction_id) REFERENCES ledger_transactions (id),
+    ADD CONSTRAINT vendor_transactions_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX vendor_transactions_player_id ON vendor_transactions (player_id);
+CREATE INDEX vendor_transactions_vendor_id ON vendor_transactions (vendor_id, created_at);
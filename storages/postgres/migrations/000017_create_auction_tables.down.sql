@@ -0,0 +1,3 @@
+DROP TABLE auction_bids;
+DROP TABLE auctions;
+DROP TABLE mail;
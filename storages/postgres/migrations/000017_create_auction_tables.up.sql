@@ -0,0 +1,73 @@
+-- mail attachments are claimed separately, the currency of a mail is held
+-- by the ledger account mail:<id> until then
+CREATE TABLE mail
+(
+    id           bigserial PRIMARY KEY,
+    recipient_id int           NOT NULL,
+    sender_id    int,
+    sender_name  varchar(32)   NOT NULL,
+    subject      varchar(64)   NOT NULL,
+    body         varchar(1024) NOT NULL DEFAULT '',
+    item_id      varchar(64),
+    quantity     int           NOT NULL DEFAULT 0,
+    currency     bigint        NOT NULL DEFAULT 0,
+    created_at   timestamp     NOT NULL DEFAULT now(),
+    claimed_at   timestamp
+);
+
+ALTER TABLE mail
+    ADD CONSTRAINT mail_recipient_id_fk_player_id
+        FOREIGN KEY (recipient_id) REFERENCES players (id) ON DELETE CASCADE,
+    ADD CONSTRAINT mail_sender_id_fk_player_id
+        FOREIGN KEY (sender_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX mail_recipient_id ON mail (recipient_id, id);
+
+-- the listed items are held by the auction, the highest bid by the ledger
+-- account auction:<id>
+CREATE TABLE auctions
+(
+    id          bigserial PRIMARY KEY,
+    seller_id   int,
+    seller_name varchar(32) NOT NULL,
+    item_id     varchar(64) NOT NULL,
+    item_type   varchar(16) NOT NULL,
+    rarity      varchar(16) NOT NULL,
+    quantity    int         NOT NULL CHECK (quantity > 0),
+    start_bid   bigint      NOT NULL CHECK (start_bid > 0),
+    buyout      bigint      NOT NULL DEFAULT 0,
+    bid         bigint      NOT NULL DEFAULT 0,
+    bidder_id   int,
+    bids        int         NOT NULL DEFAULT 0,
+    status      varchar(16) NOT NULL DEFAULT 'active',
+    created_at  timestamp   NOT NULL DEFAULT now(),
+    ends_at     timestamp   NOT NULL,
+    settled_at  timestamp
+);
+
+ALTER TABLE auctions
+    ADD CONSTRAINT auctions_seller_id_fk_player_id
+        FOREIGN KEY (seller_id) REFERENCES players (id) ON DELETE SET NULL,
+    ADD CONSTRAINT auctions_bidder_id_fk_player_id
+        FOREIGN KEY (bidder_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX auctions_active ON auctions (item_type, rarity, id) WHERE status = 'active';
+CREATE INDEX auctions_ends_at ON auctions (ends_at) WHERE status = 'active';
+CREATE INDEX auctions_seller_id ON auctions (seller_id, id);
+
+CREATE TABLE auction_bids
+(
+    id         bigserial PRIMARY KEY,
+    auction_id bigint    NOT NULL,
+    bidder_id  int,
+    amount     bigint    NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+ALTER TABLE auction_bids
+    ADD CONSTRAINT auction_bids_auction_id_fk_auction_id
+        FOREIGN KEY (auction_id) REFERENCES auctions (id) ON DELETE CASCADE,
+    ADD CONSTRAINT auction_bids_bidder_id_fk_player_id
+        FOREIGN KEY (bidder_id) REFERENCES players (id) ON DELETE SET NULL;
+
+CREATE INDEX auction_bids_auction_id ON auction_bids (auction_id);
@@ -0,0 +1,5 @@
+ALTER TABLE mail
+    DROP COLUMN read_at,
+    DROP COLUMN expires_at,
+    DROP COLUMN returned,
+    DROP COLUMN idempotency_key;
@@ -0,0 +1,10 @@
+-- unclaimed attachments of expired mail go back to the sender once,
+-- returned mail is not returned again
+ALTER TABLE mail
+    ADD COLUMN read_at         timestamp,
+    ADD COLUMN expires_at      timestamp NOT NULL DEFAULT now() + interval '30 days',
+    ADD COLUMN returned        boolean   NOT NULL DEFAULT false,
+    ADD COLUMN idempotency_key varchar(128) UNIQUE;
+
+CREATE INDEX mail_expires_at ON mail (expires_at);
+CREATE INDEX mail_unread ON mail (recipient_id) WHERE read_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TABLE crafts;
+DROP TABLE player_professions;
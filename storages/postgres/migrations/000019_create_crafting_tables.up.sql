@@ -0,0 +1,35 @@
+CREATE TABLE player_professions
+(
+    player_id  int         NOT NULL,
+    profession varchar(32) NOT NULL,
+    xp         int         NOT NULL DEFAULT 0 CHECK (xp >= 0),
+    PRIMARY KEY (player_id, profession)
+);
+
+ALTER TABLE player_professions
+    ADD CONSTRAINT player_professions_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
+
+-- the ingredients are taken when a craft starts, the output and the xp
+-- are given to the player when it finishes and is collected
+CREATE TABLE crafts
+(
+    id           bigserial PRIMARY KEY,
+    player_id    int         NOT NULL,
+    recipe_id    varchar(64) NOT NULL,
+    profession   varchar(32) NOT NULL,
+    item_id      varchar(64) NOT NULL,
+    quantity     int         NOT NULL CHECK (quantity > 0),
+    xp           int         NOT NULL DEFAULT 0,
+    status       varchar(16) NOT NULL DEFAULT 'crafting',
+    started_at   timestamp   NOT NULL DEFAULT now(),
+    finishes_at  timestamp   NOT NULL,
+    collected_at timestamp
+);
+
+ALTER TABLE crafts
+    ADD CONSTRAINT crafts_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
+
+CREATE INDEX crafts_player_id ON crafts (player_id, id) WHERE status <> 'collected';
+CREATE INDEX crafts_finishes_at ON crafts (finishes_at) WHERE status = 'crafting';
@@ -0,0 +1 @@
+DROP TABLE player_effects;
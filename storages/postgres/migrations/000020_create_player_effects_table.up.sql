@@ -0,0 +1,13 @@
+-- status effects that outlast the session of their player
+CREATE TABLE player_effects
+(
+    player_id  int         NOT NULL,
+    effect_id  varchar(64) NOT NULL,
+    stacks     int         NOT NULL DEFAULT 1 CHECK (stacks > 0),
+    expires_at timestamptz NOT NULL,
+    PRIMARY KEY (player_id, effect_id)
+);
+
+ALTER TABLE player_effects
+    ADD CONSTRAINT player_effects_player_id_fk_player_id
+        FOREIGN KEY (player_id) REFERENCES players (id) ON DELETE CASCADE;
@@ -0,0 +1,4 @@
+ALTER TABLE players
+    DROP COLUMN name_skeleton;
+ALTER TABLE users
+    DROP COLUMN username_skeleton;
@@ -0,0 +1,9 @@
+-- names folded with names.Skeleton, so look-alikes of a taken name are
+-- refused. Names from before are folded by the names.skeletons job.
+ALTER TABLE users
+    ADD COLUMN username_skeleton varchar(50);
+ALTER TABLE players
+    ADD COLUMN name_skeleton varchar(32);
+
+CREATE UNIQUE INDEX skeleton_unique_users_idx on users (username_skeleton);
+CREATE UNIQUE INDEX skeleton_unique_players_idx on players (name_skeleton);
@@ -0,0 +1,4 @@
+ALTER TABLE auction_bids
+    DROP COLUMN idempotency_key,
+    DROP COLUMN asked,
+    DROP COLUMN buyout;
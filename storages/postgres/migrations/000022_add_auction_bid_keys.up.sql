@@ -0,0 +1,6 @@
+-- what was asked under the idempotency key of a bid, so a replay asking
+-- for another bid is refused
+ALTER TABLE auction_bids
+    ADD COLUMN idempotency_key varchar(128) UNIQUE,
+    ADD COLUMN asked           bigint  NOT NULL DEFAULT 0,
+    ADD COLUMN buyout          boolean NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+DROP INDEX xp_leaderboard_sprite_xp;
+DROP INDEX xp_leaderboard_xp;
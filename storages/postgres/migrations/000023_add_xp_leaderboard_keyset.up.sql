@@ -0,0 +1,3 @@
+-- pages are read by xp and player id, positions shift on every refresh
+CREATE INDEX xp_leaderboard_xp ON xp_leaderboard (xp DESC, player_id);
+CREATE INDEX xp_leaderboard_sprite_xp ON xp_leaderboard (sprite, xp DESC, player_id);
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"log"
+	"tribble/names"
+
+	"github.com/jackc/pgconn"
+)
+
+// FoldNames stores the skeletons of the users and players named before
+// skeletons were. A name whose skeleton is already taken by a look-alike
+// keeps none and is logged, it is still compared by its lower case.
+func (p Postgres) FoldNames(ctx context.Context) (int, error) {
+	folded := 0
+	for _, table := range []struct{ name, column, skeleton string }{
+		{"users", "username", "username_skeleton"},
+		{"players", "name", "name_skeleton"},
+	} {
+		rows, err := p.DB.Query(ctx, `SELECT id, `+table.column+` FROM `+table.name+` WHERE `+table.skeleton+` IS NULL`)
+		if err != nil {
+			return folded, err
+		}
+		unfolded := make(map[int]string)
+		for rows.Next() {
+			var ID int
+			var name string
+			if err = rows.Scan(&ID, &name); err != nil {
+				rows.Close()
+				return folded, err
+			}
+			unfolded[ID] = name
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil {
+			return folded, err
+		}
+
+		sql := `UPDATE ` + table.name + ` SET ` + table.skeleton + `=$2 WHERE id=$1`
+		for ID, name := range unfolded {
+			_, err := p.DB.Exec(ctx, sql, ID, names.Skeleton(name))
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				log.Printf("%v %v: %q looks like a name taken before", table.name, ID, name)
+				continue
+			}
+			if err != nil {
+				return folded, err
+			}
+			folded++
+		}
+	}
+	return folded, nil
+}
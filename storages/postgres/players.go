@@ -0,0 +1,201 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+	"tribble/names"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const playerColumns = `id, user_id, name, xp, sprite, position_x, position_y,
+	max_hp, max_mana, strength, agility, intellect, movement_speed, map, hp, mana`
+
+// playerFields are the scan destinations of playerColumns.
+func playerFields(player *models.Player) []interface{} {
+	return []interface{}{
+		&player.ID,
+		&player.UserID,
+		&player.Name,
+		&player.XP,
+		&player.Sprite,
+		&player.PositionX,
+		&player.PositionY,
+		&player.Stats.HP,
+		&player.Stats.Mana,
+		&player.Stats.Strength,
+		&player.Stats.Agility,
+		&player.Stats.Intellect,
+		&player.Stats.MovementSpeed,
+		&player.Map,
+		&player.HP,
+		&player.Mana,
+	}
+}
+
+func scanPlayer(row pgx.Row, player *models.Player) error {
+	return row.Scan(playerFields(player)...)
+}
+
+func (p Postgres) GetPlayer(ctx context.Context, ID int) (*models.Player, error) {
+	sql := `SELECT ` + playerColumns + ` FROM players WHERE id=$1`
+
+	var player models.Player
+	if err := scanPlayer(p.DB.QueryRow(ctx, sql, ID), &player); err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+func (p Postgres) GetPlayerByName(ctx context.Context, name string) (*models.Player, error) {
+	sql := `SELECT ` + playerColumns + ` FROM players WHERE LOWER(name)=LOWER($1)`
+
+	var player models.Player
+	if err := scanPlayer(p.DB.QueryRow(ctx, sql, name), &player); err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+// PlayerNameTaken tells whether a player has the name or one that looks
+// like it.
+func (p Postgres) PlayerNameTaken(ctx context.Context, name string) (bool, error) {
+	sql := `SELECT EXISTS (SELECT 1 FROM players WHERE LOWER(name)=LOWER($1) OR name_skeleton=$2)`
+	var taken bool
+	err := p.DB.QueryRow(ctx, sql, name, names.Skeleton(name)).Scan(&taken)
+	return taken, err
+}
+
+func (p Postgres) GetPlayerList(ctx context.Context, ID int) ([]*models.Player, error) {
+	sql := `SELECT ` + playerColumns + ` FROM players WHERE user_id=$1`
+	rows, err := p.DB.Query(ctx, sql, ID)
+	if err != nil {
+		return []*models.Player{}, err
+	}
+	defer rows.Close()
+
+	players := make([]*models.Player, 0)
+	for rows.Next() {
+		var player models.Player
+		if err = scanPlayer(rows, &player); err != nil {
+			return []*models.Player{}, err
+		}
+		players = append(players, &player)
+	}
+
+	return players, rows.Err()
+}
+
+// CreatePlayer locks the user row so concurrent creates of the same
+// account are counted one after the other.
+func (p Postgres) CreatePlayer(ctx context.Context, player models.Player) (*models.Player, error) {
+	sql := `INSERT INTO players (user_id, name, xp, sprite, position_x, position_y,
+				max_hp, max_mana, strength, agility, intellect, movement_speed, map, hp, mana, name_skeleton)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			RETURNING id`
+
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var userID int
+		if err := tx.QueryRow(ctx, `SELECT id FROM users WHERE id=$1 FOR UPDATE`, player.UserID).Scan(&userID); err != nil {
+			return err
+		}
+		slots, err := characterSlots(ctx, tx, player.UserID)
+		if err != nil {
+			return err
+		}
+		if slots.Used >= slots.Max {
+			return &models.CharacterLimitError{Current: slots.Used, Max: slots.Max}
+		}
+
+		return tx.QueryRow(
+			ctx,
+			sql,
+			player.UserID,
+			player.Name,
+			player.XP,
+			player.Sprite,
+			player.PositionX,
+			player.PositionY,
+			player.Stats.HP,
+			player.Stats.Mana,
+			player.Stats.Strength,
+			player.Stats.Agility,
+			player.Stats.Intellect,
+			player.Stats.MovementSpeed,
+			player.Map,
+			player.HP,
+			player.Mana,
+			names.Skeleton(player.Name),
+		).Scan(&player.ID)
+	})
+	if err != nil {
+		return &player, err
+	}
+
+	return &player, nil
+}
+
+func (p Postgres) GrantXP(ctx context.Context, ID int, amount int) (*models.Player, error) {
+	sql := `UPDATE players SET xp = xp + $2 WHERE id=$1 RETURNING ` + playerColumns
+
+	var player models.Player
+	if err := scanPlayer(p.DB.QueryRow(ctx, sql, ID, amount), &player); err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+func (p Postgres) UpdatePlayerStats(ctx context.Context, ID int, stats models.Stats) error {
+	sql := `UPDATE players
+			SET max_hp=$2, max_mana=$3, strength=$4, agility=$5, intellect=$6, movement_speed=$7
+			WHERE id=$1`
+	res, err := p.DB.Exec(
+		ctx,
+		sql,
+		ID,
+		stats.HP,
+		stats.Mana,
+		stats.Strength,
+		stats.Agility,
+		stats.Intellect,
+		stats.MovementSpeed,
+	)
+	if err != nil {
+		return err
+	}
+	if rowsAffected := res.RowsAffected(); rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (p Postgres) UpdatePlayerVitals(ctx context.Context, ID int, hp, mana int) error {
+	sql := `UPDATE players SET hp=$2, mana=$3 WHERE id=$1`
+	res, err := p.DB.Exec(ctx, sql, ID, hp, mana)
+	if err != nil {
+		return err
+	}
+	if rowsAffected := res.RowsAffected(); rowsAffected == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// RespawnPlayer only updates dead players, so concurrent respawns apply the
+// XP penalty once.
+func (p Postgres) RespawnPlayer(ctx context.Context, ID int, mapName string, x, y, hp, mana int, xpLoss int) (*models.Player, error) {
+	sql := `UPDATE players
+			SET xp=GREATEST(xp - $7, 0), map=$2, position_x=$3, position_y=$4, hp=$5, mana=$6
+			WHERE id=$1 AND hp=0
+			RETURNING ` + playerColumns
+
+	var player models.Player
+	err := scanPlayer(p.DB.QueryRow(ctx, sql, ID, mapName, x, y, hp, mana, xpLoss), &player)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrNotDead
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
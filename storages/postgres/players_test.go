@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"tribble/models"
+	"tribble/settings"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestRespawnPlayer(t *testing.T) {
+	ctx := context.Background()
+	player := createTestPlayer(t)
+	_, err := pg.GrantXP(ctx, player.ID, 50)
+	assert.Equal(t, err, nil)
+
+	_, err = pg.RespawnPlayer(ctx, player.ID, "village", 1, 1, 130, 60, 10)
+	assert.Equal(t, err, models.ErrNotDead)
+
+	assert.Equal(t, pg.UpdatePlayerVitals(ctx, player.ID, 0, 0), nil)
+	respawned, err := pg.RespawnPlayer(ctx, player.ID, "village", 1, 1, 130, 60, 10)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, respawned.XP, 40)
+	assert.Equal(t, respawned.HP, 130)
+	assert.Equal(t, respawned.Mana, 60)
+
+	// a second respawn doesn't take the penalty again
+	_, err = pg.RespawnPlayer(ctx, player.ID, "village", 1, 1, 130, 60, 10)
+	assert.Equal(t, err, models.ErrNotDead)
+	player, err = pg.GetPlayer(ctx, player.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, player.XP, 40)
+}
+
+func TestCreatePlayerLimit(t *testing.T) {
+	ctx := context.Background()
+	user := createTestUser(t)
+	for i := 0; i < settings.CharacterSlots; i++ {
+		_, err := pg.CreatePlayer(ctx, newTestPlayer(user.ID))
+		assert.Equal(t, err, nil)
+	}
+
+	_, err := pg.CreatePlayer(ctx, newTestPlayer(user.ID))
+	limit, ok := err.(*models.CharacterLimitError)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, limit.Current, settings.CharacterSlots)
+	assert.Equal(t, limit.Max, settings.CharacterSlots)
+
+	// a granted slot makes room for one more
+	_, err = pg.GrantEntitlement(ctx, user.ID, models.Entitlement{Kind: models.EntitlementCharacterSlot, Quantity: 1})
+	assert.Equal(t, err, nil)
+	_, err = pg.CreatePlayer(ctx, newTestPlayer(user.ID))
+	assert.Equal(t, err, nil)
+	_, err = pg.CreatePlayer(ctx, newTestPlayer(user.ID))
+	assert.Equal(t, errors.Is(err, models.ErrCharacterLimit), true)
+}
+
+func TestCreatePlayerLimitConcurrent(t *testing.T) {
+	ctx := context.Background()
+	user := createTestUser(t)
+	errs := make([]error, settings.CharacterSlots+4)
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = pg.CreatePlayer(ctx, newTestPlayer(user.ID))
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.Equal(t, errors.Is(err, models.ErrCharacterLimit), true)
+	}
+	assert.Equal(t, created, settings.CharacterSlots)
+}
+
+func TestPlayerNameTaken(t *testing.T) {
+	ctx := context.Background()
+	player := createTestPlayer(t)
+	lookalike := "T3st" + player.Name[len("test"):]
+
+	taken, err := pg.PlayerNameTaken(ctx, lookalike)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, taken, true)
+	taken, err = pg.PlayerNameTaken(ctx, lookalike+"x")
+	assert.Equal(t, err, nil)
+	assert.Equal(t, taken, false)
+
+	other := newTestPlayer(createTestUser(t).ID)
+	other.Name = lookalike
+	_, err = pg.CreatePlayer(ctx, other)
+	assert.NotEqual(t, err, nil)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 	"tribble/models"
+	"tribble/names"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 )
@@ -73,6 +74,14 @@ func (p Postgres) GetUserByUsername(ctx context.Context, username string) (*mode
 	return &user, nil
 }
 
+// UsernameTaken tells whether a user has the name or one that looks like it.
+func (p Postgres) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	sql := `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username)=$1 OR username_skeleton=$2)`
+	var taken bool
+	err := p.DB.QueryRow(ctx, sql, strings.ToLower(username), names.Skeleton(username)).Scan(&taken)
+	return taken, err
+}
+
 func (p Postgres) GetUserByRefresh(ctx context.Context, refresh string) (*models.User, error) {
 	sql := `SELECT id, username, email, password, date_joined FROM users WHERE refresh_token=$1`
 
@@ -107,8 +116,8 @@ func (p Postgres) GetUserList(ctx context.Context) ([]*models.User, error) {
 
 func (p Postgres) CreateUser(ctx context.Context, user models.User) (*models.User, error) {
 
-	sql := `INSERT INTO users (username, email, date_joined, password, token, refresh_token) 
-			VALUES ($1, $2, $3, $4, $5, $6) 
+	sql := `INSERT INTO users (username, email, date_joined, password, token, refresh_token, username_skeleton) 
+			VALUES ($1, $2, $3, $4, $5, $6, $7) 
 			RETURNING id`
 
 	var id int
@@ -126,6 +135,7 @@ func (p Postgres) CreateUser(ctx context.Context, user models.User) (*models.Use
 		user.Password,
 		user.Token,
 		user.RefreshToken,
+		names.Skeleton(user.Username),
 	).Scan(&id); err != nil {
 		return nil, err
 	}
@@ -136,8 +146,8 @@ func (p Postgres) CreateUser(ctx context.Context, user models.User) (*models.Use
 
 func (p Postgres) UpdateUser(ctx context.Context, user models.User) (*models.User, error) {
 	// TODO: updating only username for now
-	sql := `UPDATE users SET username=$2 WHERE id=$1`
-	res, err := p.DB.Exec(ctx, sql, user.ID, user.Username)
+	sql := `UPDATE users SET username=$2, username_skeleton=$3 WHERE id=$1`
+	res, err := p.DB.Exec(ctx, sql, user.ID, user.Username, names.Skeleton(user.Username))
 	if err != nil {
 		return nil, err
 	}
@@ -167,57 +177,6 @@ func (p Postgres) DeleteUser(ctx context.Context, ID int) error {
 	return nil
 }
 
-func (p Postgres) GetPlayerList(ctx context.Context, ID int) ([]*models.Player, error) {
-	sql := `SELECT name, xp, sprite, position_x, position_y FROM players WHERE user_id=$1`
-	rows, err := p.DB.Query(ctx, sql, ID)
-	if err != nil {
-		return []*models.Player{}, err
-	}
-
-	players := make([]*models.Player, 0)
-	for rows.Next() {
-		var player models.Player
-		err = rows.Scan(
-			&player.Name,
-			&player.XP,
-			&player.Sprite,
-			&player.PositionX,
-			&player.PositionY,
-		)
-		if err != nil {
-			return []*models.Player{}, err
-		}
-		players = append(players, &player)
-	}
-
-	return players, nil
-}
-
-func (p Postgres) CreatePlayer(ctx context.Context, player models.Player) (*models.Player, error) {
-	sql := `INSERT INTO players (user_id, name, xp, sprite, position_x, position_y)
-			VALUES ($1, $2, $3, $4, $5, $6)
-			RETURNING id`
-
-	var playerID int
-	err := p.DB.QueryRow(
-		ctx,
-		sql,
-		player.UserID,
-		player.Name,
-		player.XP,
-		player.Sprite,
-		player.PositionX,
-		player.PositionY,
-	).Scan(&playerID)
-	player.ID = playerID
-
-	if err != nil {
-		return &player, err
-	}
-
-	return &player, nil
-}
-
 func (p Postgres) ValidateToken(ctx context.Context, refresh string) (bool, error) {
 	sql := `SELECT id FROM users WHERE refresh_token=$1`
 	var userId int
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tribble/models"
+)
+
+var pg = GetPostgres()
+
+var testUsers int64 = time.Now().UnixNano() % 1e9
+
+// testName is unique even folded into a skeleton, which digits and letters
+// like i and l are not.
+func testName() string {
+	const letters = "abcdefghjkmnopqstuxyz"
+	name := []byte("test")
+	for n := atomic.AddInt64(&testUsers, 1); n > 0; n /= int64(len(letters)) {
+		name = append(name, letters[n%int64(len(letters))])
+	}
+	return string(name)
+}
+
+// createTestUser creates a user deleted with its players when the test
+// ends.
+func createTestUser(t *testing.T) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	user, err := pg.CreateUser(ctx, models.User{
+		Username:     testName(),
+		Password:     "password",
+		Token:        "token",
+		RefreshToken: "refresh",
+		DateJoined:   time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("%s FAILED: could not create user: %v", t.Name(), err)
+	}
+	t.Cleanup(func() {
+		_, _ = pg.DB.Exec(ctx, `DELETE FROM users WHERE id=$1`, user.ID)
+	})
+	return user
+}
+
+func newTestPlayer(userID int) models.Player {
+	return models.Player{
+		UserID: userID,
+		Name:   testName(),
+		Sprite: "warrior",
+		Map:    "village",
+		Stats:  models.Stats{HP: 100, Mana: 50},
+		HP:     100,
+		Mana:   50,
+	}
+}
+
+// createTestPlayer creates a player of a new user.
+func createTestPlayer(t *testing.T) *models.Player {
+	t.Helper()
+	player, err := pg.CreatePlayer(context.Background(), newTestPlayer(createTestUser(t).ID))
+	if err != nil {
+		t.Fatalf("%s FAILED: could not create player: %v", t.Name(), err)
+	}
+	return player
+}
+
+func grantTestCurrency(t *testing.T, playerID int, amount int64) {
+	t.Helper()
+	key := fmt.Sprintf("test:%d:%d", playerID, time.Now().UnixNano())
+	if _, err := pg.GrantCurrency(context.Background(), playerID, amount, models.ReasonAdminGrant, key); err != nil {
+		t.Fatalf("%s FAILED: could not grant currency: %v", t.Name(), err)
+	}
+}
+
+func addTestItem(t *testing.T, playerID int, itemID string, quantity int) {
+	t.Helper()
+	item := models.Item{ID: itemID, StackSize: stackSize(itemID)}
+	if _, err := pg.AddItem(context.Background(), playerID, item, quantity); err != nil {
+		t.Fatalf("%s FAILED: could not add item: %v", t.Name(), err)
+	}
+}
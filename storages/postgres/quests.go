@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const questColumns = `quest_id, status, progress, accepted_at, completed_at`
+
+func scanQuest(row pgx.Row, q *models.PlayerQuest) error {
+	var progress []int32
+	if err := row.Scan(&q.QuestID, &q.Status, &progress, &q.AcceptedAt, &q.CompletedAt); err != nil {
+		return err
+	}
+	q.Progress = make([]int, len(progress))
+	for i, n := range progress {
+		q.Progress[i] = int(n)
+	}
+	return nil
+}
+
+func (p Postgres) GetQuests(ctx context.Context, playerID int) ([]*models.PlayerQuest, error) {
+	sql := `SELECT ` + questColumns + ` FROM player_quests WHERE player_id=$1 ORDER BY accepted_at, quest_id`
+	rows, err := p.DB.Query(ctx, sql, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	quests := make([]*models.PlayerQuest, 0)
+	for rows.Next() {
+		var q models.PlayerQuest
+		if err = scanQuest(rows, &q); err != nil {
+			return nil, err
+		}
+		quests = append(quests, &q)
+	}
+	return quests, rows.Err()
+}
+
+func (p Postgres) AcceptQuest(ctx context.Context, playerID int, questID string, progress []int) (*models.PlayerQuest, error) {
+	var quest models.PlayerQuest
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		var status string
+		err := tx.QueryRow(ctx, `SELECT status FROM player_quests WHERE player_id=$1 AND quest_id=$2`, playerID, questID).Scan(&status)
+		switch {
+		case err == nil && status == models.QuestCompleted:
+			return models.ErrQuestCompleted
+		case err == nil:
+			return models.ErrQuestAccepted
+		case err != pgx.ErrNoRows:
+			return err
+		}
+
+		var active int
+		sql := `SELECT count(*) FROM player_quests WHERE player_id=$1 AND status=$2`
+		if err = tx.QueryRow(ctx, sql, playerID, models.QuestActive).Scan(&active); err != nil {
+			return err
+		}
+		if active >= settings.MaxActiveQuests {
+			return models.ErrTooManyQuests
+		}
+
+		sql = `INSERT INTO player_quests (player_id, quest_id, status, progress)
+				VALUES ($1, $2, $3, $4)
+				RETURNING ` + questColumns
+		return scanQuest(tx.QueryRow(ctx, sql, playerID, questID, models.QuestActive, progress), &quest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &quest, nil
+}
+
+func (p Postgres) AbandonQuest(ctx context.Context, playerID int, questID string) error {
+	sql := `DELETE FROM player_quests WHERE player_id=$1 AND quest_id=$2 AND status=$3`
+	tag, err := p.DB.Exec(ctx, sql, playerID, questID, models.QuestActive)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrQuestNotActive
+	}
+	return nil
+}
+
+// AddQuestProgress raises the count of an objective, never above what the
+// objective requires. Objectives are numbered from zero.
+func (p Postgres) AddQuestProgress(ctx context.Context, playerID int, questID string, objective, amount, required int) error {
+	sql := `UPDATE player_quests
+			SET progress[$4] = LEAST(progress[$4] + $5, $6)
+			WHERE player_id=$1 AND quest_id=$2 AND status=$3`
+	_, err := p.DB.Exec(ctx, sql, playerID, questID, models.QuestActive, objective+1, amount, required)
+	return err
+}
+
+// CompleteQuest takes the cost out of the inventory, hands out the reward
+// items and XP and marks the quest as completed, all or nothing.
+func (p Postgres) CompleteQuest(ctx context.Context, playerID int, questID string, cost, rewards []models.ItemStack, xp int) (*models.PlayerQuest, *models.Player, error) {
+	var quest models.PlayerQuest
+	var player *models.Player
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockPlayer(ctx, tx, playerID); err != nil {
+			return err
+		}
+
+		sql := `UPDATE player_quests SET status=$4, completed_at=now()
+				WHERE player_id=$1 AND quest_id=$2 AND status=$3
+				RETURNING ` + questColumns
+		err := scanQuest(tx.QueryRow(ctx, sql, playerID, questID, models.QuestActive, models.QuestCompleted), &quest)
+		if err == pgx.ErrNoRows {
+			return models.ErrQuestNotActive
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, stack := range cost {
+			if err = takeItems(ctx, tx, playerID, stack.ItemID, stack.Quantity); err != nil {
+				return err
+			}
+		}
+		for _, stack := range rewards {
+			item, ok := items.Default.Get(stack.ItemID)
+			if !ok {
+				return fmt.Errorf("unknown item %v", stack.ItemID)
+			}
+			if err = addItem(ctx, tx, playerID, *item, stack.Quantity); err != nil {
+				return err
+			}
+		}
+		if xp <= 0 {
+			return nil
+		}
+		player = &models.Player{}
+		sql = `UPDATE players SET xp = xp + $2 WHERE id=$1 RETURNING ` + playerColumns
+		return scanPlayer(tx.QueryRow(ctx, sql, playerID, xp), player)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &quest, player, nil
+}
@@ -0,0 +1,28 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestCompleteQuestGrantsXP(t *testing.T) {
+	ctx := context.Background()
+	player := createTestPlayer(t)
+	_, err := pg.AcceptQuest(ctx, player.ID, "test_quest", []int{1})
+	assert.Equal(t, err, nil)
+
+	quest, rewarded, err := pg.CompleteQuest(ctx, player.ID, "test_quest", nil, nil, 30)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, quest.Status, models.QuestCompleted)
+	assert.Equal(t, rewarded.XP, 30)
+
+	// the reward comes with the completion only
+	_, _, err = pg.CompleteQuest(ctx, player.ID, "test_quest", nil, nil, 30)
+	assert.Equal(t, err, models.ErrQuestNotActive)
+	player, err = pg.GetPlayer(ctx, player.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, player.XP, 30)
+}
@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+	"tribble/settings"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// lockUsers serializes social changes between two users, locking in ID
+// order so concurrent requests in both directions can't deadlock.
+func lockUsers(ctx context.Context, tx pgx.Tx, a, b int) error {
+	sql := `SELECT id FROM users WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`
+	rows, err := tx.Query(ctx, sql, a, b)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	locked := 0
+	for rows.Next() {
+		locked++
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	if locked < 2 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+type queryRower interface {
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+func isBlocked(ctx context.Context, q queryRower, a, b int) (bool, error) {
+	var blocked bool
+	sql := `SELECT EXISTS (SELECT 1 FROM blocks WHERE (user_id=$1 AND blocked_id=$2) OR (user_id=$2 AND blocked_id=$1))`
+	err := q.QueryRow(ctx, sql, a, b).Scan(&blocked)
+	return blocked, err
+}
+
+func (p Postgres) GetFriends(ctx context.Context, userID int) ([]*models.Friend, error) {
+	sql := `SELECT u.id, u.username, f.created_at
+			FROM friendships f JOIN users u ON u.id = f.friend_id
+			WHERE f.user_id=$1 ORDER BY LOWER(u.username)`
+	rows, err := p.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friends := make([]*models.Friend, 0)
+	for rows.Next() {
+		var f models.Friend
+		if err = rows.Scan(&f.UserID, &f.Username, &f.Since); err != nil {
+			return nil, err
+		}
+		friends = append(friends, &f)
+	}
+	return friends, rows.Err()
+}
+
+const friendRequestSelect = `SELECT r.id, r.sender_id, s.username, r.recipient_id, t.username, r.created_at
+		FROM friend_requests r
+		JOIN users s ON s.id = r.sender_id
+		JOIN users t ON t.id = r.recipient_id`
+
+func scanFriendRequest(row pgx.Row, r *models.FriendRequest) error {
+	return row.Scan(&r.ID, &r.SenderID, &r.SenderName, &r.RecipientID, &r.RecipientName, &r.CreatedAt)
+}
+
+func (p Postgres) GetFriendRequests(ctx context.Context, userID int) ([]*models.FriendRequest, error) {
+	sql := friendRequestSelect + ` WHERE r.sender_id=$1 OR r.recipient_id=$1 ORDER BY r.created_at`
+	rows, err := p.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]*models.FriendRequest, 0)
+	for rows.Next() {
+		var r models.FriendRequest
+		if err = scanFriendRequest(rows, &r); err != nil {
+			return nil, err
+		}
+		requests = append(requests, &r)
+	}
+	return requests, rows.Err()
+}
+
+func countFriends(ctx context.Context, tx pgx.Tx, userID int) (int, error) {
+	var n int
+	err := tx.QueryRow(ctx, `SELECT count(*) FROM friendships WHERE user_id=$1`, userID).Scan(&n)
+	return n, err
+}
+
+func (p Postgres) SendFriendRequest(ctx context.Context, senderID, recipientID int) (*models.FriendRequest, error) {
+	if senderID == recipientID {
+		return nil, models.ErrSelfReference
+	}
+	var request models.FriendRequest
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockUsers(ctx, tx, senderID, recipientID); err != nil {
+			return err
+		}
+		blocked, err := isBlocked(ctx, tx, senderID, recipientID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return models.ErrBlocked
+		}
+
+		var friends, pending bool
+		sql := `SELECT EXISTS (SELECT 1 FROM friendships WHERE user_id=$1 AND friend_id=$2),
+					EXISTS (SELECT 1 FROM friend_requests
+						WHERE (sender_id=$1 AND recipient_id=$2) OR (sender_id=$2 AND recipient_id=$1))`
+		if err = tx.QueryRow(ctx, sql, senderID, recipientID).Scan(&friends, &pending); err != nil {
+			return err
+		}
+		if friends {
+			return models.ErrAlreadyFriends
+		}
+		if pending {
+			return models.ErrFriendRequestExists
+		}
+		n, err := countFriends(ctx, tx, senderID)
+		if err != nil {
+			return err
+		}
+		if n >= settings.MaxFriends {
+			return models.ErrTooManyFriends
+		}
+
+		var ID int
+		sql = `INSERT INTO friend_requests (sender_id, recipient_id) VALUES ($1, $2) RETURNING id`
+		if err = tx.QueryRow(ctx, sql, senderID, recipientID).Scan(&ID); err != nil {
+			return err
+		}
+		return scanFriendRequest(tx.QueryRow(ctx, friendRequestSelect+` WHERE r.id=$1`, ID), &request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (p Postgres) AcceptFriendRequest(ctx context.Context, recipientID, requestID int) error {
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var senderID int
+		sql := `SELECT sender_id FROM friend_requests WHERE id=$1 AND recipient_id=$2`
+		if err := tx.QueryRow(ctx, sql, requestID, recipientID).Scan(&senderID); err != nil {
+			return err
+		}
+		if err := lockUsers(ctx, tx, senderID, recipientID); err != nil {
+			return err
+		}
+		// the request may have been withdrawn while waiting for the lock
+		tag, err := tx.Exec(ctx, `DELETE FROM friend_requests WHERE id=$1`, requestID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+
+		for _, ID := range []int{senderID, recipientID} {
+			n, err := countFriends(ctx, tx, ID)
+			if err != nil {
+				return err
+			}
+			if n >= settings.MaxFriends {
+				return models.ErrTooManyFriends
+			}
+		}
+		sql = `INSERT INTO friendships (user_id, friend_id) VALUES ($1, $2), ($2, $1) ON CONFLICT DO NOTHING`
+		_, err = tx.Exec(ctx, sql, senderID, recipientID)
+		return err
+	})
+}
+
+func (p Postgres) DeclineFriendRequest(ctx context.Context, recipientID, requestID int) error {
+	tag, err := p.DB.Exec(ctx, `DELETE FROM friend_requests WHERE id=$1 AND recipient_id=$2`, requestID, recipientID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (p Postgres) CancelFriendRequest(ctx context.Context, senderID, requestID int) error {
+	tag, err := p.DB.Exec(ctx, `DELETE FROM friend_requests WHERE id=$1 AND sender_id=$2`, requestID, senderID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (p Postgres) RemoveFriend(ctx context.Context, userID, friendID int) error {
+	sql := `DELETE FROM friendships WHERE (user_id=$1 AND friend_id=$2) OR (user_id=$2 AND friend_id=$1)`
+	tag, err := p.DB.Exec(ctx, sql, userID, friendID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFriends
+	}
+	return nil
+}
+
+func (p Postgres) GetBlocks(ctx context.Context, userID int) ([]*models.BlockedUser, error) {
+	sql := `SELECT u.id, u.username, b.created_at
+			FROM blocks b JOIN users u ON u.id = b.blocked_id
+			WHERE b.user_id=$1 ORDER BY b.created_at`
+	rows, err := p.DB.Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := make([]*models.BlockedUser, 0)
+	for rows.Next() {
+		var b models.BlockedUser
+		if err = rows.Scan(&b.UserID, &b.Username, &b.Since); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, &b)
+	}
+	return blocks, rows.Err()
+}
+
+func (p Postgres) BlockUser(ctx context.Context, userID, blockedID int) error {
+	if userID == blockedID {
+		return models.ErrSelfReference
+	}
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if err := lockUsers(ctx, tx, userID, blockedID); err != nil {
+			return err
+		}
+		sql := `INSERT INTO blocks (user_id, blocked_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(ctx, sql, userID, blockedID); err != nil {
+			return err
+		}
+		sql = `DELETE FROM friendships WHERE (user_id=$1 AND friend_id=$2) OR (user_id=$2 AND friend_id=$1)`
+		if _, err := tx.Exec(ctx, sql, userID, blockedID); err != nil {
+			return err
+		}
+		sql = `DELETE FROM friend_requests WHERE (sender_id=$1 AND recipient_id=$2) OR (sender_id=$2 AND recipient_id=$1)`
+		_, err := tx.Exec(ctx, sql, userID, blockedID)
+		return err
+	})
+}
+
+func (p Postgres) UnblockUser(ctx context.Context, userID, blockedID int) error {
+	tag, err := p.DB.Exec(ctx, `DELETE FROM blocks WHERE user_id=$1 AND blocked_id=$2`, userID, blockedID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (p Postgres) IsBlocked(ctx context.Context, a, b int) (bool, error) {
+	return isBlocked(ctx, p.DB, a, b)
+}
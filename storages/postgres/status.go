@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"tribble/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (p Postgres) GetPlayerEffects(ctx context.Context, playerID int) ([]*models.PlayerEffect, error) {
+	sql := `SELECT effect_id, stacks, expires_at FROM player_effects
+			WHERE player_id=$1 AND expires_at > now() ORDER BY expires_at`
+	rows, err := p.DB.Query(ctx, sql, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	effects := make([]*models.PlayerEffect, 0)
+	for rows.Next() {
+		var e models.PlayerEffect
+		if err = rows.Scan(&e.EffectID, &e.Stacks, &e.ExpiresAt); err != nil {
+			return nil, err
+		}
+		effects = append(effects, &e)
+	}
+	return effects, rows.Err()
+}
+
+func (p Postgres) SavePlayerEffects(ctx context.Context, playerID int, effects []*models.PlayerEffect) error {
+	return p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DELETE FROM player_effects WHERE player_id=$1`, playerID); err != nil {
+			return err
+		}
+		for _, e := range effects {
+			sql := `INSERT INTO player_effects (player_id, effect_id, stacks, expires_at) VALUES ($1, $2, $3, $4)`
+			if _, err := tx.Exec(ctx, sql, playerID, e.EffectID, e.Stacks, e.ExpiresAt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,167 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"tribble/items"
+	"tribble/models"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// lockOffer locks the offered inventory rows and checks they still hold
+// the offered items, which can be traded.
+func lockOffer(ctx context.Context, tx pgx.Tx, offer models.TradeOffer) error {
+	slots := make([]int, 0, len(offer.Items))
+	for _, item := range offer.Items {
+		if !validSlot(item.Slot) {
+			return models.ErrInvalidSlot
+		}
+		if item.Quantity < 1 {
+			return models.ErrNotEnoughItems
+		}
+		slots = append(slots, item.Slot)
+	}
+	sql := `SELECT slot, item_id, quantity FROM inventory_items
+			WHERE player_id=$1 AND slot = ANY($2) ORDER BY slot FOR UPDATE`
+	rows, err := tx.Query(ctx, sql, offer.PlayerID, slots)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	locked := make(map[int]models.InventorySlot, len(slots))
+	for rows.Next() {
+		var s models.InventorySlot
+		if err = rows.Scan(&s.Slot, &s.ItemID, &s.Quantity); err != nil {
+			return err
+		}
+		locked[s.Slot] = s
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for _, item := range offer.Items {
+		s, ok := locked[item.Slot]
+		if !ok || s.ItemID != item.ItemID || s.Quantity < item.Quantity {
+			return models.ErrItemChanged
+		}
+		if !items.Tradable(s.ItemID) {
+			return models.ErrNotTradable
+		}
+	}
+	return nil
+}
+
+// receive adds the traded items to the inventory of the recipient.
+func receive(ctx context.Context, tx pgx.Tx, recipientID int, items []models.TradeItem) error {
+	for _, item := range items {
+		if err := addItem(ctx, tx, recipientID, models.Item{ID: item.ItemID, StackSize: stackSize(item.ItemID)}, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func logTrade(ctx context.Context, tx pgx.Tx, offer, counter models.TradeOffer) (int64, error) {
+	sql := `INSERT INTO trades (player_id, player_name, partner_id, partner_name, player_currency, partner_currency)
+			SELECT p.id, p.name, c.id, c.name, $3, $4 FROM players p, players c WHERE p.id=$1 AND c.id=$2
+			RETURNING id`
+	var tradeID int64
+	if err := tx.QueryRow(ctx, sql, offer.PlayerID, counter.PlayerID, offer.Currency, counter.Currency).Scan(&tradeID); err != nil {
+		return 0, err
+	}
+
+	sql = `INSERT INTO trade_items (trade_id, from_player, item_id, quantity) VALUES ($1, $2, $3, $4)`
+	for i, o := range []models.TradeOffer{offer, counter} {
+		for _, item := range o.Items {
+			if _, err := tx.Exec(ctx, sql, tradeID, i == 0, item.ItemID, item.Quantity); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return tradeID, nil
+}
+
+// tradeCurrency moves the currency of both offers in one ledger
+// transaction.
+func tradeCurrency(ctx context.Context, tx pgx.Tx, tradeID int64, offer, counter models.TradeOffer) error {
+	if offer.Currency == 0 && counter.Currency == 0 {
+		return nil
+	}
+	from, err := openPlayerAccount(ctx, tx, offer.PlayerID)
+	if err != nil {
+		return err
+	}
+	to, err := openPlayerAccount(ctx, tx, counter.PlayerID)
+	if err != nil {
+		return err
+	}
+	postings := make([]posting, 0, 4)
+	if offer.Currency > 0 {
+		postings = append(postings, posting{from, -offer.Currency}, posting{to, offer.Currency})
+	}
+	if counter.Currency > 0 {
+		postings = append(postings, posting{to, -counter.Currency}, posting{from, counter.Currency})
+	}
+	_, err = post(ctx, tx, models.ReasonTrade, fmt.Sprintf("trade:%d", tradeID), postings)
+	return err
+}
+
+// ExecuteTrade locks both players in ID order, then the offered rows. Both
+// offers are taken out before anything is added so slots freed by one side
+// can receive the items of the other.
+func (p Postgres) ExecuteTrade(ctx context.Context, offer, counter models.TradeOffer) (int64, error) {
+	if offer.PlayerID == counter.PlayerID {
+		return 0, models.ErrSelfReference
+	}
+	if offer.Currency < 0 || counter.Currency < 0 {
+		return 0, models.ErrInvalidAmount
+	}
+	if len(offer.Items) == 0 && len(counter.Items) == 0 && offer.Currency == 0 && counter.Currency == 0 {
+		return 0, models.ErrEmptyTrade
+	}
+	var tradeID int64
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		first, second := offer.PlayerID, counter.PlayerID
+		if second < first {
+			first, second = second, first
+		}
+		if err := lockPlayer(ctx, tx, first); err != nil {
+			return err
+		}
+		if err := lockPlayer(ctx, tx, second); err != nil {
+			return err
+		}
+		for _, o := range []models.TradeOffer{offer, counter} {
+			if err := lockOffer(ctx, tx, o); err != nil {
+				return err
+			}
+		}
+
+		for _, item := range offer.Items {
+			if _, err := removeItem(ctx, tx, offer.PlayerID, item.Slot, item.Quantity); err != nil {
+				return err
+			}
+		}
+		for _, item := range counter.Items {
+			if _, err := removeItem(ctx, tx, counter.PlayerID, item.Slot, item.Quantity); err != nil {
+				return err
+			}
+		}
+		if err := receive(ctx, tx, counter.PlayerID, offer.Items); err != nil {
+			return err
+		}
+		if err := receive(ctx, tx, offer.PlayerID, counter.Items); err != nil {
+			return err
+		}
+
+		var err error
+		if tradeID, err = logTrade(ctx, tx, offer, counter); err != nil {
+			return err
+		}
+		return tradeCurrency(ctx, tx, tradeID, offer, counter)
+	})
+	return tradeID, err
+}
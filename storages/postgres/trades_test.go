@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"tribble/items"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestTradeNotTradable(t *testing.T) {
+	catalog, err := items.Load(strings.NewReader(`{"items": [
+		{"id": "iron_sword", "type": "weapon", "rarity": "common", "stack_size": 1},
+		{"id": "relic", "type": "quest", "rarity": "common", "stack_size": 1}
+	]}`))
+	assert.Equal(t, err, nil)
+	defaults := items.Default
+	items.Default = catalog
+	defer func() { items.Default = defaults }()
+
+	ctx := context.Background()
+	first, second := createTestPlayer(t), createTestPlayer(t)
+	addTestItem(t, first.ID, "relic", 1)
+	addTestItem(t, first.ID, "iron_sword", 1)
+
+	relic := models.TradeOffer{PlayerID: first.ID, Items: []models.TradeItem{{Slot: 0, ItemID: "relic", Quantity: 1}}}
+	_, err = pg.ExecuteTrade(ctx, relic, models.TradeOffer{PlayerID: second.ID})
+	assert.Equal(t, err, models.ErrNotTradable)
+	inventory, err := pg.GetInventory(ctx, second.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(inventory), 0)
+
+	sword := models.TradeOffer{PlayerID: first.ID, Items: []models.TradeItem{{Slot: 1, ItemID: "iron_sword", Quantity: 1}}}
+	_, err = pg.ExecuteTrade(ctx, sword, models.TradeOffer{PlayerID: second.ID})
+	assert.Equal(t, err, nil)
+	inventory, err = pg.GetInventory(ctx, second.ID)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(inventory), 1)
+	assert.Equal(t, inventory[0].ItemID, "iron_sword")
+}
@@ -0,0 +1,169 @@
+package postgres
+
+import (
+	"context"
+	"time"
+	"tribble/models"
+	"tribble/vendors"
+
+	"github.com/jackc/pgx/v4"
+)
+
+func (p Postgres) GetVendorStock(ctx context.Context, vendorID string) ([]*models.VendorStock, error) {
+	sql := `SELECT item_id, stock, restocked_at FROM vendor_stock WHERE vendor_id=$1 ORDER BY item_id`
+	rows, err := p.DB.Query(ctx, sql, vendorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stock := make([]*models.VendorStock, 0)
+	for rows.Next() {
+		var s models.VendorStock
+		if err = rows.Scan(&s.ItemID, &s.Stock, &s.RestockedAt); err != nil {
+			return nil, err
+		}
+		stock = append(stock, &s)
+	}
+	return stock, rows.Err()
+}
+
+// takeStock locks the stock of a limited offer, restocks it and takes the
+// ordered items out.
+func takeStock(ctx context.Context, tx pgx.Tx, order models.VendorOrder) error {
+	sql := `INSERT INTO vendor_stock (vendor_id, item_id, stock) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+	if _, err := tx.Exec(ctx, sql, order.VendorID, order.ItemID, order.Stock); err != nil {
+		return err
+	}
+	var stock int
+	var since, now time.Time
+	sql = `SELECT stock, restocked_at, now() FROM vendor_stock WHERE vendor_id=$1 AND item_id=$2 FOR UPDATE`
+	if err := tx.QueryRow(ctx, sql, order.VendorID, order.ItemID).Scan(&stock, &since, &now); err != nil {
+		return err
+	}
+	stock, since = vendors.Restock(stock, order.Stock, order.Restock, since, now)
+	if stock < order.Quantity {
+		return models.ErrOutOfStock
+	}
+	sql = `UPDATE vendor_stock SET stock=$3, restocked_at=$4 WHERE vendor_id=$1 AND item_id=$2`
+	_, err := tx.Exec(ctx, sql, order.VendorID, order.ItemID, stock-order.Quantity, since)
+	return err
+}
+
+func logVendorTransaction(ctx context.Context, tx pgx.Tx, t *models.VendorTransaction) error {
+	sql := `INSERT INTO vendor_transactions (transaction_id, player_id, vendor_id, action, item_id, quantity, price, total)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at`
+	return tx.QueryRow(ctx, sql, t.TransactionID, t.PlayerID, t.VendorID, t.Action, t.ItemID, t.Quantity, t.Price, t.Total).
+		Scan(&t.ID, &t.CreatedAt)
+}
+
+// replayVendorTransaction returns the record of a ledger transaction that
+// was replayed, nothing else must change then.
+func replayVendorTransaction(ctx context.Context, tx pgx.Tx, transactionID int64) (*models.VendorTransaction, error) {
+	sql := `SELECT id, transaction_id, COALESCE(player_id, 0), vendor_id, action, item_id, quantity, price, total, created_at
+			FROM vendor_transactions WHERE transaction_id=$1`
+	var t models.VendorTransaction
+	err := tx.QueryRow(ctx, sql, transactionID).Scan(&t.ID, &t.TransactionID, &t.PlayerID, &t.VendorID, &t.Action,
+		&t.ItemID, &t.Quantity, &t.Price, &t.Total, &t.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, models.ErrIdempotencyConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Replayed = true
+	return &t, nil
+}
+
+// settleVendorOrder moves the currency of the order between the player and
+// a system account, then lets move change the inventory unless the key was
+// already used.
+func settleVendorOrder(ctx context.Context, tx pgx.Tx, playerID int, order models.VendorOrder, action, key string, move func() error) (*models.VendorTransaction, error) {
+	if order.Quantity < 1 {
+		return nil, models.ErrNotEnoughItems
+	}
+	if err := lockPlayer(ctx, tx, playerID); err != nil {
+		return nil, err
+	}
+	player, err := openPlayerAccount(ctx, tx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	total := order.Price * int64(order.Quantity)
+	var transaction *models.LedgerTransaction
+	if action == models.VendorBuy {
+		var sink int64
+		if sink, err = openAccount(ctx, tx, sinkAccount); err != nil {
+			return nil, err
+		}
+		transaction, err = transfer(ctx, tx, player, sink, total, models.ReasonVendorBuy, key)
+	} else {
+		var world int64
+		if world, err = openAccount(ctx, tx, worldAccount); err != nil {
+			return nil, err
+		}
+		transaction, err = transfer(ctx, tx, world, player, total, models.ReasonVendorSell, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Replayed {
+		return replayVendorTransaction(ctx, tx, transaction.ID)
+	}
+
+	if err = move(); err != nil {
+		return nil, err
+	}
+	t := &models.VendorTransaction{
+		TransactionID: transaction.ID,
+		PlayerID:      playerID,
+		VendorID:      order.VendorID,
+		Action:        action,
+		ItemID:        order.ItemID,
+		Quantity:      order.Quantity,
+		Price:         order.Price,
+		Total:         total,
+	}
+	return t, logVendorTransaction(ctx, tx, t)
+}
+
+func (p Postgres) BuyFromVendor(ctx context.Context, playerID int, order models.VendorOrder, key string) (*models.VendorTransaction, error) {
+	var t *models.VendorTransaction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		t, err = settleVendorOrder(ctx, tx, playerID, order, models.VendorBuy, key, func() error {
+			if order.Stock > 0 {
+				if err := takeStock(ctx, tx, order); err != nil {
+					return err
+				}
+			}
+			return addItem(ctx, tx, playerID, models.Item{ID: order.ItemID, StackSize: stackSize(order.ItemID)}, order.Quantity)
+		})
+		return err
+	})
+	return t, err
+}
+
+func (p Postgres) SellToVendor(ctx context.Context, playerID int, order models.VendorOrder, key string) (*models.VendorTransaction, error) {
+	if !validSlot(order.Slot) {
+		return nil, models.ErrInvalidSlot
+	}
+	var t *models.VendorTransaction
+	err := p.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		var err error
+		t, err = settleVendorOrder(ctx, tx, playerID, order, models.VendorSell, key, func() error {
+			s, err := getSlot(ctx, tx, playerID, order.Slot)
+			if err != nil {
+				return err
+			}
+			// the order was priced for the item the slot held then
+			if s == nil || s.ItemID != order.ItemID {
+				return models.ErrItemChanged
+			}
+			_, err = removeItem(ctx, tx, playerID, order.Slot, order.Quantity)
+			return err
+		})
+		return err
+	})
+	return t, err
+}
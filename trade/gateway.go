@@ -0,0 +1,153 @@
+package trade
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"tribble/gateway"
+	"tribble/items"
+	"tribble/models"
+	"tribble/social"
+)
+
+// Repository is what the trades read and write.
+type Repository interface {
+	models.BlockRepository
+	GetInventory(ctx context.Context, playerID int) ([]*models.InventorySlot, error)
+	GetBalance(ctx context.Context, playerID int) (int64, error)
+	ExecuteTrade(ctx context.Context, offer, counter models.TradeOffer) (int64, error)
+}
+
+type requestData struct {
+	Name string `json:"name"`
+}
+
+type playerData struct {
+	PlayerID int `json:"player_id"`
+}
+
+type offerData struct {
+	Items []struct {
+		Slot     int `json:"slot"`
+		Quantity int `json:"quantity"`
+	} `json:"items"`
+	Currency int64 `json:"currency"`
+}
+
+// offer reads the offered slots from the inventory of the player, keeping
+// the item each slot holds now. Items that can't be traded are refused.
+func offer(ctx context.Context, repo Repository, playerID int, data offerData) ([]models.TradeItem, error) {
+	inventory, err := repo.GetInventory(ctx, playerID)
+	if err != nil {
+		return nil, err
+	}
+	slots := make(map[int]*models.InventorySlot, len(inventory))
+	for _, s := range inventory {
+		slots[s.Slot] = s
+	}
+	offered := make([]models.TradeItem, 0, len(data.Items))
+	for _, item := range data.Items {
+		s, ok := slots[item.Slot]
+		if !ok {
+			return nil, models.ErrEmptySlot
+		}
+		if s.Quantity < item.Quantity {
+			return nil, models.ErrNotEnoughItems
+		}
+		if !items.Tradable(s.ItemID) {
+			return nil, models.ErrNotTradable
+		}
+		offered = append(offered, models.TradeItem{Slot: item.Slot, ItemID: s.ItemID, Quantity: item.Quantity})
+	}
+	return offered, nil
+}
+
+// Register routes the trade messages of clients to the manager, sends
+// trade events to their sessions and executes trades with the repository.
+// Requests between users that blocked each other are refused.
+func (m *Manager) Register(hub *gateway.Hub, repo Repository) {
+	m.Send = func(playerID int, e Event) {
+		if s, ok := hub.Session(playerID); ok {
+			s.Send("trade", e)
+		}
+	}
+	m.Execute = func(offer, counter models.TradeOffer) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		tradeID, err := repo.ExecuteTrade(ctx, offer, counter)
+		if err != nil {
+			log.Printf("trade between players %v and %v failed: %v", offer.PlayerID, counter.PlayerID, err.Error())
+			return err
+		}
+		log.Printf("trade %v between players %v and %v completed", tradeID, offer.PlayerID, counter.PlayerID)
+		return nil
+	}
+
+	hub.Handle("trade.request", func(s *gateway.Session, data json.RawMessage) error {
+		var request requestData
+		if err := json.Unmarshal(data, &request); err != nil {
+			return err
+		}
+		to, ok := hub.Find(request.Name)
+		if !ok {
+			return models.ErrPlayerOffline
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if err := social.CheckBlocked(ctx, repo, s.UserID, to.UserID); err != nil {
+			return err
+		}
+		return m.Request(s.PlayerID, to.PlayerID)
+	})
+
+	withPlayer := func(op func(playerID, other int) error) gateway.Handler {
+		return func(s *gateway.Session, data json.RawMessage) error {
+			var p playerData
+			if err := json.Unmarshal(data, &p); err != nil {
+				return err
+			}
+			return op(s.PlayerID, p.PlayerID)
+		}
+	}
+	hub.Handle("trade.accept", withPlayer(m.Accept))
+	hub.Handle("trade.decline", withPlayer(m.Decline))
+
+	hub.Handle("trade.offer", func(s *gateway.Session, data json.RawMessage) error {
+		var o offerData
+		if err := json.Unmarshal(data, &o); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		items, err := offer(ctx, repo, s.PlayerID, o)
+		if err != nil {
+			return err
+		}
+		if o.Currency > 0 {
+			balance, err := repo.GetBalance(ctx, s.PlayerID)
+			if err != nil {
+				return err
+			}
+			if balance < o.Currency {
+				return models.ErrInsufficientFunds
+			}
+		}
+		return m.Offer(s.PlayerID, items, o.Currency)
+	})
+
+	withSelf := func(op func(playerID int) error) gateway.Handler {
+		return func(s *gateway.Session, data json.RawMessage) error {
+			return op(s.PlayerID)
+		}
+	}
+	hub.Handle("trade.lock", withSelf(m.Lock))
+	hub.Handle("trade.confirm", withSelf(m.Confirm))
+	hub.Handle("trade.cancel", withSelf(m.Cancel))
+
+	hub.OnDisconnect(func(s *gateway.Session) {
+		m.Disconnect(s.PlayerID)
+	})
+}
+
+var Default = NewManager()
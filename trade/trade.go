@@ -0,0 +1,355 @@
+package trade
+
+import (
+	"sync"
+	"time"
+	"tribble/models"
+	"tribble/settings"
+)
+
+// Side is the offer of one of the players of a trade. Locked offers can
+// be confirmed once both sides locked, changing an offer unlocks both.
+type Side struct {
+	PlayerID  int                `json:"player_id"`
+	Items     []models.TradeItem `json:"items"`
+	Currency  int64              `json:"currency"`
+	Locked    bool               `json:"locked"`
+	Confirmed bool               `json:"confirmed"`
+}
+
+func (s *Side) empty() bool {
+	return len(s.Items) == 0 && s.Currency == 0
+}
+
+func (s *Side) offer() models.TradeOffer {
+	return models.TradeOffer{PlayerID: s.PlayerID, Items: s.Items, Currency: s.Currency}
+}
+
+type Trade struct {
+	ID    int     `json:"id"`
+	Sides [2]Side `json:"sides"`
+}
+
+const (
+	EventRequested = "requested"
+	EventDeclined  = "declined"
+	EventOpened    = "opened"
+	EventUpdated   = "updated"
+	EventCancelled = "cancelled"
+	EventFailed    = "failed"
+	EventCompleted = "completed"
+)
+
+// Event is sent to players through the gateway when their trade changes.
+type Event struct {
+	Event    string `json:"event"`
+	PlayerID int    `json:"player_id,omitempty"`
+	Trade    *Trade `json:"trade,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type trade struct {
+	id    int
+	sides [2]*Side
+	// executing is set while the transfer runs, the trade can't change
+	// until it is done. A player disconnecting meanwhile abandons it.
+	executing bool
+	abandoned int
+}
+
+func (t *trade) view() *Trade {
+	v := &Trade{ID: t.id}
+	for i, s := range t.sides {
+		v.Sides[i] = *s
+		v.Sides[i].Items = append([]models.TradeItem{}, s.Items...)
+	}
+	return v
+}
+
+func (t *trade) side(playerID int) *Side {
+	if t.sides[0].PlayerID == playerID {
+		return t.sides[0]
+	}
+	return t.sides[1]
+}
+
+func (t *trade) reset() {
+	for _, s := range t.sides {
+		s.Locked = false
+		s.Confirmed = false
+	}
+}
+
+type requestKey struct {
+	from, to int
+}
+
+// Manager keeps the open trades in memory, moving the items only once both
+// players confirmed. All operations take player IDs and are safe to call
+// from any goroutine.
+type Manager struct {
+	// Send delivers an event to a connected player.
+	Send func(playerID int, e Event)
+	// Execute moves the offers of a confirmed trade.
+	Execute func(offer, counter models.TradeOffer) error
+	Now     func() time.Time
+
+	mu       sync.Mutex
+	nextID   int
+	byPlayer map[int]*trade
+	requests map[requestKey]time.Time
+}
+
+func NewManager() *Manager {
+	return &Manager{
+		Send:     func(int, Event) {},
+		Execute:  func(models.TradeOffer, models.TradeOffer) error { return nil },
+		Now:      time.Now,
+		byPlayer: make(map[int]*trade),
+		requests: make(map[requestKey]time.Time),
+	}
+}
+
+func (m *Manager) notify(t *trade, e Event) {
+	for _, s := range t.sides {
+		m.Send(s.PlayerID, e)
+	}
+}
+
+// Get returns the trade of the player.
+func (m *Manager) Get(playerID int) (*Trade, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.byPlayer[playerID]
+	if !ok {
+		return nil, false
+	}
+	return t.view(), true
+}
+
+func (m *Manager) Request(from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	if from == to {
+		return models.ErrSelfReference
+	}
+	if _, ok := m.byPlayer[from]; ok {
+		return models.ErrAlreadyTrading
+	}
+	if _, ok := m.byPlayer[to]; ok {
+		return models.ErrAlreadyTrading
+	}
+	m.requests[requestKey{from, to}] = m.Now().Add(settings.TradeRequestTTL)
+	m.Send(to, Event{Event: EventRequested, PlayerID: from})
+	return nil
+}
+
+// Accept opens a trade with the requester, dropping the other requests of
+// both players.
+func (m *Manager) Accept(to, from int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+
+	if _, ok := m.requests[requestKey{from, to}]; !ok {
+		return models.ErrNoTradeRequest
+	}
+	delete(m.requests, requestKey{from, to})
+	if _, ok := m.byPlayer[from]; ok {
+		return models.ErrAlreadyTrading
+	}
+	if _, ok := m.byPlayer[to]; ok {
+		return models.ErrAlreadyTrading
+	}
+
+	m.dropRequests(from)
+	m.dropRequests(to)
+	m.nextID++
+	t := &trade{id: m.nextID, sides: [2]*Side{{PlayerID: from}, {PlayerID: to}}}
+	m.byPlayer[from] = t
+	m.byPlayer[to] = t
+	m.notify(t, Event{Event: EventOpened, Trade: t.view()})
+	return nil
+}
+
+func (m *Manager) Decline(to, from int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.requests[requestKey{from, to}]; !ok {
+		return models.ErrNoTradeRequest
+	}
+	delete(m.requests, requestKey{from, to})
+	m.Send(from, Event{Event: EventDeclined, PlayerID: to})
+	return nil
+}
+
+// open returns the trade of the player unless it is being executed.
+func (m *Manager) open(playerID int) (*trade, error) {
+	t, ok := m.byPlayer[playerID]
+	if !ok {
+		return nil, models.ErrNotTrading
+	}
+	if t.executing {
+		return nil, models.ErrTradeBusy
+	}
+	return t, nil
+}
+
+// Offer replaces the offer of the player, unlocking both sides.
+func (m *Manager) Offer(playerID int, items []models.TradeItem, currency int64) error {
+	if len(items) > settings.MaxTradeItems {
+		return models.ErrTooManyTradeItems
+	}
+	if currency < 0 {
+		return models.ErrInvalidAmount
+	}
+	slots := make(map[int]bool, len(items))
+	for _, item := range items {
+		if item.Quantity < 1 {
+			return models.ErrNotEnoughItems
+		}
+		if slots[item.Slot] {
+			return models.ErrInvalidSlot
+		}
+		slots[item.Slot] = true
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.open(playerID)
+	if err != nil {
+		return err
+	}
+	side := t.side(playerID)
+	side.Items = append([]models.TradeItem{}, items...)
+	side.Currency = currency
+	t.reset()
+	m.notify(t, Event{Event: EventUpdated, PlayerID: playerID, Trade: t.view()})
+	return nil
+}
+
+func (m *Manager) Lock(playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.open(playerID)
+	if err != nil {
+		return err
+	}
+	t.side(playerID).Locked = true
+	m.notify(t, Event{Event: EventUpdated, PlayerID: playerID, Trade: t.view()})
+	return nil
+}
+
+// Confirm accepts the locked offers, the second confirmation executes the
+// trade. A failed execution resets both sides so the players can fix their
+// offers, the confirming player gets the error.
+func (m *Manager) Confirm(playerID int) error {
+	m.mu.Lock()
+	t, err := m.open(playerID)
+	if err != nil {
+		m.mu.Unlock()
+		return err
+	}
+	if !t.sides[0].Locked || !t.sides[1].Locked {
+		m.mu.Unlock()
+		return models.ErrTradeNotLocked
+	}
+	if t.sides[0].empty() && t.sides[1].empty() {
+		m.mu.Unlock()
+		return models.ErrEmptyTrade
+	}
+	t.side(playerID).Confirmed = true
+	if !t.sides[0].Confirmed || !t.sides[1].Confirmed {
+		m.notify(t, Event{Event: EventUpdated, PlayerID: playerID, Trade: t.view()})
+		m.mu.Unlock()
+		return nil
+	}
+
+	t.executing = true
+	view := t.view()
+	m.mu.Unlock()
+
+	err = m.Execute(
+		view.Sides[0].offer(),
+		view.Sides[1].offer(),
+	)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t.executing = false
+	if err != nil && t.abandoned != 0 {
+		m.close(t)
+		m.notify(t, Event{Event: EventCancelled, PlayerID: t.abandoned})
+		return err
+	}
+	if err != nil {
+		t.reset()
+		m.notify(t, Event{Event: EventFailed, Trade: t.view(), Error: err.Error()})
+		return err
+	}
+	m.close(t)
+	m.notify(t, Event{Event: EventCompleted, Trade: view})
+	return nil
+}
+
+func (m *Manager) Cancel(playerID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, err := m.open(playerID)
+	if err != nil {
+		return err
+	}
+	m.close(t)
+	m.notify(t, Event{Event: EventCancelled, PlayerID: playerID})
+	return nil
+}
+
+// Disconnect drops the requests of the player and cancels its trade. A
+// trade already executing may still complete, it is cancelled if it fails.
+func (m *Manager) Disconnect(playerID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dropRequests(playerID)
+	t, ok := m.byPlayer[playerID]
+	if !ok {
+		return
+	}
+	if t.executing {
+		t.abandoned = playerID
+		return
+	}
+	m.close(t)
+	m.notify(t, Event{Event: EventCancelled, PlayerID: playerID})
+}
+
+func (m *Manager) close(t *trade) {
+	for _, s := range t.sides {
+		delete(m.byPlayer, s.PlayerID)
+	}
+}
+
+func (m *Manager) dropRequests(playerID int) {
+	for key := range m.requests {
+		if key.from == playerID || key.to == playerID {
+			delete(m.requests, key)
+		}
+	}
+}
+
+// Sweep drops the expired requests.
+func (m *Manager) Sweep() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep()
+}
+
+func (m *Manager) sweep() {
+	now := m.Now()
+	for key, expires := range m.requests {
+		if !now.Before(expires) {
+			delete(m.requests, key)
+		}
+	}
+}
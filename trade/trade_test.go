@@ -0,0 +1,277 @@
+package trade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+type inbox struct {
+	mu     sync.Mutex
+	events map[int][]Event
+}
+
+func (i *inbox) send(playerID int, e Event) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.events[playerID] = append(i.events[playerID], e)
+}
+
+func (i *inbox) last(playerID int) Event {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	events := i.events[playerID]
+	if len(events) == 0 {
+		return Event{}
+	}
+	return events[len(events)-1]
+}
+
+func testManager() (*Manager, *inbox) {
+	i := &inbox{events: make(map[int][]Event)}
+	m := NewManager()
+	m.Now = func() time.Time { return time.Unix(0, 0) }
+	m.Send = i.send
+	return m, i
+}
+
+func open(t *testing.T, m *Manager, from, to int) {
+	if err := m.Request(from, to); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Accept(to, from); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var sword = []models.TradeItem{{Slot: 0, ItemID: "iron_sword", Quantity: 1}}
+var potions = []models.TradeItem{{Slot: 3, ItemID: "health_potion", Quantity: 5}}
+
+func TestRequest(t *testing.T) {
+	m, events := testManager()
+	now := time.Unix(0, 0)
+	m.Now = func() time.Time { return now }
+
+	assert.Equal(t, m.Request(1, 1), models.ErrSelfReference)
+	assert.Equal(t, m.Request(1, 2), nil)
+	assert.Equal(t, events.last(2), Event{Event: EventRequested, PlayerID: 1})
+	assert.Equal(t, m.Decline(2, 1), nil)
+	assert.Equal(t, events.last(1), Event{Event: EventDeclined, PlayerID: 2})
+	assert.Equal(t, m.Accept(2, 1), models.ErrNoTradeRequest)
+
+	assert.Equal(t, m.Request(1, 2), nil)
+	now = now.Add(settings.TradeRequestTTL)
+	assert.Equal(t, m.Accept(2, 1), models.ErrNoTradeRequest)
+
+	assert.Equal(t, m.Request(1, 2), nil)
+	assert.Equal(t, m.Request(3, 2), nil)
+	assert.Equal(t, m.Accept(2, 1), nil)
+	assert.Equal(t, events.last(1).Event, EventOpened)
+	// the other requests of both players are dropped
+	assert.Equal(t, m.Accept(2, 3), models.ErrNoTradeRequest)
+	assert.Equal(t, m.Request(3, 1), models.ErrAlreadyTrading)
+}
+
+func TestConfirm(t *testing.T) {
+	m, events := testManager()
+	var executed [][2]models.TradeOffer
+	m.Execute = func(offer, counter models.TradeOffer) error {
+		executed = append(executed, [2]models.TradeOffer{offer, counter})
+		return nil
+	}
+	open(t, m, 1, 2)
+
+	assert.Equal(t, m.Lock(1), nil)
+	assert.Equal(t, m.Lock(2), nil)
+	assert.Equal(t, m.Confirm(1), models.ErrEmptyTrade)
+
+	assert.Equal(t, m.Offer(1, sword, 0), nil)
+	assert.Equal(t, m.Confirm(1), models.ErrTradeNotLocked)
+	assert.Equal(t, m.Offer(2, potions, 0), nil)
+	assert.Equal(t, m.Lock(1), nil)
+	assert.Equal(t, m.Lock(2), nil)
+	assert.Equal(t, m.Confirm(1), nil)
+
+	// changing an offer after locking resets both sides
+	assert.Equal(t, m.Offer(2, nil, 0), nil)
+	trade, _ := m.Get(1)
+	assert.Equal(t, trade.Sides[0], Side{PlayerID: 1, Items: sword})
+	assert.Equal(t, m.Confirm(2), models.ErrTradeNotLocked)
+
+	assert.Equal(t, m.Offer(2, potions, 0), nil)
+	assert.Equal(t, m.Lock(1), nil)
+	assert.Equal(t, m.Lock(2), nil)
+	assert.Equal(t, m.Confirm(2), nil)
+	assert.Equal(t, len(executed), 0)
+	assert.Equal(t, m.Confirm(1), nil)
+
+	assert.Equal(t, executed, [][2]models.TradeOffer{{{PlayerID: 1, Items: sword}, {PlayerID: 2, Items: potions}}})
+	assert.Equal(t, events.last(2).Event, EventCompleted)
+	_, ok := m.Get(1)
+	assert.Equal(t, ok, false)
+	assert.Equal(t, m.Confirm(1), models.ErrNotTrading)
+}
+
+func TestCurrencyOffer(t *testing.T) {
+	m, _ := testManager()
+	var executed []models.TradeOffer
+	m.Execute = func(offer, counter models.TradeOffer) error {
+		executed = append(executed, offer, counter)
+		return nil
+	}
+	open(t, m, 1, 2)
+	assert.Equal(t, m.Offer(2, nil, -5), models.ErrInvalidAmount)
+	assert.Equal(t, m.Offer(2, nil, 50), nil)
+	assert.Equal(t, m.Lock(1), nil)
+	assert.Equal(t, m.Lock(2), nil)
+	assert.Equal(t, m.Confirm(1), nil)
+	assert.Equal(t, m.Confirm(2), nil)
+	assert.Equal(t, executed, []models.TradeOffer{{PlayerID: 1, Items: []models.TradeItem{}}, {PlayerID: 2, Items: []models.TradeItem{}, Currency: 50}})
+}
+
+func TestFailedTrade(t *testing.T) {
+	m, events := testManager()
+	m.Execute = func(offer, counter models.TradeOffer) error {
+		return models.ErrItemChanged
+	}
+	open(t, m, 1, 2)
+	assert.Equal(t, m.Offer(1, sword, 0), nil)
+	assert.Equal(t, m.Lock(1), nil)
+	assert.Equal(t, m.Lock(2), nil)
+	assert.Equal(t, m.Confirm(1), nil)
+	assert.Equal(t, m.Confirm(2), models.ErrItemChanged)
+
+	e := events.last(1)
+	assert.Equal(t, e.Event, EventFailed)
+	assert.Equal(t, e.Error, models.ErrItemChanged.Error())
+	trade, ok := m.Get(2)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, trade.Sides[0].Locked || trade.Sides[1].Confirmed, false)
+}
+
+func TestCancelAndDisconnect(t *testing.T) {
+	m, events := testManager()
+	open(t, m, 1, 2)
+	assert.Equal(t, m.Cancel(2), nil)
+	assert.Equal(t, events.last(1), Event{Event: EventCancelled, PlayerID: 2})
+	assert.Equal(t, m.Cancel(2), models.ErrNotTrading)
+
+	open(t, m, 1, 2)
+	assert.Equal(t, m.Request(3, 4), nil)
+	m.Disconnect(1)
+	m.Disconnect(4)
+	assert.Equal(t, events.last(2), Event{Event: EventCancelled, PlayerID: 1})
+	assert.Equal(t, m.Accept(4, 3), models.ErrNoTradeRequest)
+	assert.Equal(t, m.Request(2, 3), nil)
+}
+
+// A player disconnecting while the trade executes can't cancel it
+// halfway, a failed execution then closes the trade.
+func TestDisconnectWhileExecuting(t *testing.T) {
+	for _, failure := range []error{nil, models.ErrInventoryFull} {
+		m, events := testManager()
+		started := make(chan bool)
+		release := make(chan bool)
+		m.Execute = func(offer, counter models.TradeOffer) error {
+			started <- true
+			<-release
+			return failure
+		}
+		open(t, m, 1, 2)
+		assert.Equal(t, m.Offer(1, sword, 0), nil)
+		assert.Equal(t, m.Lock(1), nil)
+		assert.Equal(t, m.Lock(2), nil)
+		assert.Equal(t, m.Confirm(1), nil)
+
+		done := make(chan error)
+		go func() { done <- m.Confirm(2) }()
+		<-started
+		m.Disconnect(1)
+		assert.Equal(t, m.Cancel(2), models.ErrTradeBusy)
+		assert.Equal(t, m.Offer(2, potions, 0), models.ErrTradeBusy)
+		release <- true
+
+		if err := <-done; !errors.Is(err, failure) {
+			t.Errorf("%s FAILED: got %v want %v", t.Name(), err, failure)
+		}
+		_, ok := m.Get(2)
+		assert.Equal(t, ok, false)
+		if failure == nil {
+			assert.Equal(t, events.last(2).Event, EventCompleted)
+		} else {
+			assert.Equal(t, events.last(2), Event{Event: EventCancelled, PlayerID: 1})
+		}
+	}
+}
+
+func TestRacingConfirms(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		m, _ := testManager()
+		var mu sync.Mutex
+		executed := 0
+		m.Execute = func(offer, counter models.TradeOffer) error {
+			mu.Lock()
+			defer mu.Unlock()
+			executed++
+			return nil
+		}
+		open(t, m, 1, 2)
+		assert.Equal(t, m.Offer(1, sword, 0), nil)
+		assert.Equal(t, m.Lock(1), nil)
+		assert.Equal(t, m.Lock(2), nil)
+
+		var wg sync.WaitGroup
+		for _, ID := range []int{1, 2, 1, 2} {
+			wg.Add(1)
+			go func(ID int) {
+				defer wg.Done()
+				_ = m.Confirm(ID)
+			}(ID)
+		}
+		wg.Wait()
+		assert.Equal(t, executed, 1)
+	}
+}
+
+type inventoryRepo struct {
+	Repository
+	inventory []*models.InventorySlot
+}
+
+func (r inventoryRepo) GetInventory(ctx context.Context, playerID int) ([]*models.InventorySlot, error) {
+	return r.inventory, nil
+}
+
+func TestOfferNotTradable(t *testing.T) {
+	catalog, err := items.Load(strings.NewReader(`{"items": [
+		{"id": "iron_sword", "type": "weapon", "rarity": "common", "stack_size": 1},
+		{"id": "relic", "type": "quest", "rarity": "common", "stack_size": 1}
+	]}`))
+	assert.Equal(t, err, nil)
+	defaults := items.Default
+	items.Default = catalog
+	defer func() { items.Default = defaults }()
+
+	repo := inventoryRepo{inventory: []*models.InventorySlot{
+		{Slot: 0, ItemID: "iron_sword", Quantity: 1},
+		{Slot: 1, ItemID: "relic", Quantity: 1},
+	}}
+	var data offerData
+	assert.Equal(t, json.Unmarshal([]byte(`{"items": [{"slot": 0, "quantity": 1}]}`), &data), nil)
+	offered, err := offer(context.Background(), repo, 1, data)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, offered, sword)
+
+	assert.Equal(t, json.Unmarshal([]byte(`{"items": [{"slot": 0, "quantity": 1}, {"slot": 1, "quantity": 1}]}`), &data), nil)
+	_, err = offer(context.Background(), repo, 1, data)
+	assert.Equal(t, err, models.ErrNotTradable)
+}
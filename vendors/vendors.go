@@ -0,0 +1,268 @@
+package vendors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+	"tribble/data"
+	"tribble/items"
+	"tribble/models"
+	"tribble/settings"
+	"tribble/world"
+)
+
+// Offer is an item the vendor sells, at the item value unless a price is
+// given. Limited offers hold up to Stock items and get one back every
+// RestockSeconds.
+type Offer struct {
+	Item           string `json:"item"`
+	Price          int64  `json:"price"`
+	Stock          int    `json:"stock,omitempty"`
+	RestockSeconds int    `json:"restock_seconds,omitempty"`
+}
+
+func (o *Offer) Limited() bool {
+	return o.Stock > 0
+}
+
+type Vendor struct {
+	ID       string      `json:"id"`
+	Name     string      `json:"name"`
+	Map      string      `json:"map"`
+	Position world.Point `json:"position"`
+	Sells    []*Offer    `json:"sells"`
+	// Buys lists the item types the vendor buys back.
+	Buys []string `json:"buys,omitempty"`
+
+	sells map[string]*Offer
+}
+
+// SellbackPrice is what vendors pay for one item, a share of its value.
+// Items without value can't be sold.
+func SellbackPrice(item *models.Item) int64 {
+	if item.Value <= 0 || item.Type == items.TypeQuest {
+		return 0
+	}
+	price := int64(item.Value) * settings.VendorSellbackPercent / 100
+	if price < 1 {
+		price = 1
+	}
+	return price
+}
+
+// Restock returns the stock of a limited offer holding max items when full
+// after the time passed since its last restock, and when that restock
+// happened. A full offer starts counting again from now.
+func Restock(stock, max int, every time.Duration, since, now time.Time) (int, time.Time) {
+	if stock >= max {
+		return max, now
+	}
+	if every <= 0 || now.Before(since) {
+		return stock, since
+	}
+	n := int(now.Sub(since) / every)
+	if stock+n >= max {
+		return max, now
+	}
+	return stock + n, since.Add(time.Duration(n) * every)
+}
+
+func (v *Vendor) Offer(itemID string) (*Offer, bool) {
+	o, ok := v.sells[itemID]
+	return o, ok
+}
+
+func (v *Vendor) buys(item *models.Item) bool {
+	for _, t := range v.Buys {
+		if t == item.Type {
+			return SellbackPrice(item) > 0
+		}
+	}
+	return false
+}
+
+// InRange tells whether the player stands close enough to trade with the
+// vendor.
+func (v *Vendor) InRange(player *models.Player) bool {
+	if player.Map != v.Map {
+		return false
+	}
+	dx, dy := player.PositionX-v.Position.X, player.PositionY-v.Position.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx <= settings.VendorRange && dy <= settings.VendorRange
+}
+
+// Available returns the current stock of the limited offers given their
+// stored stock, offers never bought from are full.
+func (v *Vendor) Available(stored []*models.VendorStock, now time.Time) map[string]int {
+	byItem := make(map[string]*models.VendorStock, len(stored))
+	for _, s := range stored {
+		byItem[s.ItemID] = s
+	}
+	available := make(map[string]int)
+	for _, o := range v.Sells {
+		if !o.Limited() {
+			continue
+		}
+		available[o.Item] = o.Stock
+		if s, ok := byItem[o.Item]; ok {
+			available[o.Item], _ = Restock(s.Stock, o.Stock, o.restock(), s.RestockedAt, now)
+		}
+	}
+	return available
+}
+
+func (o *Offer) restock() time.Duration {
+	return time.Duration(o.RestockSeconds) * time.Second
+}
+
+// BuyOrder prices quantity items bought from the vendor.
+func (v *Vendor) BuyOrder(itemID string, quantity int) (models.VendorOrder, error) {
+	o, ok := v.Offer(itemID)
+	if !ok {
+		return models.VendorOrder{}, models.ErrNotSold
+	}
+	return models.VendorOrder{
+		VendorID: v.ID,
+		ItemID:   o.Item,
+		Quantity: quantity,
+		Price:    o.Price,
+		Stock:    o.Stock,
+		Restock:  o.restock(),
+	}, nil
+}
+
+// SellOrder prices quantity items of the slot sold to the vendor.
+func (v *Vendor) SellOrder(slot *models.InventorySlot, quantity int) (models.VendorOrder, error) {
+	item, ok := items.Default.Get(slot.ItemID)
+	if !ok || !v.buys(item) {
+		return models.VendorOrder{}, models.ErrNotBought
+	}
+	if slot.Quantity < quantity {
+		return models.VendorOrder{}, models.ErrNotEnoughItems
+	}
+	return models.VendorOrder{
+		VendorID: v.ID,
+		ItemID:   item.ID,
+		Slot:     slot.Slot,
+		Quantity: quantity,
+		Price:    SellbackPrice(item),
+	}, nil
+}
+
+func (v *Vendor) validate() error {
+	m, ok := world.Maps.Get(v.Map)
+	if !ok {
+		return fmt.Errorf("vendor %v is on unknown map %v", v.ID, v.Map)
+	}
+	if !m.Walkable(v.Position.X, v.Position.Y) {
+		return fmt.Errorf("vendor %v stands on a blocked tile", v.ID)
+	}
+	if len(v.Sells) == 0 && len(v.Buys) == 0 {
+		return fmt.Errorf("vendor %v neither sells nor buys", v.ID)
+	}
+	v.sells = make(map[string]*Offer, len(v.Sells))
+	for _, o := range v.Sells {
+		item, ok := items.Default.Get(o.Item)
+		if !ok {
+			return fmt.Errorf("vendor %v sells unknown item %v", v.ID, o.Item)
+		}
+		if _, ok = v.sells[o.Item]; ok {
+			return fmt.Errorf("vendor %v sells %v twice", v.ID, o.Item)
+		}
+		if o.Price == 0 {
+			o.Price = int64(item.Value)
+		}
+		if o.Price < 1 {
+			return fmt.Errorf("vendor %v sells %v without price", v.ID, o.Item)
+		}
+		if o.Stock < 0 || o.RestockSeconds < 0 || (o.RestockSeconds > 0 && !o.Limited()) {
+			return fmt.Errorf("vendor %v has an invalid %v stock", v.ID, o.Item)
+		}
+		// bought back for more than sold would print currency
+		if v.buys(item) && SellbackPrice(item) > o.Price {
+			return fmt.Errorf("vendor %v buys %v back above its price", v.ID, o.Item)
+		}
+		v.sells[o.Item] = o
+	}
+	for _, t := range v.Buys {
+		if !items.ValidType(t) {
+			return fmt.Errorf("vendor %v buys invalid type %v", v.ID, t)
+		}
+	}
+	return nil
+}
+
+type Catalog struct {
+	vendors []*Vendor
+	byID    map[string]*Vendor
+}
+
+func Load(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Vendors []*Vendor `json:"vendors"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Vendors) == 0 {
+		return nil, errors.New("vendor catalog is empty")
+	}
+
+	catalog := &Catalog{vendors: file.Vendors, byID: make(map[string]*Vendor)}
+	for _, v := range file.Vendors {
+		if v.ID == "" {
+			return nil, errors.New("vendor without id")
+		}
+		if _, ok := catalog.byID[v.ID]; ok {
+			return nil, fmt.Errorf("duplicated vendor %v", v.ID)
+		}
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+		catalog.byID[v.ID] = v
+	}
+	return catalog, nil
+}
+
+func (c *Catalog) Get(ID string) (*Vendor, bool) {
+	v, ok := c.byID[ID]
+	return v, ok
+}
+
+// List returns the vendors of the map, or every vendor when map is empty.
+func (c *Catalog) List(mapName string) []*Vendor {
+	if mapName == "" {
+		return c.vendors
+	}
+	vendors := make([]*Vendor, 0)
+	for _, v := range c.vendors {
+		if v.Map == mapName {
+			vendors = append(vendors, v)
+		}
+	}
+	return vendors
+}
+
+var Default = mustLoad()
+
+func mustLoad() *Catalog {
+	f, err := data.Open("vendors.json", settings.VendorCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open vendor catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := Load(f)
+	if err != nil {
+		log.Fatalf("Unable to load vendor catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,81 @@
+package vendors
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"tribble/models"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	v, ok := Default.Get("village_merchant")
+	assert.Equal(t, ok, true)
+	potion, _ := v.Offer("health_potion")
+	assert.Equal(t, potion.Price, int64(10))
+	assert.Equal(t, len(Default.List("forest")), 1)
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown map":   `{"vendors": [{"id": "a", "map": "moon", "sells": [{"item": "bread"}]}]}`,
+		"unknown item":  `{"vendors": [{"id": "a", "map": "village", "position": {"x": 12, "y": 10}, "sells": [{"item": "gold"}]}]}`,
+		"blocked tile":  `{"vendors": [{"id": "a", "map": "village", "sells": [{"item": "bread"}]}]}`,
+		"restock only":  `{"vendors": [{"id": "a", "map": "village", "position": {"x": 12, "y": 10}, "sells": [{"item": "bread", "restock_seconds": 5}]}]}`,
+		"invalid type":  `{"vendors": [{"id": "a", "map": "village", "position": {"x": 12, "y": 10}, "buys": ["gems"]}]}`,
+		"sellback loop": `{"vendors": [{"id": "a", "map": "village", "position": {"x": 12, "y": 10}, "sells": [{"item": "templar_plate", "price": 100}], "buys": ["armor"]}]}`,
+	}
+	for name, file := range tests {
+		if _, err := Load(strings.NewReader(file)); err == nil {
+			t.Errorf("%s FAILED: %s want error", t.Name(), name)
+		}
+	}
+}
+
+func TestOrders(t *testing.T) {
+	v, _ := Default.Get("forest_trader")
+	_, err := v.BuyOrder("iron_sword", 1)
+	assert.Equal(t, err, models.ErrNotSold)
+	order, err := v.BuyOrder("short_bow", 1)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, order, models.VendorOrder{VendorID: "forest_trader", ItemID: "short_bow", Quantity: 1, Price: 35, Stock: 1, Restock: 30 * time.Minute})
+
+	_, err = v.SellOrder(&models.InventorySlot{Slot: 2, ItemID: "bread", Quantity: 5}, 1)
+	assert.Equal(t, err, models.ErrNotBought)
+	_, err = v.SellOrder(&models.InventorySlot{Slot: 2, ItemID: "iron_ore", Quantity: 5}, 6)
+	assert.Equal(t, err, models.ErrNotEnoughItems)
+	order, err = v.SellOrder(&models.InventorySlot{Slot: 2, ItemID: "iron_ore", Quantity: 5}, 5)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, order, models.VendorOrder{VendorID: "forest_trader", ItemID: "iron_ore", Slot: 2, Quantity: 5, Price: 2})
+	// cheap items still sell for something
+	order, _ = v.SellOrder(&models.InventorySlot{ItemID: "goblin_ear", Quantity: 1}, 1)
+	assert.Equal(t, order.Price, int64(1))
+}
+
+func TestRestock(t *testing.T) {
+	since := time.Unix(0, 0)
+	every := time.Minute
+	stock, at := Restock(1, 5, every, since, since.Add(150*time.Second))
+	assert.Equal(t, stock, 3)
+	assert.Equal(t, at, since.Add(2*time.Minute))
+
+	now := since.Add(time.Hour)
+	stock, at = Restock(1, 5, every, since, now)
+	assert.Equal(t, stock, 5)
+	assert.Equal(t, at, now)
+
+	stock, at = Restock(0, 5, 0, since, now)
+	assert.Equal(t, stock, 0)
+	assert.Equal(t, at, since)
+}
+
+func TestInRange(t *testing.T) {
+	v, _ := Default.Get("village_merchant")
+	assert.Equal(t, v.InRange(&models.Player{Map: "village", PositionX: 17, PositionY: 5}), true)
+	assert.Equal(t, v.InRange(&models.Player{Map: "village", PositionX: 18, PositionY: 10}), false)
+	assert.Equal(t, v.InRange(&models.Player{Map: "forest", PositionX: 12, PositionY: 10}), false)
+
+	available := v.Available([]*models.VendorStock{{ItemID: "dagger", Stock: 0, RestockedAt: time.Unix(0, 0)}}, time.Unix(600, 0))
+	assert.Equal(t, available, map[string]int{"dagger": 1, "iron_sword": 3, "leather_cap": 2, "leather_armor": 2})
+}
@@ -0,0 +1,137 @@
+package world
+
+type Kind string
+
+const (
+	KindPlayer  Kind = "player"
+	KindMonster Kind = "monster"
+)
+
+// Ref identifies an entity inside a map regardless of its kind,
+// players and monsters may share numeric IDs.
+type Ref struct {
+	Kind Kind `json:"kind"`
+	ID   int  `json:"id"`
+}
+
+type Entity struct {
+	Ref
+	PositionX int `json:"position_x"`
+	PositionY int `json:"position_y"`
+}
+
+type cell struct {
+	x, y int
+}
+
+// Grid is a uniform spatial hash. Entities are bucketed by the cell that
+// contains their position so range queries only touch nearby buckets.
+type Grid struct {
+	size     int
+	cells    map[cell]map[Ref]*Entity
+	entities map[Ref]*Entity
+}
+
+func NewGrid(cellSize int) *Grid {
+	if cellSize < 1 {
+		cellSize = 1
+	}
+	return &Grid{
+		size:     cellSize,
+		cells:    make(map[cell]map[Ref]*Entity),
+		entities: make(map[Ref]*Entity),
+	}
+}
+
+func (g *Grid) cellOf(x, y int) cell {
+	return cell{floorDiv(x, g.size), floorDiv(y, g.size)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+func (g *Grid) Len() int {
+	return len(g.entities)
+}
+
+func (g *Grid) Get(ref Ref) (Entity, bool) {
+	e, ok := g.entities[ref]
+	if !ok {
+		return Entity{}, false
+	}
+	return *e, true
+}
+
+// Set inserts the entity or moves it to its new position.
+func (g *Grid) Set(entity Entity) {
+	if e, ok := g.entities[entity.Ref]; ok {
+		from, to := g.cellOf(e.PositionX, e.PositionY), g.cellOf(entity.PositionX, entity.PositionY)
+		e.PositionX, e.PositionY = entity.PositionX, entity.PositionY
+		if from != to {
+			g.unlink(from, e.Ref)
+			g.link(to, e)
+		}
+		return
+	}
+	e := entity
+	g.entities[e.Ref] = &e
+	g.link(g.cellOf(e.PositionX, e.PositionY), &e)
+}
+
+func (g *Grid) Remove(ref Ref) bool {
+	e, ok := g.entities[ref]
+	if !ok {
+		return false
+	}
+	g.unlink(g.cellOf(e.PositionX, e.PositionY), ref)
+	delete(g.entities, ref)
+	return true
+}
+
+func (g *Grid) link(c cell, e *Entity) {
+	bucket, ok := g.cells[c]
+	if !ok {
+		bucket = make(map[Ref]*Entity)
+		g.cells[c] = bucket
+	}
+	bucket[e.Ref] = e
+}
+
+func (g *Grid) unlink(c cell, ref Ref) {
+	bucket := g.cells[c]
+	delete(bucket, ref)
+	if len(bucket) == 0 {
+		delete(g.cells, c)
+	}
+}
+
+// Query calls fn for every entity inside the square of the given radius
+// centered at (x, y), borders included.
+func (g *Grid) Query(x, y, radius int, fn func(Entity)) {
+	min, max := g.cellOf(x-radius, y-radius), g.cellOf(x+radius, y+radius)
+	for cx := min.x; cx <= max.x; cx++ {
+		for cy := min.y; cy <= max.y; cy++ {
+			for _, e := range g.cells[cell{cx, cy}] {
+				if within(e.PositionX, e.PositionY, x, y, radius) {
+					fn(*e)
+				}
+			}
+		}
+	}
+}
+
+func within(x1, y1, x2, y2, radius int) bool {
+	return abs(x1-x2) <= radius && abs(y1-y2) <= radius
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,224 @@
+package world
+
+import "sync"
+
+type EventType string
+
+const (
+	EventEnter  EventType = "enter"
+	EventLeave  EventType = "leave"
+	EventUpdate EventType = "update"
+)
+
+// Event is addressed to a single observer and describes a change of an
+// entity inside the observer's area of interest.
+type Event struct {
+	Type     EventType   `json:"type"`
+	Observer Ref         `json:"-"`
+	Entity   Entity      `json:"entity"`
+	Data     interface{} `json:"data,omitempty"`
+}
+
+// Space tracks the entities of a single map and the area of interest of
+// every observer (connected client) in it. Visibility is symmetric: an
+// entity is visible when it is inside the square of side 2*radius+1
+// centered at the observer.
+type Space struct {
+	mu        sync.Mutex
+	radius    int
+	grid      *Grid
+	observers map[Ref]map[Ref]struct{}
+}
+
+func NewSpace(radius int) *Space {
+	return &Space{
+		radius:    radius,
+		grid:      NewGrid(radius),
+		observers: make(map[Ref]map[Ref]struct{}),
+	}
+}
+
+func (s *Space) Radius() int {
+	return s.radius
+}
+
+func (s *Space) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.grid.Len()
+}
+
+func (s *Space) Get(ref Ref) (Entity, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.grid.Get(ref)
+}
+
+// Visible returns the entities currently inside the observer's area of interest.
+func (s *Space) Visible(observer Ref) []Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entities := make([]Entity, 0, len(s.observers[observer]))
+	for ref := range s.observers[observer] {
+		if e, ok := s.grid.Get(ref); ok {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// Nearby returns every entity inside the square of the given radius
+// centered at (x, y).
+func (s *Space) Nearby(x, y, radius int) []Entity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entities := make([]Entity, 0)
+	s.grid.Query(x, y, radius, func(e Entity) {
+		entities = append(entities, e)
+	})
+	return entities
+}
+
+// Join adds an entity to the space. Observers receive an enter event for
+// every entity already in range and are announced to nearby observers.
+func (s *Space) Join(entity Entity, observer bool) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.grid.Get(entity.Ref); ok {
+		return s.move(entity)
+	}
+	s.grid.Set(entity)
+
+	events := make([]Event, 0)
+	if observer {
+		visible := make(map[Ref]struct{})
+		s.grid.Query(entity.PositionX, entity.PositionY, s.radius, func(e Entity) {
+			if e.Ref == entity.Ref {
+				return
+			}
+			visible[e.Ref] = struct{}{}
+			events = append(events, Event{Type: EventEnter, Observer: entity.Ref, Entity: e})
+		})
+		s.observers[entity.Ref] = visible
+	}
+	s.grid.Query(entity.PositionX, entity.PositionY, s.radius, func(e Entity) {
+		visible, ok := s.observers[e.Ref]
+		if !ok || e.Ref == entity.Ref {
+			return
+		}
+		visible[entity.Ref] = struct{}{}
+		events = append(events, Event{Type: EventEnter, Observer: e.Ref, Entity: entity})
+	})
+	return events
+}
+
+// Move changes the position of an entity and returns the enter, leave and
+// update events caused by it.
+func (s *Space) Move(ref Ref, x, y int) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.move(Entity{ref, x, y})
+}
+
+func (s *Space) move(entity Entity) []Event {
+	old, ok := s.grid.Get(entity.Ref)
+	if !ok {
+		return nil
+	}
+	s.grid.Set(entity)
+
+	events := make([]Event, 0)
+	if visible, ok := s.observers[entity.Ref]; ok {
+		current := make(map[Ref]struct{}, len(visible))
+		s.grid.Query(entity.PositionX, entity.PositionY, s.radius, func(e Entity) {
+			if e.Ref == entity.Ref {
+				return
+			}
+			current[e.Ref] = struct{}{}
+			if _, seen := visible[e.Ref]; !seen {
+				events = append(events, Event{Type: EventEnter, Observer: entity.Ref, Entity: e})
+			}
+		})
+		for ref := range visible {
+			if _, ok := current[ref]; !ok {
+				e, _ := s.grid.Get(ref)
+				events = append(events, Event{Type: EventLeave, Observer: entity.Ref, Entity: e})
+			}
+		}
+		s.observers[entity.Ref] = current
+	}
+
+	// observers that could see the entity before or after the move are
+	// all within radius of either position
+	candidates := make(map[Ref]Entity)
+	collect := func(e Entity) {
+		if _, ok := s.observers[e.Ref]; ok && e.Ref != entity.Ref {
+			candidates[e.Ref] = e
+		}
+	}
+	s.grid.Query(old.PositionX, old.PositionY, s.radius, collect)
+	s.grid.Query(entity.PositionX, entity.PositionY, s.radius, collect)
+
+	for ref, o := range candidates {
+		visible := s.observers[ref]
+		_, was := visible[entity.Ref]
+		now := within(o.PositionX, o.PositionY, entity.PositionX, entity.PositionY, s.radius)
+		switch {
+		case was && now:
+			events = append(events, Event{Type: EventUpdate, Observer: ref, Entity: entity})
+		case !was && now:
+			visible[entity.Ref] = struct{}{}
+			events = append(events, Event{Type: EventEnter, Observer: ref, Entity: entity})
+		case was && !now:
+			delete(visible, entity.Ref)
+			events = append(events, Event{Type: EventLeave, Observer: ref, Entity: entity})
+		}
+	}
+	return events
+}
+
+// Touch notifies every observer that can see the entity that its state
+// changed without moving.
+func (s *Space) Touch(ref Ref) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entity, ok := s.grid.Get(ref)
+	if !ok {
+		return nil
+	}
+	events := make([]Event, 0)
+	s.grid.Query(entity.PositionX, entity.PositionY, s.radius, func(e Entity) {
+		if visible, ok := s.observers[e.Ref]; ok {
+			if _, seen := visible[ref]; seen {
+				events = append(events, Event{Type: EventUpdate, Observer: e.Ref, Entity: entity})
+			}
+		}
+	})
+	return events
+}
+
+// Leave removes an entity from the space, sending leave events to every
+// observer that could see it.
+func (s *Space) Leave(ref Ref) []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entity, ok := s.grid.Get(ref)
+	if !ok {
+		return nil
+	}
+	events := make([]Event, 0)
+	s.grid.Query(entity.PositionX, entity.PositionY, s.radius, func(e Entity) {
+		if visible, ok := s.observers[e.Ref]; ok {
+			if _, seen := visible[ref]; seen {
+				delete(visible, ref)
+				events = append(events, Event{Type: EventLeave, Observer: e.Ref, Entity: entity})
+			}
+		}
+	})
+	delete(s.observers, ref)
+	s.grid.Remove(ref)
+	return events
+}
@@ -0,0 +1,176 @@
+package world
+
+import (
+	"math/rand"
+	"testing"
+
+	"gopkg.in/go-playground/assert.v1"
+)
+
+var (
+	frodo  = Ref{KindPlayer, 1}
+	sam    = Ref{KindPlayer, 2}
+	goblin = Ref{KindMonster, 1}
+)
+
+func eventsFor(events []Event, observer Ref) map[Ref]EventType {
+	result := make(map[Ref]EventType)
+	for _, e := range events {
+		if e.Observer == observer {
+			result[e.Entity.Ref] = e.Type
+		}
+	}
+	return result
+}
+
+func TestJoinSendsEnterBothWays(t *testing.T) {
+	s := NewSpace(5)
+	s.Join(Entity{frodo, 0, 0}, true)
+	events := s.Join(Entity{sam, 5, -5}, true)
+
+	assert.Equal(t, len(events), 2)
+	assert.Equal(t, eventsFor(events, frodo)[sam], EventEnter)
+	assert.Equal(t, eventsFor(events, sam)[frodo], EventEnter)
+}
+
+func TestJoinOutOfRange(t *testing.T) {
+	s := NewSpace(5)
+	s.Join(Entity{frodo, 0, 0}, true)
+	events := s.Join(Entity{sam, 6, 0}, true)
+
+	if len(events) != 0 {
+		t.Errorf("%s FAILED: want no events got %v", t.Name(), events)
+	}
+	assert.Equal(t, len(s.Visible(frodo)), 0)
+}
+
+func TestMoveAcrossBoundary(t *testing.T) {
+	s := NewSpace(5)
+	s.Join(Entity{frodo, 0, 0}, true)
+	s.Join(Entity{goblin, 6, 0}, false)
+	assert.Equal(t, len(s.Visible(frodo)), 0)
+
+	// stepping onto the border enters the area of interest
+	events := s.Move(goblin, 5, 0)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventEnter)
+
+	events = s.Move(goblin, 4, 5)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventUpdate)
+
+	events = s.Move(goblin, 4, 6)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventLeave)
+	assert.Equal(t, len(s.Visible(frodo)), 0)
+}
+
+func TestObserverMoveAcrossBoundary(t *testing.T) {
+	s := NewSpace(5)
+	s.Join(Entity{frodo, 0, 0}, true)
+	s.Join(Entity{sam, -5, 0}, true)
+	s.Join(Entity{goblin, 10, 0}, false)
+
+	events := s.Move(frodo, 5, 0)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventEnter)
+	assert.Equal(t, eventsFor(events, frodo)[sam], EventLeave)
+	assert.Equal(t, eventsFor(events, sam)[frodo], EventLeave)
+	assert.Equal(t, len(events), 3)
+
+	visible := s.Visible(frodo)
+	assert.Equal(t, len(visible), 1)
+	assert.Equal(t, visible[0].Ref, goblin)
+}
+
+func TestMoveAcrossNegativeCells(t *testing.T) {
+	s := NewSpace(3)
+	s.Join(Entity{frodo, -1, -1}, true)
+	s.Join(Entity{goblin, -5, -1}, false)
+
+	events := s.Move(goblin, -4, -1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventEnter)
+
+	events = s.Move(goblin, 2, 2)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventUpdate)
+}
+
+func TestTouchAndLeave(t *testing.T) {
+	s := NewSpace(5)
+	s.Join(Entity{frodo, 0, 0}, true)
+	s.Join(Entity{sam, 20, 0}, true)
+	s.Join(Entity{goblin, 3, 3}, false)
+
+	events := s.Touch(goblin)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventUpdate)
+
+	events = s.Leave(goblin)
+	assert.Equal(t, len(events), 1)
+	assert.Equal(t, eventsFor(events, frodo)[goblin], EventLeave)
+	assert.Equal(t, s.Len(), 2)
+
+	if events := s.Move(goblin, 1, 1); events != nil {
+		t.Errorf("%s FAILED: want nil got %v", t.Name(), events)
+	}
+}
+
+func TestWorldSpaces(t *testing.T) {
+	w := New(5)
+	w.Space("forest").Join(Entity{frodo, 0, 0}, true)
+	events := w.Space("village").Join(Entity{sam, 0, 0}, true)
+
+	assert.Equal(t, len(events), 0)
+	assert.Equal(t, w.Space("forest").Len(), 1)
+	assert.Equal(t, w.Space("village").Len(), 1)
+}
+
+func populate(b *testing.B, entities, observers, size int) *Space {
+	b.Helper()
+	r := rand.New(rand.NewSource(1))
+	s := NewSpace(12)
+	for i := 0; i < entities; i++ {
+		kind := KindMonster
+		if i < observers {
+			kind = KindPlayer
+		}
+		s.Join(Entity{Ref{kind, i}, r.Intn(size), r.Intn(size)}, kind == KindPlayer)
+	}
+	return s
+}
+
+func benchmarkMove(b *testing.B, entities, observers int) {
+	s := populate(b, entities, observers, 1000)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ref := Ref{KindMonster, observers + r.Intn(entities-observers)}
+		e, _ := s.Get(ref)
+		s.Move(ref, e.PositionX+r.Intn(3)-1, e.PositionY+r.Intn(3)-1)
+	}
+}
+
+func BenchmarkMove1000(b *testing.B)  { benchmarkMove(b, 1000, 200) }
+func BenchmarkMove5000(b *testing.B)  { benchmarkMove(b, 5000, 1000) }
+func BenchmarkMove20000(b *testing.B) { benchmarkMove(b, 20000, 4000) }
+
+func BenchmarkObserverMove5000(b *testing.B) {
+	s := populate(b, 5000, 1000, 1000)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ref := Ref{KindPlayer, r.Intn(1000)}
+		e, _ := s.Get(ref)
+		s.Move(ref, e.PositionX+r.Intn(3)-1, e.PositionY+r.Intn(3)-1)
+	}
+}
+
+func BenchmarkJoinLeave5000(b *testing.B) {
+	s := populate(b, 5000, 1000, 1000)
+	r := rand.New(rand.NewSource(2))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ref := Ref{KindPlayer, 5000 + i}
+		s.Join(Entity{ref, r.Intn(1000), r.Intn(1000)}, true)
+		s.Leave(ref)
+	}
+}
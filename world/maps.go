@@ -0,0 +1,228 @@
+package world
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"tribble/data"
+	"tribble/settings"
+)
+
+type Point struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type Area struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Spawn keeps count monsters of a kind alive inside the area.
+type Spawn struct {
+	Monster string `json:"monster"`
+	Count   int    `json:"count"`
+	Area    Area   `json:"area"`
+}
+
+func (a Area) Contains(x, y int) bool {
+	return x >= a.X && y >= a.Y && x < a.X+a.Width && y < a.Y+a.Height
+}
+
+// Landmark is a named place of a map that players can be sent to visit.
+type Landmark struct {
+	Name string `json:"name"`
+	Area Area   `json:"area"`
+}
+
+type Map struct {
+	Name      string     `json:"name"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Spawn     Point      `json:"spawn"`
+	Spawns    []Spawn    `json:"spawns,omitempty"`
+	Landmarks []Landmark `json:"landmarks,omitempty"`
+	// Collision names the file of the collision layer, one line per row
+	// where '#' marks a blocked tile and '.' a walkable one.
+	Collision string `json:"collision,omitempty"`
+
+	blocked []bool
+}
+
+func (m *Map) Contains(x, y int) bool {
+	return x >= 0 && y >= 0 && x < m.Width && y < m.Height
+}
+
+func (m *Map) Walkable(x, y int) bool {
+	if !m.Contains(x, y) {
+		return false
+	}
+	return m.blocked == nil || !m.blocked[y*m.Width+x]
+}
+
+// LandmarkAt returns the name of the landmark at the tile, if any.
+func (m *Map) LandmarkAt(x, y int) string {
+	for _, l := range m.Landmarks {
+		if l.Area.Contains(x, y) {
+			return l.Name
+		}
+	}
+	return ""
+}
+
+func (m *Map) Landmark(name string) (*Landmark, bool) {
+	for i := range m.Landmarks {
+		if m.Landmarks[i].Name == name {
+			return &m.Landmarks[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetCollision parses the collision layer of the map.
+func (m *Map) SetCollision(r io.Reader) error {
+	blocked := make([]bool, 0, m.Width*m.Height)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for row := 0; scanner.Scan(); row++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+		if len(line) != m.Width {
+			return fmt.Errorf("map %v collision row %d must have %d tiles", m.Name, row, m.Width)
+		}
+		for _, tile := range line {
+			switch tile {
+			case '.':
+				blocked = append(blocked, false)
+			case '#':
+				blocked = append(blocked, true)
+			default:
+				return fmt.Errorf("map %v collision has invalid tile %q", m.Name, tile)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(blocked) != m.Width*m.Height {
+		return fmt.Errorf("map %v collision must have %d rows", m.Name, m.Height)
+	}
+	m.blocked = blocked
+	return nil
+}
+
+func (a Area) walkable(m *Map) bool {
+	for y := a.Y; y < a.Y+a.Height; y++ {
+		for x := a.X; x < a.X+a.Width; x++ {
+			if m.Walkable(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type MapCatalog struct {
+	maps   []*Map
+	byName map[string]*Map
+}
+
+// LoadMaps reads the map catalog, collision layers are read with open.
+func LoadMaps(r io.Reader, open func(name string) (io.ReadCloser, error)) (*MapCatalog, error) {
+	var file struct {
+		Maps []*Map `json:"maps"`
+	}
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	if len(file.Maps) == 0 {
+		return nil, errors.New("map catalog is empty")
+	}
+
+	catalog := &MapCatalog{maps: file.Maps, byName: make(map[string]*Map)}
+	for _, m := range file.Maps {
+		if m.Name == "" {
+			return nil, errors.New("map without name")
+		}
+		if _, ok := catalog.byName[m.Name]; ok {
+			return nil, fmt.Errorf("duplicated map %v", m.Name)
+		}
+		if m.Collision != "" {
+			if err := loadCollision(m, open); err != nil {
+				return nil, err
+			}
+		}
+		if !m.Walkable(m.Spawn.X, m.Spawn.Y) {
+			return nil, fmt.Errorf("map %v spawn point is not walkable", m.Name)
+		}
+		for _, spawn := range m.Spawns {
+			a := spawn.Area
+			if spawn.Count < 1 || a.Width < 1 || a.Height < 1 ||
+				!m.Contains(a.X, a.Y) || !m.Contains(a.X+a.Width-1, a.Y+a.Height-1) || !a.walkable(m) {
+				return nil, fmt.Errorf("map %v has an invalid %v spawn region", m.Name, spawn.Monster)
+			}
+		}
+		landmarks := make(map[string]bool)
+		for _, l := range m.Landmarks {
+			if l.Name == "" || landmarks[l.Name] || l.Area.Width < 1 || l.Area.Height < 1 || !l.Area.walkable(m) {
+				return nil, fmt.Errorf("map %v has an invalid landmark %q", m.Name, l.Name)
+			}
+			landmarks[l.Name] = true
+		}
+		catalog.byName[m.Name] = m
+	}
+	return catalog, nil
+}
+
+func loadCollision(m *Map, open func(name string) (io.ReadCloser, error)) error {
+	f, err := open(m.Collision)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return m.SetCollision(f)
+}
+
+func (c *MapCatalog) Get(name string) (*Map, bool) {
+	m, ok := c.byName[name]
+	return m, ok
+}
+
+// Start is the map where new players are created.
+func (c *MapCatalog) Start() *Map {
+	return c.maps[0]
+}
+
+func (c *MapCatalog) List() []*Map {
+	return c.maps
+}
+
+var Maps = mustLoadMaps()
+
+func mustLoadMaps() *MapCatalog {
+	f, err := data.Open("maps.json", settings.MapCatalogFile)
+	if err != nil {
+		log.Fatalf("Unable to open map catalog: %v", err)
+	}
+	defer f.Close()
+	catalog, err := LoadMaps(f, func(name string) (io.ReadCloser, error) {
+		if settings.MapCatalogFile != "" {
+			return os.Open(filepath.Join(filepath.Dir(settings.MapCatalogFile), name))
+		}
+		return data.Files.Open(name)
+	})
+	if err != nil {
+		log.Fatalf("Unable to load map catalog: %v", err)
+	}
+	return catalog
+}
@@ -0,0 +1,34 @@
+package world
+
+import (
+	"sync"
+	"tribble/settings"
+)
+
+// Spaces is the world shared by the game server.
+var Spaces = New(settings.InterestRadius)
+
+// World holds one Space per map, created on first use.
+type World struct {
+	mu     sync.Mutex
+	radius int
+	spaces map[string]*Space
+}
+
+func New(radius int) *World {
+	return &World{
+		radius: radius,
+		spaces: make(map[string]*Space),
+	}
+}
+
+func (w *World) Space(mapName string) *Space {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	space, ok := w.spaces[mapName]
+	if !ok {
+		space = NewSpace(w.radius)
+		w.spaces[mapName] = space
+	}
+	return space
+}